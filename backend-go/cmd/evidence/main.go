@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	evidenceuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/evidence"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+// Tamper-evidence of admin logs, audit runs and script results, the same as
+// GET /api/admin/audit/verify and POST /api/admin/audit/checkpoints:
+//
+//	evidence verify [-chain admin_action_logs|audit_runs|audit_script_results]
+//	evidence checkpoint
+//
+// verify exits with code 1 when a chain was tampered with and 2 when it is
+// intact but has checkpoints signed with keys that are not trusted.
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("usage: evidence verify|checkpoint [flags]")
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	chain := fs.String("chain", "", "verify only this chain")
+	_ = fs.Parse(os.Args[2:])
+
+	cfg := config.LoadConfig()
+	secret := cfg.JWTSecret
+	if secret == config.DefaultJWTSecret {
+		secret = ""
+	}
+	signer, err := security.LoadEvidenceSigner(cfg.EvidenceSigningKeyID, cfg.EvidenceSigningKey, cfg.EvidenceTrustedKeys, secret)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	db, err := config.NewGormDB(cfg)
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	uc := evidenceuc.NewEvidenceChainUseCase(repositories.NewGormEvidenceChainRepository(db), signer, cfg.EvidenceCheckpointInterval)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	switch cmd {
+	case "verify":
+		report, err := uc.Verify(*chain)
+		if err != nil {
+			log.Fatalf("verify failed: %v", err)
+		}
+		_ = enc.Encode(report)
+		switch report.Status {
+		case evidenceuc.EvidenceTampered:
+			os.Exit(1)
+		case evidenceuc.EvidenceUnverifiable:
+			os.Exit(2)
+		}
+	case "checkpoint":
+		created, err := uc.Checkpoint()
+		if err != nil {
+			log.Fatalf("checkpoint failed: %v", err)
+		}
+		_ = enc.Encode(created)
+	default:
+		log.Fatalf("unknown command %q (verify or checkpoint)", cmd)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"log"
 	"time"
 
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlite/migrations"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 	repoport "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/sqlite"
@@ -143,7 +145,15 @@ func main() {
 	}
 
 	// Seed default roles and permissions in destination DB as part of migration
-	if err := repositories.SeedDefaultRolesAndPermissions(dstDB); err != nil {
+	policyDoc, err := config.LoadRBACPolicy(cfg.RBACPolicyFile)
+	if err != nil {
+		log.Fatalf("failed to read RBAC policy: %v", err)
+	}
+	policy, err := useruc.ParsePolicy(policyDoc)
+	if err != nil {
+		log.Fatalf("failed to parse RBAC policy: %v", err)
+	}
+	if err := useruc.NewRBACPolicyUseCase(repositories.NewGormRoleRepository(dstDB), repositories.NewGormPermissionRepository(dstDB), nil).Seed(policy); err != nil {
 		log.Fatalf("failed to seed roles/permissions on destination DB: %v", err)
 	}
 
@@ -185,6 +195,16 @@ func main() {
 		}
 		usersCopied = len(goUsers)
 		log.Printf("✓ Migrated %d users\n", usersCopied)
+
+		// roles live in user_roles; the Django role column only seeds them
+		backfilled, orphaned, err := migrations.BackfillUserRoles(dstDB)
+		if err != nil {
+			log.Fatalf("failed to backfill user roles: %v", err)
+		}
+		log.Printf("✓ Backfilled %d user role assignments\n", backfilled)
+		if len(orphaned) > 0 {
+			log.Printf("warning: %d users have a role that does not exist and got no assignment: %v\n", len(orphaned), orphaned)
+		}
 	}
 
 	// 2. Migrate ActiveConnections
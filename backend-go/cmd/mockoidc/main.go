@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc/oidctest"
+)
+
+// Local OpenID Connect provider for trying SSO without a real IdP. Every
+// authorization request signs in the user given by the flags, without a login page.
+// Point the backend at it with OIDC_ISSUER=<issuer> OIDC_CLIENT_ID=<client-id>.
+func main() {
+	addr := flag.String("addr", ":9400", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9400", "issuer URL as seen by the backend and the browser")
+	clientID := flag.String("client-id", "microsql", "accepted client_id")
+	clientSecret := flag.String("client-secret", "", "required client secret (empty for a public client)")
+	sub := flag.String("sub", "mock-user", "subject of the signed-in user")
+	email := flag.String("email", "mock@example.com", "email of the signed-in user")
+	username := flag.String("username", "mock", "preferred_username of the signed-in user")
+	groups := flag.String("groups", "", "comma-separated groups of the signed-in user")
+	flag.Parse()
+
+	p := oidctest.New(strings.TrimRight(*issuer, "/"), *clientID)
+	p.ClientSecret = *clientSecret
+	var gs []string
+	for _, g := range strings.Split(*groups, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			gs = append(gs, g)
+		}
+	}
+	p.SetUser(oidctest.User{Subject: *sub, Email: *email, EmailVerified: true, Username: *username, Groups: gs})
+
+	log.Printf("mock OIDC provider %s for client %q on %s (user %q, groups %v)", p.Issuer, *clientID, *addr, *username, gs)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+// Roles and permissions as code, the same as /api/admin/rbac/export and /import:
+//
+//	rbac export [-format yaml|json]       print the current policy
+//	rbac plan  -f policy.yaml [-prune]    show the changes the policy would make
+//	rbac apply -f policy.yaml [-prune]    make them
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("usage: rbac export|plan|apply [flags]")
+	}
+	cmd := os.Args[1]
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	file := fs.String("f", "", "policy file (YAML or JSON); - reads stdin")
+	prune := fs.Bool("prune", false, "also delete roles and permissions the policy does not declare")
+	format := fs.String("format", "yaml", "export format: yaml or json")
+	_ = fs.Parse(os.Args[2:])
+
+	cfg := config.LoadConfig()
+	db, err := config.NewGormDB(cfg)
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	roleRepo := repositories.NewGormRoleRepository(db)
+	assignments := useruc.NewRoleAssignmentsUseCase(persistence.NewUserRepository(db), roleRepo, repositories.NewGormSessionRepository(db), nil)
+	uc := useruc.NewRBACPolicyUseCase(roleRepo, repositories.NewGormPermissionRepository(db), repositories.NewGormResourceScopeRepository(db)).
+		WithAssignments(assignments).
+		WithTransactions(repositories.NewGormRBACTransactor(db))
+
+	switch cmd {
+	case "export":
+		policy, err := uc.Export()
+		if err != nil {
+			log.Fatalf("export failed: %v", err)
+		}
+		out, err := useruc.MarshalPolicy(policy, *format)
+		if err != nil {
+			log.Fatalf("export failed: %v", err)
+		}
+		os.Stdout.Write(out)
+	case "plan", "apply":
+		if *file == "" {
+			log.Fatalf("%s needs -f <policy file>", cmd)
+		}
+		var data []byte
+		if *file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(*file)
+		}
+		if err != nil {
+			log.Fatalf("failed to read policy: %v", err)
+		}
+		policy, err := useruc.ParsePolicy(data)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		var plan *useruc.PolicyPlan
+		if cmd == "plan" {
+			plan, err = uc.Plan(policy, *prune)
+		} else {
+			plan, err = uc.Apply(policy, *prune)
+			if plan != nil && len(plan.Changes) > 0 {
+				details := plan.Summary()
+				if *prune {
+					details += " prune=true"
+				}
+				if err != nil {
+					details += " (failed: " + err.Error() + ")"
+				}
+				entry := &entities.AdminActionLog{ActorName: "cli", Action: "rbac.import", TargetType: "policy", Details: details}
+				if logErr := repositories.NewGormAdminAuditRepository(db).Create(entry); logErr != nil {
+					log.Printf("warning: failed to record audit log: %v", logErr)
+				}
+			}
+		}
+		if plan != nil {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(plan)
+			fmt.Fprintln(os.Stderr, plan.Summary())
+		}
+		if err != nil {
+			log.Fatalf("%s failed: %v", cmd, err)
+		}
+	default:
+		log.Fatalf("unknown command %q (export, plan or apply)", cmd)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/encryption"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	connectionuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/connection"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+// Re-encrypts active_connections passwords with the current key (ENCRYPTION_KEY_ID).
+// Run after adding a new key to ENCRYPTION_KEYS; once it reports failed=0 the old key can be removed.
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report how many passwords would be re-encrypted")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	keyring, err := encryption.ParseKeyring(cfg.EncKeyID, cfg.EncKeys, cfg.EncKey)
+	if err != nil {
+		log.Fatalf("invalid keyring configuration: %v", err)
+	}
+
+	db, err := config.NewGormDB(cfg)
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+
+	uc := connectionuc.NewRotateCredentialsUseCase(repositories.NewGormConnectionRepository(db), keyring)
+	report, err := uc.Execute(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalf("rotation failed: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
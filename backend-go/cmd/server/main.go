@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,6 +16,7 @@ import (
 	httpadp "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlite/migrations"
 	cfg "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/persistence"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
 	pkglog "github.com/yken-neky/MicroSQL-AGo/backend-go/pkg/utils"
@@ -32,22 +38,56 @@ func main() {
 		logger.Fatal("failed to run migrations", zap.Error(err))
 	}
 
-	// Seed initial roles & permissions (idempotent)
-	if err := repositories.SeedDefaultRolesAndPermissions(db); err != nil {
+	// Seed roles & permissions from the default RBAC policy (idempotent, never removes anything)
+	policyDoc, err := cfg.LoadRBACPolicy(cfgVal.RBACPolicyFile)
+	if err != nil {
+		logger.Fatal("failed reading RBAC policy", zap.Error(err))
+	}
+	policy, err := useruc.ParsePolicy(policyDoc)
+	if err != nil {
+		logger.Fatal("failed parsing RBAC policy", zap.Error(err))
+	}
+	if err := useruc.NewRBACPolicyUseCase(repositories.NewGormRoleRepository(db), repositories.NewGormPermissionRepository(db), nil).Seed(policy); err != nil {
 		logger.Fatal("failed seeding roles/permissions", zap.Error(err))
 	}
 
+	// user_roles is the only source of roles: copy legacy users.role values into it (idempotent)
+	backfilled, orphaned, err := migrations.BackfillUserRoles(db)
+	if err != nil {
+		logger.Fatal("failed backfilling user roles", zap.Error(err))
+	}
+	if backfilled > 0 {
+		logger.Info("backfilled user roles from users.role", zap.Int64("assignments", backfilled))
+	}
+	if len(orphaned) > 0 {
+		logger.Warn("users with a role that does not exist; they have no permissions", zap.Strings("users", orphaned))
+	}
+
 	// Attach a zap-backed GORM logger for structured SQL logging
 	// Use a conservative slow query threshold (200ms) and Info level.
 	gormLogger := persistence.NewZapGormLogger(logger, 200*time.Millisecond, gormlogger.Info)
 	db = db.Session(&gorm.Session{Logger: gormLogger})
 
+	// SIGINT/SIGTERM stop the background jobs and drain in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	r := gin.Default()
-	httpadp.RegisterRoutes(r, db, logger)
+	httpadp.RegisterRoutes(ctx, r, db, logger)
 
 	addr := fmt.Sprintf(":%s", cfgVal.ServerPort)
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("graceful shutdown failed", zap.Error(err))
+		}
+	}()
 	logger.Info("starting server", zap.String("addr", addr))
-	if err := r.Run(addr); err != nil {
-		log.Fatalf("server exited with: %v", err)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatal("server exited", zap.Error(err))
 	}
+	logger.Info("server stopped")
 }
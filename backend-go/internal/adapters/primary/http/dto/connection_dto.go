@@ -8,6 +8,14 @@ type ConnectRequestDTO struct {
 	Port     string `json:"port" binding:"required"`     // port e.g. "1433"
 	DBUser   string `json:"db_user" binding:"required"`  // DB username
 	Password string `json:"password" binding:"required"` // DB password (plain-text over TLS expected)
+
+	// Optional TLS settings; defaults keep the previous behaviour (encrypt, trust server cert)
+	Encrypt               string `json:"encrypt" binding:"omitempty,oneof=disable false true"`
+	ValidateCertificate   bool   `json:"validate_certificate"`
+	CACertificate         string `json:"ca_certificate"`           // PEM bundle used to validate the server
+	CertFingerprint       string `json:"cert_fingerprint"`         // pinned SHA-256 of the server certificate
+	HostNameInCertificate string `json:"host_name_in_certificate"` // expected CN/SAN when it differs from server
+	AppName               string `json:"app_name"`
 }
 
 // ConnectionResponseDTO shape returned to client (redact password)
@@ -17,8 +25,14 @@ type ConnectionResponseDTO struct {
 	Manager          string `json:"manager"`
 	Driver           string `json:"driver"`
 	Server           string `json:"server"`
+	Port             string `json:"port,omitempty"`
 	DBUser           string `json:"db_user"`
+	Encrypt          string `json:"encrypt,omitempty"`
+	ValidateCert     bool   `json:"validate_certificate"`
 	IsConnected      bool   `json:"is_connected"`
 	LastConnected    string `json:"last_connected"`
 	LastDisconnected string `json:"last_disconnected,omitempty"`
+	HealthStatus     string `json:"health_status,omitempty"` // unknown|healthy|unhealthy
+	LastHealthCheck  string `json:"last_health_check,omitempty"`
+	LatencyMs        int64  `json:"latency_ms"`
 }
@@ -4,6 +4,7 @@ package dto
 type ConnectionLogDTO struct {
 	ID        uint   `json:"id"`
 	UserID    uint   `json:"user_id"`
+	Manager   string `json:"manager"`
 	Driver    string `json:"driver"`
 	Server    string `json:"server"`
 	DBUser    string `json:"db_user"`
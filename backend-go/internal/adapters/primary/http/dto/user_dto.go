@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 // RegisterRequest represents payload for user registration
 type RegisterRequest struct {
 	Username  string `json:"username" binding:"required,min=3,max=150"`
@@ -13,6 +15,8 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Optional client-chosen label shown in the user's session list
+	Device string `json:"device,omitempty"`
 }
 
 // UserResponse represents user data in responses
@@ -29,6 +33,107 @@ type UserResponse struct {
 type LoginResponse struct {
 	Token string       `json:"token"`
 	User  UserResponse `json:"user"`
+	// Refresh token (login only); exchange it at POST /api/auth/refresh
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	ExpiresIn        int64  `json:"expires_in,omitempty"`         // access token lifetime in seconds
+	RefreshExpiresIn int64  `json:"refresh_expires_in,omitempty"` // refresh token lifetime in seconds
+	// Only when 2FA was just enabled during login; shown once
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// TwoFactorChallengeResponse replaces LoginResponse when a second factor is needed
+type TwoFactorChallengeResponse struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // role requires 2FA and the user has none yet
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+// TwoFactorChallengeRequest carries the challenge token returned by login
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// TwoFactorVerifyRequest completes a login with a TOTP code or a recovery code
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// TwoFactorConfirmRequest confirms an enrollment started with a challenge token
+type TwoFactorConfirmRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorCodeRequest carries a current TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorEnrollResponse returns the new secret; render provisioning_uri as a QR code
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RefreshRequest exchanges a refresh token for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshResponse returns the rotated token pair
+type RefreshResponse struct {
+	Token            string `json:"token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+}
+
+// SessionResponse is one of the caller's own sessions (never includes tokens)
+type SessionResponse struct {
+	ID         uint       `json:"id"`
+	Device     string     `json:"device,omitempty"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"` // last login or refresh
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Current    bool       `json:"current"`
+}
+
+// OIDCCallbackRequest carries the values the identity provider sent to the redirect URL
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// CreateAPIKeyRequest creates a personal API key restricted to scopes (permission names)
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 = API_KEY_DEFAULT_TTL
+}
+
+// APIKeyResponse describes a key without its secret
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse carries the secret key; it is shown only once
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
 }
 
 // AuthResponse returns user + token (kept for compatibility)
@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	connectionuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/connection"
+	activityuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/activity"
+	evidenceuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/evidence"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
 	"gorm.io/gorm"
 )
 
@@ -20,14 +27,47 @@ type AdminHandler struct {
 	RoleRepo    repositories.RoleRepository
 	PermRepo    repositories.PermissionRepository
 	AuditRepo   repositories.AdminAuditRepository
+	// Pools exposes live SQL Server pool statistics (optional)
+	Pools services.PoolStatsProvider
+	// KeyRotation re-encrypts stored DB passwords with the current key (optional)
+	KeyRotation *connectionuc.RotateCredentialsUseCase
+	// SessionCache is invalidated after revocations so they apply immediately (optional)
+	SessionCache services.SessionInvalidator
+	// LoginGuard clears login lockouts; AuthAudit lists authentication events (optional)
+	LoginGuard *useruc.LoginGuard
+	AuthAudit  repositories.AuthAuditRepository
+	// TwoFactor resets lost 2FA enrollments (optional)
+	TwoFactor *useruc.TwoFactorUseCase
+	// APIKeys lists and revokes personal API keys of any user (optional)
+	APIKeys *useruc.APIKeysUseCase
+	// ResourceScopes manages server groups and per-resource permission scopes (optional)
+	ResourceScopes *useruc.ResourceScopesUseCase
+	// RoleHierarchy validates parent roles and shows inherited permissions (optional)
+	RoleHierarchy *useruc.RoleHierarchyUseCase
+	// RoleGrants assigns time-bound roles and reviews just-in-time requests (optional)
+	RoleGrants *useruc.RoleGrantsUseCase
+	// RoleAssignments keeps users.role in sync with user_roles and renews tokens (optional)
+	RoleAssignments *useruc.RoleAssignmentsUseCase
+	// RBACPolicy exports and imports roles and permissions as a policy document (optional)
+	RBACPolicy *useruc.RBACPolicyUseCase
+	// Authz explains permission decisions with the resolver the middleware uses (optional)
+	Authz *useruc.PermissionsUseCase
+	// Evidence verifies the hash chains of the audit records (optional)
+	Evidence *evidenceuc.EvidenceChainUseCase
+	// Activity is the API activity log: admin actions are recorded there too (optional)
+	Activity *activityuc.ActivityLogUseCase
+	// SIEM exposes the queue, retry and drop counters of the syslog exporter (optional)
+	SIEM services.SIEMStatsProvider
 }
 
 func NewAdminHandler(db *gorm.DB, logger *zap.Logger, sr repositories.SessionRepository, rr repositories.RoleRepository, pr repositories.PermissionRepository, ar repositories.AdminAuditRepository) *AdminHandler {
 	return &AdminHandler{DB: db, Logger: logger, SessionRepo: sr, RoleRepo: rr, PermRepo: pr, AuditRepo: ar}
 }
 
-// recordRBACLog writes an AdminActionLog if AuditRepo is configured. Will not fail the handler on error.
+// recordRBACLog writes an AdminActionLog if AuditRepo is configured and the
+// matching admin.* event to the activity log. Will not fail the handler on error.
 func (h *AdminHandler) recordRBACLog(c *gin.Context, action, targetType string, targetID *uint, targetName string, details string) {
+	h.recordActivity(c, action, targetType, targetID, targetName, details)
 	if h.AuditRepo == nil {
 		return
 	}
@@ -57,6 +97,30 @@ func (h *AdminHandler) recordRBACLog(c *gin.Context, action, targetType string,
 	}
 }
 
+func (h *AdminHandler) recordActivity(c *gin.Context, action, targetType string, targetID *uint, targetName string, details string) {
+	if h.Activity == nil {
+		return
+	}
+	ev := &entities.ActivityEvent{Action: entities.ActivityAdminPrefix + action, ResourceType: targetType, ResourceID: targetName}
+	if targetID != nil {
+		ev.ResourceID = fmt.Sprint(*targetID)
+	}
+	if u, ok := c.Get("userID"); ok {
+		if v, ok2 := u.(uint); ok2 {
+			ev.ActorID = &v
+		}
+	}
+	fields := map[string]interface{}{}
+	if targetName != "" {
+		fields["target_name"] = targetName
+	}
+	if details != "" {
+		fields["details"] = details
+	}
+	ev.Details = entities.ActivityDetails(fields)
+	h.Activity.Record(c.Request.Context(), ev)
+}
+
 // ListActiveSessions returns all active sessions with user info (admin only)
 func (h *AdminHandler) ListActiveSessions(c *gin.Context) {
 	if h.SessionRepo == nil {
@@ -102,6 +166,269 @@ func (h *AdminHandler) ListActiveSessions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"sessions": resp})
 }
 
+// RevokeSession revokes one login: the session and every session of its refresh token family
+func (h *AdminHandler) RevokeSession(c *gin.Context) {
+	if h.SessionRepo == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session repository not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	s, err := h.SessionRepo.GetByID(id)
+	if err != nil {
+		h.Logger.Error("failed reading session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	if s == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	now := time.Now()
+	if s.FamilyID != "" {
+		err = h.SessionRepo.RevokeFamily(s.FamilyID, now)
+	} else {
+		err = h.SessionRepo.RevokeByID(s.ID, now)
+	}
+	if err != nil {
+		h.Logger.Error("failed revoking session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if h.SessionCache != nil {
+		h.SessionCache.InvalidateFamily(s.FamilyID)
+		h.SessionCache.InvalidateToken(s.Token)
+	}
+
+	h.recordRBACLog(c, "session.revoke", "session", &s.ID, "", fmt.Sprintf("user_id=%d", s.UserID))
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked", "session_id": s.ID, "user_id": s.UserID})
+}
+
+// RevokeUserSessions revokes every session of a user
+func (h *AdminHandler) RevokeUserSessions(c *gin.Context) {
+	if h.SessionRepo == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session repository not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var userID uint
+	if _, err := fmt.Sscanf(idParam, "%d", &userID); err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	n, err := h.SessionRepo.RevokeByUser(userID, time.Now())
+	if err != nil {
+		h.Logger.Error("failed revoking user sessions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	if h.SessionCache != nil {
+		h.SessionCache.InvalidateUser(userID)
+	}
+
+	h.recordRBACLog(c, "session.revoke_user", "user", &userID, "", fmt.Sprintf("revoked=%d", n))
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked", "user_id": userID, "revoked": n})
+}
+
+// RevokeAllSessions revokes every session of every user (including the caller's).
+// Requires ?confirm=true to avoid accidental global logouts.
+func (h *AdminHandler) RevokeAllSessions(c *gin.Context) {
+	if h.SessionRepo == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "session repository not configured"})
+		return
+	}
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "add ?confirm=true to revoke all sessions"})
+		return
+	}
+
+	n, err := h.SessionRepo.RevokeAll(time.Now())
+	if err != nil {
+		h.Logger.Error("failed revoking all sessions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	if h.SessionCache != nil {
+		h.SessionCache.InvalidateAll()
+	}
+
+	h.recordRBACLog(c, "session.revoke_all", "session", nil, "", fmt.Sprintf("revoked=%d", n))
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked", "revoked": n})
+}
+
+// UnlockUser clears the failed-login counter and lockout of a user
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	if h.LoginGuard == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login lockout not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var userID uint
+	if _, err := fmt.Sscanf(idParam, "%d", &userID); err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var user entities.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		h.Logger.Error("failed fetching user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	actor, _ := c.Get("username")
+	if err := h.LoginGuard.Unlock(c.Request.Context(), user.Username, user.ID, fmt.Sprint(actor)); err != nil {
+		h.Logger.Error("failed unlocking user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock user"})
+		return
+	}
+
+	h.recordRBACLog(c, "user.unlock", "user", &user.ID, user.Username, "")
+	c.JSON(http.StatusOK, gin.H{"message": "user unlocked", "user_id": user.ID})
+}
+
+// ResetUserTwoFactor removes a user's TOTP enrollment and recovery codes (lost
+// device). If the role requires 2FA the user enrolls again at the next login.
+func (h *AdminHandler) ResetUserTwoFactor(c *gin.Context) {
+	if h.TwoFactor == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "two-factor authentication not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var userID uint
+	if _, err := fmt.Sscanf(idParam, "%d", &userID); err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var user entities.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		h.Logger.Error("failed fetching user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	if err := h.TwoFactor.Reset(user.ID); err != nil {
+		h.Logger.Error("failed resetting 2FA", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset two-factor authentication"})
+		return
+	}
+
+	h.recordRBACLog(c, "user.2fa_reset", "user", &user.ID, user.Username, "")
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication reset", "user_id": user.ID})
+}
+
+// ListAPIKeys lists the API keys of every user, or of one with ?user_id=
+func (h *AdminHandler) ListAPIKeys(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "api keys not configured"})
+		return
+	}
+	var userID *uint
+	if v := c.Query("user_id"); v != "" {
+		var id uint
+		if _, err := fmt.Sscanf(v, "%d", &id); err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+		userID = &id
+	}
+
+	list, err := h.APIKeys.List(userID)
+	if err != nil {
+		h.Logger.Error("list api keys failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": apiKeyResponses(list)})
+}
+
+// RevokeAPIKey revokes any user's API key
+func (h *AdminHandler) RevokeAPIKey(c *gin.Context) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "api keys not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
+		return
+	}
+
+	k, err := h.APIKeys.Revoke(id)
+	if err != nil {
+		if errors.Is(err, useruc.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.Logger.Error("failed revoking api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+
+	h.recordRBACLog(c, "api_key.revoke", "api_key", &k.ID, k.Name, fmt.Sprintf("user_id=%d prefix=%s", k.UserID, k.Prefix))
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked", "api_key_id": k.ID, "user_id": k.UserID})
+}
+
+// ListAuthAuditLogs returns authentication events (logins, failures, lockouts, unlocks).
+// Optional filters: event, username, ip, limit, offset.
+func (h *AdminHandler) ListAuthAuditLogs(c *gin.Context) {
+	if h.AuthAudit == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "auth audit repository not configured"})
+		return
+	}
+
+	var event, username, ip *string
+	if v := c.Query("event"); v != "" {
+		event = &v
+	}
+	if v := c.Query("username"); v != "" {
+		username = &v
+	}
+	if v := c.Query("ip"); v != "" {
+		ip = &v
+	}
+
+	limit := 100
+	offset := 0
+	if v := c.Query("limit"); v != "" {
+		var l int
+		if _, err := fmt.Sscanf(v, "%d", &l); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		var o int
+		if _, err := fmt.Sscanf(v, "%d", &o); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	list, err := h.AuthAudit.List(event, username, ip, limit, offset)
+	if err != nil {
+		h.Logger.Error("list auth audit logs failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list audit logs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"logs": list})
+}
+
 // GetUsersMetrics returns basic user metrics (counts and distribution by role)
 func (h *AdminHandler) GetUsersMetrics(c *gin.Context) {
 	var total int64
@@ -253,6 +580,46 @@ func (h *AdminHandler) GetSystemMetrics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"table_counts": resp})
 }
 
+// ListSQLPools returns sql.DBStats for every live SQL Server pool
+func (h *AdminHandler) ListSQLPools(c *gin.Context) {
+	if h.Pools == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "sql pool manager not configured"})
+		return
+	}
+	pools := h.Pools.PoolStats()
+	c.JSON(http.StatusOK, gin.H{"pools": pools, "total": len(pools)})
+}
+
+// GetSIEMMetrics returns the counters of the SIEM exporter; enabled=false when
+// no syslog collector is configured
+func (h *AdminHandler) GetSIEMMetrics(c *gin.Context) {
+	if h.SIEM == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "siem": h.SIEM.SIEMStats()})
+}
+
+// RotateEncryptionKeys re-encrypts active connection passwords with the current key
+func (h *AdminHandler) RotateEncryptionKeys(c *gin.Context) {
+	if h.KeyRotation == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "key rotation not configured"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.KeyRotation.Execute(c.Request.Context(), dryRun)
+	if err != nil {
+		h.Logger.Error("failed rotating encryption keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate encryption keys"})
+		return
+	}
+	if !dryRun {
+		details := fmt.Sprintf("key_id=%s rotated=%d skipped=%d failed=%d", report.KeyID, report.Rotated, report.Skipped, report.Failed)
+		h.recordRBACLog(c, "encryption.rotate", "active_connections", nil, report.KeyID, details)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
 // --- Roles / Permissions CRUD and assignments ---
 
 // ListRoles returns available roles with permissions
@@ -285,6 +652,14 @@ func (h *AdminHandler) CreateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "name required"})
 		return
 	}
+	if h.RoleHierarchy != nil {
+		if err := h.RoleHierarchy.CheckParent(0, body.ParentID); err != nil {
+			h.roleHierarchyError(c, err)
+			return
+		}
+	} else {
+		body.ParentID = nil
+	}
 	if err := h.RoleRepo.Create(&body); err != nil {
 		h.Logger.Error("create role failed", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create role"})
@@ -352,11 +727,35 @@ func (h *AdminHandler) DeleteRole(c *gin.Context) {
 	if r, _ := h.RoleRepo.GetByID(id); r != nil {
 		name = r.Name
 	}
+	// child roles would silently lose the permissions they inherit
+	if h.RoleHierarchy != nil {
+		if err := h.RoleHierarchy.CheckDelete(id); err != nil {
+			h.roleHierarchyError(c, err)
+			return
+		}
+	}
+	// users holding the role get a new primary role and must renew their tokens
+	holders, err := h.RoleRepo.ListRoleUsers(id)
+	if err != nil {
+		h.Logger.Error("list role users failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete role"})
+		return
+	}
 	if err := h.RoleRepo.Delete(id); err != nil {
 		h.Logger.Error("delete role failed", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete role"})
 		return
 	}
+	if h.RoleAssignments != nil {
+		if err := h.RoleAssignments.RoleDeleted(holders); err != nil {
+			h.Logger.Warn("failed updating users of deleted role", zap.Error(err))
+		}
+	}
+	if h.ResourceScopes != nil {
+		if err := h.ResourceScopes.ClearRole(id); err != nil {
+			h.Logger.Warn("failed clearing role scopes", zap.Error(err))
+		}
+	}
 	// record audit
 	h.recordRBACLog(c, "role.delete", "role", &id, name, "")
 	c.JSON(http.StatusNoContent, gin.H{})
@@ -376,18 +775,39 @@ func (h *AdminHandler) AssignRoleToUser(c *gin.Context) {
 	}
 	var body struct {
 		RoleID uint `json:"role_id"`
+		// optional validity window for temporary access
+		ValidFrom  *time.Time `json:"valid_from"`
+		ValidUntil *time.Time `json:"valid_until"`
+		Reason     string     `json:"reason"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil || body.RoleID == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "role_id required"})
 		return
 	}
-	if err := h.RoleRepo.AssignToUser(userID, body.RoleID); err != nil {
+	details := fmt.Sprintf("role_id=%d assigned to user_id=%d", body.RoleID, userID)
+	if body.ValidFrom != nil || body.ValidUntil != nil {
+		if h.RoleGrants == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "role grants not configured"})
+			return
+		}
+		actorID, _ := currentUserID(c)
+		grant, err := h.RoleGrants.Grant(actorID, userID, body.RoleID, body.ValidFrom, body.ValidUntil, body.Reason)
+		if err != nil {
+			roleGrantError(c, h.Logger, err)
+			return
+		}
+		if grant.ValidFrom != nil {
+			details += " from " + grant.ValidFrom.UTC().Format(time.RFC3339)
+		}
+		if grant.ValidUntil != nil {
+			details += " until " + grant.ValidUntil.UTC().Format(time.RFC3339)
+		}
+	} else if err := h.assignRole(userID, body.RoleID); err != nil {
 		h.Logger.Error("assign role failed", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assign role"})
 		return
 	}
 	// audit
-	details := fmt.Sprintf("role_id=%d assigned to user_id=%d", body.RoleID, userID)
 	targetName := fmt.Sprintf("user:%d", userID)
 	h.recordRBACLog(c, "role.assign", "user_role", nil, targetName, details)
 	c.JSON(http.StatusOK, gin.H{"ok": true})
@@ -412,7 +832,13 @@ func (h *AdminHandler) RevokeRoleFromUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "role_id required"})
 		return
 	}
-	if err := h.RoleRepo.RevokeFromUser(userID, body.RoleID); err != nil {
+	var err error
+	if h.RoleAssignments != nil {
+		err = h.RoleAssignments.Revoke(userID, body.RoleID)
+	} else {
+		err = h.RoleRepo.RevokeFromUser(userID, body.RoleID)
+	}
+	if err != nil {
 		h.Logger.Error("revoke role failed", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke role"})
 		return
@@ -423,6 +849,14 @@ func (h *AdminHandler) RevokeRoleFromUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
+// assignRole grants a permanent role, through RoleAssignments when configured
+func (h *AdminHandler) assignRole(userID, roleID uint) error {
+	if h.RoleAssignments != nil {
+		return h.RoleAssignments.Assign(userID, roleID)
+	}
+	return h.RoleRepo.AssignToUser(userID, roleID)
+}
+
 // ---- Permissions ----
 
 func (h *AdminHandler) ListPermissions(c *gin.Context) {
@@ -528,6 +962,11 @@ func (h *AdminHandler) DeletePermission(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete permission"})
 		return
 	}
+	if h.ResourceScopes != nil {
+		if err := h.ResourceScopes.ClearPermission(id); err != nil {
+			h.Logger.Warn("failed clearing permission scopes", zap.Error(err))
+		}
+	}
 	h.recordRBACLog(c, "permission.delete", "permission", &id, pname, "")
 	c.JSON(http.StatusNoContent, gin.H{})
 }
@@ -585,6 +1024,12 @@ func (h *AdminHandler) RevokePermissionFromRole(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke permission from role"})
 		return
 	}
+	// scopes belong to the assignment: a later re-assignment starts global
+	if h.ResourceScopes != nil {
+		if err := h.ResourceScopes.ClearRolePermission(roleID, body.PermissionID); err != nil {
+			h.Logger.Warn("failed clearing permission scopes", zap.Error(err))
+		}
+	}
 	details := fmt.Sprintf("permission_id=%d revoked from role_id=%d", body.PermissionID, roleID)
 	targetName := fmt.Sprintf("role:%d", roleID)
 	h.recordRBACLog(c, "permission.revoke", "role_permission", nil, targetName, details)
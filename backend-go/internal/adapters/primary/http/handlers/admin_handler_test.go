@@ -391,3 +391,66 @@ func TestAdminHandler_ListUsersWithRoles(t *testing.T) {
         }
     }
 }
+
+func TestAdminHandler_RevokeSessions(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.Session{}, &entities.AdminActionLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	exp := time.Now().Add(time.Hour)
+	s1 := &entities.Session{UserID: 1, Token: "t1", ExpiresAt: &exp, IsActive: true, FamilyID: "f1"}
+	s1b := &entities.Session{UserID: 1, Token: "t1b", ExpiresAt: &exp, IsActive: true, FamilyID: "f1"}
+	s2 := &entities.Session{UserID: 1, Token: "t2", ExpiresAt: &exp, IsActive: true, FamilyID: "f2"}
+	s3 := &entities.Session{UserID: 2, Token: "t3", ExpiresAt: &exp, IsActive: true, FamilyID: "f3"}
+	for _, s := range []*entities.Session{s1, s1b, s2, s3} {
+		db.Create(s)
+	}
+
+	auditRepo := repo.NewGormAdminAuditRepository(db)
+	h := NewAdminHandler(db, zap.NewNop(), repo.NewGormSessionRepository(db), nil, nil, auditRepo)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userID", uint(99)); c.Set("username", "test-admin"); c.Next() })
+	r.DELETE("/sessions/:id", h.RevokeSession)
+	r.DELETE("/users/:id/sessions", h.RevokeUserSessions)
+	r.DELETE("/sessions", h.RevokeAllSessions)
+
+	do := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		return w
+	}
+	active := func(id uint) bool {
+		var s entities.Session
+		db.First(&s, id)
+		return s.IsActive
+	}
+
+	// one session revokes its whole refresh family only
+	if w := do(fmt.Sprintf("/sessions/%d", s1.ID)); w.Code != http.StatusOK {
+		t.Fatalf("revoke session: %d %s", w.Code, w.Body.String())
+	}
+	if active(s1.ID) || active(s1b.ID) || !active(s2.ID) {
+		t.Fatalf("expected only family f1 to be revoked")
+	}
+
+	if w := do("/users/1/sessions"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":1`) {
+		t.Fatalf("revoke user sessions: %d %s", w.Code, w.Body.String())
+	}
+	if active(s2.ID) || !active(s3.ID) {
+		t.Fatalf("expected only user 1 sessions to be revoked")
+	}
+
+	if w := do("/sessions"); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected global revoke to require confirmation, got %d", w.Code)
+	}
+	if w := do("/sessions?confirm=true"); w.Code != http.StatusOK || active(s3.ID) {
+		t.Fatalf("revoke all: %d %s", w.Code, w.Body.String())
+	}
+
+	var actions []string
+	db.Model(&entities.AdminActionLog{}).Order("id").Pluck("action", &actions)
+	if strings.Join(actions, ",") != "session.revoke,session.revoke_user,session.revoke_all" {
+		t.Fatalf("unexpected audit trail: %v", actions)
+	}
+}
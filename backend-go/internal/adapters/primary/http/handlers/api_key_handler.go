@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	dto "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/dto"
+	entities "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// CreateAPIKey creates a personal API key for the caller. The key is returned once.
+func (h *UserHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be positive"})
+		return
+	}
+	userID, ok := h.apiKeyUser(c)
+	if !ok {
+		return
+	}
+
+	created, err := h.APIKeys.Create(userID, req.Name, req.Scopes, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		switch {
+		case errors.Is(err, useruc.ErrAPIKeyScope),
+			errors.Is(err, useruc.ErrAPIKeyNoScopes),
+			errors.Is(err, useruc.ErrAPIKeyTTLTooLong),
+			errors.Is(err, useruc.ErrAPIKeyNameRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.Logger.Error("failed creating api key", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, dto.CreateAPIKeyResponse{APIKeyResponse: apiKeyResponse(created.Key), Key: created.Secret})
+}
+
+// ListMyAPIKeys lists the caller's API keys (never the secrets)
+func (h *UserHandler) ListMyAPIKeys(c *gin.Context) {
+	userID, ok := h.apiKeyUser(c)
+	if !ok {
+		return
+	}
+	list, err := h.APIKeys.ListForUser(userID)
+	if err != nil {
+		h.Logger.Error("failed listing api keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": apiKeyResponses(list)})
+}
+
+// RevokeMyAPIKey revokes one of the caller's API keys
+func (h *UserHandler) RevokeMyAPIKey(c *gin.Context) {
+	userID, ok := h.apiKeyUser(c)
+	if !ok {
+		return
+	}
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
+		return
+	}
+	if err := h.APIKeys.RevokeForUser(userID, id); err != nil {
+		if errors.Is(err, useruc.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.Logger.Error("failed revoking api key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "api key revoked", "api_key_id": id})
+}
+
+func (h *UserHandler) apiKeyUser(c *gin.Context) (uint, bool) {
+	if h.APIKeys == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "api keys not configured"})
+		return 0, false
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return 0, false
+	}
+	return userID, true
+}
+
+func apiKeyResponse(k *entities.APIKey) dto.APIKeyResponse {
+	scopes := k.ScopeList()
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return dto.APIKeyResponse{
+		ID:         k.ID,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+func apiKeyResponses(list []entities.APIKey) []dto.APIKeyResponse {
+	out := make([]dto.APIKeyResponse, 0, len(list))
+	for i := range list {
+		out = append(out, apiKeyResponse(&list[i]))
+	}
+	return out
+}
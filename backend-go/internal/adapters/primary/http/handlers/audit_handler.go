@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
 	controlsuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/controls"
 )
 
@@ -29,6 +31,10 @@ func (h *AuditHandler) ExecuteAudit(c *gin.Context) {
 	manager := c.Param("manager")
 
 	res, err := h.auditUC.Execute(c.Request.Context(), userID.(uint), manager, req)
+	if errors.Is(err, services.ErrResourceAccessDenied) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -52,10 +58,14 @@ func (h *AuditHandler) GetAudit(c *gin.Context) {
 
 	res, run, err := h.auditUC.GetAuditRun(c.Request.Context(), userID.(uint), uint(id))
 	if err != nil {
-		if err.Error() == "forbidden" {
+		if errors.Is(err, controlsuc.ErrAuditRunForbidden) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 			return
 		}
+		if errors.Is(err, services.ErrResourceAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
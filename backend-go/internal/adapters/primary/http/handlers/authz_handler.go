@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// ExplainAuthz tells whether ?user= (id or username) has ?permission= and why:
+// the roles, inherited roles, scoped grants and assignment windows behind the
+// decision. ?resource=server or server/database also checks the scopes.
+func (h *AdminHandler) ExplainAuthz(c *gin.Context) {
+	if h.Authz == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "authorization resolver not configured"})
+		return
+	}
+	permission := strings.TrimSpace(c.Query("permission"))
+	if permission == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "permission is required"})
+		return
+	}
+	user, ok := h.lookupUser(c, c.Query("user"))
+	if !ok {
+		return
+	}
+	var resource *useruc.AuthzResource
+	if raw := strings.TrimSpace(c.Query("resource")); raw != "" {
+		server, database, _ := strings.Cut(raw, "/")
+		if server == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resource must be server or server/database"})
+			return
+		}
+		resource = &useruc.AuthzResource{Server: server, Database: database}
+	}
+	decision, err := h.Authz.Explain(user.ID, permission, resource)
+	if err != nil {
+		h.authzError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, decision)
+}
+
+// UserCapabilities lists the effective permissions of a user with the grants behind each one
+func (h *AdminHandler) UserCapabilities(c *gin.Context) {
+	if h.Authz == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "authorization resolver not configured"})
+		return
+	}
+	user, ok := h.lookupUser(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	caps, err := h.Authz.Capabilities(user.ID)
+	if err != nil {
+		h.authzError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, caps)
+}
+
+// lookupUser finds a user by id or username and writes the error response when it cannot
+func (h *AdminHandler) lookupUser(c *gin.Context, ref string) (*entities.User, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user is required"})
+		return nil, false
+	}
+	var user entities.User
+	q := h.DB.Where("username = ?", ref)
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		q = h.DB.Where("id = ?", id)
+	}
+	if err := q.First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return nil, false
+		}
+		h.Logger.Error("failed fetching user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h *AdminHandler) authzError(c *gin.Context, err error) {
+	if errors.Is(err, useruc.ErrUserNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	h.Logger.Error("authorization explain failed", zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve permissions"})
+}
@@ -1,14 +1,21 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
 	dto "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/dto"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
 	connectionuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/connection"
 )
 
@@ -56,12 +63,23 @@ func (h *ConnectionHandler) Connect(c *gin.Context) {
 		Port:     req.Port,
 		DBUser:   req.DBUser,
 		Password: req.Password,
+
+		Encrypt:               req.Encrypt,
+		ValidateCertificate:   req.ValidateCertificate,
+		CACertificate:         req.CACertificate,
+		CertFingerprint:       req.CertFingerprint,
+		HostNameInCertificate: req.HostNameInCertificate,
+		AppName:               req.AppName,
 	}
 
 	// override driver with manager from path
 	// input.Driver = manager
 
 	conn, err := h.connectUC.Execute(c.Request.Context(), userID, input)
+	if errors.Is(err, services.ErrResourceAccessDenied) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -73,7 +91,10 @@ func (h *ConnectionHandler) Connect(c *gin.Context) {
 		Manager:       conn.Manager,
 		Driver:        conn.Driver,
 		Server:        conn.Server,
+		Port:          conn.Port,
 		DBUser:        conn.DBUser,
+		Encrypt:       conn.Encrypt,
+		ValidateCert:  conn.ValidateCertificate,
 		IsConnected:   conn.IsConnected,
 		LastConnected: conn.LastConnected.Format(time.RFC3339),
 	}
@@ -125,20 +146,29 @@ func (h *ConnectionHandler) GetActive(c *gin.Context) {
 		// transform to DTOs
 		var resp []dto.ConnectionResponseDTO
 		for _, conn := range list {
-			var lastDisconnected string
+			var lastDisconnected, lastHealthCheck string
 			if conn.LastDisconnected != nil {
 				lastDisconnected = conn.LastDisconnected.Format(time.RFC3339)
 			}
+			if conn.LastHealthCheck != nil {
+				lastHealthCheck = conn.LastHealthCheck.Format(time.RFC3339)
+			}
 			resp = append(resp, dto.ConnectionResponseDTO{
 				ID:               conn.ID,
 				UserID:           conn.UserID,
 				Manager:          conn.Manager,
 				Driver:           conn.Driver,
 				Server:           conn.Server,
+				Port:             conn.Port,
 				DBUser:           conn.DBUser,
+				Encrypt:          conn.Encrypt,
+				ValidateCert:     conn.ValidateCertificate,
 				IsConnected:      conn.IsConnected,
 				LastConnected:    conn.LastConnected.Format(time.RFC3339),
 				LastDisconnected: lastDisconnected,
+				HealthStatus:     conn.HealthStatus,
+				LastHealthCheck:  lastHealthCheck,
+				LatencyMs:        conn.LastLatencyMs,
 			})
 		}
 		c.JSON(http.StatusOK, gin.H{"connections": resp})
@@ -160,26 +190,37 @@ func (h *ConnectionHandler) GetActive(c *gin.Context) {
 		return
 	}
 
-	var lastDisconnected string
+	var lastDisconnected, lastHealthCheck string
 	if conn.LastDisconnected != nil {
 		lastDisconnected = conn.LastDisconnected.Format(time.RFC3339)
 	}
+	if conn.LastHealthCheck != nil {
+		lastHealthCheck = conn.LastHealthCheck.Format(time.RFC3339)
+	}
 
 	resp := dto.ConnectionResponseDTO{
 		ID:               conn.ID,
 		UserID:           conn.UserID,
 		Driver:           conn.Driver,
 		Server:           conn.Server,
+		Port:             conn.Port,
 		DBUser:           conn.DBUser,
+		Encrypt:          conn.Encrypt,
+		ValidateCert:     conn.ValidateCertificate,
 		IsConnected:      conn.IsConnected,
 		LastConnected:    conn.LastConnected.Format(time.RFC3339),
 		LastDisconnected: lastDisconnected,
+		HealthStatus:     conn.HealthStatus,
+		LastHealthCheck:  lastHealthCheck,
+		LatencyMs:        conn.LastLatencyMs,
 	}
 
 	c.JSON(http.StatusOK, gin.H{"connection": resp})
 }
 
-// GetHistory devuelve logs de conexión del usuario filtrados por manager (si se pasa)
+// GetHistory devuelve el historial de conexiones del usuario autenticado.
+// Filtros: from, to (RFC3339 o YYYY-MM-DD), status, server, manager; paginación: limit, offset.
+// En /api/db/:manager/history el gestor se toma de la ruta.
 func (h *ConnectionHandler) GetHistory(c *gin.Context) {
 	if h.listHistoryUC == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "history usecase not configured"})
@@ -189,33 +230,246 @@ func (h *ConnectionHandler) GetHistory(c *gin.Context) {
 	uid, _ := c.Get("userID")
 	userID := uid.(uint)
 
-	manager := c.Param("manager")
-	// Accept limit/offset query params
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filters, err := parseHistoryFilters(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.listHistoryUC.Execute(c.Request.Context(), userID, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, historyPageResponse(page))
+}
+
+// ExportHistory exporta (streaming) el historial del usuario en CSV (por defecto) o JSON
+func (h *ConnectionHandler) ExportHistory(c *gin.Context) {
+	if h.listHistoryUC == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "history usecase not configured"})
+		return
+	}
+
+	uid, _ := c.Get("userID")
+	userID := uid.(uint)
+
+	filters, err := parseHistoryFilters(c, false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.streamHistory(c, func(fn func(*entities.ConnectionLog) error) error {
+		return h.listHistoryUC.Export(c.Request.Context(), userID, filters, fn)
+	})
+}
 
-	list, err := h.listHistoryUC.Execute(c.Request.Context(), userID, connectionuc.HistoryFilters{Limit: limit, Offset: offset})
+// GetAllHistory devuelve el historial de todos los usuarios (admin); acepta además user_id
+func (h *ConnectionHandler) GetAllHistory(c *gin.Context) {
+	if h.listHistoryUC == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "history usecase not configured"})
+		return
+	}
+
+	filters, err := parseHistoryFilters(c, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	uid, _ := c.Get("userID")
+	filters.ViewerID = uid.(uint)
+
+	page, err := h.listHistoryUC.ExecuteAll(c.Request.Context(), filters)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, historyPageResponse(page))
+}
+
+// ExportAllHistory exporta (streaming) el historial de todos los usuarios (admin)
+func (h *ConnectionHandler) ExportAllHistory(c *gin.Context) {
+	if h.listHistoryUC == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "history usecase not configured"})
+		return
+	}
 
-	// Filter by manager if provided
-	var out []dto.ConnectionLogDTO
-	for _, l := range list {
-		if manager != "" && manager != l.Driver {
-			continue
+	filters, err := parseHistoryFilters(c, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	uid, _ := c.Get("userID")
+	filters.ViewerID = uid.(uint)
+	h.streamHistory(c, func(fn func(*entities.ConnectionLog) error) error {
+		return h.listHistoryUC.ExportAll(c.Request.Context(), filters, fn)
+	})
+}
+
+// streamHistory writes rows as they are read so large exports never sit in memory.
+// The status is committed with the first row (or once an empty export ends), so
+// a denied scope or a failing first page still gets a proper 403/500.
+func (h *ConnectionHandler) streamHistory(c *gin.Context, export func(func(*entities.ConnectionLog) error) error) {
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	filename := fmt.Sprintf("connection-history-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	csvw := csv.NewWriter(c.Writer)
+	enc := json.NewEncoder(c.Writer)
+	started := false
+	begin := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		switch format {
+		case "csv":
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Status(http.StatusOK)
+			_ = csvw.Write([]string{"id", "user_id", "manager", "driver", "server", "db_user", "timestamp", "status"})
+		case "json":
+			c.Header("Content-Type", "application/json; charset=utf-8")
+			c.Status(http.StatusOK)
+			_, _ = c.Writer.Write([]byte("["))
 		}
-		out = append(out, dto.ConnectionLogDTO{
-			ID:        l.ID,
-			UserID:    l.UserID,
-			Driver:    l.Driver,
-			Server:    l.Server,
-			DBUser:    l.DBUser,
-			Timestamp: l.Timestamp.Format(time.RFC3339),
-			Status:    l.Status,
+	}
+
+	var err error
+	switch format {
+	case "csv":
+		n := 0
+		err = export(func(l *entities.ConnectionLog) error {
+			begin()
+			rec := []string{
+				strconv.FormatUint(uint64(l.ID), 10),
+				strconv.FormatUint(uint64(l.UserID), 10),
+				csvCell(l.Manager), csvCell(l.Driver), csvCell(l.Server), csvCell(l.DBUser),
+				l.Timestamp.UTC().Format(time.RFC3339),
+				csvCell(l.Status),
+			}
+			if werr := csvw.Write(rec); werr != nil {
+				return werr
+			}
+			if n++; n%500 == 0 {
+				csvw.Flush()
+				c.Writer.Flush()
+			}
+			return nil
+		})
+	case "json":
+		first := true
+		err = export(func(l *entities.ConnectionLog) error {
+			begin()
+			if !first {
+				if _, werr := c.Writer.Write([]byte(",")); werr != nil {
+					return werr
+				}
+			}
+			first = false
+			return enc.Encode(toConnectionLogDTO(l))
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"logs": out})
+	if err != nil && !started {
+		if errors.Is(err, services.ErrResourceAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("connection history export failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export connection history"})
+		return
+	}
+
+	begin()
+	switch format {
+	case "csv":
+		csvw.Flush()
+	case "json":
+		_, _ = c.Writer.Write([]byte("]"))
+	}
+
+	// headers are already sent: the best we can do is log and cut the stream short
+	if err != nil {
+		h.logger.Error("connection history export failed", zap.Error(err))
+	}
+}
+
+// csvCell neutralises values a spreadsheet would run as a formula (CSV injection)
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// parseHistoryFilters reads history filters from the query string; user_id only when allowUser
+func parseHistoryFilters(c *gin.Context, allowUser bool) (connectionuc.HistoryFilters, error) {
+	var f connectionuc.HistoryFilters
+	var err error
+
+	if v := c.Query("from"); v != "" {
+		if f.StartDate, err = parseHistoryDate(v); err != nil {
+			return f, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if f.EndDate, err = parseHistoryDate(v); err != nil {
+			return f, fmt.Errorf("invalid to: %w", err)
+		}
+		// a bare date means the whole day is included
+		if len(v) == len("2006-01-02") {
+			f.EndDate = f.EndDate.AddDate(0, 0, 1)
+		}
+	}
+	f.Status = c.Query("status")
+	f.Server = c.Query("server")
+	f.Manager = c.Param("manager")
+	if f.Manager == "" {
+		f.Manager = c.Query("manager")
+	}
+	if allowUser {
+		if v := c.Query("user_id"); v != "" {
+			id, perr := strconv.ParseUint(v, 10, 64)
+			if perr != nil {
+				return f, fmt.Errorf("invalid user_id")
+			}
+			uid := uint(id)
+			f.UserID = &uid
+		}
+	}
+	f.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(connectionuc.DefaultHistoryLimit)))
+	f.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	return f, nil
+}
+
+func parseHistoryDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+func toConnectionLogDTO(l *entities.ConnectionLog) dto.ConnectionLogDTO {
+	return dto.ConnectionLogDTO{
+		ID:        l.ID,
+		UserID:    l.UserID,
+		Manager:   l.Manager,
+		Driver:    l.Driver,
+		Server:    l.Server,
+		DBUser:    l.DBUser,
+		Timestamp: l.Timestamp.Format(time.RFC3339),
+		Status:    l.Status,
+	}
+}
+
+func historyPageResponse(page *connectionuc.HistoryPage) gin.H {
+	out := make([]dto.ConnectionLogDTO, 0, len(page.Logs))
+	for _, l := range page.Logs {
+		out = append(out, toConnectionLogDTO(l))
+	}
+	return gin.H{"logs": out, "total": page.Total, "limit": page.Limit, "offset": page.Offset}
 }
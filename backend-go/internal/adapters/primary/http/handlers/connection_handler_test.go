@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	connectionuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/connection"
+	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func setupHistoryRouter(t *testing.T) *gin.Engine {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.ConnectionLog{}); err != nil {
+		t.Fatalf("migrate logs: %v", err)
+	}
+	now := time.Now()
+	db.Create(&entities.ConnectionLog{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "s1", DBUser: "sa", Timestamp: now, Status: "connected"})
+	db.Create(&entities.ConnectionLog{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "s2", DBUser: "sa", Timestamp: now, Status: "failed"})
+	db.Create(&entities.ConnectionLog{UserID: 2, Manager: "mssql", Driver: "mssql", Server: "s1", DBUser: "sa", Timestamp: now, Status: "connected"})
+
+	historyUC := connectionuc.NewListConnectionHistoryUseCase(repo.NewGormConnectionRepository(db))
+	h := NewConnectionHandler(nil, nil, nil, nil, historyUC, zap.NewNop())
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userID", uint(1)); c.Next() })
+	r.GET("/history", h.GetHistory)
+	r.GET("/history/export", h.ExportHistory)
+	r.GET("/admin/history", h.GetAllHistory)
+	return r
+}
+
+func TestConnectionHandler_GetHistory_filtersToCurrentUser(t *testing.T) {
+	r := setupHistoryRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history?status=connected", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d body=%s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Logs  []map[string]interface{} `json:"logs"`
+		Total int64                    `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Total != 1 || len(body.Logs) != 1 || body.Logs[0]["server"] != "s1" {
+		t.Fatalf("expected only the user's connected log, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/history?user_id=2", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":1`) {
+		t.Fatalf("expected admin filter by user_id, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history?from=not-a-date", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid date, got %d", w.Code)
+	}
+}
+
+func TestConnectionHandler_ExportHistory_csvAndJSON(t *testing.T) {
+	r := setupHistoryRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+	if !strings.Contains(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("expected attachment disposition")
+	}
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "id" {
+		t.Fatalf("expected header + 2 rows, got %v", records)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/export?format=json", nil))
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("decode json export: %v body=%s", err, w.Body.String())
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+}
+
+// failingAccess makes every scope lookup fail with err
+type failingAccess struct{ err error }
+
+func (f failingAccess) Scope(uint, ...string) (*entities.AccessScope, error) { return nil, f.err }
+
+func TestConnectionHandler_ExportHistory_failsBeforeCommittingTheStatus(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.ConnectionLog{}); err != nil {
+		t.Fatalf("migrate logs: %v", err)
+	}
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("history: %w", services.ErrResourceAccessDenied), http.StatusForbidden},
+		{errors.New("scope lookup failed"), http.StatusInternalServerError},
+	} {
+		historyUC := connectionuc.NewListConnectionHistoryUseCase(repo.NewGormConnectionRepository(db)).WithAccess(failingAccess{tc.err})
+		h := NewConnectionHandler(nil, nil, nil, nil, historyUC, zap.NewNop())
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("userID", uint(1)); c.Next() })
+		r.GET("/history/export", h.ExportHistory)
+
+		for _, format := range []string{"csv", "json"} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/export?format="+format, nil))
+			if w.Code != tc.code || w.Header().Get("Content-Disposition") != "" {
+				t.Fatalf("expected %d without an attachment, got %d headers=%v body=%s", tc.code, w.Code, w.Header(), w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), `"error"`) {
+				t.Fatalf("expected a JSON error, got %s", w.Body.String())
+			}
+		}
+	}
+}
+
+func TestConnectionHandler_ExportHistory_neutralisesFormulas(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.ConnectionLog{}); err != nil {
+		t.Fatalf("migrate logs: %v", err)
+	}
+	db.Create(&entities.ConnectionLog{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "=HYPERLINK(\"http://x\")", DBUser: "@sa", Timestamp: time.Now(), Status: "connected"})
+	historyUC := connectionuc.NewListConnectionHistoryUseCase(repo.NewGormConnectionRepository(db))
+	h := NewConnectionHandler(nil, nil, nil, nil, historyUC, zap.NewNop())
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("userID", uint(1)); c.Next() })
+	r.GET("/history/export", h.ExportHistory)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history/export", nil))
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("expected header + 1 row, got %v (%v)", records, err)
+	}
+	if records[1][4] != "'=HYPERLINK(\"http://x\")" || records[1][5] != "'@sa" {
+		t.Fatalf("expected formula cells to be prefixed, got %v", records[1])
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	dto "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/dto"
+	entities "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// directoryLogin checks the credentials against the directory before the local
+// accounts. It returns the user on success; done reports that a response was
+// already written. Neither means the directory does not know the user (or is
+// unreachable) and the login continues with the bcrypt path.
+func (h *UserHandler) directoryLogin(c *gin.Context, req dto.LoginRequest, attempt useruc.LoginAttempt) (user *entities.User, done bool) {
+	user, err := h.Directory.Login(c.Request.Context(), req.Username, req.Password)
+	switch {
+	case err == nil:
+		return user, false
+	case errors.Is(err, services.ErrDirectoryUserNotFound):
+		return nil, false
+	case errors.Is(err, services.ErrDirectoryInvalidCredentials):
+		h.loginFailed(c, attempt)
+	case errors.Is(err, useruc.ErrNoMappedRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrExternalUserInactive):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user account is inactive"})
+	default:
+		// directory-provisioned users have a random local password, so falling
+		// back only keeps local accounts working while the directory is down
+		h.Logger.Error("directory login failed, trying local accounts", zap.Error(err))
+		return nil, false
+	}
+	return nil, true
+}
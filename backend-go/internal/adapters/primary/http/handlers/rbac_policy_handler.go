@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// maxPolicySize bounds the body of an RBAC policy import
+const maxPolicySize = 1 << 20
+
+// ExportRBACPolicy returns roles, permissions, parents and bindings as a policy
+// document (?format=yaml, the default, or json)
+func (h *AdminHandler) ExportRBACPolicy(c *gin.Context) {
+	if h.RBACPolicy == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "rbac policy not configured"})
+		return
+	}
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or json"})
+		return
+	}
+	policy, err := h.RBACPolicy.Export()
+	if err != nil {
+		h.Logger.Error("export rbac policy failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export rbac policy"})
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, policy)
+		return
+	}
+	out, err := useruc.MarshalPolicy(policy, format)
+	if err != nil {
+		h.Logger.Error("marshal rbac policy failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export rbac policy"})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", out)
+}
+
+// ImportRBACPolicy compares a YAML or JSON policy with the current state. With
+// ?mode=plan (the default) it only returns the changes; ?mode=apply makes them.
+// ?prune=true also deletes the roles and permissions the policy does not declare.
+func (h *AdminHandler) ImportRBACPolicy(c *gin.Context) {
+	if h.RBACPolicy == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "rbac policy not configured"})
+		return
+	}
+	mode := c.DefaultQuery("mode", "plan")
+	if mode != "plan" && mode != "apply" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be plan or apply"})
+		return
+	}
+	prune := c.Query("prune") == "true"
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPolicySize+1))
+	if err != nil || len(body) > maxPolicySize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	policy, err := useruc.ParsePolicy(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if mode == "plan" {
+		plan, err := h.RBACPolicy.Plan(policy, prune)
+		if err != nil {
+			h.rbacPolicyError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+	plan, err := h.RBACPolicy.Apply(policy, prune)
+	if plan != nil && len(plan.Changes) > 0 {
+		details := plan.Summary()
+		if prune {
+			details += " prune=true"
+		}
+		if err != nil {
+			details += " (failed: " + err.Error() + ")"
+		}
+		h.recordRBACLog(c, "rbac.import", "policy", nil, "", details)
+	}
+	if err != nil {
+		h.rbacPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *AdminHandler) rbacPolicyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, useruc.ErrInvalidPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrPolicyLockout):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.Logger.Error("rbac policy import failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply rbac policy"})
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+type serverGroupRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Servers     []string `json:"servers" binding:"required"`
+}
+
+type serverGroupResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Servers     []string  `json:"servers"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newServerGroupResponse(g *entities.ServerGroup) serverGroupResponse {
+	return serverGroupResponse{ID: g.ID, Name: g.Name, Description: g.Description, Servers: g.ServerList(), CreatedAt: g.CreatedAt, UpdatedAt: g.UpdatedAt}
+}
+
+// ListServerGroups lists the server groups that permission scopes can refer to
+func (h *AdminHandler) ListServerGroups(c *gin.Context) {
+	if h.ResourceScopes == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resource scopes not configured"})
+		return
+	}
+	groups, err := h.ResourceScopes.ListGroups()
+	if err != nil {
+		h.Logger.Error("list server groups failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list server groups"})
+		return
+	}
+	out := make([]serverGroupResponse, 0, len(groups))
+	for i := range groups {
+		out = append(out, newServerGroupResponse(&groups[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"server_groups": out})
+}
+
+// CreateServerGroup creates a named set of server host patterns
+func (h *AdminHandler) CreateServerGroup(c *gin.Context) {
+	if h.ResourceScopes == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resource scopes not configured"})
+		return
+	}
+	var body serverGroupRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	g, err := h.ResourceScopes.CreateGroup(body.Name, body.Description, body.Servers)
+	if err != nil {
+		h.serverGroupError(c, err)
+		return
+	}
+	h.recordRBACLog(c, "server_group.create", "server_group", &g.ID, g.Name, g.Servers)
+	c.JSON(http.StatusCreated, newServerGroupResponse(g))
+}
+
+// UpdateServerGroup replaces a group's name, description and patterns; scopes
+// using the group follow the change immediately
+func (h *AdminHandler) UpdateServerGroup(c *gin.Context) {
+	if h.ResourceScopes == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resource scopes not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid server group id"})
+		return
+	}
+	var body serverGroupRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	g, err := h.ResourceScopes.UpdateGroup(id, body.Name, body.Description, body.Servers)
+	if err != nil {
+		h.serverGroupError(c, err)
+		return
+	}
+	h.recordRBACLog(c, "server_group.update", "server_group", &g.ID, g.Name, g.Servers)
+	c.JSON(http.StatusOK, newServerGroupResponse(g))
+}
+
+// DeleteServerGroup removes a group that no scope refers to
+func (h *AdminHandler) DeleteServerGroup(c *gin.Context) {
+	if h.ResourceScopes == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resource scopes not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid server group id"})
+		return
+	}
+	g, err := h.ResourceScopes.DeleteGroup(id)
+	if err != nil {
+		h.serverGroupError(c, err)
+		return
+	}
+	h.recordRBACLog(c, "server_group.delete", "server_group", &id, g.Name, "")
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *AdminHandler) serverGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, useruc.ErrServerGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrServerGroupInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrServerGroupInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.Logger.Error("server group operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save server group"})
+	}
+}
+
+// GetPermissionScopes lists the scopes of one permission of a role; an empty
+// list means the role has the permission on every server and database
+func (h *AdminHandler) GetPermissionScopes(c *gin.Context) {
+	roleID, permID, ok := h.rolePermissionParams(c)
+	if !ok {
+		return
+	}
+	scopes, err := h.ResourceScopes.Scopes(roleID, permID)
+	if err != nil {
+		h.scopeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role_id": roleID, "permission_id": permID, "global": len(scopes) == 0, "scopes": scopes})
+}
+
+// SetPermissionScopes replaces the scopes of one permission of a role; an empty
+// list makes the permission global again
+func (h *AdminHandler) SetPermissionScopes(c *gin.Context) {
+	roleID, permID, ok := h.rolePermissionParams(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Scopes []useruc.ScopeInput `json:"scopes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scopes, permName, err := h.ResourceScopes.SetScopes(roleID, permID, body.Scopes)
+	if err != nil {
+		h.scopeError(c, err)
+		return
+	}
+	details := "global"
+	if len(scopes) > 0 {
+		parts := make([]string, 0, len(scopes))
+		for _, s := range scopes {
+			parts = append(parts, describeScope(s))
+		}
+		details = strings.Join(parts, "; ")
+	}
+	h.recordRBACLog(c, "permission.scope", "role_permission", &permID, fmt.Sprintf("role:%d %s", roleID, permName), details)
+	c.JSON(http.StatusOK, gin.H{"role_id": roleID, "permission_id": permID, "global": len(scopes) == 0, "scopes": scopes})
+}
+
+func (h *AdminHandler) rolePermissionParams(c *gin.Context) (uint, uint, bool) {
+	if h.ResourceScopes == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "resource scopes not configured"})
+		return 0, 0, false
+	}
+	var roleID, permID uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &roleID); err != nil || roleID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(c.Param("permission_id"), "%d", &permID); err != nil || permID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid permission id"})
+		return 0, 0, false
+	}
+	return roleID, permID, true
+}
+
+func (h *AdminHandler) scopeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, useruc.ErrScopeRoleNotFound), errors.Is(err, useruc.ErrScopePermissionUnset), errors.Is(err, useruc.ErrServerGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrScopeEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.Logger.Error("permission scope operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update permission scopes"})
+	}
+}
+
+func describeScope(s entities.PermissionScope) string {
+	var parts []string
+	if s.ServerGroup != nil {
+		parts = append(parts, "group="+s.ServerGroup.Name)
+	}
+	if s.Server != "" {
+		parts = append(parts, "server="+s.Server)
+	}
+	if s.Database != "" {
+		parts = append(parts, "database="+s.Database)
+	}
+	return strings.Join(parts, ",")
+}
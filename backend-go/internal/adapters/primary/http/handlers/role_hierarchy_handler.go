@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// GetRolePermissions shows a role's own permissions and those inherited from its ancestors
+func (h *AdminHandler) GetRolePermissions(c *gin.Context) {
+	if h.RoleHierarchy == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role hierarchy not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+	perms, err := h.RoleHierarchy.Permissions(id)
+	if err != nil {
+		h.roleHierarchyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, perms)
+}
+
+// SetRoleParent sets or clears (parent_id null) the role a role inherits from
+func (h *AdminHandler) SetRoleParent(c *gin.Context) {
+	if h.RoleHierarchy == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role hierarchy not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+	var body struct {
+		ParentID *uint `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	role, err := h.RoleHierarchy.SetParent(id, body.ParentID)
+	if err != nil {
+		h.roleHierarchyError(c, err)
+		return
+	}
+	details := "parent_id=null"
+	if body.ParentID != nil {
+		details = fmt.Sprintf("parent_id=%d", *body.ParentID)
+	}
+	h.recordRBACLog(c, "role.set_parent", "role", &role.ID, role.Name, details)
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *AdminHandler) roleHierarchyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, useruc.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrParentRoleNotFound), errors.Is(err, useruc.ErrRoleCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrRoleHasChildren):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.Logger.Error("role hierarchy operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve role hierarchy"})
+	}
+}
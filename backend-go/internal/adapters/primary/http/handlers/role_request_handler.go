@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// RequestRole asks for a temporary role (just-in-time elevation) with a reason
+func (h *UserHandler) RequestRole(c *gin.Context) {
+	if h.RoleGrants == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role requests not configured"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return
+	}
+	var body struct {
+		RoleID          uint   `json:"role_id" binding:"required"`
+		Reason          string `json:"reason" binding:"required"`
+		DurationMinutes int    `json:"duration_minutes"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req, err := h.RoleGrants.Request(userID, body.RoleID, body.Reason, time.Duration(body.DurationMinutes)*time.Minute)
+	if err != nil {
+		roleGrantError(c, h.Logger, err)
+		return
+	}
+	c.JSON(http.StatusCreated, req)
+}
+
+// ListMyRoleRequests lists the caller's role requests
+func (h *UserHandler) ListMyRoleRequests(c *gin.Context) {
+	if h.RoleGrants == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role requests not configured"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return
+	}
+	list, err := h.RoleGrants.ListRequests(&userID, c.Query("status"), 100, 0)
+	if err != nil {
+		roleGrantError(c, h.Logger, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": list})
+}
+
+// ListRoleRequests lists role requests, by default the pending ones (?status=all for every state)
+func (h *AdminHandler) ListRoleRequests(c *gin.Context) {
+	if h.RoleGrants == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role requests not configured"})
+		return
+	}
+	status := c.DefaultQuery("status", entities.RoleRequestPending)
+	if status == "all" {
+		status = ""
+	}
+	var userID *uint
+	if v := c.Query("user_id"); v != "" {
+		var id uint
+		if _, err := fmt.Sscanf(v, "%d", &id); err == nil {
+			userID = &id
+		}
+	}
+	limit, offset := 100, 0
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	if v, err := strconv.Atoi(c.Query("offset")); err == nil && v >= 0 {
+		offset = v
+	}
+	list, err := h.RoleGrants.ListRequests(userID, status, limit, offset)
+	if err != nil {
+		roleGrantError(c, h.Logger, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": list})
+}
+
+// ApproveRoleRequest grants the requested role until now + the requested duration
+func (h *AdminHandler) ApproveRoleRequest(c *gin.Context) {
+	h.decideRoleRequest(c, true)
+}
+
+// RejectRoleRequest closes a pending request without granting anything
+func (h *AdminHandler) RejectRoleRequest(c *gin.Context) {
+	h.decideRoleRequest(c, false)
+}
+
+func (h *AdminHandler) decideRoleRequest(c *gin.Context, approve bool) {
+	if h.RoleGrants == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role requests not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var id uint
+	if _, err := fmt.Sscanf(idParam, "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role request id"})
+		return
+	}
+	reviewerID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return
+	}
+	var body struct {
+		Note string `json:"note"`
+	}
+	_ = c.ShouldBindJSON(&body)
+
+	decide, action := h.RoleGrants.Reject, "role_request.reject"
+	if approve {
+		decide, action = h.RoleGrants.Approve, "role_request.approve"
+	}
+	req, err := decide(id, reviewerID, body.Note)
+	if err != nil {
+		roleGrantError(c, h.Logger, err)
+		return
+	}
+	roleName := fmt.Sprintf("role_id=%d", req.RoleID)
+	if req.Role != nil {
+		roleName = req.Role.Name
+	}
+	details := fmt.Sprintf("request #%d: role %s for user_id=%d (%s)", req.ID, roleName, req.UserID, req.Reason)
+	if req.ValidUntil != nil {
+		details += " until " + req.ValidUntil.UTC().Format(time.RFC3339)
+	}
+	if req.DecisionNote != "" {
+		details += "; note: " + req.DecisionNote
+	}
+	h.recordRBACLog(c, action, "user_role", &req.UserID, fmt.Sprintf("user:%d", req.UserID), details)
+	c.JSON(http.StatusOK, req)
+}
+
+// ListUserRoleGrants shows a user's role assignments with their validity window
+func (h *AdminHandler) ListUserRoleGrants(c *gin.Context) {
+	if h.RoleRepo == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "role repository not configured"})
+		return
+	}
+	idParam := c.Param("id")
+	var userID uint
+	if _, err := fmt.Sscanf(idParam, "%d", &userID); err != nil || userID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	grants, err := h.RoleRepo.ListUserGrants(userID)
+	if err != nil {
+		h.Logger.Error("list role grants failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list role grants"})
+		return
+	}
+	now := time.Now()
+	out := make([]gin.H, 0, len(grants))
+	for i := range grants {
+		out = append(out, gin.H{"grant": grants[i], "active": grants[i].Active(now)})
+	}
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "grants": out})
+}
+
+func roleGrantError(c *gin.Context, logger *zap.Logger, err error) {
+	switch {
+	case errors.Is(err, useruc.ErrRoleNotFound), errors.Is(err, useruc.ErrRoleRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrGrantWindowInvalid), errors.Is(err, useruc.ErrGrantReasonRequired), errors.Is(err, useruc.ErrGrantTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrRoleNotRequestable), errors.Is(err, useruc.ErrRoleRequestSelfReview):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrRoleAlreadyGranted), errors.Is(err, useruc.ErrRoleGrantedPermanent), errors.Is(err, useruc.ErrRoleRequestPending), errors.Is(err, useruc.ErrRoleRequestDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Error("role grant operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
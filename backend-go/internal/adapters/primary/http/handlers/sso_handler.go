@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	dto "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/dto"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// oidcStateCookie binds the state of an SSO login to the browser that started it,
+// so a callback URL cannot be completed from another browser (login CSRF)
+const oidcStateCookie = "oidc_state"
+
+// OIDCLogin starts an SSO login: it redirects the browser to the identity provider,
+// or returns the URL with ?format=json for frontends that navigate themselves.
+func (h *UserHandler) OIDCLogin(c *gin.Context) {
+	if h.SSO == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+	login, err := h.SSO.Begin(c.Request.Context())
+	if err != nil {
+		h.Logger.Error("failed to start SSO login", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, login.State, int(time.Until(login.ExpiresAt).Seconds()), "/api/auth/oidc", "", c.Request.TLS != nil, true)
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{"authorization_url": login.URL})
+		return
+	}
+	c.Redirect(http.StatusFound, login.URL)
+}
+
+// OIDCCallback completes the SSO login and issues the usual token pair (or a 2FA
+// challenge). It accepts the provider redirect (GET ?code=&state=) or the same
+// values as a JSON body; either way the browser must send the state cookie.
+func (h *UserHandler) OIDCCallback(c *gin.Context) {
+	if h.SSO == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "identity provider error: " + e})
+		return
+	}
+	req := dto.OIDCCallbackRequest{Code: c.Query("code"), State: c.Query("state")}
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Code == "" || req.State == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+	bound, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+	if bound == "" || subtle.ConstantTimeCompare([]byte(bound), []byte(req.State)) != 1 {
+		h.Logger.Warn("SSO login rejected: state does not match the browser's")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": useruc.ErrInvalidSSOState.Error()})
+		return
+	}
+
+	user, err := h.SSO.Complete(c.Request.Context(), req.Code, req.State)
+	if err != nil {
+		switch {
+		case errors.Is(err, useruc.ErrInvalidSSOState),
+			errors.Is(err, useruc.ErrSSOUserInactive),
+			errors.Is(err, oidc.ErrInvalidIDToken):
+			h.Logger.Warn("SSO login rejected", zap.Error(err))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, useruc.ErrSSONoRole):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			h.Logger.Error("SSO login failed", zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": "single sign-on failed"})
+		}
+		return
+	}
+	// local 2FA applies as on a password login
+	if h.challengeSecondFactor(c, user, "sso") {
+		return
+	}
+	h.completeLogin(c, user, "sso", nil)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	dto "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/dto"
+	entities "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// VerifyTwoFactor completes a login that returned mfa_required with a TOTP code
+// or a single-use recovery code.
+func (h *UserHandler) VerifyTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+	if !h.requireTwoFactor(c) {
+		return
+	}
+
+	user, device, err := h.TwoFactor.VerifyChallenge(c.Request.Context(), req.ChallengeToken, req.Code, req.RecoveryCode, loginAttempt(c))
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	h.completeLogin(c, user, device, nil)
+}
+
+// SetupTwoFactorWithChallenge starts the enrollment required by the user's role
+// during login (the login returned enrollment_required).
+func (h *UserHandler) SetupTwoFactorWithChallenge(c *gin.Context) {
+	var req dto.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.requireTwoFactor(c) {
+		return
+	}
+
+	e, err := h.TwoFactor.EnrollWithChallenge(req.ChallengeToken)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorEnrollResponse{Secret: e.Secret, ProvisioningURI: e.ProvisioningURI})
+}
+
+// ConfirmTwoFactorWithChallenge enables 2FA with the first code and completes the
+// login; the response carries the recovery codes once.
+func (h *UserHandler) ConfirmTwoFactorWithChallenge(c *gin.Context) {
+	var req dto.TwoFactorConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.requireTwoFactor(c) {
+		return
+	}
+
+	user, device, codes, err := h.TwoFactor.ConfirmWithChallenge(c.Request.Context(), req.ChallengeToken, req.Code, loginAttempt(c))
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	h.completeLogin(c, user, device, codes)
+}
+
+// TwoFactorStatus reports whether the caller has 2FA enabled or required
+func (h *UserHandler) TwoFactorStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	st, err := h.TwoFactor.Status(user)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": st.Enabled, "required": st.Required, "recovery_codes_remaining": st.RecoveryCodes})
+}
+
+// EnrollTwoFactor creates a pending TOTP secret for the caller
+func (h *UserHandler) EnrollTwoFactor(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	e, err := h.TwoFactor.Enroll(user)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorEnrollResponse{Secret: e.Secret, ProvisioningURI: e.ProvisioningURI})
+}
+
+// ConfirmTwoFactor enables the pending secret and returns the recovery codes
+func (h *UserHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	codes, err := h.TwoFactor.Confirm(user.ID, req.Code)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	codes, err := h.TwoFactor.RegenerateRecoveryCodes(user.ID, req.Code)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor removes the caller's 2FA (not allowed when the role requires it)
+func (h *UserHandler) DisableTwoFactor(c *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if err := h.TwoFactor.Disable(user, req.Code); err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func (h *UserHandler) requireTwoFactor(c *gin.Context) bool {
+	if h.TwoFactor == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "two-factor authentication not configured"})
+		return false
+	}
+	return true
+}
+
+// currentUser loads the authenticated user, answering the request itself on failure
+func (h *UserHandler) currentUser(c *gin.Context) (*entities.User, bool) {
+	if !h.requireTwoFactor(c) {
+		return nil, false
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return nil, false
+	}
+	var user entities.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h *UserHandler) twoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, useruc.ErrInvalidChallenge),
+		errors.Is(err, useruc.ErrInvalidTwoFactorCode),
+		errors.Is(err, useruc.ErrTooManyChallengeTries):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrTwoFactorNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, useruc.ErrTwoFactorRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		h.Logger.Error("two-factor operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,6 +15,7 @@ import (
 	dto "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/dto"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
 	entities "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,6 +24,20 @@ type UserHandler struct {
 	DB         *gorm.DB
 	Logger     *zap.Logger
 	JWTService *security.JWTService
+	// Sessions issues access/refresh token pairs (login, refresh, logout)
+	Sessions *useruc.SessionTokensUseCase
+	// Guard throttles failed logins per user and IP (optional)
+	Guard *useruc.LoginGuard
+	// TwoFactor adds the TOTP step to logins (optional)
+	TwoFactor *useruc.TwoFactorUseCase
+	// APIKeys manages the caller's personal API keys (optional)
+	APIKeys *useruc.APIKeysUseCase
+	// SSO logs users in through the OpenID Connect provider (optional)
+	SSO *useruc.SSOUseCase
+	// Directory checks passwords against LDAP / Active Directory before the local accounts (optional)
+	Directory *useruc.DirectoryLoginUseCase
+	// RoleGrants handles just-in-time role requests (optional)
+	RoleGrants *useruc.RoleGrantsUseCase
 }
 
 func NewUserHandler(db *gorm.DB, logger *zap.Logger) *UserHandler {
@@ -88,27 +107,26 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Crear registro en user_roles con role_id = 3
-	userRole := entities.UserRole{
-	    UserID: user.ID,
-	    RoleID: 3, // ID del rol "user"
-	}
-	if err := h.DB.Create(&userRole).Error; err != nil {
+	// user_roles es la fuente de los roles: users.role solo refleja el principal
+	var role entities.Role
+	if err := h.DB.Where("name = ?", user.Role).First(&role).Error; err != nil {
+	    h.Logger.Error("default role not found", zap.String("role", user.Role), zap.Error(err))
+	} else if err := h.DB.Create(&entities.UserRole{UserID: user.ID, RoleID: role.ID}).Error; err != nil {
 	    h.Logger.Error("failed to create user role", zap.Error(err))
 	    // No hacemos return porque el usuario ya fue creado exitosamente
 	    // Solo loggeamos el error pero continuamos
 	}
 
-	// Generate JWT token for the new user
-	if h.JWTService == nil {
-		h.Logger.Warn("JWTService is nil, cannot generate token for new user")
+	// Start a session for the new user (the auth middleware only accepts tokens with a session)
+	if h.Sessions == nil {
+		h.Logger.Warn("session service is nil, cannot generate token for new user")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication service not available"})
 		return
 	}
 
-	token, err := h.JWTService.GenerateToken(user.ID, user.Username, user.Role)
+	pair, err := h.Sessions.Start(c.Request.Context(), &user, sessionMeta(c, ""))
 	if err != nil {
-		h.Logger.Error("failed to generate token", zap.Error(err))
+		h.Logger.Error("failed to start session", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
@@ -125,7 +143,13 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Role:      user.Role,
 	}
 
-	c.JSON(http.StatusCreated, dto.LoginResponse{Token: token, User: userResp})
+	c.JSON(http.StatusCreated, dto.LoginResponse{
+		Token:            pair.AccessToken,
+		User:             userResp,
+		RefreshToken:     pair.RefreshToken,
+		ExpiresIn:        secondsUntil(pair.AccessExpiresAt),
+		RefreshExpiresIn: secondsUntil(pair.RefreshExpiresAt),
+	})
 }
 
 // Login logs the user in and returns JWT token
@@ -136,22 +160,57 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Find user by username
+	attempt := loginAttempt(c)
+	attempt.Username = req.Username
+	if h.Guard != nil {
+		wait, err := h.Guard.RetryAfter(c.Request.Context(), attempt)
+		if err != nil {
+			h.Logger.Error("failed checking login lockout", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		if wait > 0 {
+			// locked or still within the progressive delay; the password is not
+			// checked, so the answer is the same whether the user exists or not
+			setRetryAfter(c, wait)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts, retry later"})
+			return
+		}
+	}
+
+	// directory (LDAP) users first; unknown ones fall back to local accounts
 	var user entities.User
-	if err := h.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	var fromDirectory bool
+	if h.Directory != nil {
+		du, done := h.directoryLogin(c, req, attempt)
+		if done {
 			return
 		}
-		h.Logger.Error("database error", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
-		return
+		if du != nil {
+			user, fromDirectory = *du, true
+		}
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
-		return
+	if !fromDirectory {
+		// Find user by username
+		if err := h.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// compare anyway so unknown users take as long as wrong passwords
+				_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(req.Password))
+				h.loginFailed(c, attempt)
+				return
+			}
+			h.Logger.Error("database error", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		attempt.UserID = &user.ID
+
+		// Verify password
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			h.loginFailed(c, attempt)
+			return
+		}
 	}
 
 	// Check if user is active
@@ -160,48 +219,65 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Prevent login when user already has an active API session (single-session policy)
-	var existingSession entities.Session
-	if err := h.DB.Where("user_id = ? AND is_active = ?", user.ID, true).First(&existingSession).Error; err == nil {
-		// if session exists and is not expired, disallow new login
-		if existingSession.ExpiresAt == nil || existingSession.ExpiresAt.After(time.Now()) {
-			c.JSON(http.StatusConflict, gin.H{"error": "user already has an active session"})
-			return
-		}
-		// otherwise mark as inactive and allow login
-		existingSession.IsActive = false
-		_ = h.DB.Save(&existingSession).Error
-	} else if err != nil && err != gorm.ErrRecordNotFound {
-		h.Logger.Error("failed checking existing session", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	if h.challengeSecondFactor(c, &user, req.Device) {
 		return
 	}
+	h.completeLogin(c, &user, req.Device, nil)
+}
+
+// challengeSecondFactor answers with a 2FA challenge instead of tokens for users
+// with 2FA (or whose role requires it). It returns true when it has responded.
+func (h *UserHandler) challengeSecondFactor(c *gin.Context, user *entities.User, device string) bool {
+	if h.TwoFactor == nil {
+		return false
+	}
+	challenge, err := h.TwoFactor.BeginLogin(user, device)
+	if err != nil {
+		h.Logger.Error("failed to start 2FA challenge", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return true
+	}
+	if challenge == nil {
+		return false
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorChallengeResponse{
+		MFARequired:        true,
+		EnrollmentRequired: challenge.EnrollmentRequired,
+		ChallengeToken:     challenge.Token,
+		ExpiresIn:          secondsUntil(challenge.ExpiresAt),
+	})
+	return true
+}
 
-	// Generate JWT token
-	if h.JWTService == nil {
-		h.Logger.Warn("JWTService is nil, cannot generate token")
+// completeLogin issues the token pair once every factor has been checked
+func (h *UserHandler) completeLogin(c *gin.Context, user *entities.User, device string, recoveryCodes []string) {
+	if h.Sessions == nil {
+		h.Logger.Warn("session service is nil, cannot generate token")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication service not available"})
 		return
 	}
 
-	token, err := h.JWTService.GenerateToken(user.ID, user.Username, user.Role)
+	// the session policy (SESSION_POLICY / SESSION_ROLE_POLICIES) may reject the
+	// login or close the user's oldest session
+	pair, err := h.Sessions.Start(c.Request.Context(), user, sessionMeta(c, device))
 	if err != nil {
-		h.Logger.Error("failed to generate token", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		if errors.Is(err, useruc.ErrSessionLimitReached) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.Logger.Error("failed to start session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
 		return
 	}
 
 	// Update LastLogin
-	h.DB.Model(&user).Update("last_login", gorm.Expr("CURRENT_TIMESTAMP"))
-
-	// persist session for the generated token
-	expires := time.Now().Add(24 * time.Hour)
-	session := entities.Session{UserID: user.ID, Token: token, ExpiresAt: &expires, IsActive: true}
-	if err := h.DB.Create(&session).Error; err != nil {
-		h.Logger.Error("failed to persist session", zap.Error(err))
-		// return internal error since session persistence is required for single-login enforcement
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session"})
-		return
+	h.DB.Model(user).Update("last_login", gorm.Expr("CURRENT_TIMESTAMP"))
+	if h.Guard != nil {
+		attempt := loginAttempt(c)
+		attempt.Username, attempt.UserID = user.Username, &user.ID
+		if err := h.Guard.Success(c.Request.Context(), attempt); err != nil {
+			h.Logger.Warn("failed resetting login failures", zap.Error(err))
+		}
 	}
 
 	// Return response
@@ -215,9 +291,183 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, dto.LoginResponse{
-		Token: token,
-		User:  userResp,
+		Token:            pair.AccessToken,
+		User:             userResp,
+		RefreshToken:     pair.RefreshToken,
+		ExpiresIn:        secondsUntil(pair.AccessExpiresAt),
+		RefreshExpiresIn: secondsUntil(pair.RefreshExpiresAt),
+		RecoveryCodes:    recoveryCodes,
+	})
+}
+
+// loginFailed counts the failure and answers with the same error whatever the
+// reason; Retry-After tells the client how long the progressive delay lasts
+func (h *UserHandler) loginFailed(c *gin.Context, attempt useruc.LoginAttempt) {
+	if h.Guard != nil {
+		wait, err := h.Guard.Failure(c.Request.Context(), attempt)
+		if err != nil {
+			h.Logger.Error("failed recording login failure", zap.Error(err))
+		}
+		if wait > 0 {
+			setRetryAfter(c, wait)
+		}
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+}
+
+// loginAttempt describes the request to the login guard; the caller sets the user
+func loginAttempt(c *gin.Context) useruc.LoginAttempt {
+	return useruc.LoginAttempt{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// setRetryAfter sets Retry-After in whole seconds, rounded up
+func setRetryAfter(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash is compared against when there is no real hash to check
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("microsql-dummy-password"), bcrypt.DefaultCost)
 	})
+	return dummyHash
+}
+
+// Refresh exchanges a refresh token for a new access/refresh pair. Refresh tokens
+// are single use: presenting one twice revokes every session of its family.
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req dto.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if h.Sessions == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication service not available"})
+		return
+	}
+
+	pair, err := h.Sessions.Refresh(req.RefreshToken, sessionMeta(c, ""))
+	if err != nil {
+		switch {
+		case errors.Is(err, useruc.ErrRefreshTokenReused):
+			h.Logger.Warn("refresh token reuse detected", zap.Error(err))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, useruc.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			h.Logger.Error("failed to refresh token", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RefreshResponse{
+		Token:            pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		ExpiresIn:        secondsUntil(pair.AccessExpiresAt),
+		RefreshExpiresIn: secondsUntil(pair.RefreshExpiresAt),
+	})
+}
+
+func secondsUntil(t time.Time) int64 {
+	return int64(time.Until(t).Round(time.Second) / time.Second)
+}
+
+// sessionMeta records where a login or refresh comes from
+func sessionMeta(c *gin.Context, device string) useruc.SessionMeta {
+	return useruc.SessionMeta{
+		Device:    device,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// ListMySessions lists the caller's own active sessions (one per login)
+func (h *UserHandler) ListMySessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return
+	}
+	if h.Sessions == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication service not available"})
+		return
+	}
+
+	list, err := h.Sessions.ListForUser(userID)
+	if err != nil {
+		h.Logger.Error("failed listing sessions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	currentID, _ := c.Get("sessionID")
+	out := make([]dto.SessionResponse, 0, len(list))
+	for _, s := range list {
+		started := s.CreatedAt
+		if s.StartedAt != nil {
+			started = *s.StartedAt
+		}
+		expires := s.RefreshExpiresAt
+		if expires == nil {
+			expires = s.ExpiresAt
+		}
+		out = append(out, dto.SessionResponse{
+			ID:         s.ID,
+			Device:     s.Device,
+			IPAddress:  s.IPAddress,
+			UserAgent:  s.UserAgent,
+			StartedAt:  started,
+			LastSeenAt: s.CreatedAt,
+			ExpiresAt:  expires,
+			Current:    currentID == s.ID,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": out})
+}
+
+// TerminateMySession closes one of the caller's sessions (including the current one)
+func (h *UserHandler) TerminateMySession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user context"})
+		return
+	}
+	if h.Sessions == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication service not available"})
+		return
+	}
+
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	if err := h.Sessions.TerminateForUser(userID, id); err != nil {
+		if errors.Is(err, useruc.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.Logger.Error("failed terminating session", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to terminate session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "session terminated", "session_id": id})
+}
+
+func currentUserID(c *gin.Context) (uint, bool) {
+	uid, exists := c.Get("userID")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := uid.(uint)
+	return userID, ok
 }
 
 // Logout invalidates the currently presented token and marks the API session inactive.
@@ -257,11 +507,21 @@ func (h *UserHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	s.IsActive = false
-	if err := h.DB.Save(&s).Error; err != nil {
-		h.Logger.Error("failed deactivating session", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
-		return
+	// revoke the session and the refresh tokens of this login; the auth
+	// middleware rejects the access token from now on
+	if h.Sessions != nil {
+		if err := h.Sessions.Logout(c.Request.Context(), &s); err != nil {
+			h.Logger.Error("failed revoking session", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+			return
+		}
+	} else {
+		s.IsActive = false
+		if err := h.DB.Save(&s).Error; err != nil {
+			h.Logger.Error("failed deactivating session", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/ldap"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/ldap/ldaptest"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc/oidctest"
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/totp"
+)
+
+func TestUserHandler_LoginLockoutIsIndistinguishable(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.User{}, &entities.Session{}, &entities.LoginThrottle{}, &entities.AuthAuditLog{}, &entities.AdminActionLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct-horse"), bcrypt.MinCost)
+	alice := &entities.User{Username: "alice", Email: "a@e", Password: string(hash), Role: "user", IsActive: true}
+	db.Omit("last_login").Create(alice)
+
+	authAudit := repo.NewGormAuthAuditRepository(db)
+	guard := useruc.NewLoginGuard(repo.NewGormLoginThrottleRepository(db), authAudit, useruc.LockoutPolicy{
+		MaxUserFailures: 3,
+		FailureWindow:   time.Minute,
+		LockoutDuration: time.Hour,
+	})
+	jwt := security.NewJWTServiceWithTTL("secret", time.Minute, time.Hour)
+	uh := NewUserHandlerWithJWT(db, zap.NewNop(), jwt)
+	uh.Sessions = useruc.NewSessionTokensUseCase(repo.NewGormSessionRepository(db), persistence.NewUserRepository(db), jwt, nil)
+	uh.Guard = guard
+	ah := NewAdminHandler(db, zap.NewNop(), nil, nil, nil, repo.NewGormAdminAuditRepository(db))
+	ah.LoginGuard = guard
+	ah.AuthAudit = authAudit
+
+	r := gin.New()
+	r.POST("/login", uh.Login)
+	r.POST("/users/:id/unlock", ah.UnlockUser)
+	login := func(user, pass string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"username":%q,"password":%q}`, user, pass)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := login("alice", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d", i+1, w.Code)
+		}
+		if w := login("nobody", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401 for an unknown user, got %d", i+1, w.Code)
+		}
+	}
+
+	// the right password no longer works: 429 with Retry-After, the same answer
+	// as for a locked unknown user
+	locked := login("alice", "correct-horse")
+	unknown := login("nobody", "whatever")
+	if locked.Code != http.StatusTooManyRequests || locked.Code != unknown.Code || locked.Body.String() != unknown.Body.String() {
+		t.Fatalf("locked (%d %s) and unknown (%d %s) responses differ", locked.Code, locked.Body, unknown.Code, unknown.Body)
+	}
+	if got := locked.Header().Get("Retry-After"); got != "3600" || unknown.Header().Get("Retry-After") != got {
+		t.Fatalf("expected Retry-After of the lockout on both, got %q and %q", got, unknown.Header().Get("Retry-After"))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/users/%d/unlock", alice.ID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unlock: %d %s", w.Code, w.Body.String())
+	}
+	if w := login("alice", "correct-horse"); w.Code != http.StatusOK {
+		t.Fatalf("expected login after unlock, got %d %s", w.Code, w.Body.String())
+	}
+
+	var events []string
+	db.Model(&entities.AuthAuditLog{}).Where("username = ? AND event IN ?", "alice", []string{entities.AuthEventAccountLocked, entities.AuthEventUnlocked}).Order("id").Pluck("event", &events)
+	if strings.Join(events, ",") != "account.locked,account.unlocked" {
+		t.Fatalf("unexpected lockout events: %v", events)
+	}
+	var adminActions int64
+	db.Model(&entities.AdminActionLog{}).Where("action = ?", "user.unlock").Count(&adminActions)
+	if adminActions != 1 {
+		t.Fatalf("expected unlock in admin action log")
+	}
+}
+
+type plainEnc struct{}
+
+func (plainEnc) Encrypt(s string) (string, error) { return s, nil }
+func (plainEnc) Decrypt(s string) (string, error) { return s, nil }
+
+func TestUserHandler_LoginRequiresTwoFactorEnrollmentForAdmins(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.User{}, &entities.Session{}, &entities.UserTOTP{}, &entities.RecoveryCode{}, &entities.LoginChallenge{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct-horse"), bcrypt.MinCost)
+	db.Omit("last_login").Create(&entities.User{Username: "root", Email: "r@e", Password: string(hash), Role: "admin", IsActive: true})
+
+	jwt := security.NewJWTServiceWithTTL("secret", time.Minute, time.Hour)
+	users := persistence.NewUserRepository(db)
+	uh := NewUserHandlerWithJWT(db, zap.NewNop(), jwt)
+	uh.Sessions = useruc.NewSessionTokensUseCase(repo.NewGormSessionRepository(db), users, jwt, nil)
+	uh.TwoFactor = useruc.NewTwoFactorUseCase(repo.NewGormMFARepository(db), users, plainEnc{}, useruc.TwoFactorOptions{RequiredRoles: []string{"admin"}})
+
+	r := gin.New()
+	r.POST("/login", uh.Login)
+	r.POST("/2fa/setup", uh.SetupTwoFactorWithChallenge)
+	r.POST("/2fa/setup/confirm", uh.ConfirmTwoFactorWithChallenge)
+	post := func(path, body string, out interface{}) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s: decode %s: %v", path, w.Body.String(), err)
+		}
+		return w.Code
+	}
+
+	var challenge map[string]interface{}
+	if code := post("/login", `{"username":"root","password":"correct-horse"}`, &challenge); code != http.StatusOK {
+		t.Fatalf("login: %d", code)
+	}
+	if challenge["token"] != nil || challenge["mfa_required"] != true || challenge["enrollment_required"] != true {
+		t.Fatalf("expected an enrollment challenge instead of a token, got %v", challenge)
+	}
+	token, _ := challenge["challenge_token"].(string)
+
+	var enroll map[string]string
+	if code := post("/2fa/setup", fmt.Sprintf(`{"challenge_token":%q}`, token), &enroll); code != http.StatusOK || !strings.HasPrefix(enroll["provisioning_uri"], "otpauth://totp/") {
+		t.Fatalf("setup: %d %v", code, enroll)
+	}
+	otp, _ := totp.CodeAt(enroll["secret"], totp.Step(time.Now()))
+
+	var loggedIn map[string]interface{}
+	if code := post("/2fa/setup/confirm", fmt.Sprintf(`{"challenge_token":%q,"code":%q}`, token, otp), &loggedIn); code != http.StatusOK {
+		t.Fatalf("confirm: %d %v", code, loggedIn)
+	}
+	if loggedIn["token"] == "" || loggedIn["refresh_token"] == "" {
+		t.Fatalf("expected tokens after confirming 2FA, got %v", loggedIn)
+	}
+	if codes, _ := loggedIn["recovery_codes"].([]interface{}); len(codes) != useruc.RecoveryCodeCount {
+		t.Fatalf("expected recovery codes in the response, got %v", loggedIn["recovery_codes"])
+	}
+}
+
+func TestUserHandler_SSOLoginIsBoundToTheBrowserAndAsksForTwoFactor(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.Session{}, &entities.UserIdentity{}, &entities.OIDCLoginState{}, &entities.UserTOTP{}, &entities.RecoveryCode{}, &entities.LoginChallenge{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db.Create(&entities.Role{Name: "admin"})
+
+	mock, srv := oidctest.Start("microsql")
+	defer srv.Close()
+	mock.SetUser(oidctest.User{Subject: "s-ana", Email: "ana@example.com", EmailVerified: true, Username: "ana"})
+	idp := oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "microsql", RedirectURL: "http://app/callback"})
+	jwt := security.NewJWTServiceWithTTL("secret", time.Minute, time.Hour)
+	users := persistence.NewUserRepository(db)
+	uh := NewUserHandlerWithJWT(db, zap.NewNop(), jwt)
+	uh.Sessions = useruc.NewSessionTokensUseCase(repo.NewGormSessionRepository(db), users, jwt, nil)
+	uh.TwoFactor = useruc.NewTwoFactorUseCase(repo.NewGormMFARepository(db), users, plainEnc{}, useruc.TwoFactorOptions{RequiredRoles: []string{"admin"}})
+	uh.SSO = useruc.NewSSOUseCase(idp, repo.NewGormSSORepository(db), users, repo.NewGormRoleRepository(db), useruc.SSOOptions{
+		ExternalAccountOptions: useruc.ExternalAccountOptions{DefaultRole: "admin"},
+	})
+
+	r := gin.New()
+	r.GET("/oidc/login", uh.OIDCLogin)
+	r.GET("/oidc/callback", uh.OIDCCallback)
+	// start returns the callback query the provider redirects to and the state cookie
+	start := func() (string, *http.Cookie) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oidc/login", nil))
+		cookies := w.Result().Cookies()
+		if w.Code != http.StatusFound || len(cookies) != 1 || !cookies[0].HttpOnly {
+			t.Fatalf("login: expected a redirect with an HttpOnly state cookie, got %d %v", w.Code, cookies)
+		}
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("authorize: %v", err)
+		}
+		resp.Body.Close()
+		loc, _ := url.Parse(resp.Header.Get("Location"))
+		return loc.RawQuery, cookies[0]
+	}
+	callback := func(query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/oidc/callback?"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// a callback URL opened in another browser (no cookie, or another login's) is refused
+	query, _ := start()
+	_, other := start()
+	if w := callback(query, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without the state cookie, got %d", w.Code)
+	}
+	if w := callback(query, other); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with another login's cookie, got %d", w.Code)
+	}
+
+	// the browser that started the login gets the 2FA challenge of its role, not tokens
+	query, cookie := start()
+	w := callback(query, cookie)
+	var body map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusOK || body["mfa_required"] != true || body["enrollment_required"] != true || body["token"] != nil {
+		t.Fatalf("expected a 2FA enrollment challenge, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestUserHandler_LoginThroughDirectory(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&entities.User{}, &entities.Session{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.UserIdentity{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, name := range []string{"admin", "auditor", "user"} {
+		db.Create(&entities.Role{Name: name})
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("local-pass"), bcrypt.MinCost)
+	db.Omit("last_login").Create(&entities.User{Username: "root", Email: "root@local", Password: string(hash), Role: "admin", IsActive: true})
+
+	dirSrv, err := ldaptest.Start(true)
+	if err != nil {
+		t.Fatalf("start ldap stand-in: %v", err)
+	}
+	defer dirSrv.Close()
+	dirSrv.Add(ldaptest.Entry{DN: "uid=dba1,ou=people,dc=corp", Password: "dir-pass", Attributes: map[string][]string{
+		"uid": {"dba1"}, "mail": {"dba1@corp"}, "memberOf": {"cn=dba,ou=groups,dc=corp"},
+	}})
+	dirSrv.Add(ldaptest.Entry{DN: "uid=temp,ou=people,dc=corp", Password: "dir-pass", Attributes: map[string][]string{"uid": {"temp"}}})
+	dir, err := ldap.NewAuthenticator(ldap.Config{URL: dirSrv.URL, TLS: &tls.Config{RootCAs: dirSrv.RootCAs}, BaseDN: "dc=corp", GroupAttribute: "memberOf"})
+	if err != nil {
+		t.Fatalf("authenticator: %v", err)
+	}
+
+	jwt := security.NewJWTServiceWithTTL("secret", time.Minute, time.Hour)
+	users := persistence.NewUserRepository(db)
+	roles := repo.NewGormRoleRepository(db)
+	uh := NewUserHandlerWithJWT(db, zap.NewNop(), jwt)
+	uh.Sessions = useruc.NewSessionTokensUseCase(repo.NewGormSessionRepository(db), users, jwt, nil).WithPolicies(useruc.SessionPolicies{Default: useruc.SessionPolicy{Mode: useruc.SessionPolicyReplaceOldest, Max: 1}})
+	uh.Directory = useruc.NewDirectoryLoginUseCase(dir, repo.NewGormSSORepository(db), users, roles, useruc.ExternalAccountOptions{
+		RoleMappings: []useruc.RoleMapping{{Group: "dba", Role: "auditor"}},
+	})
+
+	r := gin.New()
+	r.POST("/login", uh.Login)
+	login := func(user, pass string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(fmt.Sprintf(`{"username":%q,"password":%q}`, user, pass))))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	// first directory login provisions the user with the role of their group
+	code, body := login("dba1", "dir-pass")
+	if code != http.StatusOK {
+		t.Fatalf("directory login: %d %v", code, body)
+	}
+	if u, _ := body["user"].(map[string]interface{}); u["username"] != "dba1" || u["role"] != "auditor" {
+		t.Fatalf("unexpected user %v", body["user"])
+	}
+	provisioned, _ := users.FindByUsername("dba1")
+	if r, _ := roles.GetUserRoles(provisioned.ID); len(r) != 1 || r[0].Name != "auditor" {
+		t.Fatalf("expected auditor in user_roles, got %v", r)
+	}
+	if code, _ := login("dba1", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong directory password: %d", code)
+	}
+	// directory users with no mapped group are refused when there is no default role
+	if code, body := login("temp", "dir-pass"); code != http.StatusForbidden {
+		t.Fatalf("unmapped user: %d %v", code, body)
+	}
+	// local accounts keep working, also while the directory is unreachable
+	if code, body := login("root", "local-pass"); code != http.StatusOK {
+		t.Fatalf("local login: %d %v", code, body)
+	}
+	dirSrv.Close()
+	if code, body := login("root", "local-pass"); code != http.StatusOK {
+		t.Fatalf("local login with the directory down: %d %v", code, body)
+	}
+	if code, _ := login("dba1", "dir-pass"); code != http.StatusUnauthorized {
+		t.Fatalf("directory users must not get in through the local path: %d", code)
+	}
+}
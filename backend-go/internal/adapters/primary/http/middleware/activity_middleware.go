@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	activityuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/activity"
+)
+
+// ActivityMiddleware feeds the API activity log. It puts the request details in
+// the request context so the use case hooks (login, connections, audits,
+// downloads) can tag their events with them, and records an api.request event
+// for the requests no hook described: every request that changes something or
+// is rejected with 401/403, and reads too when LogReads is set. Those events go
+// through the activity queue, so a flood of rejected requests is written in
+// batches instead of one insert per request.
+type ActivityMiddleware struct {
+	Queue    services.ActivityQueue
+	LogReads bool
+}
+
+// NewActivityMiddleware creates the middleware bound to the activity log
+func NewActivityMiddleware(q services.ActivityQueue, logReads bool) *ActivityMiddleware {
+	return &ActivityMiddleware{Queue: q, LogReads: logReads}
+}
+
+// Handler returns the gin.HandlerFunc; it must run after RequestLogger to reuse its request id
+func (m *ActivityMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqID := c.GetString("request_id")
+		if reqID == "" {
+			reqID = uuid.New().String()
+		}
+		info := &activityuc.RequestInfo{
+			RequestID: reqID,
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+		}
+		c.Request = c.Request.WithContext(activityuc.WithRequest(c.Request.Context(), info))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		denied := status == http.StatusUnauthorized || status == http.StatusForbidden
+		if info.Recorded() || (!denied && !m.LogReads && isRead(c.Request.Method)) {
+			return
+		}
+		ev := &entities.ActivityEvent{Action: entities.ActivityAPIRequest, Status: status}
+		switch {
+		case denied:
+			ev.Outcome = entities.OutcomeDenied
+		case status >= http.StatusBadRequest:
+			ev.Outcome = entities.OutcomeFailure
+		}
+		if v, ok := c.Get("userID"); ok {
+			if id, ok := v.(uint); ok {
+				ev.ActorID = &id
+			}
+		}
+		ev.ActorName = c.GetString("username")
+		if route := c.FullPath(); route != "" {
+			ev.Details = entities.ActivityDetails(map[string]interface{}{"route": route})
+		}
+		m.Queue.Enqueue(c.Request.Context(), ev)
+	}
+}
+
+func isRead(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
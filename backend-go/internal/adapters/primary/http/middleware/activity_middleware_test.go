@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	activityuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/activity"
+	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestActivityMiddleware_recordsWritesAndRejectionsOnce(t *testing.T) {
+	db, jwt, cache := setupSessionAuth(t)
+	if err := db.AutoMigrate(&entities.ActivityEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	activity := activityuc.NewActivityLogUseCase(repo.NewGormActivityRepository(db), activityuc.Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	written := make(chan struct{})
+	go func() {
+		activity.Run(ctx)
+		close(written)
+	}()
+	keys := staticAPIKeys{"msk_ci": {KeyID: 9, User: &entities.User{ID: 3, Username: "ci"}, Scopes: []string{"audits:execute"}}}
+	m := NewAuthMiddlewareWithSessions(jwt, cache).WithAPIKeys(keys)
+
+	r := gin.New()
+	r.Use(NewLoggingMiddleware(zap.NewNop()).RequestLogger(), NewActivityMiddleware(activity, false).Handler())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/roles", m.RequireAuth(), ok)
+	r.POST("/roles", m.RequireAuth(), ok)
+	r.POST("/audits", m.RequireAuth("audits:execute"), func(c *gin.Context) {
+		// a use case hook describes the request; the middleware adds nothing
+		activity.Record(c.Request.Context(), &entities.ActivityEvent{Action: entities.ActivityAuditExecute, ActorID: ptr(c.GetUint("userID"))})
+		c.Status(http.StatusOK)
+	})
+	call := func(method, path, auth string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", auth)
+		req.Header.Set("User-Agent", "cli/1.0")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	token, _ := issue(t, db, jwt, 1, "user")
+	call(http.MethodGet, "/roles", "Bearer "+token)
+	call(http.MethodPost, "/roles", "Bearer "+token)
+	call(http.MethodGet, "/roles", "Bearer bogus")
+	call(http.MethodPost, "/audits", "ApiKey msk_ci")
+	// the middleware's events are queued; stopping the writer flushes them
+	cancel()
+	<-written
+
+	events, total, err := activity.List(repositories.ActivityFilter{})
+	if err != nil || total != 3 {
+		t.Fatalf("expected the write, the rejection and the hook event, got %d %+v %v", total, events, err)
+	}
+	hook, denied, write := events[0], events[1], events[2]
+	if write.Action != entities.ActivityAPIRequest || write.Outcome != entities.OutcomeSuccess || write.ActorID == nil || *write.ActorID != 1 ||
+		write.ActorName != "u" || write.Method != http.MethodPost || write.Path != "/roles" || write.Status != http.StatusOK ||
+		write.RequestID == "" || write.UserAgent != "cli/1.0" || write.Category != "api" {
+		t.Fatalf("unexpected write event: %+v", write)
+	}
+	if denied.Outcome != entities.OutcomeDenied || denied.ActorType != entities.ActorAnonymous || denied.Status != http.StatusUnauthorized {
+		t.Fatalf("expected the GET rejected with 401 to be recorded as denied, got %+v", denied)
+	}
+	if hook.Action != entities.ActivityAuditExecute || hook.ActorType != entities.ActorAPIKey || hook.APIKeyID == nil || *hook.APIKeyID != 9 ||
+		hook.ActorName != "ci" || hook.RequestID == "" || hook.RequestID == write.RequestID {
+		t.Fatalf("expected the hook event to carry the request and key, got %+v", hook)
+	}
+}
+
+func ptr(v uint) *uint { return &v }
@@ -1,90 +1,146 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
+	activityuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/activity"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
 )
 
+// APIKeyAuthenticator validates "Authorization: ApiKey <key>" credentials
+type APIKeyAuthenticator interface {
+	Authenticate(key, ip string) (*useruc.APIKeyPrincipal, error)
+}
+
 // AuthMiddleware handles JWT validation and user context
 type AuthMiddleware struct {
 	jwtService *security.JWTService
+	// sessions, when set, rejects tokens whose session was logged out or revoked
+	sessions *security.SessionCache
+	// apiKeys, when set, accepts personal API keys on routes that declare scopes
+	apiKeys APIKeyAuthenticator
 }
 
 func NewAuthMiddleware(jwtService *security.JWTService) *AuthMiddleware {
 	return &AuthMiddleware{jwtService: jwtService}
 }
 
-// RequireAuth verifies JWT token and sets user info in context
-func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
+// NewAuthMiddlewareWithSessions also checks that the token's session is still active
+func NewAuthMiddlewareWithSessions(jwtService *security.JWTService, sessions *security.SessionCache) *AuthMiddleware {
+	return &AuthMiddleware{jwtService: jwtService, sessions: sessions}
+}
+
+// WithAPIKeys enables "Authorization: ApiKey <key>" on routes that declare scopes
+func (m *AuthMiddleware) WithAPIKeys(a APIKeyAuthenticator) *AuthMiddleware {
+	m.apiKeys = a
+	return m
+}
+
+// RequireAuth verifies JWT token and sets user info in context.
+// API keys are only accepted when the route lists scopes, and the key must carry
+// at least one of them; routes without scopes are reserved for interactive logins.
+func (m *AuthMiddleware) RequireAuth(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		header := c.GetHeader("Authorization")
-		if header == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "no token provided"})
+		if !m.authenticate(c, scopes) {
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Extract Bearer token
-		const bearerSchema string = "Bearer "
-		if len(header) < len(bearerSchema) || header[:len(bearerSchema)] != bearerSchema {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
-			return
-		}
+// authenticate validates the bearer token and its session and fills the context.
+// It aborts the request and returns false on failure; it never calls c.Next().
+func (m *AuthMiddleware) authenticate(c *gin.Context, scopes []string) bool {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "no token provided"})
+		return false
+	}
 
-		token := header[len(bearerSchema):]
+	const apiKeySchema string = "ApiKey "
+	if m.apiKeys != nil && strings.HasPrefix(header, apiKeySchema) {
+		return m.authenticateAPIKey(c, strings.TrimSpace(header[len(apiKeySchema):]), scopes)
+	}
 
-		// Validate token
-		claims, err := m.jwtService.ValidateToken(token)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token: " + err.Error()})
-			return
-		}
+	// Extract Bearer token
+	const bearerSchema string = "Bearer "
+	if len(header) < len(bearerSchema) || header[:len(bearerSchema)] != bearerSchema {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+		return false
+	}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
-		c.Next()
+	token := header[len(bearerSchema):]
+
+	// Validate token
+	claims, err := m.jwtService.ValidateToken(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token: " + err.Error()})
+		return false
 	}
-}
 
-// RequireRole ensures user has one of the required roles
-func (m *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// First validate token (via RequireAuth)
-		m.RequireAuth()(c)
-		if c.IsAborted() {
-			return
+	// Server-side session check: logout/revocation must take effect before the JWT expires
+	if m.sessions != nil {
+		session, active, err := m.sessions.Lookup(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify session"})
+			return false
 		}
-
-		// Get user role from context
-		userRole, exists := c.Get("role")
-		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "role not found in token"})
-			return
+		if !active || session.UserID != claims.UserID {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session is no longer active"})
+			return false
 		}
+		c.Set("sessionID", session.ID)
+	}
 
-		role, ok := userRole.(string)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid role type"})
-			return
-		}
+	// Set user info in context
+	c.Set("userID", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("role", claims.Role)
+	if info := activityuc.RequestFrom(c.Request.Context()); info != nil {
+		info.SetActor(claims.UserID, claims.Username, nil)
+	}
+	return true
+}
 
-		// Check if role is in allowed list
-		roleAllowed := false
-		for _, allowed := range allowedRoles {
-			if role == allowed {
-				roleAllowed = true
-				break
-			}
+// authenticateAPIKey validates a personal API key and checks it against the route scopes
+func (m *AuthMiddleware) authenticateAPIKey(c *gin.Context, key string, scopes []string) bool {
+	if len(scopes) == 0 {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API keys are not accepted on this endpoint"})
+		return false
+	}
+	p, err := m.apiKeys.Authenticate(key, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, useruc.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify API key"})
 		}
-
-		if !roleAllowed {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
-			return
+		return false
+	}
+	allowed := false
+	for _, have := range p.Scopes {
+		for _, want := range scopes {
+			if have == want {
+				allowed = true
+			}
 		}
+	}
+	if !allowed {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key lacks the required scope: " + strings.Join(scopes, " or ")})
+		return false
+	}
 
-		c.Next()
+	c.Set("userID", p.User.ID)
+	c.Set("username", p.User.Username)
+	c.Set("role", p.User.Role)
+	c.Set("apiKeyID", p.KeyID)
+	c.Set("apiKeyScopes", p.Scopes)
+	if info := activityuc.RequestFrom(c.Request.Context()); info != nil {
+		info.SetActor(p.User.ID, p.User.Username, &p.KeyID)
 	}
+	return true
 }
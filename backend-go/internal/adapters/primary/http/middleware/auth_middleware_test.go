@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func setupSessionAuth(t *testing.T) (*gorm.DB, *security.JWTService, *security.SessionCache) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.Session{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	jwt := security.NewJWTServiceWithTTL("secret", time.Hour, time.Hour)
+	// a long TTL proves revocation works through explicit invalidation, not expiry
+	cache := security.NewSessionCache(repositories.NewGormSessionRepository(db), time.Hour)
+	return db, jwt, cache
+}
+
+func issue(t *testing.T, db *gorm.DB, jwt *security.JWTService, userID uint, role string) (string, *entities.Session) {
+	token, err := jwt.GenerateToken(userID, "u", role)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	exp := time.Now().Add(time.Hour)
+	s := &entities.Session{UserID: userID, Token: token, ExpiresAt: &exp, IsActive: true}
+	if err := db.Create(s).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	return token, s
+}
+
+func get(r *gin.Engine, path, token string) int {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRequireAuth_rejectsRevokedSessionImmediately(t *testing.T) {
+	db, jwt, cache := setupSessionAuth(t)
+	m := NewAuthMiddlewareWithSessions(jwt, cache)
+
+	r := gin.New()
+	r.GET("/me", m.RequireAuth(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	token, s := issue(t, db, jwt, 1, "user")
+	if code := get(r, "/me", token); code != http.StatusOK {
+		t.Fatalf("expected 200 for active session, got %d", code)
+	}
+
+	// revoke in the database; the cached "active" state is still served...
+	if err := repositories.NewGormSessionRepository(db).RevokeByID(s.ID, time.Now()); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	// ...until the revocation path invalidates it
+	cache.InvalidateToken(token)
+	if code := get(r, "/me", token); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after revocation, got %d", code)
+	}
+
+	// a valid JWT without a session is rejected too
+	orphan, _ := jwt.GenerateToken(1, "u", "user")
+	if code := get(r, "/me", orphan); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for token without session, got %d", code)
+	}
+}
+
+type staticAPIKeys map[string]*useruc.APIKeyPrincipal
+
+func (s staticAPIKeys) Authenticate(key, ip string) (*useruc.APIKeyPrincipal, error) {
+	if p, ok := s[key]; ok {
+		return p, nil
+	}
+	return nil, useruc.ErrInvalidAPIKey
+}
+
+func TestRequireAuth_apiKeysOnlyReachScopedRoutes(t *testing.T) {
+	db, jwt, cache := setupSessionAuth(t)
+	keys := staticAPIKeys{"msk_ci": {KeyID: 9, User: &entities.User{ID: 3, Username: "ci", Role: "admin"}, Scopes: []string{"audits:execute", "audits:view"}}}
+	m := NewAuthMiddlewareWithSessions(jwt, cache).WithAPIKeys(keys)
+
+	r := gin.New()
+	ok := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": c.GetUint("userID"), "key": c.GetUint("apiKeyID")})
+	}
+	r.GET("/audits", m.RequireAuth("audits:execute"), ok)
+	r.GET("/connections", m.RequireAuth("connections:manage"), ok)
+	r.GET("/sessions", m.RequireAuth(), ok)
+	call := func(path, auth string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", auth)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := call("/audits", "ApiKey msk_ci"); w.Code != http.StatusOK || w.Body.String() != `{"key":9,"user":3}` {
+		t.Fatalf("expected scoped route to accept the key, got %d %s", w.Code, w.Body)
+	}
+	if w := call("/connections", "ApiKey msk_ci"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a scope the key lacks, got %d", w.Code)
+	}
+	// routes without scopes stay closed to keys, even an admin's
+	if w := call("/sessions", "ApiKey msk_ci"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an API key, got %d", w.Code)
+	}
+	if w := call("/audits", "ApiKey msk_unknown"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown key, got %d", w.Code)
+	}
+	// login tokens are not restricted by route scopes
+	token, _ := issue(t, db, jwt, 1, "user")
+	if got := get(r, "/connections", token); got != http.StatusOK {
+		t.Fatalf("expected bearer token to pass, got %d", got)
+	}
+}
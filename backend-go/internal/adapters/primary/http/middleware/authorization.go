@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionsKey guarda en el contexto los permisos efectivos ya resueltos
+const permissionsKey = "permissions"
+
+// PermissionResolver returns the effective permissions of a user
+type PermissionResolver interface {
+	Effective(userID uint) (map[string]bool, error)
+}
+
+// AuthorizationMiddleware authenticates the caller and checks the permission a
+// route declares. Permissions come from the database (user_roles plus the
+// primary role), not from the token, so role changes apply to the next request;
+// they are resolved at most once per request.
+type AuthorizationMiddleware struct {
+	auth  *AuthMiddleware
+	perms PermissionResolver
+}
+
+func NewAuthorizationMiddleware(auth *AuthMiddleware, perms PermissionResolver) *AuthorizationMiddleware {
+	return &AuthorizationMiddleware{auth: auth, perms: perms}
+}
+
+// RequireLogin only authenticates; for self-service routes every user may call
+func (m *AuthorizationMiddleware) RequireLogin() gin.HandlerFunc {
+	return m.auth.RequireAuth()
+}
+
+// RequirePermission requires at least one of perms. API keys are accepted when
+// one of their scopes is among perms.
+func (m *AuthorizationMiddleware) RequirePermission(perms ...string) gin.HandlerFunc {
+	return m.require(perms, true)
+}
+
+// RequireLoginPermission is RequirePermission for routes API keys must not reach
+func (m *AuthorizationMiddleware) RequireLoginPermission(perms ...string) gin.HandlerFunc {
+	return m.require(perms, false)
+}
+
+func (m *AuthorizationMiddleware) require(perms []string, apiKeys bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes := perms
+		if !apiKeys {
+			scopes = nil
+		}
+		if !m.auth.authenticate(c, scopes) {
+			return
+		}
+		granted, err := m.Permissions(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve permissions"})
+			return
+		}
+		for _, p := range perms {
+			if granted[p] {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission: " + strings.Join(perms, " or ")})
+	}
+}
+
+// Permissions returns the effective permissions of the authenticated caller,
+// cached in the request context. For an API key they are the key's scopes,
+// which the key authenticator already limited to the owner's permissions.
+func (m *AuthorizationMiddleware) Permissions(c *gin.Context) (map[string]bool, error) {
+	if v, ok := c.Get(permissionsKey); ok {
+		return v.(map[string]bool), nil
+	}
+	granted := map[string]bool{}
+	if scopes, ok := c.Get("apiKeyScopes"); ok {
+		for _, s := range scopes.([]string) {
+			granted[s] = true
+		}
+	} else {
+		var err error
+		if granted, err = m.perms.Effective(c.GetUint("userID")); err != nil {
+			return nil, err
+		}
+	}
+	c.Set(permissionsKey, granted)
+	return granted, nil
+}
+
+// PermissionList returns the caller's permissions sorted, for responses
+func PermissionList(granted map[string]bool) []string {
+	out := make([]string, 0, len(granted))
+	for p, ok := range granted {
+		if ok {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+)
+
+// countingResolver serves fixed permissions and counts lookups
+type countingResolver struct {
+	perms map[uint][]string
+	calls int
+}
+
+func (r *countingResolver) Effective(userID uint) (map[string]bool, error) {
+	r.calls++
+	out := map[string]bool{}
+	for _, p := range r.perms[userID] {
+		out[p] = true
+	}
+	return out, nil
+}
+
+func TestRequirePermission_resolvesOncePerRequest(t *testing.T) {
+	db, jwt, cache := setupSessionAuth(t)
+	resolver := &countingResolver{perms: map[uint][]string{1: {"audits:view"}, 2: {"connections:manage"}}}
+	keys := staticAPIKeys{"msk_ci": {KeyID: 9, User: &entities.User{ID: 1}, Scopes: []string{"audits:view"}}}
+	m := NewAuthorizationMiddleware(NewAuthMiddlewareWithSessions(jwt, cache).WithAPIKeys(keys), resolver)
+
+	ran := false
+	r := gin.New()
+	// the second check reuses the permissions resolved by the first
+	r.GET("/audits", m.RequirePermission("audits:view", "audits:execute"), m.RequirePermission("audits:view"), func(c *gin.Context) {
+		ran = true
+		c.Status(http.StatusOK)
+	})
+	r.GET("/admin", m.RequireLoginPermission("audits:view"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	viewer, _ := issue(t, db, jwt, 1, "user")
+	if code := get(r, "/audits", viewer); code != http.StatusOK || !ran {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if resolver.calls != 1 {
+		t.Fatalf("expected one permission lookup per request, got %d", resolver.calls)
+	}
+
+	// the role name in the token does not matter, only the resolved permissions
+	ran = false
+	operator, _ := issue(t, db, jwt, 2, "admin")
+	if code := get(r, "/audits", operator); code != http.StatusForbidden || ran {
+		t.Fatalf("expected 403 without the permission, got %d", code)
+	}
+
+	call := func(path string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "ApiKey msk_ci")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	calls := resolver.calls
+	if code := call("/audits"); code != http.StatusOK {
+		t.Fatalf("expected the key scope to grant the route, got %d", code)
+	}
+	if resolver.calls != calls {
+		t.Fatalf("API keys are authorized by their scopes, not a new lookup")
+	}
+	if code := call("/admin"); code != http.StatusForbidden {
+		t.Fatalf("expected login-only route to refuse the key, got %d", code)
+	}
+}
+
+var _ PermissionResolver = (*useruc.PermissionsUseCase)(nil)
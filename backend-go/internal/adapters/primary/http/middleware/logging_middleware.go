@@ -39,8 +39,9 @@ func (m *LoggingMiddleware) RequestLogger() gin.HandlerFunc {
 			rl = rl.With(zap.String("authorization", logging.RedactAuthHeader(auth)))
 		}
 
-		// attach logger to context for handlers to reuse
+		// attach logger and request id to context for handlers to reuse
 		c.Set("logger", rl)
+		c.Set("request_id", reqID)
 
 		rl.Info("request.start")
 
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	middleware "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/middleware"
+)
+
+// Access levels of the route table
+const (
+	AccessPublic        = "public"
+	AccessAuthenticated = "authenticated"
+	AccessPermission    = "permission"
+)
+
+// RouteRule is the access rule of one route, as served to the frontend
+type RouteRule struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Access string `json:"access"`
+	// Permissions required by the route, any of them (only for AccessPermission)
+	Permissions []string `json:"permissions,omitempty"`
+	// APIKeys tells whether "Authorization: ApiKey" is accepted
+	APIKeys bool `json:"api_keys"`
+}
+
+// RouteTable collects the rule of every route while it is registered, so the
+// table cannot drift from the middleware actually applied
+type RouteTable struct {
+	rules []RouteRule
+}
+
+// Rules returns the routes in registration order
+func (t *RouteTable) Rules() []RouteRule {
+	return append([]RouteRule(nil), t.rules...)
+}
+
+// router registers routes on a gin group and records their rule in the table.
+// Every route must declare its access through Public, Authenticated or Permission.
+type router struct {
+	group *gin.RouterGroup
+	table *RouteTable
+	authz *middleware.AuthorizationMiddleware
+	// noAPIKeys keeps API keys off the permission routes of this group
+	noAPIKeys bool
+}
+
+// Group returns a router for a sub-path
+func (r router) Group(relativePath string) router {
+	r.group = r.group.Group(relativePath)
+	return r
+}
+
+// WithoutAPIKeys returns a router whose permission routes only accept logins
+func (r router) WithoutAPIKeys() router {
+	r.noAPIKeys = true
+	return r
+}
+
+// Public routes need no credentials
+func (r router) Public() routeAccess {
+	return routeAccess{r: r, rule: RouteRule{Access: AccessPublic}}
+}
+
+// Authenticated routes accept any logged-in user (self-service); API keys are refused
+func (r router) Authenticated() routeAccess {
+	return routeAccess{r: r, rule: RouteRule{Access: AccessAuthenticated}, guard: r.authz.RequireLogin()}
+}
+
+// Permission routes require at least one of perms
+func (r router) Permission(perms ...string) routeAccess {
+	guard := r.authz.RequirePermission(perms...)
+	if r.noAPIKeys {
+		guard = r.authz.RequireLoginPermission(perms...)
+	}
+	return routeAccess{r: r, rule: RouteRule{Access: AccessPermission, Permissions: perms, APIKeys: !r.noAPIKeys}, guard: guard}
+}
+
+type routeAccess struct {
+	r     router
+	rule  RouteRule
+	guard gin.HandlerFunc
+}
+
+func (a routeAccess) GET(relativePath string, h ...gin.HandlerFunc) {
+	a.handle(http.MethodGet, relativePath, h)
+}
+
+func (a routeAccess) POST(relativePath string, h ...gin.HandlerFunc) {
+	a.handle(http.MethodPost, relativePath, h)
+}
+
+func (a routeAccess) PUT(relativePath string, h ...gin.HandlerFunc) {
+	a.handle(http.MethodPut, relativePath, h)
+}
+
+func (a routeAccess) DELETE(relativePath string, h ...gin.HandlerFunc) {
+	a.handle(http.MethodDelete, relativePath, h)
+}
+
+func (a routeAccess) handle(method, relativePath string, h []gin.HandlerFunc) {
+	rule := a.rule
+	rule.Method = method
+	rule.Path = path.Join(a.r.group.BasePath(), relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(rule.Path, "/") {
+		rule.Path += "/"
+	}
+	a.r.table.rules = append(a.r.table.rules, rule)
+	if a.guard != nil {
+		h = append([]gin.HandlerFunc{a.guard}, h...)
+	}
+	a.r.group.Handle(method, relativePath, h...)
+}
+
+// routeTableHandler serves the route table with, for each route, whether the
+// caller may use it, plus the caller's effective permissions
+func routeTableHandler(table *RouteTable, authz *middleware.AuthorizationMiddleware) gin.HandlerFunc {
+	type routeView struct {
+		RouteRule
+		Allowed bool `json:"allowed"`
+	}
+	return func(c *gin.Context) {
+		granted, err := authz.Permissions(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve permissions"})
+			return
+		}
+		rules := table.Rules()
+		out := make([]routeView, 0, len(rules))
+		for _, rule := range rules {
+			allowed := rule.Access != AccessPermission
+			for _, p := range rule.Permissions {
+				allowed = allowed || granted[p]
+			}
+			out = append(out, routeView{RouteRule: rule, Allowed: allowed})
+		}
+		c.JSON(http.StatusOK, gin.H{"routes": out, "permissions": middleware.PermissionList(granted)})
+	}
+}
@@ -1,7 +1,10 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -9,140 +12,499 @@ import (
 
 	handlers "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/handlers"
 	middleware "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/primary/http/middleware"
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
 	sqladp "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/sqlserver"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	activityuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/activity"
 	connectionuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/connection"
 	controlsuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/controls"
+	evidenceuc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/evidence"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
 	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
 	sqlexec "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/sqlserver"
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/encryption"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/ldap"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/secrets"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/siem"
 )
 
-// RegisterRoutes wires HTTP routes with JWT middleware.
-func RegisterRoutes(r *gin.Engine, db *gorm.DB, logger *zap.Logger) {
+// RegisterRoutes wires HTTP routes with JWT middleware. Background jobs (sweepers,
+// monitors, exporters) run until ctx is cancelled.
+func RegisterRoutes(ctx context.Context, r *gin.Engine, db *gorm.DB, logger *zap.Logger) {
 	// attach request-scoped logging middleware
 	logMW := middleware.NewLoggingMiddleware(logger)
 	r.Use(logMW.RequestLogger())
 	// Create JWT service from config
 	cfg := config.LoadConfig()
-	jwtService := security.NewJWTService(cfg.JWTSecret)
+	// API activity log: the middleware tags each request and records the ones no
+	// use case described (queued and written in batches); old events are purged
+	// after ACTIVITY_RETENTION
+	activity := activityuc.NewActivityLogUseCase(repo.NewGormActivityRepository(db), activityuc.Options{
+		Retention:     cfg.ActivityRetention,
+		PurgeInterval: cfg.ActivityPurgeInterval,
+	}).WithLogger(logger)
+	go activity.Run(ctx)
+	// SIEM: security events of the activity log are also shipped to syslog
+	var siemExporter *siem.Exporter
+	if cfg.SIEMAddress != "" {
+		facility, err := siem.ParseFacility(cfg.SIEMFacility)
+		if err != nil {
+			logger.Fatal("invalid SIEM facility", zap.Error(err))
+		}
+		var categories []string
+		if cfg.SIEMCategories != "all" {
+			categories = strings.Split(cfg.SIEMCategories, ",")
+		}
+		siemExporter, err = siem.NewExporter(siem.Config{
+			Network:      cfg.SIEMNetwork,
+			Address:      cfg.SIEMAddress,
+			Format:       cfg.SIEMFormat,
+			Facility:     &facility,
+			AppName:      cfg.SIEMAppName,
+			Categories:   categories,
+			BufferSize:   cfg.SIEMBufferSize,
+			MaxRetries:   cfg.SIEMMaxRetries,
+			RetryBackoff: cfg.SIEMRetryBackoff,
+			CAFile:       cfg.SIEMTLSCAFile,
+		})
+		if err != nil {
+			logger.Fatal("invalid SIEM configuration", zap.Error(err))
+		}
+		siemExporter.WithLogger(logger)
+		go siemExporter.Run(ctx)
+		activity.WithSink(siemExporter)
+	}
+	r.Use(middleware.NewActivityMiddleware(activity, cfg.ActivityLogReads).Handler())
+	jwtService := security.NewJWTServiceWithTTL(cfg.JWTSecret, cfg.JWTAccessTTL, cfg.JWTRefreshTTL)
+	if security.NormalizeJWTAlg(cfg.JWTAlg) != security.JWTAlgHS256 {
+		// asymmetric signing: other services verify our tokens through /.well-known/jwks.json;
+		// the retire time of rotated keys is kept in the database so restarts do not extend it
+		jwtKeys, err := security.LoadJWTKeyring(cfg.JWTAlg, cfg.JWTKeyID, cfg.JWTKeys, cfg.JWTKeyOverlap, repo.NewGormJWTKeyRepository(db))
+		if err != nil {
+			logger.Fatal("failed to load JWT signing keys", zap.Error(err))
+		}
+		jwtService = security.NewJWTServiceWithKeyring(jwtKeys, cfg.JWTAccessTTL, cfg.JWTRefreshTTL)
+	}
+	// access/refresh token pairs backed by the sessions table; the cache lets the
+	// auth middleware reject logged-out/revoked tokens before they expire
+	sessionRepo := repo.NewGormSessionRepository(db)
+	sessionCache := security.NewSessionCache(sessionRepo, cfg.SessionCacheTTL)
+	sessionPolicies, err := useruc.ParseSessionPolicies(cfg.SessionPolicy, cfg.SessionRolePolicies)
+	if err != nil {
+		logger.Fatal("invalid session policy", zap.Error(err))
+	}
+	sessionPolicies.Idle = cfg.SessionIdleTimeout
+	sessionTokens := useruc.NewSessionTokensUseCase(sessionRepo, persistence.NewUserRepository(db), jwtService, sessionCache).
+		WithPolicies(sessionPolicies).
+		WithRoles(repo.NewGormRoleRepository(db)).
+		WithActivity(activity)
+	// user_roles is the only source of a user's roles; after a change the primary
+	// role in users.role is derived again and the user's access tokens expire so
+	// the client refreshes them with the new claims
+	roleAssignments := useruc.NewRoleAssignmentsUseCase(persistence.NewUserRepository(db), repo.NewGormRoleRepository(db), sessionRepo, sessionCache).WithLogger(logger)
+
+	// failed-login counters per user and IP, progressive delays and temporary lockouts
+	authAudit := repo.NewGormAuthAuditRepository(db)
+	loginGuard := useruc.NewLoginGuard(repo.NewGormLoginThrottleRepository(db), authAudit, useruc.LockoutPolicy{
+		MaxUserFailures: cfg.LoginMaxUserFailures,
+		MaxIPFailures:   cfg.LoginMaxIPFailures,
+		FailureWindow:   cfg.LoginFailureWindow,
+		LockoutDuration: cfg.LoginLockoutDuration,
+		BaseDelay:       cfg.LoginDelayBase,
+		MaxDelay:        cfg.LoginDelayMax,
+	}).WithActivity(activity)
+
+	// SQL Server pools are shared by the db routes and the admin stats endpoint
+	sqlService := sqladp.NewSQLServerAdapterWithOptions(logger, sqladp.PoolOptions{
+		MaxPools:        cfg.SQLPoolMaxPools,
+		IdleTTL:         cfg.SQLPoolIdleTTL,
+		MaxOpenConns:    cfg.SQLPoolMaxOpenConns,
+		MaxIdleConns:    cfg.SQLPoolMaxIdleConns,
+		ConnMaxLifetime: cfg.SQLPoolConnMaxLifetime,
+	})
+	sqlService.StartEviction(ctx)
+
+	// keyring for stored DB passwords: encrypts with ENCRYPTION_KEY_ID, decrypts with any key in ENCRYPTION_KEYS
+	keyring, err := encryption.ParseKeyring(cfg.EncKeyID, cfg.EncKeys, cfg.EncKey)
+	if err != nil {
+		logger.Fatal("failed to configure encryption keyring", zap.Error(err))
+	}
+	// secret store for DB passwords: AES in the row (default), encrypted keystore file or Vault KV
+	secretStore, err := secrets.New(secrets.Options{
+		Backend:  cfg.SecretBackend,
+		FilePath: cfg.SecretFilePath,
+		FileKey:  cfg.SecretFileKey,
+		Vault: secrets.VaultOptions{
+			Addr:   cfg.VaultAddr,
+			Token:  cfg.VaultToken,
+			Mount:  cfg.VaultMount,
+			Prefix: cfg.VaultPathPrefix,
+		},
+	}, keyring)
+	if err != nil {
+		logger.Fatal("failed to configure secret store", zap.Error(err))
+	}
+
+	// TOTP second factor; secrets are encrypted with the credential keyring
+	twoFactor := useruc.NewTwoFactorUseCase(repo.NewGormMFARepository(db), persistence.NewUserRepository(db), keyring, useruc.TwoFactorOptions{
+		Issuer:        cfg.MFAIssuer,
+		RequiredRoles: useruc.ParseRoleList(cfg.MFARequiredRoles),
+		ChallengeTTL:  cfg.MFAChallengeTTL,
+	}).WithGuard(loginGuard).WithRoles(repo.NewGormRoleRepository(db))
+
+	// accounts holding these roles are never linked to an external identity by email
+	privilegedRoles := append([]string{"admin"}, useruc.ParseRoleList(cfg.MFARequiredRoles)...)
+
+	// personal API keys for automation, limited to a subset of the owner's permissions
+	apiKeys := useruc.NewAPIKeysUseCase(repo.NewGormAPIKeyRepository(db), persistence.NewUserRepository(db), repo.NewGormRoleRepository(db), useruc.APIKeyOptions{
+		DefaultTTL: cfg.APIKeyDefaultTTL,
+		MaxTTL:     cfg.APIKeyMaxTTL,
+	}).WithLogger(logger)
 
-	r.GET("/health", func(c *gin.Context) {
+	// OpenID Connect single sign-on (authorization code + PKCE), only when an issuer is configured
+	var sso *useruc.SSOUseCase
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "" {
+			logger.Fatal("OIDC_ISSUER requires OIDC_CLIENT_ID and OIDC_REDIRECT_URL")
+		}
+		mappings, err := useruc.ParseRoleMappings(cfg.OIDCRoleMapping)
+		if err != nil {
+			logger.Fatal("invalid OIDC role mapping", zap.Error(err))
+		}
+		idp := oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       strings.Fields(cfg.OIDCScopes),
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+		})
+		sso = useruc.NewSSOUseCase(idp, repo.NewGormSSORepository(db), persistence.NewUserRepository(db), repo.NewGormRoleRepository(db), useruc.SSOOptions{
+			ExternalAccountOptions: useruc.ExternalAccountOptions{
+				RoleMappings:    mappings,
+				DefaultRole:     cfg.OIDCDefaultRole,
+				LinkByEmail:     cfg.OIDCLinkByEmail,
+				PrivilegedRoles: privilegedRoles,
+			},
+		}).WithAssignments(roleAssignments).WithLogger(logger)
+	}
+
+	// LDAP / Active Directory: checked before the local accounts on /api/auth/login
+	var directory *useruc.DirectoryLoginUseCase
+	if cfg.LDAPURL != "" {
+		mappings, err := useruc.ParseRoleMappings(cfg.LDAPRoleMapping)
+		if err != nil {
+			logger.Fatal("invalid LDAP role mapping", zap.Error(err))
+		}
+		dir, err := ldap.NewAuthenticator(ldap.Config{
+			URL:               cfg.LDAPURL,
+			StartTLS:          cfg.LDAPStartTLS,
+			CAFile:            cfg.LDAPCAFile,
+			AllowInsecure:     cfg.LDAPAllowInsecure,
+			BindDN:            cfg.LDAPBindDN,
+			BindPassword:      cfg.LDAPBindPassword,
+			BaseDN:            cfg.LDAPBaseDN,
+			UserFilter:        cfg.LDAPUserFilter,
+			UsernameAttribute: cfg.LDAPUsernameAttribute,
+			EmailAttribute:    cfg.LDAPEmailAttribute,
+			NameAttribute:     cfg.LDAPNameAttribute,
+			IDAttribute:       cfg.LDAPIDAttribute,
+			GroupAttribute:    cfg.LDAPGroupAttribute,
+			GroupFilter:       cfg.LDAPGroupFilter,
+			GroupBaseDN:       cfg.LDAPGroupBaseDN,
+			Timeout:           cfg.LDAPTimeout,
+		})
+		if err != nil {
+			logger.Fatal("invalid LDAP configuration", zap.Error(err))
+		}
+		directory = useruc.NewDirectoryLoginUseCase(dir, repo.NewGormSSORepository(db), persistence.NewUserRepository(db), repo.NewGormRoleRepository(db), useruc.ExternalAccountOptions{
+			RoleMappings:    mappings,
+			DefaultRole:     cfg.LDAPDefaultRole,
+			LinkByEmail:     cfg.LDAPLinkByEmail,
+			PrivilegedRoles: privilegedRoles,
+		}).WithAssignments(roleAssignments).WithLogger(logger)
+	}
+
+	// every route declares who may call it (public, any login, or a permission);
+	// permissions are resolved from the user's roles once per request
+	// permissions may be scoped to server groups, servers and databases; the use
+	// cases check the scopes of the resource they touch
+	authMW := middleware.NewAuthMiddlewareWithSessions(jwtService, sessionCache).WithAPIKeys(apiKeys)
+	scopeRepo := repo.NewGormResourceScopeRepository(db)
+	permissionsUC := useruc.NewPermissionsUseCase(persistence.NewUserRepository(db), repo.NewGormRoleRepository(db), scopeRepo)
+	authz := middleware.NewAuthorizationMiddleware(authMW, permissionsUC)
+	// temporary role grants and just-in-time requests; expired grants no longer
+	// count for authorization and the sweeper removes and logs them
+	roleGrants := useruc.NewRoleGrantsUseCase(repo.NewGormRoleRepository(db), repo.NewGormRoleRequestRepository(db), useruc.RoleGrantOptions{
+		MaxDuration:      cfg.JITMaxDuration,
+		RequestableRoles: useruc.ParseRoleList(cfg.JITRequestableRoles),
+	}).WithAssignments(roleAssignments)
+	go useruc.NewRoleGrantSweeper(repo.NewGormRoleRepository(db), repo.NewGormAdminAuditRepository(db), cfg.RoleGrantSweepInterval).
+		WithAssignments(roleAssignments).
+		WithActivity(activity).
+		WithLogger(logger).
+		Run(ctx)
+	// tamper-evident audit records: admin logs, audit runs and script results are
+	// hash chains whose last link is signed periodically
+	// the default JWT secret is public: anyone could sign checkpoints with a key derived from it
+	evidenceSecret := cfg.JWTSecret
+	if evidenceSecret == config.DefaultJWTSecret {
+		evidenceSecret = ""
+	}
+	evidenceSigner, err := security.LoadEvidenceSigner(cfg.EvidenceSigningKeyID, cfg.EvidenceSigningKey, cfg.EvidenceTrustedKeys, evidenceSecret)
+	switch {
+	case errors.Is(err, security.ErrNoEvidenceKey) && gin.Mode() != gin.ReleaseMode:
+		// development setups keep the default secret: sign with a key of this
+		// process only, whose checkpoints are unverifiable after a restart
+		logger.Warn("no EVIDENCE_SIGNING_KEY and JWT_SECRET is the default; evidence checkpoints are signed with an ephemeral key and cannot be verified after a restart")
+		evidenceSigner, err = security.NewEphemeralEvidenceSigner(cfg.EvidenceTrustedKeys)
+	case err == nil && cfg.EvidenceSigningKey == "":
+		logger.Warn("EVIDENCE_SIGNING_KEY not set; evidence checkpoints are signed with a key derived from JWT_SECRET")
+	}
+	if err != nil {
+		logger.Fatal("failed to load evidence signing key", zap.Error(err))
+	}
+	evidence := evidenceuc.NewEvidenceChainUseCase(repo.NewGormEvidenceChainRepository(db), evidenceSigner, cfg.EvidenceCheckpointInterval).
+		WithLogger(logger)
+	go evidence.Run(ctx)
+	table := &RouteTable{}
+	root := router{group: &r.RouterGroup, table: table, authz: authz}
+
+	root.Public().GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// public keys for verifying our access tokens (empty in HS256 mode)
+	root.Public().GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwtService.JWKS())
+	})
+
 	// root - help message
-	r.GET("/", func(c *gin.Context) {
+	root.Public().GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"service": "MicroSQL AGo backend", "status": "ok"})
 	})
 
 	// register user routes
-	api := r.Group("/api")
+	api := root.Group("/api")
 
 	// swagger/info route
-	api.GET("/swagger", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"swagger": "not generated"}) })
+	api.Public().GET("/swagger", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"swagger": "not generated"}) })
 
 	// Auth routes (login unprotected, logout protected)
 	auth := api.Group("/auth")
 	{
 		uh := handlers.NewUserHandlerWithJWT(db, logger, jwtService)
-		auth.POST("/login", uh.Login)
+		uh.Sessions = sessionTokens
+		uh.Guard = loginGuard
+		uh.TwoFactor = twoFactor
+		uh.APIKeys = apiKeys
+		uh.SSO = sso
+		uh.Directory = directory
+		uh.RoleGrants = roleGrants
+		auth.Public().POST("/login", uh.Login)
+		// SSO: redirect to the identity provider and complete the login on its callback
+		auth.Public().GET("/oidc/login", uh.OIDCLogin)
+		auth.Public().GET("/oidc/callback", uh.OIDCCallback)
+		auth.Public().POST("/oidc/callback", uh.OIDCCallback)
+		// second step of the login (challenge_token from /login): verify a code, or
+		// enroll when the role requires 2FA
+		auth.Public().POST("/2fa/verify", uh.VerifyTwoFactor)
+		auth.Public().POST("/2fa/setup", uh.SetupTwoFactorWithChallenge)
+		auth.Public().POST("/2fa/setup/confirm", uh.ConfirmTwoFactorWithChallenge)
+		// exchange a refresh token for a new pair (rotation + reuse detection)
+		auth.Public().POST("/refresh", uh.Refresh)
 		// logout is protected: user must include valid bearer token
-		authMW := middleware.NewAuthMiddleware(jwtService)
-		auth.POST("/logout", authMW.RequireAuth(), uh.Logout)
+		auth.Authenticated().POST("/logout", uh.Logout)
+		// the caller's own sessions: list and terminate (device, IP, user agent)
+		auth.Authenticated().GET("/sessions", uh.ListMySessions)
+		auth.Authenticated().DELETE("/sessions/:id", uh.TerminateMySession)
+		// 2FA management for the logged-in user
+		auth.Authenticated().GET("/2fa", uh.TwoFactorStatus)
+		auth.Authenticated().POST("/2fa/enroll", uh.EnrollTwoFactor)
+		auth.Authenticated().POST("/2fa/confirm", uh.ConfirmTwoFactor)
+		auth.Authenticated().POST("/2fa/recovery-codes", uh.RegenerateRecoveryCodes)
+		auth.Authenticated().DELETE("/2fa", uh.DisableTwoFactor)
+		// personal API keys (managed with a login token; keys cannot create keys)
+		auth.Authenticated().GET("/api-keys", uh.ListMyAPIKeys)
+		auth.Authenticated().POST("/api-keys", uh.CreateAPIKey)
+		auth.Authenticated().DELETE("/api-keys/:id", uh.RevokeMyAPIKey)
+		// route -> permission table for the frontend, with what the caller may use
+		auth.Authenticated().GET("/routes", routeTableHandler(table, authz))
+		// just-in-time elevation: ask for a role for a limited time, with a reason
+		auth.Authenticated().POST("/role-requests", uh.RequestRole)
+		auth.Authenticated().GET("/role-requests", uh.ListMyRoleRequests)
 	}
 
 	// User routes
 	users := api.Group("/users")
 	{
-		users.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
+		users.Public().GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"ok": true}) })
 		uh := handlers.NewUserHandlerWithJWT(db, logger, jwtService)
-		users.POST("/register", uh.Register)
+		uh.Sessions = sessionTokens
+		users.Public().POST("/register", uh.Register)
 	}
 
 	// NOTE: Audit endpoints are attached under /api/db/:manager/audits to make the manager explicit
 
-	// Admin endpoints
-	admin := api.Group("/admin")
+	// Admin endpoints: one permission per area; API keys are not accepted here
+	admin := api.Group("/admin").WithoutAPIKeys()
 	{
-		adminMW := middleware.NewAuthMiddleware(jwtService)
-		// only admin role may access admin routes
-		admin.Use(adminMW.RequireRole("admin"))
-		sessionRepo := repo.NewGormSessionRepository(db)
 		roleRepo := repo.NewGormRoleRepository(db)
 		permRepo := repo.NewGormPermissionRepository(db)
 		auditRepo := repo.NewGormAdminAuditRepository(db)
 		adminHandler := handlers.NewAdminHandler(db, logger, sessionRepo, roleRepo, permRepo, auditRepo)
-		admin.GET("/sessions", adminHandler.ListActiveSessions)
+		adminHandler.Pools = sqlService
+		adminHandler.SessionCache = sessionCache
+		adminHandler.LoginGuard = loginGuard
+		adminHandler.AuthAudit = authAudit
+		adminHandler.TwoFactor = twoFactor
+		adminHandler.APIKeys = apiKeys
+		adminHandler.ResourceScopes = useruc.NewResourceScopesUseCase(scopeRepo, roleRepo)
+		adminHandler.RoleHierarchy = useruc.NewRoleHierarchyUseCase(roleRepo)
+		adminHandler.RoleGrants = roleGrants
+		adminHandler.RoleAssignments = roleAssignments
+		adminHandler.Authz = permissionsUC
+		adminHandler.Evidence = evidence
+		adminHandler.Activity = activity
+		if siemExporter != nil {
+			adminHandler.SIEM = siemExporter
+		}
+		adminHandler.RBACPolicy = useruc.NewRBACPolicyUseCase(roleRepo, permRepo, scopeRepo).WithAssignments(roleAssignments).
+			WithTransactions(repo.NewGormRBACTransactor(db))
+		adminHandler.KeyRotation = connectionuc.NewRotateCredentialsUseCase(repo.NewGormConnectionRepository(db), keyring)
+		sessions := admin.Permission(entities.PermSessionsManage)
+		sessions.GET("/sessions", adminHandler.ListActiveSessions)
+		// session revocation: one login, all of a user's sessions, or everyone (?confirm=true)
+		sessions.DELETE("/sessions/:id", adminHandler.RevokeSession)
+		sessions.DELETE("/users/:id/sessions", adminHandler.RevokeUserSessions)
+		sessions.DELETE("/sessions", adminHandler.RevokeAllSessions)
 		// role management
-		admin.GET("/roles", adminHandler.ListRoles)
-		admin.POST("/roles", adminHandler.CreateRole)
-		admin.PUT("/roles/:id", adminHandler.UpdateRole)
-		admin.DELETE("/roles/:id", adminHandler.DeleteRole)
-		admin.GET("/users", adminHandler.ListUsersWithRoles)
-		admin.POST("/users/:id/roles", adminHandler.AssignRoleToUser)
-		admin.DELETE("/users/:id/roles", adminHandler.RevokeRoleFromUser)
+		roles := admin.Permission(entities.PermRolesManage)
+		roles.GET("/roles", adminHandler.ListRoles)
+		roles.POST("/roles", adminHandler.CreateRole)
+		roles.PUT("/roles/:id", adminHandler.UpdateRole)
+		roles.DELETE("/roles/:id", adminHandler.DeleteRole)
+		// parent role (inherited permissions) and direct vs inherited permissions
+		roles.PUT("/roles/:id/parent", adminHandler.SetRoleParent)
+		roles.GET("/roles/:id/permissions", adminHandler.GetRolePermissions)
+		admin.Permission(entities.PermUsersRead).GET("/users", adminHandler.ListUsersWithRoles)
+		roles.POST("/users/:id/roles", adminHandler.AssignRoleToUser)
+		roles.DELETE("/users/:id/roles", adminHandler.RevokeRoleFromUser)
+		admin.Permission(entities.PermUsersRead).GET("/users/:id/role-grants", adminHandler.ListUserRoleGrants)
+		// why a user has (or lacks) a permission, and everything a user can do
+		admin.Permission(entities.PermUsersRead).GET("/authz/explain", adminHandler.ExplainAuthz)
+		admin.Permission(entities.PermUsersRead).GET("/authz/users/:id/permissions", adminHandler.UserCapabilities)
+		// review just-in-time role requests
+		review := admin.Permission(entities.PermRoleRequestsReview)
+		review.GET("/role-requests", adminHandler.ListRoleRequests)
+		review.POST("/role-requests/:id/approve", adminHandler.ApproveRoleRequest)
+		review.POST("/role-requests/:id/reject", adminHandler.RejectRoleRequest)
+		// clear a login lockout (failed attempts counter)
+		admin.Permission(entities.PermUsersUpdate).POST("/users/:id/unlock", adminHandler.UnlockUser)
+		// remove a user's 2FA enrollment (lost device)
+		admin.Permission(entities.PermUsersUpdate).DELETE("/users/:id/2fa", adminHandler.ResetUserTwoFactor)
+		// personal API keys of every user
+		admin.Permission(entities.PermAPIKeysManage).GET("/api-keys", adminHandler.ListAPIKeys)
+		admin.Permission(entities.PermAPIKeysManage).DELETE("/api-keys/:id", adminHandler.RevokeAPIKey)
 		// RBAC audit logs
-		admin.GET("/audit/rbac", adminHandler.ListRBACAuditLogs)
+		admin.Permission(entities.PermAuditLogsView).GET("/audit/rbac", adminHandler.ListRBACAuditLogs)
+		// authentication events: logins, failures, lockouts, unlocks
+		admin.Permission(entities.PermAuditLogsView).GET("/audit/auth", adminHandler.ListAuthAuditLogs)
+		// hash chains of admin logs and audit evidence: verify them, or seal and sign a checkpoint now
+		admin.Permission(entities.PermAuditLogsView).GET("/audit/verify", adminHandler.VerifyEvidence)
+		admin.Permission(entities.PermAuditLogsView).POST("/audit/checkpoints", adminHandler.CreateEvidenceCheckpoint)
+		// API activity log: logins, connections, audits, downloads, admin actions and other requests
+		admin.Permission(entities.PermAuditLogsView).GET("/audit/activity", adminHandler.ListActivity)
 		// permissions management
-		admin.GET("/permissions", adminHandler.ListPermissions)
-		admin.POST("/permissions", adminHandler.CreatePermission)
-		admin.PUT("/permissions/:id", adminHandler.UpdatePermission)
-		admin.DELETE("/permissions/:id", adminHandler.DeletePermission)
-		admin.POST("/roles/:id/permissions", adminHandler.AssignPermissionToRole)
-		admin.DELETE("/roles/:id/permissions", adminHandler.RevokePermissionFromRole)
+		perms := admin.Permission(entities.PermPermissionsManage)
+		perms.GET("/permissions", adminHandler.ListPermissions)
+		perms.POST("/permissions", adminHandler.CreatePermission)
+		perms.PUT("/permissions/:id", adminHandler.UpdatePermission)
+		perms.DELETE("/permissions/:id", adminHandler.DeletePermission)
+		perms.POST("/roles/:id/permissions", adminHandler.AssignPermissionToRole)
+		perms.DELETE("/roles/:id/permissions", adminHandler.RevokePermissionFromRole)
+		// limit a role's permission to server groups, servers or databases
+		perms.GET("/roles/:id/permissions/:permission_id/scopes", adminHandler.GetPermissionScopes)
+		perms.PUT("/roles/:id/permissions/:permission_id/scopes", adminHandler.SetPermissionScopes)
+		perms.GET("/server-groups", adminHandler.ListServerGroups)
+		perms.POST("/server-groups", adminHandler.CreateServerGroup)
+		perms.PUT("/server-groups/:id", adminHandler.UpdateServerGroup)
+		perms.DELETE("/server-groups/:id", adminHandler.DeleteServerGroup)
+		// roles and permissions as code: export the policy, preview (?mode=plan) or apply an import
+		perms.GET("/rbac/export", adminHandler.ExportRBACPolicy)
+		perms.POST("/rbac/import", adminHandler.ImportRBACPolicy)
 		// metrics endpoints for admin
-		admin.GET("/metrics/users", adminHandler.GetUsersMetrics)
-		admin.GET("/metrics/connections", adminHandler.GetConnectionsMetrics)
-		admin.GET("/metrics/audits", adminHandler.GetAuditsMetrics)
-		admin.GET("/metrics/roles", adminHandler.GetRolesMetrics)
-		admin.GET("/metrics/system", adminHandler.GetSystemMetrics)
+		metrics := admin.Permission(entities.PermMetricsView)
+		metrics.GET("/metrics/users", adminHandler.GetUsersMetrics)
+		metrics.GET("/metrics/connections", adminHandler.GetConnectionsMetrics)
+		metrics.GET("/metrics/audits", adminHandler.GetAuditsMetrics)
+		metrics.GET("/metrics/roles", adminHandler.GetRolesMetrics)
+		metrics.GET("/metrics/system", adminHandler.GetSystemMetrics)
+		metrics.GET("/metrics/siem", adminHandler.GetSIEMMetrics)
+		// live SQL Server pools (sql.DBStats per connection identity)
+		metrics.GET("/sql/pools", adminHandler.ListSQLPools)
+		// re-encrypt stored DB passwords with the current key (?dry_run=true to only count)
+		admin.Permission(entities.PermEncryptionRotate).POST("/encryption/rotate", adminHandler.RotateEncryptionKeys)
+		// connection history across all users (filters + CSV/JSON export)
+		adminHistory := handlers.NewConnectionHandler(nil, nil, nil, nil, connectionuc.NewListConnectionHistoryUseCase(repo.NewGormConnectionRepository(db)).WithAccess(permissionsUC).WithActivity(activity), logger)
+		admin.Permission(entities.PermConnectionsViewAll).GET("/connections/history", adminHistory.GetAllHistory)
+		admin.Permission(entities.PermConnectionsViewAll).GET("/connections/history/export", adminHistory.ExportAllHistory)
 	}
 
 	// DB connection endpoints: /api/db and /api/db/:manager
 	dbGroup := api.Group("/db")
 	{
-		connAuth := middleware.NewAuthMiddleware(jwtService)
-		dbGroup.Use(connAuth.RequireAuth())
+		// the user needs any of the listed permissions; an API key needs one of them as scope
+		connScope := dbGroup.Permission(entities.PermConnectionsManage, entities.PermAuditsExecute)
+		historyScope := dbGroup.Permission(entities.PermConnectionsManage, entities.PermAuditsView)
 
 		connRepo := repo.NewGormConnectionRepository(db)
-		sqlService := sqladp.NewSQLServerAdapter(logger)
-		// encryption service for persisting DB passwords
-		encService := encryption.NewAESGCMService(cfg.EncKey)
+		connectUC := connectionuc.NewConnectToServerUseCase(connRepo, sqlService, secretStore).WithAccess(permissionsUC).WithActivity(activity)
+		disconnectUC := connectionuc.NewDisconnectFromServerUseCase(connRepo, sqlService, secretStore).WithActivity(activity)
+		getActiveUC := connectionuc.NewGetActiveConnectionUseCase(connRepo).WithAccess(permissionsUC)
+		listUC := connectionuc.NewListActiveConnectionsUseCase(connRepo).WithAccess(permissionsUC)
 
-		connectUC := connectionuc.NewConnectToServerUseCase(connRepo, sqlService, encService)
-		disconnectUC := connectionuc.NewDisconnectFromServerUseCase(connRepo, sqlService)
-		getActiveUC := connectionuc.NewGetActiveConnectionUseCase(connRepo)
-		listUC := connectionuc.NewListActiveConnectionsUseCase(connRepo)
+		// background health checks and idle expiry for active connections
+		monitor := connectionuc.NewHealthMonitor(connRepo, sqlService, secretStore, cfg.ConnHealthInterval, cfg.ConnIdleTimeout).WithActivity(activity)
+		go monitor.Run(ctx)
 
 		// history UC to list connection logs
-		historyUC := connectionuc.NewListConnectionHistoryUseCase(repo.NewGormConnectionRepository(db))
+		historyUC := connectionuc.NewListConnectionHistoryUseCase(connRepo).WithAccess(permissionsUC).WithActivity(activity)
 		ch := handlers.NewConnectionHandler(connectUC, disconnectUC, getActiveUC, listUC, historyUC, logger)
 
 		// List all active connections for user across drivers
-		dbGroup.GET("/connections", ch.GetActive)
+		connScope.GET("/connections", ch.GetActive)
+		// Connection history of the user (filters + CSV/JSON export)
+		historyScope.GET("/history", ch.GetHistory)
+		historyScope.GET("/history/export", ch.ExportHistory)
 
 		// per-manager operations
 		mgr := dbGroup.Group(":manager")
 		{
+			mgrConn := mgr.Permission(entities.PermConnectionsManage, entities.PermAuditsExecute)
 			// open a connection: POST /api/db/:manager/open
-			mgr.POST("/open", ch.Connect)
+			mgrConn.POST("/open", ch.Connect)
 			// close connection for manager: DELETE /api/db/:manager/close
-			mgr.DELETE("/close", ch.Disconnect)
+			mgrConn.DELETE("/close", ch.Disconnect)
 			// get active connection for manager: GET /api/db/:manager/connection
-			mgr.GET("/connection", ch.GetActive)
+			mgrConn.GET("/connection", ch.GetActive)
+			// history for manager: GET /api/db/:manager/history
+			mgr.Permission(entities.PermConnectionsManage, entities.PermAuditsView).GET("/history", ch.GetHistory)
 
 			// Audits routes under the explicit manager: /api/db/:manager/audits
 			controlsRepo := repo.NewGormControlsRepository(db)
 			queryExec := sqlexec.NewSQLServerQueryExecutor()
 			auditRepo := repo.NewGormAuditRepository(db)
-			auditUC := controlsuc.NewExecuteAuditUseCase(controlsRepo, sqlService, queryExec, connRepo, auditRepo, encService)
+			auditUC := controlsuc.NewExecuteAuditUseCase(controlsRepo, sqlService, queryExec, connRepo, auditRepo, secretStore).WithAccess(permissionsUC).WithActivity(activity)
 			ah := handlers.NewAuditHandler(auditUC)
 
-			mgr.POST("/audits/execute", ah.ExecuteAudit)
-			mgr.GET("/audits/:id", ah.GetAudit)
+			mgr.Permission(entities.PermAuditsExecute).POST("/audits/execute", ah.ExecuteAudit)
+			mgr.Permission(entities.PermAuditsView).GET("/audits/:id", ah.GetAudit)
 		}
 	}
 }
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlite/migrations"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	useruc "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/usecases/user"
+	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+type routeTableResponse struct {
+	Routes []struct {
+		RouteRule
+		Allowed bool `json:"allowed"`
+	} `json:"routes"`
+	Permissions []string `json:"permissions"`
+}
+
+// newTestServer registers the real routes on an in-memory database with one
+// role that has no permissions and one that has all of them
+func newTestServer(t *testing.T) (*gin.Engine, func(username string) string) {
+	t.Setenv("MFA_REQUIRED_ROLES", "")
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := migrations.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	policy, err := useruc.ParsePolicy(config.DefaultRBACPolicy)
+	if err != nil {
+		t.Fatalf("parse policy: %v", err)
+	}
+	if err := useruc.NewRBACPolicyUseCase(repo.NewGormRoleRepository(db), repo.NewGormPermissionRepository(db), nil).Seed(policy); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	var all []entities.Permission
+	db.Find(&all)
+	operator := entities.Role{Name: "operator", Permissions: all}
+	db.Create(&entities.Role{Name: "nobody"})
+	db.Create(&operator)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+	for _, u := range []entities.User{
+		{Username: "nobody", Email: "nobody@local", Role: "nobody"},
+		{Username: "operator", Email: "operator@local", Role: "operator"},
+	} {
+		u.Password, u.IsActive = string(hash), true
+		if err := db.Omit("last_login").Create(&u).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	// like the server at startup: users.role becomes a user_roles assignment
+	if _, _, err := migrations.BackfillUserRoles(db); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+
+	r := gin.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	RegisterRoutes(ctx, r, db, zap.NewNop())
+	login := func(username string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(fmt.Sprintf(`{"username":%q,"password":"secret-pass"}`, username))))
+		var body struct {
+			Token string `json:"token"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusOK || body.Token == "" {
+			t.Fatalf("login %s: %d %s", username, w.Code, w.Body)
+		}
+		return body.Token
+	}
+	return r, login
+}
+
+func routeTable(t *testing.T, r *gin.Engine, token string) routeTableResponse {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/routes", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("route table: %d %s", w.Code, w.Body)
+	}
+	var table routeTableResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &table); err != nil {
+		t.Fatalf("decode route table: %v", err)
+	}
+	return table
+}
+
+// concretePath fills path parameters with values that match no stored row, so
+// that handlers reached by the allowed user do not change anything
+func concretePath(p string) string {
+	p = strings.ReplaceAll(p, ":manager", "sqlserver")
+	p = strings.ReplaceAll(p, ":permission_id", "999999")
+	return strings.ReplaceAll(p, ":id", "999999")
+}
+
+func TestRoutes_everyRouteDeclaresItsAccess(t *testing.T) {
+	r, login := newTestServer(t)
+	table := routeTable(t, r, login("nobody"))
+
+	declared := map[string]bool{}
+	for _, rule := range table.Routes {
+		declared[rule.Method+" "+rule.Path] = true
+		if rule.Access == AccessPermission && len(rule.Permissions) == 0 {
+			t.Errorf("%s %s: permission route without permissions", rule.Method, rule.Path)
+		}
+	}
+	for _, info := range r.Routes() {
+		if !declared[info.Method+" "+info.Path] {
+			t.Errorf("%s %s is registered without an entry in the route table", info.Method, info.Path)
+		}
+	}
+	if len(table.Permissions) != 0 {
+		t.Fatalf("expected no permissions for the nobody role, got %v", table.Permissions)
+	}
+}
+
+func TestRoutes_denyUsersWithoutThePermission(t *testing.T) {
+	r, login := newTestServer(t)
+	nobody, operator := login("nobody"), login("operator")
+	table := routeTable(t, r, nobody)
+
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	checked := 0
+	for _, rule := range table.Routes {
+		if rule.Access != AccessPermission {
+			continue
+		}
+		checked++
+		if rule.Allowed {
+			t.Errorf("%s %s: table marks the route as allowed for a user without permissions", rule.Method, rule.Path)
+		}
+		path := concretePath(rule.Path)
+		if w := call(rule.Method, path, nobody); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "missing permission") {
+			t.Errorf("%s %s: expected 403 without %v, got %d %s", rule.Method, path, rule.Permissions, w.Code, w.Body)
+		}
+		if w := call(rule.Method, path, operator); w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+			t.Errorf("%s %s: expected the permission to let the request through, got %d %s", rule.Method, path, w.Code, w.Body)
+		}
+	}
+	if checked < 40 {
+		t.Fatalf("expected every admin and db route in the table, only found %d", checked)
+	}
+
+	allowed := 0
+	for _, rule := range routeTable(t, r, operator).Routes {
+		if rule.Allowed {
+			allowed++
+		}
+	}
+	if allowed != len(table.Routes) {
+		t.Fatalf("expected the operator to be allowed everywhere, got %d of %d", allowed, len(table.Routes))
+	}
+}
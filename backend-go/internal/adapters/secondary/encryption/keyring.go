@@ -0,0 +1,170 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// envelopeVersion prefixes every ciphertext produced by the Keyring:
+//
+//	v1:<key-id>:<base64(nonce + ciphertext)>
+//
+// Ciphertexts without the prefix come from the single-key AESGCMService and
+// are decrypted by trying every key in the ring.
+const envelopeVersion = "v1"
+
+// ErrUnknownKeyID is returned when a ciphertext references a key that is not in the ring
+var ErrUnknownKeyID = errors.New("unknown encryption key id")
+
+// Keyring encrypts with the current key and decrypts with any configured key,
+// so ENCRYPTION_KEYS can be rotated without losing stored credentials.
+type Keyring struct {
+	current string
+	keys    map[string]*AESGCMService
+}
+
+// NewKeyring builds a keyring from key-id -> key. Every key must be 16, 24 or 32 bytes.
+func NewKeyring(current string, keys map[string]string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring needs at least one key")
+	}
+	k := &Keyring{current: current, keys: make(map[string]*AESGCMService, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		svc := NewAESGCMService(key)
+		if _, err := svc.Encrypt(""); err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.keys[id] = svc
+	}
+	if _, ok := k.keys[current]; !ok {
+		return nil, fmt.Errorf("current key id %q is not in the keyring", current)
+	}
+	return k, nil
+}
+
+// ParseKeyring reads ENCRYPTION_KEYS ("id1:key1,id2:key2"). When spec is empty the
+// ring holds only fallbackKey under the id "default", matching the old single-key setup.
+func ParseKeyring(current, spec, fallbackKey string) (*Keyring, error) {
+	keys := map[string]string{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, key, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid ENCRYPTION_KEYS entry %q (want id:key)", part)
+		}
+		keys[strings.TrimSpace(id)] = key
+	}
+	if len(keys) == 0 {
+		keys["default"] = fallbackKey
+		if current == "" {
+			current = "default"
+		}
+	}
+	if current == "" && len(keys) == 1 {
+		for id := range keys {
+			current = id
+		}
+	}
+	return NewKeyring(current, keys)
+}
+
+// CurrentKeyID returns the id new ciphertexts are written with
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+// KeyIDs returns the configured key ids, sorted
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt encrypts with the current key and wraps the result in a v1 envelope
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	ct, err := k.keys[k.current].Encrypt(plain)
+	if err != nil {
+		return "", err
+	}
+	return envelopeVersion + ":" + k.current + ":" + ct, nil
+}
+
+// Decrypt opens v1 envelopes with the referenced key and legacy ciphertexts with any key.
+// It never returns the input unchanged: failures are errors.
+func (k *Keyring) Decrypt(encrypted string) (string, error) {
+	id, ct, versioned := parseEnvelope(encrypted)
+	if versioned {
+		svc, ok := k.keys[id]
+		if !ok {
+			return "", fmt.Errorf("%w %q", ErrUnknownKeyID, id)
+		}
+		pt, err := svc.Decrypt(ct)
+		if err != nil {
+			return "", fmt.Errorf("decrypt with key %q: %w", id, err)
+		}
+		return pt, nil
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(encrypted); err != nil {
+		return "", fmt.Errorf("decode: %w", err)
+	}
+	// AES-GCM authenticates, so a wrong key cannot produce a false positive
+	for _, id := range k.KeyIDs() {
+		if pt, err := k.keys[id].Decrypt(encrypted); err == nil {
+			return pt, nil
+		}
+	}
+	return "", errors.New("decrypt: no key in the keyring matches the ciphertext")
+}
+
+// KeyID reports the key id of a ciphertext ("" for legacy ciphertexts) and whether
+// it looks like something this keyring produced at all
+func (k *Keyring) KeyID(encrypted string) (id string, ok bool) {
+	if id, _, versioned := parseEnvelope(encrypted); versioned {
+		return id, true
+	}
+	if _, err := base64.StdEncoding.DecodeString(encrypted); err != nil || encrypted == "" {
+		return "", false
+	}
+	return "", true
+}
+
+// NeedsRotation is true for legacy ciphertexts and envelopes written with an old key.
+// Values that are not ciphertexts (e.g. external secret references) never need rotation.
+func (k *Keyring) NeedsRotation(encrypted string) bool {
+	id, ok := k.KeyID(encrypted)
+	return ok && id != k.current
+}
+
+// Reencrypt decrypts with whichever key matches and encrypts with the current key
+func (k *Keyring) Reencrypt(encrypted string) (string, error) {
+	pt, err := k.Decrypt(encrypted)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(pt)
+}
+
+func parseEnvelope(s string) (id, ct string, ok bool) {
+	rest, found := strings.CutPrefix(s, envelopeVersion+":")
+	if !found {
+		return "", "", false
+	}
+	id, ct, found = strings.Cut(rest, ":")
+	if !found || id == "" {
+		return "", "", false
+	}
+	return id, ct, true
+}
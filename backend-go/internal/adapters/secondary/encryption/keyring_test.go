@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	oldKey = "0123456789abcdef0123456789abcdef"
+	newKey = "fedcba9876543210fedcba9876543210"
+)
+
+func TestKeyring_rotationKeepsOldCiphertextsReadable(t *testing.T) {
+	legacy, err := NewAESGCMService(oldKey).Encrypt("from-before-keyring")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	k1, err := ParseKeyring("", "k1:"+oldKey, "")
+	if err != nil {
+		t.Fatalf("keyring k1: %v", err)
+	}
+	v1, err := k1.Encrypt("written-with-k1")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if !strings.HasPrefix(v1, "v1:k1:") {
+		t.Fatalf("expected versioned envelope, got %q", v1)
+	}
+
+	// rotate: k2 is current, k1 still available for decryption
+	k2, err := ParseKeyring("k2", "k1:"+oldKey+",k2:"+newKey, "")
+	if err != nil {
+		t.Fatalf("keyring k2: %v", err)
+	}
+	if got, err := k2.Decrypt(v1); err != nil || got != "written-with-k1" {
+		t.Fatalf("expected old envelope to decrypt, got %q err=%v", got, err)
+	}
+	if got, err := k2.Decrypt(legacy); err != nil || got != "from-before-keyring" {
+		t.Fatalf("expected legacy ciphertext to decrypt, got %q err=%v", got, err)
+	}
+	if !k2.NeedsRotation(v1) || !k2.NeedsRotation(legacy) {
+		t.Fatalf("expected old ciphertexts to need rotation")
+	}
+	if k2.NeedsRotation("vault:microsql/connections/1/mssql") {
+		t.Fatalf("external references must not be rotated")
+	}
+
+	rotated, err := k2.Reencrypt(v1)
+	if err != nil {
+		t.Fatalf("reencrypt: %v", err)
+	}
+	if !strings.HasPrefix(rotated, "v1:k2:") || k2.NeedsRotation(rotated) {
+		t.Fatalf("expected ciphertext under k2, got %q", rotated)
+	}
+
+	// once k1 is dropped its envelopes are a hard error, never returned as-is
+	onlyK2, _ := ParseKeyring("k2", "k2:"+newKey, "")
+	if _, err := onlyK2.Decrypt(v1); !errors.Is(err, ErrUnknownKeyID) {
+		t.Fatalf("expected unknown key id error, got %v", err)
+	}
+	if _, err := onlyK2.Decrypt("not-a-ciphertext"); err == nil {
+		t.Fatalf("expected error for garbage input")
+	}
+}
+
+func TestParseKeyring_rejectsInvalidConfig(t *testing.T) {
+	if _, err := ParseKeyring("k3", "k1:"+oldKey, ""); err == nil {
+		t.Fatalf("expected error when current key is missing")
+	}
+	if _, err := ParseKeyring("k1", "k1:short", ""); err == nil {
+		t.Fatalf("expected error for invalid key length")
+	}
+	if _, err := ParseKeyring("", "k1:"+oldKey+",k2:"+newKey, ""); err == nil {
+		t.Fatalf("expected error when several keys and no current id")
+	}
+}
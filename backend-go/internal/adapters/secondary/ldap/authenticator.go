@@ -0,0 +1,317 @@
+// Package ldap autentica usuarios contra un directorio LDAP / Active Directory
+// (bind simple y búsqueda con github.com/go-ldap/ldap) sobre LDAPS o StartTLS.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Config describe el directorio y cómo encontrar usuarios y grupos
+type Config struct {
+	// URL es ldap://host:389 o ldaps://host:636
+	URL string
+	// StartTLS eleva una conexión ldap:// a TLS antes de enviar credenciales
+	StartTLS bool
+	// TLS permite fijar la CA del directorio (RootCAs); nil usa las del sistema
+	TLS *tls.Config
+	// CAFile es un PEM con la CA del directorio, alternativa a TLS.RootCAs
+	CAFile string
+	// AllowInsecure permite ldap:// sin StartTLS (solo para pruebas)
+	AllowInsecure bool
+
+	// BindDN/BindPassword es la cuenta de servicio para buscar usuarios; vacío = bind anónimo
+	BindDN       string
+	BindPassword string
+
+	BaseDN string
+	// UserFilter localiza al usuario; %s se sustituye por el nombre escapado,
+	// p.ej. (uid=%s) en OpenLDAP o (sAMAccountName=%s) en Active Directory
+	UserFilter        string
+	UsernameAttribute string
+	EmailAttribute    string
+	NameAttribute     string
+	// IDAttribute es un identificador estable de la entrada (entryUUID, objectGUID);
+	// vacío usa el DN, que cambia si se mueve o renombra la entrada
+	IDAttribute string
+
+	// GroupAttribute lista los DN de grupos en la entrada del usuario (memberOf)
+	GroupAttribute string
+	// GroupFilter busca grupos bajo GroupBaseDN; %s se sustituye por el DN del usuario,
+	// p.ej. (member=%s). Vacío = solo GroupAttribute
+	GroupFilter        string
+	GroupBaseDN        string
+	GroupNameAttribute string
+
+	Timeout time.Duration
+}
+
+// Authenticator implementa services.DirectoryAuthenticator: busca la entrada
+// del usuario con la cuenta de servicio, comprueba la contraseña con un bind
+// como ese DN y lee sus grupos. Cada login usa una conexión nueva.
+type Authenticator struct {
+	cfg Config
+}
+
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("LDAP URL and base DN are required")
+	}
+	if strings.HasPrefix(cfg.URL, "ldap://") && !cfg.StartTLS && !cfg.AllowInsecure {
+		return nil, errors.New("refusing to send passwords over plain ldap://; use ldaps:// or StartTLS")
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read LDAP CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("LDAP CA file %q has no PEM certificates", cfg.CAFile)
+		}
+		if cfg.TLS == nil {
+			cfg.TLS = &tls.Config{}
+		}
+		cfg.TLS = cfg.TLS.Clone()
+		cfg.TLS.RootCAs = pool
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(uid=%s)"
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, fmt.Errorf("LDAP user filter %q must contain %%s", cfg.UserFilter)
+	}
+	if _, err := goldap.CompileFilter(strings.ReplaceAll(cfg.UserFilter, "%s", "x")); err != nil {
+		return nil, fmt.Errorf("invalid LDAP user filter: %w", err)
+	}
+	if cfg.GroupFilter != "" {
+		if _, err := goldap.CompileFilter(strings.ReplaceAll(cfg.GroupFilter, "%s", "x")); err != nil {
+			return nil, fmt.Errorf("invalid LDAP group filter: %w", err)
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.UsernameAttribute == "" {
+		cfg.UsernameAttribute = "uid"
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "mail"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "displayName"
+	}
+	if cfg.GroupBaseDN == "" {
+		cfg.GroupBaseDN = cfg.BaseDN
+	}
+	if cfg.GroupNameAttribute == "" {
+		cfg.GroupNameAttribute = "cn"
+	}
+	return &Authenticator{cfg: cfg}, nil
+}
+
+// Issuer identifica este directorio en los enlaces de identidad. Se basa en el
+// base DN y no en la URL para que cambiar de servidor no rompa los enlaces.
+func (a *Authenticator) Issuer() string {
+	return "ldap:" + strings.ToLower(a.cfg.BaseDN)
+}
+
+// Authenticate comprueba usuario y contraseña contra el directorio
+func (a *Authenticator) Authenticate(ctx context.Context, username, password string) (*services.ExternalIdentity, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || password == "" {
+		return nil, services.ErrDirectoryInvalidCredentials
+	}
+	conn, err := a.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// go-ldap no recibe contextos: cancelar el login cierra la conexión
+	defer context.AfterFunc(ctx, func() { conn.Close() })()
+
+	attrs := []string{a.cfg.UsernameAttribute, a.cfg.EmailAttribute, a.cfg.NameAttribute, "cn"}
+	if a.cfg.IDAttribute != "" {
+		attrs = append(attrs, a.cfg.IDAttribute)
+	}
+	if a.cfg.GroupAttribute != "" {
+		attrs = append(attrs, a.cfg.GroupAttribute)
+	}
+	res, err := conn.Search(goldap.NewSearchRequest(
+		a.cfg.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, 0, false,
+		strings.ReplaceAll(a.cfg.UserFilter, "%s", goldap.EscapeFilter(username)),
+		attrs, nil,
+	))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("search user: %w", err)
+	}
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return nil, services.ErrDirectoryUserNotFound
+	case len(res.Entries) > 1:
+		return nil, fmt.Errorf("username %q matches more than one directory entry", username)
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, services.ErrDirectoryInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind as user: %w", err)
+	}
+
+	groups, err := a.groups(conn, entry)
+	if err != nil {
+		return nil, err
+	}
+	id := &services.ExternalIdentity{
+		Issuer:   a.Issuer(),
+		Subject:  a.subject(entry),
+		Email:    entry.GetEqualFoldAttributeValue(a.cfg.EmailAttribute),
+		Username: entry.GetEqualFoldAttributeValue(a.cfg.UsernameAttribute),
+		Name:     entry.GetEqualFoldAttributeValue(a.cfg.NameAttribute),
+		Groups:   groups,
+	}
+	// el email del directorio lo gestiona la organización, no el usuario
+	id.EmailVerified = id.Email != ""
+	if id.Username == "" {
+		id.Username = username
+	}
+	if id.Name == "" {
+		id.Name = entry.GetEqualFoldAttributeValue("cn")
+	}
+	return id, nil
+}
+
+func (a *Authenticator) connect(ctx context.Context) (*goldap.Conn, error) {
+	u, err := url.Parse(a.cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("unsupported LDAP URL scheme %q (use ldap:// or ldaps://)", u.Scheme)
+	}
+	tlsConfig := withServerName(a.cfg.TLS, u.Hostname())
+	dialer := &net.Dialer{Timeout: a.cfg.Timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	conn, err := goldap.DialURL(a.cfg.URL, goldap.DialWithDialer(dialer), goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("connect to directory: %w", err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+	if a.cfg.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS: %w", err)
+		}
+	}
+	if err := a.serviceBind(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func withServerName(cfg *tls.Config, host string) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	cfg = cfg.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	return cfg
+}
+
+func (a *Authenticator) serviceBind(conn *goldap.Conn) error {
+	if a.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+		return fmt.Errorf("service account bind: %w", err)
+	}
+	return nil
+}
+
+// groups devuelve el nombre (primer RDN o GroupNameAttribute) de los grupos del usuario
+func (a *Authenticator) groups(conn *goldap.Conn, entry *goldap.Entry) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	if a.cfg.GroupAttribute != "" {
+		for _, dn := range entry.GetEqualFoldAttributeValues(a.cfg.GroupAttribute) {
+			add(rdnValue(dn))
+		}
+	}
+	if a.cfg.GroupFilter == "" {
+		return out, nil
+	}
+	// la búsqueda de grupos se hace con la cuenta de servicio, no con la del usuario
+	if err := a.serviceBind(conn); err != nil {
+		return nil, err
+	}
+	found, err := conn.Search(goldap.NewSearchRequest(
+		a.cfg.GroupBaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+		strings.ReplaceAll(a.cfg.GroupFilter, "%s", goldap.EscapeFilter(entry.DN)),
+		[]string{a.cfg.GroupNameAttribute}, nil,
+	))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("search groups: %w", err)
+	}
+	for _, g := range found.Entries {
+		if name := g.GetEqualFoldAttributeValue(a.cfg.GroupNameAttribute); name != "" {
+			add(name)
+		} else {
+			add(rdnValue(g.DN))
+		}
+	}
+	return out, nil
+}
+
+func (a *Authenticator) subject(entry *goldap.Entry) string {
+	if a.cfg.IDAttribute != "" {
+		if v := entry.GetEqualFoldAttributeValue(a.cfg.IDAttribute); v != "" {
+			// objectGUID y similares son binarios
+			if !utf8.ValidString(v) {
+				return hex.EncodeToString([]byte(v))
+			}
+			return v
+		}
+	}
+	return strings.ToLower(entry.DN)
+}
+
+// rdnValue devuelve el valor del primer RDN: "cn=DBA Team,ou=groups,..." -> "DBA Team"
+func rdnValue(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(parsed.RDNs[0].Attributes[0].Value)
+}
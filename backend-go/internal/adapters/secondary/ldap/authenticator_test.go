@@ -0,0 +1,123 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/ldap/ldaptest"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+func directory(t *testing.T, ldaps bool) *ldaptest.Server {
+	t.Helper()
+	srv, err := ldaptest.Start(ldaps)
+	if err != nil {
+		t.Fatalf("start ldap stand-in: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	srv.RequireTLS = true
+	srv.Add(ldaptest.Entry{DN: "cn=svc,dc=corp,dc=local", Password: "svc-pass"})
+	srv.Add(ldaptest.Entry{DN: "uid=ana,ou=people,dc=corp,dc=local", Password: "ana-pass", Attributes: map[string][]string{
+		"uid":         {"ana"},
+		"mail":        {"ana@corp.local"},
+		"displayName": {"Ana Pérez"},
+		"memberOf":    {"cn=dba,ou=groups,dc=corp,dc=local"},
+	}})
+	srv.Add(ldaptest.Entry{DN: "cn=auditors,ou=groups,dc=corp,dc=local", Attributes: map[string][]string{
+		"cn":     {"auditors"},
+		"member": {"uid=ana,ou=people,dc=corp,dc=local"},
+	}})
+	return srv
+}
+
+func TestAuthenticator_StartTLSBindAndGroups(t *testing.T) {
+	srv := directory(t, false)
+	a, err := NewAuthenticator(Config{
+		URL: srv.URL, StartTLS: true, TLS: &tls.Config{RootCAs: srv.RootCAs},
+		BindDN: "cn=svc,dc=corp,dc=local", BindPassword: "svc-pass",
+		BaseDN: "dc=corp,dc=local", UserFilter: "(&(uid=%s)(mail=*))",
+		GroupAttribute: "memberOf", GroupFilter: "(member=%s)", GroupBaseDN: "ou=groups,dc=corp,dc=local",
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	ctx := context.Background()
+
+	id, err := a.Authenticate(ctx, "ana", "ana-pass")
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if id.Issuer != "ldap:dc=corp,dc=local" || id.Subject != "uid=ana,ou=people,dc=corp,dc=local" ||
+		id.Username != "ana" || id.Email != "ana@corp.local" || !id.EmailVerified || id.Name != "Ana Pérez" {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+	if len(id.Groups) != 2 || id.Groups[0] != "dba" || id.Groups[1] != "auditors" {
+		t.Fatalf("expected groups from memberOf and the group search, got %v", id.Groups)
+	}
+
+	if _, err := a.Authenticate(ctx, "ana", "wrong"); !errors.Is(err, services.ErrDirectoryInvalidCredentials) {
+		t.Fatalf("wrong password: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, "ana", ""); !errors.Is(err, services.ErrDirectoryInvalidCredentials) {
+		t.Fatalf("empty password must never reach the directory: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, "nobody", "x"); !errors.Is(err, services.ErrDirectoryUserNotFound) {
+		t.Fatalf("unknown user: %v", err)
+	}
+	// filter metacharacters are escaped, so they cannot widen the search
+	if _, err := a.Authenticate(ctx, "*", "ana-pass"); !errors.Is(err, services.ErrDirectoryUserNotFound) {
+		t.Fatalf("wildcard username: %v", err)
+	}
+}
+
+func TestAuthenticator_LDAPSAndTransportSecurity(t *testing.T) {
+	srv := directory(t, true)
+	cfg := Config{URL: srv.URL, TLS: &tls.Config{RootCAs: srv.RootCAs}, BindDN: "cn=svc,dc=corp,dc=local", BindPassword: "svc-pass", BaseDN: "dc=corp,dc=local"}
+	a, err := NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if id, err := a.Authenticate(context.Background(), "ana", "ana-pass"); err != nil || id.Username != "ana" {
+		t.Fatalf("ldaps login: %+v %v", id, err)
+	}
+
+	// an untrusted certificate fails before any credential is sent
+	cfg.TLS = nil
+	untrusted, _ := NewAuthenticator(cfg)
+	if _, err := untrusted.Authenticate(context.Background(), "ana", "ana-pass"); err == nil {
+		t.Fatalf("expected certificate verification to fail")
+	}
+	if len(srv.Binds()) != 2 {
+		t.Fatalf("expected only the binds of the trusted login, got %v", srv.Binds())
+	}
+
+	if _, err := NewAuthenticator(Config{URL: "ldap://127.0.0.1:389", BaseDN: "dc=corp,dc=local"}); err == nil {
+		t.Fatalf("plain ldap:// without StartTLS must be refused")
+	}
+}
+
+func TestNewAuthenticator_rejectsInvalidFilters(t *testing.T) {
+	for _, f := range []struct{ user, group string }{
+		{user: "(uid=%s"},
+		{user: "(&)(uid=%s)"},
+		{user: "(uid=%s)", group: "(member=%s"},
+	} {
+		if _, err := NewAuthenticator(Config{URL: "ldaps://dir", BaseDN: "dc=corp,dc=local", UserFilter: f.user, GroupFilter: f.group}); err == nil {
+			t.Errorf("%q / %q: expected an error", f.user, f.group)
+		}
+	}
+}
+
+func TestRDNValue(t *testing.T) {
+	for dn, want := range map[string]string{
+		"cn=DBA Team,ou=groups,dc=corp,dc=local": "DBA Team",
+		`cn=Smith\, John,ou=people,dc=corp`:      "Smith, John",
+		"not a dn":                               "",
+	} {
+		if got := rdnValue(dn); got != want {
+			t.Errorf("%q: got %q, want %q", dn, got, want)
+		}
+	}
+}
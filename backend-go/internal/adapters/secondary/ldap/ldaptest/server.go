@@ -0,0 +1,419 @@
+// Package ldaptest is an in-process LDAP stand-in for tests and local runs: it
+// answers simple binds, searches and StartTLS over plain or TLS listeners with a
+// self-signed certificate.
+package ldaptest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// Entry is a directory entry. Password, if set, allows binding as the entry.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+	Password   string
+}
+
+// Server is the stand-in directory
+type Server struct {
+	// URL is ldap://127.0.0.1:port, or ldaps:// when started with TLS
+	URL string
+	// RootCAs trusts the server certificate
+	RootCAs *x509.CertPool
+	// RequireTLS rejects binds on plain connections (confidentialityRequired),
+	// like an OpenLDAP configured with security tls=1
+	RequireTLS bool
+
+	mu       sync.Mutex
+	entries  []*Entry
+	binds    []string
+	listener net.Listener
+	tlsCfg   *tls.Config
+}
+
+// Start listens on 127.0.0.1; with ldaps=true the listener speaks TLS directly,
+// otherwise clients can upgrade with StartTLS
+func Start(ldaps bool) (*Server, error) {
+	cert, pool, err := selfSigned()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{RootCAs: pool, tlsCfg: &tls.Config{Certificates: []tls.Certificate{cert}}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s.URL = "ldap://" + l.Addr().String()
+	if ldaps {
+		l = tls.NewListener(l, s.tlsCfg)
+		s.URL = "ldaps://" + l.Addr().String()
+	}
+	s.listener = l
+	go s.serve()
+	return s, nil
+}
+
+// Close stops the listener
+func (s *Server) Close() {
+	s.listener.Close()
+}
+
+// Add stores an entry, replacing one with the same DN
+func (s *Server) Add(e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, old := range s.entries {
+		if strings.EqualFold(old.DN, e.DN) {
+			s.entries[i] = &e
+			return
+		}
+	}
+	s.entries = append(s.entries, &e)
+}
+
+// Binds returns the DNs of the successful binds so far
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.binds...)
+}
+
+func (s *Server) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+type session struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	tls   bool
+	bound string
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer func() { c.Close() }()
+	_, isTLS := c.(*tls.Conn)
+	sess := &session{conn: c, r: bufio.NewReader(c), tls: isTLS}
+	for {
+		sess.conn.SetDeadline(time.Now().Add(30 * time.Second))
+		msg, err := ber.ReadPacket(sess.r)
+		if err != nil {
+			return
+		}
+		id := intValue(child(msg, 0))
+		op := child(msg, 1)
+		if op == nil || op.ClassType != ber.ClassApplication {
+			return
+		}
+		switch op.Tag {
+		case 0:
+			s.bind(sess, id, op)
+		case 2:
+			return
+		case 3:
+			s.search(sess, id, op)
+		case 23:
+			if str(child(op, 0)) != "1.3.6.1.4.1.1466.20037" || sess.tls {
+				s.reply(sess, id, 24, 2, "unsupported extended operation")
+				continue
+			}
+			s.reply(sess, id, 24, 0, "")
+			tc := tls.Server(sess.conn, s.tlsCfg)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			sess.conn, sess.r, sess.tls = tc, bufio.NewReader(tc), true
+			c = tc
+		default:
+			return
+		}
+	}
+}
+
+func (s *Server) bind(sess *session, id int64, op *ber.Packet) {
+	dn, password := str(child(op, 1)), str(child(op, 2))
+	if s.RequireTLS && !sess.tls {
+		s.reply(sess, id, 1, 13, "TLS confidentiality required")
+		return
+	}
+	if dn == "" && password == "" {
+		sess.bound = ""
+		s.reply(sess, id, 1, 0, "")
+		return
+	}
+	e := s.find(dn)
+	if e == nil || e.Password == "" || e.Password != password {
+		s.reply(sess, id, 1, 49, "invalid credentials")
+		return
+	}
+	sess.bound = e.DN
+	s.mu.Lock()
+	s.binds = append(s.binds, e.DN)
+	s.mu.Unlock()
+	s.reply(sess, id, 1, 0, "")
+}
+
+func (s *Server) search(sess *session, id int64, op *ber.Packet) {
+	base := str(child(op, 0))
+	scope := intValue(child(op, 1))
+	sizeLimit := intValue(child(op, 3))
+	filter := child(op, 6)
+	var attrs []string
+	if list := child(op, 7); list != nil {
+		for _, a := range list.Children {
+			attrs = append(attrs, strings.ToLower(str(a)))
+		}
+	}
+
+	s.mu.Lock()
+	entries := append([]*Entry(nil), s.entries...)
+	s.mu.Unlock()
+	sent := 0
+	for _, e := range entries {
+		if !inScope(e.DN, base, scope) || !matches(e, filter) {
+			continue
+		}
+		if sizeLimit > 0 && int64(sent) >= sizeLimit {
+			s.reply(sess, id, 5, 4, "size limit exceeded")
+			return
+		}
+		entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, 4, nil, "Search Result Entry")
+		entry.AppendChild(octetString(e.DN))
+		entry.AppendChild(attributeList(e, attrs))
+		s.write(sess, id, entry)
+		sent++
+	}
+	s.reply(sess, id, 5, 0, "")
+}
+
+func attributeList(e *Entry, want []string) *ber.Packet {
+	list := ber.NewSequence("Attributes")
+	for name, values := range e.Attributes {
+		if len(want) > 0 && !contains(want, strings.ToLower(name)) {
+			continue
+		}
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			set.AppendChild(octetString(v))
+		}
+		attr := ber.NewSequence("Attribute")
+		attr.AppendChild(octetString(name))
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	return list
+}
+
+func (s *Server) reply(sess *session, id int64, tag ber.Tag, code int64, msg string) {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	op.AppendChild(octetString(""))
+	op.AppendChild(octetString(msg))
+	s.write(sess, id, op)
+}
+
+func (s *Server) write(sess *session, id int64, op *ber.Packet) {
+	msg := ber.NewSequence("LDAP Response")
+	msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+	msg.AppendChild(op)
+	sess.conn.Write(msg.Bytes())
+}
+
+func octetString(s string) *ber.Packet {
+	return ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, s, "")
+}
+
+// child returns the i-th child of p, or nil
+func child(p *ber.Packet, i int) *ber.Packet {
+	if p == nil || i >= len(p.Children) {
+		return nil
+	}
+	return p.Children[i]
+}
+
+// str is the raw content of a primitive packet, whatever its class
+func str(p *ber.Packet) string {
+	if p == nil || p.Data == nil {
+		return ""
+	}
+	return p.Data.String()
+}
+
+func intValue(p *ber.Packet) int64 {
+	if p == nil || p.Data == nil {
+		return 0
+	}
+	n, _ := ber.ParseInt64(p.Data.Bytes())
+	return n
+}
+
+func (s *Server) find(dn string) *Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		if strings.EqualFold(e.DN, dn) {
+			return e
+		}
+	}
+	return nil
+}
+
+func inScope(dn, base string, scope int64) bool {
+	dn, base = strings.ToLower(dn), strings.ToLower(base)
+	switch scope {
+	case 0:
+		return dn == base
+	case 1:
+		_, parent, _ := strings.Cut(dn, ",")
+		return parent == base
+	default:
+		return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+// matches evaluates a BER filter; comparisons ignore case like most directory attributes
+func matches(e *Entry, f *ber.Packet) bool {
+	if f == nil || f.ClassType != ber.ClassContext {
+		return false
+	}
+	values := func(attr string) []string {
+		for name, v := range e.Attributes {
+			if strings.EqualFold(name, attr) {
+				return v
+			}
+		}
+		return nil
+	}
+	switch f.Tag {
+	case 0:
+		for _, c := range f.Children {
+			if !matches(e, c) {
+				return false
+			}
+		}
+		return true
+	case 1:
+		for _, c := range f.Children {
+			if matches(e, c) {
+				return true
+			}
+		}
+		return false
+	case 2:
+		return !matches(e, child(f, 0))
+	case 3, 8:
+		want := str(child(f, 1))
+		for _, v := range values(str(child(f, 0))) {
+			if strings.EqualFold(v, want) {
+				return true
+			}
+		}
+		return false
+	case 5, 6:
+		want := strings.ToLower(str(child(f, 1)))
+		for _, v := range values(str(child(f, 0))) {
+			v = strings.ToLower(v)
+			if (f.Tag == 5 && v >= want) || (f.Tag == 6 && v <= want) {
+				return true
+			}
+		}
+		return false
+	case 7:
+		if strings.EqualFold(str(f), "objectClass") {
+			return true
+		}
+		return len(values(str(f))) > 0
+	case 4:
+		subs := child(f, 1)
+		if subs == nil {
+			return false
+		}
+		for _, v := range values(str(child(f, 0))) {
+			if substringMatch(strings.ToLower(v), subs.Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func substringMatch(v string, parts []*ber.Packet) bool {
+	for _, p := range parts {
+		s := strings.ToLower(str(p))
+		switch p.Tag {
+		case 0:
+			if !strings.HasPrefix(v, s) {
+				return false
+			}
+			v = v[len(s):]
+		case 1:
+			i := strings.Index(v, s)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(s):]
+		case 2:
+			if !strings.HasSuffix(v, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func selfSigned() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldaptest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("self-signed certificate: %w", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
@@ -0,0 +1,114 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc/oidctest"
+)
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize follows the provider's /authorize like a browser and returns the code
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil {
+		t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	return loc.Query()
+}
+
+func TestProvider_authorizationCodeWithPKCE(t *testing.T) {
+	mock, srv := oidctest.Start("microsql")
+	defer srv.Close()
+	mock.ClientSecret = "s3cret"
+	mock.SetUser(oidctest.User{Subject: "u-1", Email: "ana@example.com", EmailVerified: true, Username: "ana", Groups: []string{"dba", "staff"}})
+
+	p := NewProvider(Config{Issuer: srv.URL + "/", ClientID: "microsql", ClientSecret: "s3cret", RedirectURL: "http://app/callback"})
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "st", "n-1", challenge("verifier-1"))
+	if err != nil {
+		t.Fatalf("auth url: %v", err)
+	}
+	back := authorize(t, authURL)
+	if back.Get("state") != "st" {
+		t.Fatalf("state not returned: %v", back)
+	}
+
+	// a wrong verifier is refused by the provider (PKCE)
+	if _, err := p.Exchange(ctx, back.Get("code"), "other-verifier", "n-1"); err == nil {
+		t.Fatalf("expected exchange with the wrong verifier to fail")
+	}
+	back = authorize(t, authURL)
+	id, err := p.Exchange(ctx, back.Get("code"), "verifier-1", "n-1")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if id.Issuer != srv.URL || id.Subject != "u-1" || id.Username != "ana" || !id.EmailVerified || len(id.Groups) != 2 {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+
+	// nonce mismatch (a token obtained for another login)
+	back = authorize(t, authURL)
+	if _, err := p.Exchange(ctx, back.Get("code"), "verifier-1", "n-2"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected nonce mismatch, got %v", err)
+	}
+}
+
+func TestProvider_verifyRejectsBadIDTokens(t *testing.T) {
+	mock, srv := oidctest.Start("microsql")
+	defer srv.Close()
+	p := NewProvider(Config{Issuer: srv.URL, ClientID: "microsql", RedirectURL: "http://app/callback"})
+	meta, err := p.discover(context.Background())
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+
+	base := func() jwt.MapClaims {
+		return jwt.MapClaims{"iss": srv.URL, "aud": "microsql", "sub": "u", "nonce": "n", "exp": time.Now().Add(time.Minute).Unix()}
+	}
+	cases := map[string]func(jwt.MapClaims){
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "someone-else" },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no expiry":      func(c jwt.MapClaims) { delete(c, "exp") },
+		"no subject":     func(c jwt.MapClaims) { delete(c, "sub") },
+	}
+	for name, mutate := range cases {
+		c := base()
+		mutate(c)
+		raw, _ := mock.Sign(c)
+		if _, err := p.verify(context.Background(), meta, raw, "n"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("%s: expected ErrInvalidIDToken, got %v", name, err)
+		}
+	}
+
+	// unsigned tokens are never accepted
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, base()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := p.verify(context.Background(), meta, none, "n"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("alg none: expected ErrInvalidIDToken, got %v", err)
+	}
+	raw, _ := mock.Sign(base())
+	if _, err := p.verify(context.Background(), meta, raw, "n"); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+}
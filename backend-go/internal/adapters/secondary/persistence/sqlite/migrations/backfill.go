@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// BackfillUserRoles copies users.role into user_roles for users that do not
+// have that assignment yet, so user_roles can be the only source of a user's
+// roles. It is idempotent and must run after the roles are seeded. It returns
+// the assignments created and the users whose role does not exist as a role.
+func BackfillUserRoles(db *gorm.DB) (created int64, orphaned []string, err error) {
+	res := db.Exec(`INSERT INTO user_roles (user_id, role_id, reason)
+		SELECT u.id, r.id, ? FROM users u JOIN roles r ON r.name = u.role
+		WHERE NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.role_id = r.id)`,
+		"backfill from users.role")
+	if res.Error != nil {
+		return 0, nil, res.Error
+	}
+	err = db.Model(&entities.User{}).
+		Where("role <> '' AND NOT EXISTS (SELECT 1 FROM roles r WHERE r.name = users.role)").
+		Pluck("username", &orphaned).Error
+	return res.RowsAffected, orphaned, err
+}
@@ -21,5 +21,19 @@ func Migrate(db *gorm.DB) error {
 		&entities.AuditScriptResult{},
 		&entities.AdminActionLog{},
 		&entities.Session{},
+		&entities.AuthAuditLog{},
+		&entities.LoginThrottle{},
+		&entities.UserTOTP{},
+		&entities.RecoveryCode{},
+		&entities.LoginChallenge{},
+		&entities.APIKey{},
+		&entities.UserIdentity{},
+		&entities.OIDCLoginState{},
+		&entities.ServerGroup{},
+		&entities.PermissionScope{},
+		&entities.RoleRequest{},
+		&entities.ChainCheckpoint{},
+		&entities.ActivityEvent{},
+		&entities.JWTKeyRetirement{},
 	)
 }
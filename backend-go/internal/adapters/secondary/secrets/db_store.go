@@ -0,0 +1,30 @@
+package secrets
+
+import (
+	"context"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// DBStore es el modo histórico: la referencia es el propio texto cifrado con
+// AES-GCM (ENCRYPTION_KEY) y vive en la fila junto al resto de datos.
+type DBStore struct {
+	enc services.EncryptionService
+}
+
+func NewDBStore(enc services.EncryptionService) *DBStore {
+	return &DBStore{enc: enc}
+}
+
+func (s *DBStore) Put(ctx context.Context, key, secret string) (string, error) {
+	return s.enc.Encrypt(secret)
+}
+
+func (s *DBStore) Get(ctx context.Context, ref string) (string, error) {
+	return s.enc.Decrypt(ref)
+}
+
+// Delete no hace nada: el secreto desaparece con la fila
+func (s *DBStore) Delete(ctx context.Context, ref string) error {
+	return nil
+}
@@ -0,0 +1,149 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/encryption"
+)
+
+// FileStore guarda los secretos en un keystore local. El fichero completo
+// (claves incluidas) se cifra con AES-GCM usando una clave propia, distinta de
+// la de la base de datos, y se reescribe de forma atómica con permisos 0600.
+type FileStore struct {
+	path string
+	enc  *encryption.AESGCMService
+	mu   sync.Mutex
+}
+
+type keystoreFile struct {
+	Secrets map[string]string `json:"secrets"`
+}
+
+// NewFileStore valida la clave (16, 24 o 32 bytes) y, si el fichero existe, que pueda descifrarse
+func NewFileStore(path, key string) (*FileStore, error) {
+	s := &FileStore{path: path, enc: encryption.NewAESGCMService(key)}
+	if _, err := s.enc.Encrypt(""); err != nil {
+		return nil, fmt.Errorf("invalid keystore key: %w", err)
+	}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Put(ctx context.Context, key, secret string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ks, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	ks.Secrets[key] = secret
+	if err := s.save(ks); err != nil {
+		return "", err
+	}
+	return schemeFile + ":" + key, nil
+}
+
+func (s *FileStore) Get(ctx context.Context, ref string) (string, error) {
+	key, err := pathFromRef(ref, schemeFile)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ks, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := ks.Secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, ref string) error {
+	key, err := pathFromRef(ref, schemeFile)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := ks.Secrets[key]; !ok {
+		return nil
+	}
+	delete(ks.Secrets, key)
+	return s.save(ks)
+}
+
+func (s *FileStore) load() (*keystoreFile, error) {
+	ks := &keystoreFile{Secrets: map[string]string{}}
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read keystore: %w", err)
+	}
+	plain, err := s.enc.Decrypt(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keystore: %w", err)
+	}
+	if err := json.Unmarshal([]byte(plain), ks); err != nil {
+		return nil, fmt.Errorf("parse keystore: %w", err)
+	}
+	if ks.Secrets == nil {
+		ks.Secrets = map[string]string{}
+	}
+	return ks, nil
+}
+
+func (s *FileStore) save(ks *keystoreFile) error {
+	plain, err := json.Marshal(ks)
+	if err != nil {
+		return err
+	}
+	ct, err := s.enc.Encrypt(string(plain))
+	if err != nil {
+		return fmt.Errorf("encrypt keystore: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create keystore dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".keystore-*")
+	if err != nil {
+		return fmt.Errorf("write keystore: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write keystore: %w", err)
+	}
+	if _, err := tmp.WriteString(ct); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write keystore: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write keystore: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("write keystore: %w", err)
+	}
+	return nil
+}
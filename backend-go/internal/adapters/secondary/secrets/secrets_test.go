@@ -0,0 +1,181 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/encryption"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+func TestFileStore_roundTripEncryptedAtRest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore")
+	ctx := context.Background()
+
+	s, err := NewFileStore(path, testKey)
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	ref, err := s.Put(ctx, "connections/6/mssql", "S3cret!")
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if ref != "file:connections/6/mssql" {
+		t.Fatalf("unexpected ref %q", ref)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read keystore: %v", err)
+	}
+	if strings.Contains(string(raw), "S3cret!") || strings.Contains(string(raw), "connections/6") {
+		t.Fatalf("keystore must be encrypted at rest")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	// a fresh instance reads what the previous one wrote
+	s2, err := NewFileStore(path, testKey)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got, err := s2.Get(ctx, ref); err != nil || got != "S3cret!" {
+		t.Fatalf("expected secret back, got %q err=%v", got, err)
+	}
+
+	if err := s2.Delete(ctx, ref); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s2.Get(ctx, ref); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+
+	if _, err := NewFileStore(path, "another-key-of-32-bytes-long!!!!"); err == nil {
+		t.Fatalf("expected wrong key to be rejected")
+	}
+}
+
+// fakeVault emulates the subset of the Vault KV v2 HTTP API used by VaultStore
+func fakeVault(t *testing.T, token string) *httptest.Server {
+	var mu sync.Mutex
+	data := map[string]map[string]string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/kv/data/"):
+			path := strings.TrimPrefix(r.URL.Path, "/v1/kv/data/")
+			switch r.Method {
+			case http.MethodPost:
+				var body struct {
+					Data map[string]string `json:"data"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				data[path] = body.Data
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]int{"version": 1}})
+			case http.MethodGet:
+				d, ok := data[path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {}})
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": d}})
+			}
+		case strings.HasPrefix(r.URL.Path, "/v1/kv/metadata/") && r.Method == http.MethodDelete:
+			delete(data, strings.TrimPrefix(r.URL.Path, "/v1/kv/metadata/"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVaultStore_againstLocalStandIn(t *testing.T) {
+	srv := fakeVault(t, "root-token")
+	defer srv.Close()
+	ctx := context.Background()
+
+	s, err := NewVaultStore(VaultOptions{Addr: srv.URL, Token: "root-token", Mount: "kv", Prefix: "app"})
+	if err != nil {
+		t.Fatalf("new vault store: %v", err)
+	}
+	ref, err := s.Put(ctx, "connections/6/mssql", "S3cret!")
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if ref != "vault:app/connections/6/mssql" {
+		t.Fatalf("unexpected ref %q", ref)
+	}
+	if got, err := s.Get(ctx, ref); err != nil || got != "S3cret!" {
+		t.Fatalf("expected secret back, got %q err=%v", got, err)
+	}
+	if err := s.Delete(ctx, ref); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, ref); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+
+	bad, _ := NewVaultStore(VaultOptions{Addr: srv.URL, Token: "wrong", Mount: "kv"})
+	if _, err := bad.Put(ctx, "k", "v"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected vault error to surface, got %v", err)
+	}
+}
+
+func TestNew_routesReadsByReferencePrefix(t *testing.T) {
+	srv := fakeVault(t, "root-token")
+	defer srv.Close()
+	ctx := context.Background()
+	enc := encryption.NewAESGCMService(testKey)
+
+	// a row written before the switch holds DB-AES ciphertext
+	legacy, err := enc.Encrypt("old-pass")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+
+	store, err := New(Options{
+		Backend: BackendVault,
+		Vault:   VaultOptions{Addr: srv.URL, Token: "root-token", Mount: "kv"},
+	}, enc)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	ref, err := store.Put(ctx, "connections/1/mssql", "new-pass")
+	if err != nil || !strings.HasPrefix(ref, "vault:") {
+		t.Fatalf("expected vault reference, got %q err=%v", ref, err)
+	}
+	if got, err := store.Get(ctx, ref); err != nil || got != "new-pass" {
+		t.Fatalf("expected vault secret, got %q err=%v", got, err)
+	}
+	if got, err := store.Get(ctx, legacy); err != nil || got != "old-pass" {
+		t.Fatalf("expected legacy ciphertext to decrypt, got %q err=%v", got, err)
+	}
+	if _, err := store.Get(ctx, "file:connections/1/mssql"); err == nil {
+		t.Fatalf("expected error for unconfigured backend")
+	}
+
+	if _, err := New(Options{Backend: BackendFile}, enc); err == nil {
+		t.Fatalf("expected error when file backend has no key")
+	}
+}
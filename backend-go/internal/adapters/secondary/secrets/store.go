@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// Backends soportados (SECRET_BACKEND)
+const (
+	BackendDB    = "db"
+	BackendFile  = "file"
+	BackendVault = "vault"
+)
+
+// Prefijos de las referencias que se guardan en la fila. Las referencias sin
+// prefijo son texto cifrado del modo DB-AES (filas anteriores a este cambio).
+const (
+	schemeFile  = "file"
+	schemeVault = "vault"
+)
+
+// ErrSecretNotFound se devuelve cuando la referencia no existe en el almacén
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Options selecciona el backend de escritura y configura los externos
+type Options struct {
+	Backend  string
+	FilePath string
+	FileKey  string
+	Vault    VaultOptions
+}
+
+// New construye el SecretStore de la aplicación. Las nuevas credenciales se
+// escriben en opts.Backend; las lecturas se enrutan por el prefijo de la
+// referencia, de modo que las filas existentes siguen funcionando al cambiar de backend.
+func New(opts Options, enc services.EncryptionService) (services.SecretStore, error) {
+	db := NewDBStore(enc)
+	r := &Router{legacy: db, schemes: map[string]services.SecretStore{}}
+
+	if opts.FilePath != "" && opts.FileKey != "" {
+		fs, err := NewFileStore(opts.FilePath, opts.FileKey)
+		if err != nil {
+			return nil, err
+		}
+		r.schemes[schemeFile] = fs
+	}
+	if opts.Vault.Addr != "" {
+		vs, err := NewVaultStore(opts.Vault)
+		if err != nil {
+			return nil, err
+		}
+		r.schemes[schemeVault] = vs
+	}
+
+	switch opts.Backend {
+	case "", BackendDB:
+		r.writer = db
+	case BackendFile:
+		r.writer = r.schemes[schemeFile]
+	case BackendVault:
+		r.writer = r.schemes[schemeVault]
+	default:
+		return nil, fmt.Errorf("unknown secret backend %q", opts.Backend)
+	}
+	if r.writer == nil {
+		return nil, fmt.Errorf("secret backend %q is not configured", opts.Backend)
+	}
+	return r, nil
+}
+
+// Router escribe en un backend y resuelve lecturas/borrados según el prefijo de la referencia
+type Router struct {
+	writer  services.SecretStore
+	legacy  services.SecretStore
+	schemes map[string]services.SecretStore
+}
+
+func (r *Router) Put(ctx context.Context, key, secret string) (string, error) {
+	return r.writer.Put(ctx, key, secret)
+}
+
+func (r *Router) Get(ctx context.Context, ref string) (string, error) {
+	s, err := r.backendFor(ref)
+	if err != nil {
+		return "", err
+	}
+	return s.Get(ctx, ref)
+}
+
+func (r *Router) Delete(ctx context.Context, ref string) error {
+	s, err := r.backendFor(ref)
+	if err != nil {
+		return err
+	}
+	return s.Delete(ctx, ref)
+}
+
+func (r *Router) backendFor(ref string) (services.SecretStore, error) {
+	scheme, _, ok := splitRef(ref)
+	if !ok {
+		return r.legacy, nil
+	}
+	s, found := r.schemes[scheme]
+	if !found {
+		return nil, fmt.Errorf("no secret backend configured for %q references", scheme)
+	}
+	return s, nil
+}
+
+// splitRef separa "scheme:path". Solo se reconocen los esquemas de los backends
+// externos; el resto (base64 o sobres "v1:<kid>:..." del keyring) es texto cifrado DB-AES.
+func splitRef(ref string) (scheme, path string, ok bool) {
+	scheme, path, ok = strings.Cut(ref, ":")
+	if !ok || (scheme != schemeFile && scheme != schemeVault) {
+		return "", "", false
+	}
+	return scheme, path, true
+}
+
+// pathFromRef valida el prefijo esperado y devuelve la ruta
+func pathFromRef(ref, want string) (string, error) {
+	scheme, path, ok := splitRef(ref)
+	if !ok || scheme != want || path == "" {
+		return "", fmt.Errorf("invalid %s secret reference", want)
+	}
+	return path, nil
+}
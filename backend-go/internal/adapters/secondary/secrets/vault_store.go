@@ -0,0 +1,155 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultOptions configura el backend compatible con Vault (motor KV v2)
+type VaultOptions struct {
+	Addr    string // p.ej. https://vault.local:8200
+	Token   string
+	Mount   string // punto de montaje del motor KV, por defecto "secret"
+	Prefix  string // prefijo de las rutas, por defecto "microsql"
+	Timeout time.Duration
+}
+
+// VaultStore guarda cada secreto en {mount}/data/{prefix}/{key} con la API HTTP de Vault KV v2.
+// La fila solo guarda "vault:{prefix}/{key}".
+type VaultStore struct {
+	opts   VaultOptions
+	client *http.Client
+}
+
+func NewVaultStore(opts VaultOptions) (*VaultStore, error) {
+	if opts.Addr == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("vault token is required")
+	}
+	if opts.Mount == "" {
+		opts.Mount = "secret"
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "microsql"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	opts.Addr = strings.TrimRight(opts.Addr, "/")
+	opts.Mount = strings.Trim(opts.Mount, "/")
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+	return &VaultStore{opts: opts, client: &http.Client{Timeout: opts.Timeout}}, nil
+}
+
+type vaultKVData struct {
+	Data map[string]string `json:"data"`
+}
+
+type vaultKVResponse struct {
+	Data vaultKVData `json:"data"`
+}
+
+func (s *VaultStore) Put(ctx context.Context, key, secret string) (string, error) {
+	path := s.opts.Prefix + "/" + key
+	body, err := json.Marshal(vaultKVData{Data: map[string]string{"password": secret}})
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(ctx, http.MethodPost, "data/"+path, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkVaultResponse(resp); err != nil {
+		return "", err
+	}
+	return schemeVault + ":" + path, nil
+}
+
+func (s *VaultStore) Get(ctx context.Context, ref string) (string, error) {
+	path, err := pathFromRef(ref, schemeVault)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(ctx, http.MethodGet, "data/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrSecretNotFound
+	}
+	if err := checkVaultResponse(resp); err != nil {
+		return "", err
+	}
+	var out vaultKVResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+	secret, ok := out.Data.Data["password"]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret, nil
+}
+
+// Delete elimina todas las versiones del secreto (metadata); un 404 no es error
+func (s *VaultStore) Delete(ctx context.Context, ref string) error {
+	path, err := pathFromRef(ref, schemeVault)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, "metadata/"+path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkVaultResponse(resp)
+}
+
+func (s *VaultStore) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	url := fmt.Sprintf("%s/v1/%s/%s", s.opts.Addr, s.opts.Mount, path)
+	var rd io.Reader
+	if body != nil {
+		rd = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.opts.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// checkVaultResponse convierte un estado no 2xx en error usando el campo "errors" de Vault
+func checkVaultResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	var body struct {
+		Errors []string `json:"errors"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	if len(body.Errors) > 0 {
+		return fmt.Errorf("vault returned %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+	return fmt.Errorf("vault returned %d", resp.StatusCode)
+}
@@ -0,0 +1,167 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Ids of the checkpoint key when EVIDENCE_SIGNING_KEY_ID is not set; an
+// ephemeral key gets the prefix and a random suffix
+const (
+	DefaultEvidenceKeyID       = "default"
+	DerivedEvidenceKeyID       = "derived"
+	EphemeralEvidenceKeyPrefix = "ephemeral-"
+)
+
+// ErrNoEvidenceKey means there is neither a key file nor a secret to derive a key from
+var ErrNoEvidenceKey = errors.New("evidence signing key: set EVIDENCE_SIGNING_KEY or a non-default JWT_SECRET to derive one from")
+
+// EvidenceSigner signs evidence chain checkpoints with Ed25519 and verifies
+// them with its own key or any trusted public key of a previous one
+type EvidenceSigner struct {
+	keyID   string
+	key     ed25519.PrivateKey
+	trusted map[string]ed25519.PublicKey
+}
+
+// LoadEvidenceSigner reads an Ed25519 private key (PKCS#8 PEM) from path. Without
+// a path the key is derived from fallbackSecret, which keeps checkpoints signed
+// out of the box but ties them to the JWT secret; callers pass "" when that
+// secret is a well-known default. trusted lists the public keys of previous
+// signing keys ("kid:/path.pem,kid:/path.pem") so their checkpoints still verify.
+// keyID names the key either way; empty means "default" for a key file and
+// "derived" for a derived key.
+func LoadEvidenceSigner(keyID, path, trusted, fallbackSecret string) (*EvidenceSigner, error) {
+	var key ed25519.PrivateKey
+	if path == "" {
+		if fallbackSecret == "" {
+			return nil, ErrNoEvidenceKey
+		}
+		seed := sha256.Sum256([]byte("microsql-ago evidence checkpoints\x00" + fallbackSecret))
+		key = ed25519.NewKeyFromSeed(seed[:])
+		if keyID == "" {
+			keyID = DerivedEvidenceKeyID
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("evidence signing key: %w", err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil || block.Type != "PRIVATE KEY" {
+			return nil, errors.New("evidence signing key: expected a PKCS#8 PEM private key")
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("evidence signing key: %w", err)
+		}
+		var ok bool
+		if key, ok = parsed.(ed25519.PrivateKey); !ok {
+			return nil, fmt.Errorf("evidence signing key: expected Ed25519, got %T", parsed)
+		}
+		if keyID == "" {
+			keyID = DefaultEvidenceKeyID
+		}
+	}
+	s := NewEvidenceSigner(keyID, key)
+	if err := s.trustFiles(trusted); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewEphemeralEvidenceSigner signs with a random key that lives only in this
+// process, for development setups without a key. Its id is unique per process,
+// so after a restart its checkpoints are reported as unverifiable rather than
+// tampered with. trusted is the same list LoadEvidenceSigner takes.
+func NewEphemeralEvidenceSigner(trusted string) (*EvidenceSigner, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("evidence signing key: %w", err)
+	}
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("evidence signing key: %w", err)
+	}
+	s := NewEvidenceSigner(EphemeralEvidenceKeyPrefix+hex.EncodeToString(suffix), key)
+	if err := s.trustFiles(trusted); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// trustFiles trusts the public keys listed as "kid:/path.pem,kid:/path.pem"
+func (s *EvidenceSigner) trustFiles(trusted string) error {
+	for _, part := range strings.Split(trusted, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return fmt.Errorf("invalid trusted evidence key entry %q, expected kid:path", part)
+		}
+		data, err := os.ReadFile(kv[1])
+		if err != nil {
+			return fmt.Errorf("read trusted evidence key %q: %w", kv[0], err)
+		}
+		k, err := ParseSigningKeyPEM(kv[0], data)
+		if err != nil {
+			return err
+		}
+		if err := s.Trust(kv[0], k.Public); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewEvidenceSigner builds a signer from a key in memory (tests, tools)
+func NewEvidenceSigner(keyID string, key ed25519.PrivateKey) *EvidenceSigner {
+	pub := key.Public().(ed25519.PublicKey)
+	return &EvidenceSigner{keyID: keyID, key: key, trusted: map[string]ed25519.PublicKey{keyID: pub}}
+}
+
+// Trust accepts checkpoints signed by a previous key
+func (s *EvidenceSigner) Trust(keyID string, public crypto.PublicKey) error {
+	pub, ok := public.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("trusted evidence key %q: expected Ed25519, got %T", keyID, public)
+	}
+	if _, dup := s.trusted[keyID]; dup {
+		return fmt.Errorf("duplicate evidence key id %q", keyID)
+	}
+	s.trusted[keyID] = pub
+	return nil
+}
+
+func (s *EvidenceSigner) KeyID() string { return s.keyID }
+
+func (s *EvidenceSigner) Sign(payload []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, payload), nil
+}
+
+func (s *EvidenceSigner) Knows(keyID string) bool {
+	_, ok := s.trusted[keyID]
+	return ok
+}
+
+// Verify accepts signatures of the current key and of the trusted ones
+func (s *EvidenceSigner) Verify(keyID string, payload, signature []byte) bool {
+	pub, ok := s.trusted[keyID]
+	return ok && ed25519.Verify(pub, payload, signature)
+}
+
+func (s *EvidenceSigner) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
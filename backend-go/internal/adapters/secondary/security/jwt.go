@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/randtoken"
 )
 
 // TokenClaims representa las claims en el JWT con información adicional del usuario
@@ -16,15 +18,22 @@ type TokenClaims struct {
 	jwt.RegisteredClaims
 }
 
+// DefaultRefreshExpiry es la vida de un refresh token si no se configura otra
+const DefaultRefreshExpiry = 7 * 24 * time.Hour
+
 type JWTService struct {
-	secret      string
-	expiryHours int
+	secret        string
+	expiry        time.Duration
+	refreshExpiry time.Duration
+	// keys firma con RS256/EdDSA; nil = HS256 con secret
+	keys *JWTKeyring
 }
 
 func NewJWTService(secret string) *JWTService {
 	return &JWTService{
-		secret:      secret,
-		expiryHours: 24, // default 24 horas
+		secret:        secret,
+		expiry:        24 * time.Hour, // default 24 horas
+		refreshExpiry: DefaultRefreshExpiry,
 	}
 }
 
@@ -34,11 +43,46 @@ func NewJWTServiceWithExpiry(secret string, expiryHours int) *JWTService {
 		expiryHours = 24
 	}
 	return &JWTService{
-		secret:      secret,
-		expiryHours: expiryHours,
+		secret:        secret,
+		expiry:        time.Duration(expiryHours) * time.Hour,
+		refreshExpiry: DefaultRefreshExpiry,
+	}
+}
+
+// NewJWTServiceWithTTL crea un JWTService con access tokens de vida corta y una
+// expiración independiente para los refresh tokens
+func NewJWTServiceWithTTL(secret string, accessTTL, refreshTTL time.Duration) *JWTService {
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshExpiry
+	}
+	return &JWTService{
+		secret:        secret,
+		expiry:        accessTTL,
+		refreshExpiry: refreshTTL,
 	}
 }
 
+// NewJWTServiceWithKeyring crea un JWTService que firma con claves asimétricas
+// (cabecera kid) en lugar del secreto compartido
+func NewJWTServiceWithKeyring(keys *JWTKeyring, accessTTL, refreshTTL time.Duration) *JWTService {
+	s := NewJWTServiceWithTTL("", accessTTL, refreshTTL)
+	s.keys = keys
+	return s
+}
+
+// AccessExpiry devuelve la vida de los access tokens
+func (s *JWTService) AccessExpiry() time.Duration {
+	return s.expiry
+}
+
+// RefreshExpiry devuelve la vida de los refresh tokens
+func (s *JWTService) RefreshExpiry() time.Duration {
+	return s.refreshExpiry
+}
+
 // Generate genera un JWT con solo userID (método legacy, mantenido para compatibilidad)
 func (s *JWTService) Generate(userID uint, expHours int) (string, error) {
 	claims := jwt.MapClaims{
@@ -51,22 +95,27 @@ func (s *JWTService) Generate(userID uint, expHours int) (string, error) {
 
 // GenerateToken genera un JWT completo con userID, username y role
 func (s *JWTService) GenerateToken(userID uint, username string, role string) (string, error) {
+	jti, err := randtoken.New(16)
+	if err != nil {
+		return "", err
+	}
 	now := time.Now()
-	expiresAt := now.Add(time.Duration(s.expiryHours) * time.Hour)
+	expiresAt := now.Add(s.expiry)
 
 	claims := TokenClaims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// jti único: dos tokens del mismo usuario en el mismo segundo no coinciden
+			ID:        jti,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.secret))
+	tokenString, err := s.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -94,13 +143,7 @@ func (s *JWTService) Validate(tokenStr string) (uint, error) {
 func (s *JWTService) ValidateToken(tokenString string) (*TokenClaims, error) {
 	claims := &TokenClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Verificar el método de firma
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -125,3 +168,30 @@ func ExtractBearerToken(authHeader string) (string, error) {
 
 	return authHeader[7:], nil
 }
+
+// JWKS devuelve las claves públicas de verificación; vacío en modo HS256,
+// donde el secreto no se puede publicar
+func (s *JWTService) JWKS() JWKSet {
+	if s.keys == nil {
+		return JWKSet{Keys: []JWK{}}
+	}
+	return s.keys.JWKS()
+}
+
+func (s *JWTService) sign(claims jwt.Claims) (string, error) {
+	if s.keys != nil {
+		return s.keys.Sign(claims)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secret))
+}
+
+func (s *JWTService) keyFunc(token *jwt.Token) (interface{}, error) {
+	if s.keys != nil {
+		return s.keys.keyFunc(token)
+	}
+	// Verificar el método de firma
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return []byte(s.secret), nil
+}
@@ -0,0 +1,265 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+)
+
+// Algoritmos de firma soportados para los access tokens
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// NormalizeJWTAlg devuelve el nombre canónico del algoritmo sin distinguir
+// mayúsculas; los desconocidos se devuelven tal cual para que fallen al validarse
+func NormalizeJWTAlg(alg string) string {
+	switch strings.ToUpper(strings.TrimSpace(alg)) {
+	case "HS256":
+		return JWTAlgHS256
+	case "RS256":
+		return JWTAlgRS256
+	case "EDDSA":
+		return JWTAlgEdDSA
+	}
+	return alg
+}
+
+// ErrUnknownJWTKey se devuelve cuando el kid del token no está en el keyring
+// o la clave ya salió del periodo de solapamiento
+var ErrUnknownJWTKey = errors.New("unknown or retired signing key")
+
+// SigningKey es una clave asimétrica del keyring. Las claves retiradas pueden
+// no tener parte privada: solo sirven para verificar tokens emitidos antes de la rotación.
+type SigningKey struct {
+	ID      string
+	Alg     string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+	// RetireAt es el final del periodo de solapamiento; cero = sin límite
+	RetireAt time.Time
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Alg == JWTAlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+func (k *SigningKey) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && now.After(k.RetireAt)
+}
+
+// JWTKeyring firma con la clave actual y verifica con cualquier clave publicada
+type JWTKeyring struct {
+	current *SigningKey
+	keys    map[string]*SigningKey
+	now     func() time.Time
+}
+
+// NewJWTKeyring crea un keyring cuya clave de firma es current. El resto de
+// claves solo verifican; se retiran overlap después de verse por primera vez
+// como rotadas (overlap <= 0 las mantiene mientras sigan configuradas). Con
+// retirements esa hora se guarda y sobrevive a los reinicios; sin él cuenta
+// desde la creación del keyring.
+func NewJWTKeyring(current *SigningKey, others []*SigningKey, overlap time.Duration, retirements repositories.JWTKeyRepository) (*JWTKeyring, error) {
+	if current == nil || current.Private == nil {
+		return nil, errors.New("current signing key must include a private key")
+	}
+	kr := &JWTKeyring{current: current, keys: map[string]*SigningKey{current.ID: current}, now: time.Now}
+	if retirements != nil {
+		if err := retirements.Forget(current.ID); err != nil {
+			return nil, fmt.Errorf("reset retire time of JWT key %q: %w", current.ID, err)
+		}
+	}
+	for _, k := range others {
+		if _, dup := kr.keys[k.ID]; dup {
+			return nil, fmt.Errorf("duplicate signing key id %q", k.ID)
+		}
+		if overlap > 0 && k.RetireAt.IsZero() {
+			k.RetireAt = kr.now().Add(overlap)
+			if retirements != nil {
+				at, err := retirements.RetireAt(k.ID, k.RetireAt)
+				if err != nil {
+					return nil, fmt.Errorf("retire time of JWT key %q: %w", k.ID, err)
+				}
+				k.RetireAt = at
+			}
+		}
+		kr.keys[k.ID] = k
+	}
+	return kr, nil
+}
+
+// LoadJWTKeyring lee las claves PEM de spec ("kid:/ruta.pem,kid:/ruta.pem") y
+// usa currentID para firmar. Si currentID está vacío firma la primera de la lista.
+// alg no distingue mayúsculas (rs256, eddsa).
+func LoadJWTKeyring(alg, currentID, spec string, overlap time.Duration, retirements repositories.JWTKeyRepository) (*JWTKeyring, error) {
+	alg = NormalizeJWTAlg(alg)
+	if alg != JWTAlgRS256 && alg != JWTAlgEdDSA {
+		return nil, fmt.Errorf("unsupported asymmetric JWT algorithm %q", alg)
+	}
+	var current *SigningKey
+	var others []*SigningKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid JWT key entry %q, expected kid:path", part)
+		}
+		data, err := os.ReadFile(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("read JWT key %q: %w", kv[0], err)
+		}
+		k, err := ParseSigningKeyPEM(kv[0], data)
+		if err != nil {
+			return nil, err
+		}
+		if k.Alg != alg {
+			return nil, fmt.Errorf("JWT key %q is %s, expected %s", k.ID, k.Alg, alg)
+		}
+		if current == nil && (currentID == "" || currentID == k.ID) {
+			current = k
+			continue
+		}
+		others = append(others, k)
+	}
+	if current == nil {
+		if currentID != "" {
+			return nil, fmt.Errorf("current JWT key %q not found in JWT_KEYS", currentID)
+		}
+		return nil, errors.New("no JWT signing keys configured")
+	}
+	return NewJWTKeyring(current, others, overlap, retirements)
+}
+
+// ParseSigningKeyPEM acepta claves privadas RSA/Ed25519 (PKCS#1 o PKCS#8) o
+// claves públicas PKIX para claves retiradas
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %q: no PEM block found", kid)
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("JWT key %q: unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("JWT key %q: %w", kid, err)
+	}
+	k := &SigningKey{ID: kid}
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Alg, k.Private, k.Public = JWTAlgRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.Alg, k.Public = JWTAlgRS256, key
+	case ed25519.PrivateKey:
+		k.Alg, k.Private, k.Public = JWTAlgEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.Alg, k.Public = JWTAlgEdDSA, key
+	default:
+		return nil, fmt.Errorf("JWT key %q: unsupported key type %T", kid, parsed)
+	}
+	if pub, ok := k.Public.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("JWT key %q: RSA keys must be at least 2048 bits", kid)
+	}
+	return k, nil
+}
+
+// CurrentKeyID devuelve el kid con el que se firman los tokens nuevos
+func (kr *JWTKeyring) CurrentKeyID() string {
+	return kr.current.ID
+}
+
+// Sign firma las claims con la clave actual y añade el kid a la cabecera
+func (kr *JWTKeyring) Sign(claims jwt.Claims) (string, error) {
+	k := kr.current
+	token := jwt.NewWithClaims(k.method(), claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.Private)
+}
+
+// keyFunc resuelve la clave pública por kid para jwt.Parse
+func (kr *JWTKeyring) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, ok := kr.keys[kid]
+	if !ok || k.retired(kr.now()) {
+		return nil, ErrUnknownJWTKey
+	}
+	if t.Method.Alg() != k.Alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return k.Public, nil
+}
+
+// JWK es una clave pública en formato RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet es el documento servido en /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publica la clave actual y las que siguen en periodo de solapamiento
+func (kr *JWTKeyring) JWKS() JWKSet {
+	now := kr.now()
+	set := JWKSet{Keys: []JWK{}}
+	for _, k := range kr.keys {
+		if k.retired(now) {
+			continue
+		}
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Alg}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,181 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func rsaKeyFile(t *testing.T, dir, name string) (string, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa: %v", err)
+	}
+	return writePEM(t, dir, name, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)), key
+}
+
+func TestJWTKeyring_RS256RotationOverlap(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, oldKey := rsaKeyFile(t, dir, "old.pem")
+	newPath, _ := rsaKeyFile(t, dir, "new.pem")
+
+	// tokens issued before the rotation, signed with the old key
+	before, err := LoadJWTKeyring(JWTAlgRS256, "k1", "k1:"+oldPath, 0, nil)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	oldToken, err := NewJWTServiceWithKeyring(before, time.Hour, time.Hour).GenerateToken(1, "alice", "admin")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// the old key is only published as a public key after rotation
+	pubDER, _ := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	oldPub := writePEM(t, dir, "old.pub.pem", "PUBLIC KEY", pubDER)
+	kr, err := LoadJWTKeyring(JWTAlgRS256, "k2", "k1:"+oldPub+",k2:"+newPath, time.Hour, nil)
+	if err != nil {
+		t.Fatalf("load rotated: %v", err)
+	}
+	svc := NewJWTServiceWithKeyring(kr, time.Hour, time.Hour)
+
+	newToken, err := svc.GenerateToken(2, "bob", "user")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &TokenClaims{})
+	if parsed.Header["kid"] != "k2" || parsed.Header["alg"] != "RS256" {
+		t.Fatalf("unexpected header: %v", parsed.Header)
+	}
+	if c, err := svc.ValidateToken(newToken); err != nil || c.UserID != 2 {
+		t.Fatalf("validate new token: %v", err)
+	}
+	if c, err := svc.ValidateToken(oldToken); err != nil || c.UserID != 1 {
+		t.Fatalf("old token must verify during overlap: %v", err)
+	}
+	if got := len(svc.JWKS().Keys); got != 2 {
+		t.Fatalf("expected both keys in JWKS during overlap, got %d", got)
+	}
+
+	// after the overlap the old key is gone from verification and JWKS
+	kr.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := svc.ValidateToken(oldToken); !errors.Is(err, ErrUnknownJWTKey) {
+		t.Fatalf("expected retired key error, got %v", err)
+	}
+	set := svc.JWKS()
+	if len(set.Keys) != 1 || set.Keys[0].Kid != "k2" || set.Keys[0].Kty != "RSA" || set.Keys[0].E != "AQAB" {
+		t.Fatalf("unexpected JWKS after overlap: %+v", set)
+	}
+}
+
+// retireTimes is an in-memory JWTKeyRepository shared by "restarts"
+type retireTimes map[string]time.Time
+
+func (r retireTimes) RetireAt(kid string, proposed time.Time) (time.Time, error) {
+	if at, ok := r[kid]; ok {
+		return at, nil
+	}
+	r[kid] = proposed
+	return proposed, nil
+}
+
+func (r retireTimes) Forget(kid string) error {
+	delete(r, kid)
+	return nil
+}
+
+func TestJWTKeyring_restartKeepsRetireTime(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, _ := rsaKeyFile(t, dir, "old.pem")
+	newPath, _ := rsaKeyFile(t, dir, "new.pem")
+	store := retireTimes{}
+	spec := "k1:" + oldPath + ",k2:" + newPath
+
+	first, err := LoadJWTKeyring("rs256", "k2", spec, time.Hour, store)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	retireAt := first.keys["k1"].RetireAt
+
+	// a later restart must not start the overlap again
+	time.Sleep(10 * time.Millisecond)
+	second, err := LoadJWTKeyring(JWTAlgRS256, "k2", spec, time.Hour, store)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := second.keys["k1"].RetireAt; !got.Equal(retireAt) {
+		t.Fatalf("expected retire time %v to survive the restart, got %v", retireAt, got)
+	}
+
+	// rolling back to k1 makes it the signing key again and clears its retire time
+	if _, err := LoadJWTKeyring(JWTAlgRS256, "k1", spec, time.Hour, store); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if _, ok := store["k1"]; ok {
+		t.Fatalf("expected the signing key to have no retire time")
+	}
+}
+
+func TestJWTKeyring_EdDSAAndAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(priv)
+	path := writePEM(t, dir, "ed.pem", "PRIVATE KEY", der)
+
+	if _, err := LoadJWTKeyring(JWTAlgRS256, "", "ed:"+path, 0, nil); err == nil {
+		t.Fatalf("expected error loading an Ed25519 key as RS256")
+	}
+	// JWT_ALG is case-insensitive
+	kr, err := LoadJWTKeyring("eddsa", "", "ed:"+path, 0, nil)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	svc := NewJWTServiceWithKeyring(kr, time.Hour, time.Hour)
+	token, err := svc.GenerateToken(7, "carol", "user")
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if _, err := svc.ValidateToken(token); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	set := svc.JWKS()
+	if len(set.Keys) != 1 || set.Keys[0].Kty != "OKP" || set.Keys[0].Crv != "Ed25519" || set.Keys[0].X == "" {
+		t.Fatalf("unexpected JWKS: %+v", set)
+	}
+
+	// an HS256 token carrying a valid kid must not be accepted by the asymmetric service
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, TokenClaims{UserID: 1, Role: "admin"})
+	forged.Header["kid"] = "ed"
+	signed, _ := forged.SignedString([]byte("anything"))
+	if _, err := svc.ValidateToken(signed); err == nil {
+		t.Fatalf("HS256 token must be rejected in EdDSA mode")
+	}
+	// nor an HS256 service accept asymmetric tokens
+	if _, err := NewJWTServiceWithTTL("secret", time.Hour, time.Hour).ValidateToken(token); err == nil {
+		t.Fatalf("EdDSA token must be rejected in HS256 mode")
+	}
+	if got := NewJWTServiceWithTTL("secret", time.Hour, time.Hour).JWKS(); len(got.Keys) != 0 {
+		t.Fatalf("HS256 mode must not publish keys")
+	}
+}
@@ -0,0 +1,114 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+)
+
+// DefaultSessionCacheTTL bounds how long a cached session state is trusted. Revocations
+// made through this process invalidate the cache immediately; the TTL only matters
+// for changes made by other instances or directly in the database.
+const DefaultSessionCacheTTL = 30 * time.Second
+
+const maxSessionCacheEntries = 10000
+
+// CachedSession is the session state the auth middleware needs per request
+type CachedSession struct {
+	ID       uint
+	UserID   uint
+	FamilyID string
+	Active   bool
+	cachedAt time.Time
+}
+
+// SessionCache answers "is the session behind this access token still active?"
+// with a small in-memory cache in front of SessionRepository. Keys are the
+// SHA-256 of the token so raw tokens are not kept in memory longer than needed.
+type SessionCache struct {
+	repo    repositories.SessionRepository
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]CachedSession
+	now     func() time.Time
+}
+
+func NewSessionCache(repo repositories.SessionRepository, ttl time.Duration) *SessionCache {
+	if ttl <= 0 {
+		ttl = DefaultSessionCacheTTL
+	}
+	return &SessionCache{repo: repo, ttl: ttl, entries: map[string]CachedSession{}, now: time.Now}
+}
+
+// Lookup returns the session for the token; ok is false when the token has no
+// session or the session was logged out, rotated, revoked or expired
+func (c *SessionCache) Lookup(token string) (CachedSession, bool, error) {
+	key := tokenKey(token)
+	now := c.now()
+
+	c.mu.RLock()
+	e, found := c.entries[key]
+	c.mu.RUnlock()
+	if found && now.Sub(e.cachedAt) < c.ttl {
+		return e, e.Active, nil
+	}
+
+	s, err := c.repo.GetByToken(token)
+	if err != nil {
+		return CachedSession{}, false, err
+	}
+	e = CachedSession{cachedAt: now}
+	if s != nil {
+		e.ID, e.UserID, e.FamilyID = s.ID, s.UserID, s.FamilyID
+		e.Active = s.IsActive && s.RevokedAt == nil && (s.ExpiresAt == nil || s.ExpiresAt.After(now))
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= maxSessionCacheEntries {
+		c.entries = map[string]CachedSession{}
+	}
+	c.entries[key] = e
+	c.mu.Unlock()
+	return e, e.Active, nil
+}
+
+func (c *SessionCache) InvalidateToken(token string) {
+	c.mu.Lock()
+	delete(c.entries, tokenKey(token))
+	c.mu.Unlock()
+}
+
+func (c *SessionCache) InvalidateFamily(familyID string) {
+	if familyID == "" {
+		return
+	}
+	c.invalidateWhere(func(e CachedSession) bool { return e.FamilyID == familyID })
+}
+
+func (c *SessionCache) InvalidateUser(userID uint) {
+	c.invalidateWhere(func(e CachedSession) bool { return e.UserID == userID })
+}
+
+func (c *SessionCache) InvalidateAll() {
+	c.mu.Lock()
+	c.entries = map[string]CachedSession{}
+	c.mu.Unlock()
+}
+
+func (c *SessionCache) invalidateWhere(match func(CachedSession) bool) {
+	c.mu.Lock()
+	for k, e := range c.entries {
+		if match(e) {
+			delete(c.entries, k)
+		}
+	}
+	c.mu.Unlock()
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
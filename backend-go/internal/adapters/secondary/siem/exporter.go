@@ -0,0 +1,330 @@
+// Package siem exporta los eventos de seguridad del log de actividad (auth,
+// RBAC, conexiones y auditorías) a un colector syslog en formato RFC 5424 o
+// CEF, sobre UDP, TCP o TLS, con una cola acotada y reintentos.
+package siem
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// Transportes y formatos soportados
+const (
+	NetworkUDP = "udp"
+	NetworkTCP = "tcp"
+	NetworkTLS = "tls"
+
+	FormatRFC5424 = "rfc5424"
+	FormatCEF     = "cef"
+)
+
+// DefaultCategories son las categorías del log de actividad que se exportan si
+// no se indica otra cosa: autenticación, cambios de administración (RBAC),
+// conexiones, auditorías y descargas de informes
+var DefaultCategories = []string{"auth", "admin", "connection", "audit", "report"}
+
+// maxBackoff limita la espera entre reintentos
+const maxBackoff = 30 * time.Second
+
+// FacilityAuthPriv es la facility por defecto (authpriv)
+const FacilityAuthPriv = 10
+
+// Config describe el colector y cómo se le envían los eventos
+type Config struct {
+	// Network es udp, tcp o tls
+	Network string
+	// Address es host:puerto del colector
+	Address string
+	// Format es rfc5424 o cef (CEF va dentro de un mensaje syslog RFC 5424)
+	Format string
+	// Facility es la facility syslog (0-23); nil = authpriv (10). Es un puntero
+	// porque 0 (kern) es una facility válida
+	Facility *int
+	// AppName y Hostname van en la cabecera syslog; Hostname vacío usa el del sistema
+	AppName  string
+	Hostname string
+	// Categories filtra por categoría del evento; vacío = todas
+	Categories []string
+
+	// BufferSize es el tamaño de la cola; con la cola llena los eventos se descartan
+	BufferSize int
+	// MaxRetries es cuántas veces se reintenta un envío fallido antes de descartarlo
+	MaxRetries int
+	// RetryBackoff es la espera antes del primer reintento; se duplica en cada uno
+	RetryBackoff time.Duration
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// TLS permite fijar la CA del colector (RootCAs); nil usa las del sistema
+	TLS *tls.Config
+	// CAFile es un PEM con la CA del colector, alternativa a TLS.RootCAs
+	CAFile string
+}
+
+// Exporter implementa services.ActivitySink y services.SIEMStatsProvider:
+// Publish encola sin bloquear y Run envía los eventos en orden, reconectando y
+// reintentando con espera exponencial.
+type Exporter struct {
+	cfg        Config
+	facility   int
+	logger     *zap.Logger
+	categories map[string]bool
+	queue      chan entities.ActivityEvent
+	conn       net.Conn
+
+	sent, retries, dropped, failed, connects uint64
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt *time.Time
+}
+
+func NewExporter(cfg Config) (*Exporter, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("SIEM address is required")
+	}
+	if cfg.Network == "" {
+		cfg.Network = NetworkUDP
+	}
+	switch cfg.Network {
+	case NetworkUDP, NetworkTCP, NetworkTLS:
+	default:
+		return nil, fmt.Errorf("unsupported SIEM network %q (udp, tcp or tls)", cfg.Network)
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatRFC5424
+	}
+	if cfg.Format != FormatRFC5424 && cfg.Format != FormatCEF {
+		return nil, fmt.Errorf("unsupported SIEM format %q (rfc5424 or cef)", cfg.Format)
+	}
+	facility := FacilityAuthPriv
+	if cfg.Facility != nil {
+		facility = *cfg.Facility
+	}
+	if facility < 0 || facility > 23 {
+		return nil, fmt.Errorf("syslog facility %d out of range (0-23)", facility)
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "microsql-ago"
+	}
+	if cfg.Hostname == "" {
+		cfg.Hostname, _ = os.Hostname()
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read SIEM CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("SIEM CA file %q has no PEM certificates", cfg.CAFile)
+		}
+		if cfg.TLS == nil {
+			cfg.TLS = &tls.Config{}
+		} else {
+			cfg.TLS = cfg.TLS.Clone()
+		}
+		cfg.TLS.RootCAs = pool
+	}
+	e := &Exporter{cfg: cfg, facility: facility, logger: zap.NewNop(), queue: make(chan entities.ActivityEvent, cfg.BufferSize)}
+	if len(cfg.Categories) > 0 {
+		e.categories = map[string]bool{}
+		for _, c := range cfg.Categories {
+			e.categories[strings.TrimSpace(c)] = true
+		}
+	}
+	return e, nil
+}
+
+// WithLogger anota los eventos descartados tras agotar los reintentos
+func (e *Exporter) WithLogger(l *zap.Logger) *Exporter {
+	e.logger = l
+	return e
+}
+
+// Publish encola el evento si su categoría se exporta; con la cola llena lo
+// descarta y lo cuenta, para no frenar las peticiones si el colector no responde
+func (e *Exporter) Publish(ev entities.ActivityEvent) {
+	if e.categories != nil && !e.categories[ev.Category] {
+		return
+	}
+	select {
+	case e.queue <- ev:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+}
+
+// Run envía los eventos de la cola hasta que ctx se cancele
+func (e *Exporter) Run(ctx context.Context) {
+	defer e.closeConn()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-e.queue:
+			e.deliver(ctx, e.Format(ev))
+		}
+	}
+}
+
+// SIEMStats devuelve las métricas del exportador
+func (e *Exporter) SIEMStats() services.SIEMStats {
+	e.mu.Lock()
+	lastError, lastErrorAt := e.lastError, e.lastErrorAt
+	e.mu.Unlock()
+	return services.SIEMStats{
+		Network: e.cfg.Network, Address: e.cfg.Address, Format: e.cfg.Format,
+		Queued: len(e.queue), Capacity: cap(e.queue),
+		Sent:        atomic.LoadUint64(&e.sent),
+		Retries:     atomic.LoadUint64(&e.retries),
+		Dropped:     atomic.LoadUint64(&e.dropped),
+		Failed:      atomic.LoadUint64(&e.failed),
+		Connects:    atomic.LoadUint64(&e.connects),
+		LastError:   lastError,
+		LastErrorAt: lastErrorAt,
+	}
+}
+
+// deliver envía un mensaje; si falla cierra la conexión y reintenta hasta
+// MaxRetries veces, duplicando la espera. Agotados los reintentos lo descarta.
+func (e *Exporter) deliver(ctx context.Context, msg []byte) {
+	backoff := e.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := e.write(msg)
+		if err == nil {
+			atomic.AddUint64(&e.sent, 1)
+			return
+		}
+		e.noteError(err)
+		e.closeConn()
+		if attempt >= e.cfg.MaxRetries {
+			atomic.AddUint64(&e.failed, 1)
+			e.logger.Warn("siem: dropping event after retries", zap.Int("retries", attempt), zap.Error(err))
+			return
+		}
+		atomic.AddUint64(&e.retries, 1)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			atomic.AddUint64(&e.failed, 1)
+			return
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// write abre la conexión si hace falta y envía el mensaje: un datagrama por
+// mensaje en UDP y octet counting (RFC 6587) en TCP y TLS
+func (e *Exporter) write(msg []byte) error {
+	if e.conn == nil {
+		conn, err := e.dial()
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+		atomic.AddUint64(&e.connects, 1)
+	}
+	frame := msg
+	if e.cfg.Network != NetworkUDP {
+		frame = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+	if err := e.conn.SetWriteDeadline(time.Now().Add(e.cfg.WriteTimeout)); err != nil {
+		return err
+	}
+	_, err := e.conn.Write(frame)
+	return err
+}
+
+func (e *Exporter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: e.cfg.DialTimeout}
+	switch e.cfg.Network {
+	case NetworkTLS:
+		cfg := e.cfg.TLS
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName, _, _ = net.SplitHostPort(e.cfg.Address)
+		}
+		if cfg.MinVersion == 0 {
+			cfg.MinVersion = tls.VersionTLS12
+		}
+		return tls.DialWithDialer(dialer, "tcp", e.cfg.Address, cfg)
+	default:
+		return dialer.Dial(e.cfg.Network, e.cfg.Address)
+	}
+}
+
+func (e *Exporter) closeConn() {
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
+
+func (e *Exporter) noteError(err error) {
+	now := time.Now().UTC()
+	e.mu.Lock()
+	e.lastError, e.lastErrorAt = err.Error(), &now
+	e.mu.Unlock()
+}
+
+// ParseFacility acepta el nombre syslog de la facility (auth, authpriv,
+// local0..local7, ...) o su número
+func ParseFacility(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return FacilityAuthPriv, nil
+	}
+	for i, name := range facilityNames {
+		if name == s {
+			return i, nil
+		}
+	}
+	var n int
+	if _, err := fmt.Sscanf(s, "%d", &n); err != nil || n < 0 || n > 23 || fmt.Sprint(n) != s {
+		return 0, fmt.Errorf("unknown syslog facility %q", s)
+	}
+	return n, nil
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
@@ -0,0 +1,243 @@
+package siem
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/siem/siemtest"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+func collector(t *testing.T, network string) *siemtest.Listener {
+	t.Helper()
+	l, err := siemtest.Start(network)
+	if err != nil {
+		t.Fatalf("start syslog listener: %v", err)
+	}
+	t.Cleanup(l.Close)
+	return l
+}
+
+func run(t *testing.T, e *Exporter) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go e.Run(ctx)
+}
+
+func failedLogin() entities.ActivityEvent {
+	actor := uint(7)
+	return entities.ActivityEvent{
+		ID: 42, OccurredAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Action: entities.ActivityLogin, Category: "auth", Outcome: entities.OutcomeFailure,
+		ActorType: entities.ActorUser, ActorID: &actor, ActorName: `ana"]\`,
+		IPAddress: "10.0.0.7", RequestID: "req-1", Method: "POST", Path: "/api/users/login",
+		Details: `{"reason":"bad password","x":"a=b|c"}`,
+	}
+}
+
+func TestExporter_UDPRFC5424(t *testing.T) {
+	l := collector(t, "udp")
+	e, err := NewExporter(Config{Network: NetworkUDP, Address: l.Addr, Hostname: "db-host", AppName: "microsql"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	run(t, e)
+	e.Publish(failedLogin())
+
+	msgs := l.Wait(1, 2*time.Second)
+	if len(msgs) != 1 {
+		t.Fatalf("expected one message, got %v", msgs)
+	}
+	// authpriv (10) * 8 + warning (4) = 84
+	want := "<84>1 2026-03-01T10:00:00.000000Z db-host microsql "
+	if !strings.HasPrefix(msgs[0], want) {
+		t.Fatalf("expected header %q, got %q", want, msgs[0])
+	}
+	for _, part := range []string{
+		" auth.login [microsql@32473 eventId=\"42\" action=\"auth.login\" category=\"auth\" outcome=\"failure\"",
+		`actor="ana\"\]\\"`, `src="10.0.0.7"`, `details="{\"reason\":\"bad password\",\"x\":\"a=b|c\"}"`,
+		"] auth.login failure by ana", " from 10.0.0.7",
+	} {
+		if !strings.Contains(msgs[0], part) {
+			t.Fatalf("expected %q in %q", part, msgs[0])
+		}
+	}
+	if st := e.SIEMStats(); st.Sent != 1 || st.Dropped != 0 || st.Failed != 0 || st.Connects != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestExporter_TCPCEFWithOctetCounting(t *testing.T) {
+	l := collector(t, "tcp")
+	e, err := NewExporter(Config{Network: NetworkTCP, Address: l.Addr, Format: FormatCEF, Hostname: "db-host", Facility: facility(16)})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	run(t, e)
+	e.Publish(failedLogin())
+	e.Publish(entities.ActivityEvent{Action: "admin.role.update", Category: "admin", Outcome: entities.OutcomeSuccess, ActorName: "root"})
+
+	msgs := l.Wait(2, 2*time.Second)
+	if len(msgs) != 2 {
+		t.Fatalf("expected two framed messages, got %v", msgs)
+	}
+	// local0 (16) * 8 + warning (4) = 132
+	if !strings.HasPrefix(msgs[0], "<132>1 ") {
+		t.Fatalf("unexpected syslog header in %q", msgs[0])
+	}
+	cef := msgs[0][strings.Index(msgs[0], "CEF:"):]
+	wantHeader := "CEF:0|MicroSQL-AGo|MicroSQL-AGo|1.0|auth.login|auth.login failure|6|"
+	if !strings.HasPrefix(cef, wantHeader) {
+		t.Fatalf("expected CEF header %q, got %q", wantHeader, cef)
+	}
+	for _, part := range []string{
+		"rt=1772359200000", "externalId=42", "cat=auth", "outcome=failure", `suser=ana"]\\`, "suid=7",
+		"src=10.0.0.7", "dvchost=db-host", "request=/api/users/login", "cs1Label=requestId cs1=req-1",
+		`cs4={"reason":"bad password","x":"a\=b|c"}`,
+	} {
+		if !strings.Contains(cef, part) {
+			t.Fatalf("expected %q in %q", part, cef)
+		}
+	}
+	if !strings.Contains(msgs[1], "|admin.role.update|admin.role.update success|5|") {
+		t.Fatalf("expected an admin change with severity 5, got %q", msgs[1])
+	}
+}
+
+func TestExporter_TLS(t *testing.T) {
+	l := collector(t, "tls")
+	e, err := NewExporter(Config{Network: NetworkTLS, Address: l.Addr, TLS: &tls.Config{RootCAs: l.RootCAs}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	run(t, e)
+	e.Publish(failedLogin())
+	if msgs := l.Wait(1, 2*time.Second); len(msgs) != 1 || !strings.Contains(msgs[0], "[microsql@32473 ") {
+		t.Fatalf("expected the event over TLS, got %v", msgs)
+	}
+
+	untrusted, err := NewExporter(Config{Network: NetworkTLS, Address: l.Addr, MaxRetries: 1, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	untrusted.deliver(context.Background(), untrusted.Format(failedLogin()))
+	if st := untrusted.SIEMStats(); st.Sent != 0 || st.Failed != 1 || !strings.Contains(st.LastError, "certificate") {
+		t.Fatalf("expected an unknown CA to fail the handshake, got %+v", st)
+	}
+}
+
+func TestExporter_FiltersCategories(t *testing.T) {
+	e, err := NewExporter(Config{Address: "127.0.0.1:1", Categories: DefaultCategories})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	e.Publish(entities.ActivityEvent{Action: entities.ActivityAPIRequest, Category: "api"})
+	e.Publish(failedLogin())
+	if st := e.SIEMStats(); st.Queued != 1 {
+		t.Fatalf("expected only the auth event queued, got %+v", st)
+	}
+}
+
+func TestExporter_DropsWhenBufferIsFull(t *testing.T) {
+	e, err := NewExporter(Config{Address: "127.0.0.1:1", BufferSize: 2})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		e.Publish(failedLogin())
+	}
+	if st := e.SIEMStats(); st.Queued != 2 || st.Capacity != 2 || st.Dropped != 3 {
+		t.Fatalf("expected 2 queued and 3 dropped, got %+v", st)
+	}
+}
+
+func TestExporter_RetriesUntilTheCollectorIsBack(t *testing.T) {
+	l := collector(t, "tcp")
+	addr := l.Addr
+	l.Close()
+
+	e, err := NewExporter(Config{Network: NetworkTCP, Address: addr, MaxRetries: 10, RetryBackoff: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	run(t, e)
+	e.Publish(failedLogin())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for e.SIEMStats().Retries == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	back, err := siemtest.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot listen again on %s: %v", addr, err)
+	}
+	t.Cleanup(back.Close)
+
+	if msgs := back.Wait(1, 3*time.Second); len(msgs) != 1 {
+		t.Fatalf("expected the event once the collector is back, got %v", msgs)
+	}
+	st := e.SIEMStats()
+	if st.Sent != 1 || st.Retries == 0 || st.Failed != 0 || st.LastError == "" {
+		t.Fatalf("expected a delivery after retries, got %+v", st)
+	}
+}
+
+func TestExporter_FailsAfterMaxRetries(t *testing.T) {
+	l := collector(t, "tcp")
+	addr := l.Addr
+	l.Close()
+
+	e, err := NewExporter(Config{Network: NetworkTCP, Address: addr, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	e.deliver(context.Background(), e.Format(failedLogin()))
+	if st := e.SIEMStats(); st.Sent != 0 || st.Retries != 2 || st.Failed != 1 || st.LastErrorAt == nil {
+		t.Fatalf("expected 2 retries then a failure, got %+v", st)
+	}
+}
+
+func TestNewExporter_Validation(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Address: "siem:514", Network: "http"},
+		{Address: "siem:514", Format: "leef"},
+		{Address: "siem:514", Facility: facility(24)},
+		{Address: "siem:6514", Network: NetworkTLS, CAFile: "/does/not/exist.pem"},
+	} {
+		if _, err := NewExporter(cfg); err == nil {
+			t.Fatalf("expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestNewExporter_KeepsTheKernFacility(t *testing.T) {
+	for _, c := range []struct {
+		facility *int
+		want     int
+	}{{nil, FacilityAuthPriv}, {facility(0), 0}} {
+		e, err := NewExporter(Config{Address: "siem:514", Facility: c.facility})
+		if err != nil || e.facility != c.want {
+			t.Fatalf("expected facility %d, got %+v %v", c.want, e, err)
+		}
+	}
+}
+
+func facility(n int) *int { return &n }
+
+func TestParseFacility(t *testing.T) {
+	for in, want := range map[string]int{"": 10, "authpriv": 10, "AUTH": 4, "local7": 23, "16": 16} {
+		if got, err := ParseFacility(in); err != nil || got != want {
+			t.Fatalf("ParseFacility(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"24", "-1", "bogus", "1x"} {
+		if _, err := ParseFacility(in); err == nil {
+			t.Fatalf("expected %q to be rejected", in)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package siem
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// SDID es el SD-ID de los datos estructurados RFC 5424. 32473 es el número de
+// empresa reservado para ejemplos (RFC 5612); el colector solo lo usa como clave.
+const SDID = "microsql@32473"
+
+// Cabecera CEF: fabricante, producto y versión del dispositivo
+const (
+	cefVendor  = "MicroSQL-AGo"
+	cefProduct = "MicroSQL-AGo"
+	cefVersion = "1.0"
+)
+
+// Severidades syslog (RFC 5424 §6.2.1)
+const (
+	sevError   = 3
+	sevWarning = 4
+	sevNotice  = 5
+	sevInfo    = 6
+)
+
+// Format devuelve el mensaje syslog del evento en el formato configurado, sin
+// el encuadre del transporte
+func (e *Exporter) Format(ev entities.ActivityEvent) []byte {
+	pri := e.facility*8 + syslogSeverity(ev)
+	ts := ev.OccurredAt
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ", pri, ts.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		headerField(e.cfg.Hostname, 255), headerField(e.cfg.AppName, 48),
+		strconv.Itoa(os.Getpid()), headerField(ev.Action, 32))
+	if e.cfg.Format == FormatCEF {
+		b.WriteString("- ")
+		b.WriteString(cefMessage(ev, e.cfg.Hostname))
+		return []byte(b.String())
+	}
+	b.WriteString(structuredData(ev))
+	b.WriteByte(' ')
+	b.WriteString(summary(ev))
+	return []byte(b.String())
+}
+
+// syslogSeverity: bloqueos como error, fallos y denegaciones como aviso, los
+// cambios de administración como notice y el resto como informativo
+func syslogSeverity(ev entities.ActivityEvent) int {
+	switch {
+	case ev.Action == entities.ActivityLockout:
+		return sevError
+	case ev.Outcome == entities.OutcomeFailure || ev.Outcome == entities.OutcomeDenied:
+		return sevWarning
+	case ev.Category == "admin":
+		return sevNotice
+	default:
+		return sevInfo
+	}
+}
+
+// cefSeverity es la misma escala en el rango 0-10 de CEF
+func cefSeverity(ev entities.ActivityEvent) int {
+	switch {
+	case ev.Action == entities.ActivityLockout:
+		return 8
+	case ev.Outcome == entities.OutcomeDenied:
+		return 7
+	case ev.Outcome == entities.OutcomeFailure:
+		return 6
+	case ev.Category == "admin":
+		return 5
+	default:
+		return 3
+	}
+}
+
+// structuredData es el elemento [microsql@32473 ...] con los campos no vacíos del evento
+func structuredData(ev entities.ActivityEvent) string {
+	var b strings.Builder
+	b.WriteString("[" + SDID)
+	param := func(name, value string) {
+		if value != "" {
+			b.WriteString(" " + name + `="` + sdEscape(value) + `"`)
+		}
+	}
+	if ev.ID != 0 {
+		param("eventId", strconv.FormatUint(uint64(ev.ID), 10))
+	}
+	param("action", ev.Action)
+	param("category", ev.Category)
+	param("outcome", ev.Outcome)
+	param("actorType", ev.ActorType)
+	param("actorId", uintString(ev.ActorID))
+	param("actor", ev.ActorName)
+	param("apiKeyId", uintString(ev.APIKeyID))
+	param("src", ev.IPAddress)
+	param("requestId", ev.RequestID)
+	param("method", ev.Method)
+	param("path", ev.Path)
+	if ev.Status != 0 {
+		param("status", strconv.Itoa(ev.Status))
+	}
+	param("resourceType", ev.ResourceType)
+	param("resourceId", ev.ResourceID)
+	param("details", ev.Details)
+	b.WriteString("]")
+	return b.String()
+}
+
+// summary es el texto libre del mensaje RFC 5424
+func summary(ev entities.ActivityEvent) string {
+	actor := ev.ActorName
+	if actor == "" {
+		actor = ev.ActorType
+	}
+	s := fmt.Sprintf("%s %s by %s", ev.Action, ev.Outcome, actor)
+	if ev.IPAddress != "" {
+		s += " from " + ev.IPAddress
+	}
+	if ev.ResourceType != "" {
+		s += " on " + ev.ResourceType
+		if ev.ResourceID != "" {
+			s += " " + ev.ResourceID
+		}
+	}
+	return s
+}
+
+// cefMessage es el evento en CEF: CEF:0|fabricante|producto|versión|clase|nombre|severidad|extensión
+func cefMessage(ev entities.ActivityEvent, host string) string {
+	header := []string{"CEF:0", cefEscapeHeader(cefVendor), cefEscapeHeader(cefProduct), cefEscapeHeader(cefVersion),
+		cefEscapeHeader(ev.Action), cefEscapeHeader(ev.Action + " " + ev.Outcome), strconv.Itoa(cefSeverity(ev))}
+	var ext []string
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+cefEscapeValue(value))
+		}
+	}
+	if !ev.OccurredAt.IsZero() {
+		add("rt", strconv.FormatInt(ev.OccurredAt.UnixMilli(), 10))
+	}
+	if ev.ID != 0 {
+		add("externalId", strconv.FormatUint(uint64(ev.ID), 10))
+	}
+	add("cat", ev.Category)
+	add("act", ev.Action)
+	add("outcome", ev.Outcome)
+	add("suser", ev.ActorName)
+	add("suid", uintString(ev.ActorID))
+	add("src", ev.IPAddress)
+	add("dvchost", host)
+	add("requestMethod", ev.Method)
+	add("request", ev.Path)
+	add("requestClientApplication", ev.UserAgent)
+	if ev.Status != 0 {
+		add("cn1Label", "httpStatus")
+		add("cn1", strconv.Itoa(ev.Status))
+	}
+	if ev.RequestID != "" {
+		add("cs1Label", "requestId")
+		add("cs1", ev.RequestID)
+	}
+	if ev.ResourceType != "" {
+		add("cs2Label", "resourceType")
+		add("cs2", ev.ResourceType)
+	}
+	if ev.ResourceID != "" {
+		add("cs3Label", "resourceId")
+		add("cs3", ev.ResourceID)
+	}
+	if ev.Details != "" {
+		add("cs4Label", "details")
+		add("cs4", ev.Details)
+	}
+	if ev.APIKeyID != nil {
+		add("cs5Label", "apiKeyId")
+		add("cs5", uintString(ev.APIKeyID))
+	}
+	if ev.ActorType != "" {
+		add("cs6Label", "actorType")
+		add("cs6", ev.ActorType)
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " ")
+}
+
+// headerField deja un campo de cabecera RFC 5424 en ASCII imprimible sin
+// espacios y con su longitud máxima; vacío es "-"
+func headerField(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+		if b.Len() >= max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// sdEscape escapa '"', '\' y ']' en un PARAM-VALUE (RFC 5424 §6.3.3)
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// cefEscapeHeader escapa '\' y '|' en los campos de la cabecera CEF
+func cefEscapeHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+// cefEscapeValue escapa '\', '=' y los saltos de línea en los valores de la extensión CEF
+func cefEscapeValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`).Replace(s)
+}
+
+func uintString(v *uint) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*v), 10)
+}
@@ -0,0 +1,206 @@
+// Package siemtest is an in-process syslog collector for tests and local runs:
+// it receives RFC 5424 / CEF messages over UDP, TCP or TLS (octet-counting or
+// newline framing) with a self-signed certificate.
+package siemtest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Listener is the stand-in collector
+type Listener struct {
+	// Network is udp, tcp or tls
+	Network string
+	// Addr is 127.0.0.1:port
+	Addr string
+	// RootCAs trusts the TLS certificate
+	RootCAs *x509.CertPool
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	messages []string
+	closers  []io.Closer
+}
+
+// Start listens on a free port of 127.0.0.1
+func Start(network string) (*Listener, error) {
+	return Listen(network, "127.0.0.1:0")
+}
+
+// Listen listens on addr, e.g. to bring a collector back on the port of one that was closed
+func Listen(network, addr string) (*Listener, error) {
+	l := &Listener{Network: network}
+	l.cond = sync.NewCond(&l.mu)
+	switch network {
+	case "udp":
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		l.Addr = pc.LocalAddr().String()
+		l.closers = append(l.closers, pc)
+		go l.serveUDP(pc)
+	case "tcp", "tls":
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		l.Addr = ln.Addr().String()
+		if network == "tls" {
+			cert, pool, err := selfSigned()
+			if err != nil {
+				ln.Close()
+				return nil, err
+			}
+			l.RootCAs = pool
+			ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}})
+		}
+		l.closers = append(l.closers, ln)
+		go l.serveStream(ln)
+	default:
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+	return l, nil
+}
+
+// Close stops the listener and drops open connections
+func (l *Listener) Close() {
+	l.mu.Lock()
+	closers := l.closers
+	l.closers = nil
+	l.mu.Unlock()
+	for _, c := range closers {
+		c.Close()
+	}
+}
+
+// Messages returns the messages received so far, without framing
+func (l *Listener) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.messages...)
+}
+
+// Wait returns once n messages have arrived, or what arrived before the timeout
+func (l *Listener) Wait(n int, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	})
+	defer timer.Stop()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for len(l.messages) < n && time.Now().Before(deadline) {
+		l.cond.Wait()
+	}
+	return append([]string(nil), l.messages...)
+}
+
+func (l *Listener) add(msg string) {
+	l.mu.Lock()
+	l.messages = append(l.messages, msg)
+	l.cond.Broadcast()
+	l.mu.Unlock()
+}
+
+func (l *Listener) serveUDP(pc net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		l.add(string(buf[:n]))
+	}
+}
+
+func (l *Listener) serveStream(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		l.closers = append(l.closers, conn)
+		l.mu.Unlock()
+		go l.readFrames(conn)
+	}
+}
+
+// readFrames reads octet-counted frames ("LEN SP MSG", RFC 6587 3.4.1) and,
+// when a frame does not start with a digit, newline-terminated messages
+func (l *Listener) readFrames(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		first, err := r.Peek(1)
+		if err != nil {
+			return
+		}
+		if first[0] < '0' || first[0] > '9' {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			l.add(strings.TrimRight(line, "\r\n"))
+			continue
+		}
+		size, err := r.ReadString(' ')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n <= 0 {
+			return
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return
+		}
+		l.add(string(msg))
+	}
+}
+
+func selfSigned() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "siemtest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("self-signed certificate: %w", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
@@ -0,0 +1,432 @@
+package sqlserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// PoolOptions configura el PoolManager
+type PoolOptions struct {
+	MaxPools        int           // maximum live pools; least recently used is evicted beyond it (0 = unlimited)
+	IdleTTL         time.Duration // pools unused for longer than this are closed (0 = never)
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultPoolOptions mirrors the limits the adapter used before pools were managed
+func DefaultPoolOptions() PoolOptions {
+	return PoolOptions{
+		MaxPools:        50,
+		IdleTTL:         15 * time.Minute,
+		MaxOpenConns:    10,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: time.Hour,
+	}
+}
+
+// PoolKey identifies a pool by connection identity and TLS settings, so
+// connections that differ only in how the server certificate is checked never
+// share a pool. It carries no password, only a keyed hash of the credential
+// that String and the admin stats endpoint never expose, so a connection opened
+// with a different password never reuses a pool authenticated with another one.
+type PoolKey struct {
+	UserID   uint
+	Manager  string
+	Server   string
+	Port     string
+	DBUser   string
+	Database string
+
+	Encrypt                string
+	TrustServerCertificate bool
+	CAHash                 string // SHA-256 of the CA bundle, empty without one
+	CertFingerprint        string
+	HostNameInCertificate  string
+
+	credential string // HMAC of user and password under a per-process key
+}
+
+// credentialKey keys the credential hashes; it never leaves the process, so
+// a hash cannot be checked against guessed passwords elsewhere
+var credentialKey = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("sqlserver: cannot generate pool credential key: %v", err))
+	}
+	return b
+}()
+
+func credentialHash(user, password string) string {
+	mac := hmac.New(sha256.New, credentialKey)
+	mac.Write([]byte(user))
+	mac.Write([]byte{0})
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func poolKeyFromConfig(cfg services.SQLServerConfig) PoolKey {
+	k := PoolKey{
+		UserID:   cfg.UserID,
+		Manager:  cfg.Manager,
+		Server:   cfg.Server,
+		Port:     cfg.Port,
+		DBUser:   cfg.User,
+		Database: cfg.Database,
+
+		Encrypt:                cfg.Encrypt,
+		TrustServerCertificate: cfg.TrustServerCertificate,
+		CertFingerprint:        cfg.CertFingerprint,
+		HostNameInCertificate:  cfg.HostNameInCertificate,
+
+		credential: credentialHash(cfg.User, cfg.Password),
+	}
+	if cfg.CACertificate != "" {
+		sum := sha256.Sum256([]byte(cfg.CACertificate))
+		k.CAHash = hex.EncodeToString(sum[:])
+	}
+	return k
+}
+
+func (k PoolKey) String() string {
+	s := fmt.Sprintf("%d/%s/%s@%s:%s/%s?encrypt=%s&trust=%t", k.UserID, k.Manager, k.DBUser, k.Server, k.Port, k.Database, k.Encrypt, k.TrustServerCertificate)
+	if k.CAHash != "" {
+		s += "&ca=" + k.CAHash[:12]
+	}
+	if k.CertFingerprint != "" {
+		s += "&pin=" + k.CertFingerprint
+	}
+	if k.HostNameInCertificate != "" {
+		s += "&host=" + k.HostNameInCertificate
+	}
+	return s
+}
+
+type pooledDB struct {
+	db       *sql.DB
+	created  time.Time
+	lastUsed time.Time
+	// refs counts callers using the pool (Acquire); a retired pool is no longer
+	// handed out and is closed by its last user
+	refs    int
+	retired bool
+}
+
+// busy reports whether the pool is in use, through Acquire or by a caller
+// still holding connections of the *sql.DB
+func (p *pooledDB) busy() bool {
+	return p.refs > 0 || p.db.Stats().InUse > 0
+}
+
+// PoolManager keeps one *sql.DB per connection identity, isolating users from
+// each other, and closes pools that are idle, released or over the limit.
+type PoolManager struct {
+	logger *zap.Logger
+	opts   PoolOptions
+	now    func() time.Time
+
+	mu    sync.Mutex
+	pools map[PoolKey]*pooledDB
+	// byDB also holds retired pools that are still in use
+	byDB map[*sql.DB]*pooledDB
+}
+
+func NewPoolManager(logger *zap.Logger, opts PoolOptions) *PoolManager {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &PoolManager{
+		logger: logger,
+		opts:   opts,
+		now:    time.Now,
+		pools:  make(map[PoolKey]*pooledDB),
+		byDB:   make(map[*sql.DB]*pooledDB),
+	}
+}
+
+// Configure applies the per-pool limits to a freshly opened *sql.DB
+func (m *PoolManager) Configure(db *sql.DB) {
+	if m.opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(m.opts.MaxOpenConns)
+	}
+	if m.opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(m.opts.MaxIdleConns)
+	}
+	if m.opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(m.opts.ConnMaxLifetime)
+	}
+}
+
+// Get returns the pool for key and marks it as used
+func (m *PoolManager) Get(key PoolKey) (*sql.DB, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pools[key]
+	if !ok {
+		return nil, false
+	}
+	p.lastUsed = m.now()
+	return p.db, true
+}
+
+// Acquire marks db as in use until the returned func is called, which also
+// refreshes its last use. A pool retired meanwhile (released, replaced or
+// evicted) is closed by its last user instead of under a running query.
+func (m *PoolManager) Acquire(db *sql.DB) (release func()) {
+	m.mu.Lock()
+	p, ok := m.byDB[db]
+	if !ok {
+		m.mu.Unlock()
+		return func() {}
+	}
+	p.refs++
+	p.lastUsed = m.now()
+	m.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			p.refs--
+			p.lastUsed = m.now()
+			closeNow := p.retired && p.refs == 0
+			if closeNow {
+				delete(m.byDB, p.db)
+			}
+			m.mu.Unlock()
+			if closeNow {
+				_ = p.db.Close()
+			}
+		})
+	}
+}
+
+// Put stores db under key. An existing pool for the same key is retired, and the
+// least recently used idle pool is evicted when MaxPools would be exceeded; busy
+// pools are never evicted, so the limit can be exceeded while all are in use.
+func (m *PoolManager) Put(key PoolKey, db *sql.DB) {
+	m.mu.Lock()
+	var toClose []*sql.DB
+	if old, ok := m.pools[key]; ok && old.db != db {
+		toClose = appendDB(toClose, m.retireLocked(key, old))
+	}
+	if m.opts.MaxPools > 0 {
+		for len(m.pools) >= m.opts.MaxPools {
+			lruKey, lru := m.oldestIdleLocked()
+			if lru == nil {
+				m.logger.Warn("sql pool limit exceeded: every pool is in use", zap.Int("pools", len(m.pools)+1))
+				break
+			}
+			m.logger.Info("evicting least recently used sql pool", zap.String("pool", lruKey.String()))
+			toClose = appendDB(toClose, m.retireLocked(lruKey, lru))
+		}
+	}
+	now := m.now()
+	p := &pooledDB{db: db, created: now, lastUsed: now}
+	m.pools[key] = p
+	m.byDB[db] = p
+	m.mu.Unlock()
+
+	closeAll(toClose)
+}
+
+// Retire stops handing out db and closes it now when nobody uses it, or
+// through the last Acquire release otherwise. A db the manager does not know
+// is closed right away.
+func (m *PoolManager) Retire(db *sql.DB) {
+	m.mu.Lock()
+	p, ok := m.byDB[db]
+	if !ok {
+		m.mu.Unlock()
+		_ = db.Close()
+		return
+	}
+	// A pool missing from the live ones is already retired and waits for its last user
+	var toClose *sql.DB
+	for k, live := range m.pools {
+		if live == p {
+			toClose = m.retireLocked(k, p)
+			break
+		}
+	}
+	m.mu.Unlock()
+
+	if toClose != nil {
+		_ = toClose.Close()
+	}
+}
+
+// Release retires every pool owned by userID for manager and returns how many
+// were retired; idle ones are closed now, busy ones by their last user
+func (m *PoolManager) Release(userID uint, manager string) int {
+	m.mu.Lock()
+	var toClose []*sql.DB
+	n := 0
+	for k, p := range m.pools {
+		if k.UserID == userID && k.Manager == manager {
+			toClose = appendDB(toClose, m.retireLocked(k, p))
+			n++
+		}
+	}
+	m.mu.Unlock()
+
+	closeAll(toClose)
+	return n
+}
+
+// EvictIdle closes pools unused for longer than IdleTTL and returns how many
+// were closed. A pool with connections in use counts as used now.
+func (m *PoolManager) EvictIdle() int {
+	if m.opts.IdleTTL <= 0 {
+		return 0
+	}
+	now := m.now()
+	cutoff := now.Add(-m.opts.IdleTTL)
+
+	m.mu.Lock()
+	var toClose []*sql.DB
+	for k, p := range m.pools {
+		if p.busy() {
+			p.lastUsed = now
+			continue
+		}
+		if p.lastUsed.Before(cutoff) {
+			m.logger.Info("closing idle sql pool", zap.String("pool", k.String()))
+			toClose = appendDB(toClose, m.retireLocked(k, p))
+		}
+	}
+	m.mu.Unlock()
+
+	closeAll(toClose)
+	return len(toClose)
+}
+
+// Run evicts idle pools periodically until ctx is cancelled, then closes all pools
+func (m *PoolManager) Run(ctx context.Context) {
+	if m.opts.IdleTTL <= 0 {
+		return
+	}
+	interval := m.opts.IdleTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.CloseAll()
+			return
+		case <-ticker.C:
+			m.EvictIdle()
+		}
+	}
+}
+
+// CloseAll closes every pool
+func (m *PoolManager) CloseAll() {
+	m.mu.Lock()
+	toClose := make([]*sql.DB, 0, len(m.pools))
+	for k := range m.pools {
+		delete(m.pools, k)
+	}
+	for db := range m.byDB {
+		toClose = append(toClose, db)
+		delete(m.byDB, db)
+	}
+	m.mu.Unlock()
+
+	closeAll(toClose)
+}
+
+// Len returns the number of live pools
+func (m *PoolManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pools)
+}
+
+// Stats returns sql.DBStats for each live pool, ordered by identity
+func (m *PoolManager) Stats() []services.PoolStats {
+	m.mu.Lock()
+	out := make([]services.PoolStats, 0, len(m.pools))
+	for k, p := range m.pools {
+		out = append(out, services.PoolStats{
+			UserID:   k.UserID,
+			Manager:  k.Manager,
+			Server:   k.Server,
+			Port:     k.Port,
+			DBUser:   k.DBUser,
+			Database: k.Database,
+			Created:  p.created,
+			LastUsed: p.lastUsed,
+			Stats:    p.db.Stats(),
+		})
+	}
+	m.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].UserID != out[j].UserID {
+			return out[i].UserID < out[j].UserID
+		}
+		if out[i].Manager != out[j].Manager {
+			return out[i].Manager < out[j].Manager
+		}
+		return out[i].Database < out[j].Database
+	})
+	return out
+}
+
+// retireLocked takes p out of the live pools and returns its db when nobody
+// uses it, for the caller to close after unlocking; otherwise the last Acquire
+// release closes it
+func (m *PoolManager) retireLocked(k PoolKey, p *pooledDB) *sql.DB {
+	delete(m.pools, k)
+	p.retired = true
+	if p.refs > 0 {
+		return nil
+	}
+	delete(m.byDB, p.db)
+	return p.db
+}
+
+func (m *PoolManager) oldestIdleLocked() (PoolKey, *pooledDB) {
+	var (
+		oldestKey PoolKey
+		oldest    *pooledDB
+	)
+	for k, p := range m.pools {
+		if p.busy() {
+			continue
+		}
+		if oldest == nil || p.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = k, p
+		}
+	}
+	return oldestKey, oldest
+}
+
+func appendDB(dbs []*sql.DB, db *sql.DB) []*sql.DB {
+	if db != nil {
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+func closeAll(dbs []*sql.DB) {
+	for _, db := range dbs {
+		_ = db.Close()
+	}
+}
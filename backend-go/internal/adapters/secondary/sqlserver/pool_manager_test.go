@@ -0,0 +1,206 @@
+package sqlserver
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// openLazyDB returns a *sql.DB that never dials (sql.Open does not connect)
+func openLazyDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlserver", "sqlserver://sa:pw@127.0.0.1:1?database=master")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db
+}
+
+func isClosed(db *sql.DB) bool {
+	// Stats still works after Close, but Ping returns sql: database is closed
+	err := db.Ping()
+	return err != nil && strings.Contains(err.Error(), "database is closed")
+}
+
+func TestPoolKey_containsNoPassword(t *testing.T) {
+	key := poolKeyFromConfig(services.SQLServerConfig{
+		UserID: 1, Manager: "mssql", Server: "host", Port: "1433", User: "sa", Password: "S3cret!", Database: "master",
+	})
+	if strings.Contains(key.String(), "S3cret!") {
+		t.Fatalf("pool key must not contain the password: %s", key)
+	}
+}
+
+func TestPoolKey_separatesCredentials(t *testing.T) {
+	cfg := services.SQLServerConfig{
+		UserID: 1, Manager: "mssql", Server: "host", Port: "1433", User: "sa", Password: "S3cret!", Database: "master",
+	}
+	right := poolKeyFromConfig(cfg)
+	cfg.Password = "wrong"
+	wrong := poolKeyFromConfig(cfg)
+	if right == wrong {
+		t.Fatalf("a different password must not reuse the pool")
+	}
+	if right.String() != wrong.String() {
+		t.Fatalf("the credential must not show in the key: %s vs %s", right, wrong)
+	}
+
+	m := NewPoolManager(nil, PoolOptions{})
+	db := openLazyDB(t)
+	m.Put(right, db)
+	if _, ok := m.Get(wrong); ok {
+		t.Fatalf("a pool must only be handed out for the credential that opened it")
+	}
+}
+
+func TestPoolManager_retireWaitsForTheLastUser(t *testing.T) {
+	m := NewPoolManager(nil, PoolOptions{})
+	k := PoolKey{UserID: 1, Manager: "mssql", Database: "a"}
+	db := openLazyDB(t)
+	m.Put(k, db)
+	release := m.Acquire(db)
+
+	// /open failing after Connect retires the pool an audit is still using
+	m.Retire(db)
+	if isClosed(db) {
+		t.Fatalf("a pool in use must not be closed by Retire")
+	}
+	if _, ok := m.Get(k); ok {
+		t.Fatalf("a retired pool must not be handed out")
+	}
+	release()
+	if !isClosed(db) {
+		t.Fatalf("expected the last user to close the retired pool")
+	}
+
+	idle := openLazyDB(t)
+	m.Put(k, idle)
+	m.Retire(idle)
+	if !isClosed(idle) || m.Len() != 0 {
+		t.Fatalf("expected an idle pool to be closed by Retire")
+	}
+}
+
+func TestPoolManager_isolatesUsersAndReleases(t *testing.T) {
+	m := NewPoolManager(nil, PoolOptions{})
+	a := PoolKey{UserID: 1, Manager: "mssql", Server: "host", Port: "1433", DBUser: "sa", Database: "master"}
+	b := a
+	b.UserID = 2
+
+	dbA, dbB := openLazyDB(t), openLazyDB(t)
+	m.Put(a, dbA)
+	m.Put(b, dbB)
+
+	if got, ok := m.Get(a); !ok || got != dbA {
+		t.Fatalf("expected user 1 pool")
+	}
+	if got, ok := m.Get(b); !ok || got != dbB {
+		t.Fatalf("expected user 2 pool to be separate")
+	}
+
+	if n := m.Release(1, "mssql"); n != 1 {
+		t.Fatalf("expected 1 pool released, got %d", n)
+	}
+	if !isClosed(dbA) {
+		t.Fatalf("expected released pool to be closed")
+	}
+	if _, ok := m.Get(b); !ok || isClosed(dbB) {
+		t.Fatalf("other users' pools must survive release")
+	}
+}
+
+func TestPoolManager_evictsIdleAndLRU(t *testing.T) {
+	now := time.Now()
+	m := NewPoolManager(nil, PoolOptions{MaxPools: 2, IdleTTL: time.Minute})
+	m.now = func() time.Time { return now }
+
+	k1 := PoolKey{UserID: 1, Manager: "mssql", Database: "a"}
+	k2 := PoolKey{UserID: 1, Manager: "mssql", Database: "b"}
+	k3 := PoolKey{UserID: 1, Manager: "mssql", Database: "c"}
+	db1, db2, db3 := openLazyDB(t), openLazyDB(t), openLazyDB(t)
+
+	m.Put(k1, db1)
+	now = now.Add(time.Second)
+	m.Put(k2, db2)
+	now = now.Add(time.Second)
+	// touching k1 makes k2 the least recently used
+	m.Get(k1)
+	now = now.Add(time.Second)
+	m.Put(k3, db3)
+
+	if m.Len() != 2 {
+		t.Fatalf("expected max 2 pools, got %d", m.Len())
+	}
+	if !isClosed(db2) {
+		t.Fatalf("expected LRU pool to be evicted")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if n := m.EvictIdle(); n != 2 {
+		t.Fatalf("expected 2 idle pools evicted, got %d", n)
+	}
+	if len(m.Stats()) != 0 {
+		t.Fatalf("expected no live pools after idle eviction")
+	}
+}
+
+func TestPoolKey_separatesTLSSettings(t *testing.T) {
+	cfg := services.SQLServerConfig{UserID: 1, Manager: "mssql", Server: "host", Port: "1433", User: "sa", Database: "master", Encrypt: "true"}
+	trusting := cfg
+	trusting.TrustServerCertificate = true
+	withCA := cfg
+	withCA.CACertificate = "-----BEGIN CERTIFICATE-----"
+
+	keys := map[PoolKey]bool{poolKeyFromConfig(cfg): true, poolKeyFromConfig(trusting): true, poolKeyFromConfig(withCA): true}
+	if len(keys) != 3 {
+		t.Fatalf("expected connections with different TLS settings to use different pools")
+	}
+	if strings.Contains(poolKeyFromConfig(withCA).String(), "BEGIN CERTIFICATE") {
+		t.Fatalf("pool key must carry only a hash of the CA bundle")
+	}
+}
+
+func TestPoolManager_keepsPoolsInUseOpen(t *testing.T) {
+	now := time.Now()
+	m := NewPoolManager(nil, PoolOptions{MaxPools: 1, IdleTTL: time.Minute})
+	m.now = func() time.Time { return now }
+
+	k1 := PoolKey{UserID: 1, Manager: "mssql", Database: "a"}
+	k2 := PoolKey{UserID: 1, Manager: "mssql", Database: "b"}
+	db1, db2 := openLazyDB(t), openLazyDB(t)
+	m.Put(k1, db1)
+	release := m.Acquire(db1)
+
+	// a query running on db1 survives idle eviction, the pool limit and /close
+	now = now.Add(2 * time.Minute)
+	if n := m.EvictIdle(); n != 0 || isClosed(db1) {
+		t.Fatalf("a pool in use must not be evicted as idle")
+	}
+	m.Put(k2, db2)
+	if isClosed(db1) || m.Len() != 2 {
+		t.Fatalf("a pool in use must not be evicted over the limit, got %d pools", m.Len())
+	}
+	if n := m.Release(1, "mssql"); n != 2 || isClosed(db1) || !isClosed(db2) {
+		t.Fatalf("expected the idle pool closed now and the busy one retired, got %d", n)
+	}
+	if _, ok := m.Get(k1); ok {
+		t.Fatalf("a retired pool must not be handed out")
+	}
+	release()
+	release()
+	if !isClosed(db1) {
+		t.Fatalf("expected the last user to close the retired pool")
+	}
+
+	// releasing refreshes the last use, so a pool used until now is not idle
+	db3 := openLazyDB(t)
+	m.Put(k1, db3)
+	now = now.Add(50 * time.Second)
+	m.Acquire(db3)()
+	now = now.Add(50 * time.Second)
+	if n := m.EvictIdle(); n != 0 {
+		t.Fatalf("expected a recently used pool to stay open")
+	}
+}
@@ -1,15 +1,20 @@
 package sqlserver
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
 
-	_ "github.com/denisenkom/go-mssqldb"
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/denisenkom/go-mssqldb/msdsn"
 	"go.uber.org/zap"
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
@@ -18,37 +23,45 @@ import (
 // SQLServerAdapter implementa SQLServerService con pool de conexiones
 type SQLServerAdapter struct {
 	logger *zap.Logger
-	mu     sync.RWMutex
-	pools  map[string]*sql.DB // key: DSN
+	pools  *PoolManager
 }
 
 func NewSQLServerAdapter(logger *zap.Logger) *SQLServerAdapter {
+	return NewSQLServerAdapterWithOptions(logger, DefaultPoolOptions())
+}
+
+// NewSQLServerAdapterWithOptions crea el adaptador con límites de pool configurables
+func NewSQLServerAdapterWithOptions(logger *zap.Logger, opts PoolOptions) *SQLServerAdapter {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 	return &SQLServerAdapter{
 		logger: logger,
-		pools:  make(map[string]*sql.DB),
+		pools:  NewPoolManager(logger, opts),
 	}
 }
 
+// StartEviction runs idle-pool eviction in the background until ctx is cancelled
+func (a *SQLServerAdapter) StartEviction(ctx context.Context) {
+	go a.pools.Run(ctx)
+}
+
 func (a *SQLServerAdapter) Connect(ctx context.Context, cfg services.SQLServerConfig) (*sql.DB, error) {
-	dsn := buildDSN(cfg)
+	key := poolKeyFromConfig(cfg)
 
-	// Check if we already have a pool for this DSN
-	a.mu.RLock()
-	if db, exists := a.pools[dsn]; exists {
-		a.mu.RUnlock()
+	// Reuse the pool for this connection identity while it is alive; the key
+	// includes a hash of the credential, so a different password never matches
+	// a pool opened with another one and has to authenticate on its own
+	if db, exists := a.pools.Get(key); exists {
 		if err := a.ValidateConnection(ctx, db); err == nil {
 			return db, nil
 		}
-		// Connection is dead, remove it and create new one
-		a.mu.Lock()
-		delete(a.pools, dsn)
-		a.mu.Unlock()
-	} else {
-		a.mu.RUnlock()
+		// Connection is dead, retire it (its current users still finish) and create a new one
+		a.pools.Retire(db)
 	}
 
 	// Create new connection pool
-	db, err := sql.Open("sqlserver", dsn)
+	db, err := openDB(buildDSN(cfg), cfg)
 	if err != nil {
 		return nil, &services.ConnectionError{
 			Message: "failed to open connection",
@@ -57,9 +70,7 @@ func (a *SQLServerAdapter) Connect(ctx context.Context, cfg services.SQLServerCo
 	}
 
 	// Configure pool
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(time.Hour)
+	a.pools.Configure(db)
 
 	// Validate connection
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -73,15 +84,13 @@ func (a *SQLServerAdapter) Connect(ctx context.Context, cfg services.SQLServerCo
 		}
 	}
 
-	// Store in pool map
-	a.mu.Lock()
-	a.pools[dsn] = db
-	a.mu.Unlock()
+	a.pools.Put(key, db)
 
 	return db, nil
 }
 
 func (a *SQLServerAdapter) ExecuteQuery(ctx context.Context, db *sql.DB, query string) (bool, error) {
+	defer a.pools.Acquire(db)()
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -128,25 +137,30 @@ func parseBoolString(s string) (bool, error) {
 }
 
 func (a *SQLServerAdapter) ValidateConnection(ctx context.Context, db *sql.DB) error {
+	defer a.pools.Acquire(db)()
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	return db.PingContext(ctx)
 }
 
+// Close retires db; a pool still in use through Acquire is closed by its last user
 func (a *SQLServerAdapter) Close(db *sql.DB) error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	// Find and remove from pools
-	for dsn, pool := range a.pools {
-		if pool == db {
-			delete(a.pools, dsn)
-			break
-		}
+	a.pools.Retire(db)
+	return nil
+}
+
+// Release cierra los pools del usuario para el gestor indicado
+func (a *SQLServerAdapter) Release(userID uint, manager string) error {
+	if n := a.pools.Release(userID, manager); n > 0 {
+		a.logger.Info("released sql pools", zap.Uint("user_id", userID), zap.String("manager", manager), zap.Int("pools", n))
 	}
+	return nil
+}
 
-	return db.Close()
+// PoolStats devuelve estadísticas de cada pool vivo
+func (a *SQLServerAdapter) PoolStats() []services.PoolStats {
+	return a.pools.Stats()
 }
 
 // buildDSN construye la cadena de conexión para SQL Server
@@ -166,6 +180,17 @@ func buildDSN(cfg services.SQLServerConfig) string {
 		q.Set("database", cfg.Database)
 	}
 
+	if cfg.Encrypt != "" {
+		q.Set("encrypt", cfg.Encrypt)
+		q.Set("TrustServerCertificate", fmt.Sprintf("%t", cfg.TrustServerCertificate))
+	}
+	if cfg.HostNameInCertificate != "" {
+		q.Set("hostNameInCertificate", cfg.HostNameInCertificate)
+	}
+	if cfg.AppName != "" {
+		q.Set("app name", cfg.AppName)
+	}
+
 	// Add additional options and normalize booleans yes/no -> true/false
 	for k, v := range cfg.Options {
 		lower := strings.ToLower(v)
@@ -180,3 +205,60 @@ func buildDSN(cfg services.SQLServerConfig) string {
 	u.RawQuery = q.Encode()
 	return u.String()
 }
+
+// openDB creates the *sql.DB through a driver connector so TLS settings that
+// have no DSN equivalent (inline CA bundle, pinned fingerprint) can be applied.
+func openDB(dsn string, cfg services.SQLServerConfig) (*sql.DB, error) {
+	if err := cfg.ValidateTLS(); err != nil {
+		return nil, err
+	}
+	params, _, err := msdsn.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if params.TLSConfig != nil {
+		if err := applyTLSSettings(params.TLSConfig, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(mssql.NewConnectorConfig(params)), nil
+}
+
+// applyTLSSettings adds the CA bundle and certificate pinning to the driver tls.Config
+func applyTLSSettings(tc *tls.Config, cfg services.SQLServerConfig) error {
+	if cfg.CACertificate != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(cfg.CACertificate)) {
+			return fmt.Errorf("invalid CA certificate bundle")
+		}
+		tc.RootCAs = pool
+	}
+
+	if cfg.CertFingerprint != "" {
+		want, err := normalizeFingerprint(cfg.CertFingerprint)
+		if err != nil {
+			return err
+		}
+		tc.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("server presented no certificate")
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], want) {
+				return fmt.Errorf("server certificate fingerprint mismatch")
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// normalizeFingerprint accepts hex SHA-256 fingerprints with or without ':' separators
+func normalizeFingerprint(fp string) ([]byte, error) {
+	clean := strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fp))
+	raw, err := hex.DecodeString(clean)
+	if err != nil || len(raw) != sha256.Size {
+		return nil, fmt.Errorf("invalid certificate fingerprint: expected SHA-256 hex")
+	}
+	return raw, nil
+}
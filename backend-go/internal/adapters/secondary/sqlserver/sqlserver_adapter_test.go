@@ -1,7 +1,16 @@
 package sqlserver
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/denisenkom/go-mssqldb/msdsn"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
 )
 
 func TestConvertResultToBool_variousTypes(t *testing.T) {
@@ -40,3 +49,67 @@ func TestConvertResultToBool_variousTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildDSN_includesTLSSettings(t *testing.T) {
+	dsn := buildDSN(services.SQLServerConfig{
+		Server:                 "db.internal",
+		Port:                   "14330",
+		User:                   "sa",
+		Password:               "p@ss",
+		Database:               "master",
+		Encrypt:                "true",
+		TrustServerCertificate: false,
+		HostNameInCertificate:  "sql.example.com",
+		AppName:                "MicroSQL-AGo",
+	})
+
+	params, _, err := msdsn.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse dsn: %v", err)
+	}
+	if params.Port != 14330 {
+		t.Fatalf("expected port 14330, got %d", params.Port)
+	}
+	if params.Encryption != msdsn.EncryptionRequired {
+		t.Fatalf("expected encryption required")
+	}
+	if params.TLSConfig == nil || params.TLSConfig.InsecureSkipVerify {
+		t.Fatalf("expected certificate validation to be enabled")
+	}
+	if params.TLSConfig.ServerName != "sql.example.com" {
+		t.Fatalf("expected host name in certificate, got %q", params.TLSConfig.ServerName)
+	}
+	if params.AppName != "MicroSQL-AGo" {
+		t.Fatalf("expected app name, got %q", params.AppName)
+	}
+}
+
+func TestApplyTLSSettings_pinnedFingerprint(t *testing.T) {
+	leaf := []byte("fake-der-certificate")
+	sum := sha256.Sum256(leaf)
+
+	tc := &tls.Config{}
+	if err := applyTLSSettings(tc, services.SQLServerConfig{CertFingerprint: strings.ToUpper(hex.EncodeToString(sum[:]))}); err != nil {
+		t.Fatalf("apply tls: %v", err)
+	}
+	if err := tc.VerifyPeerCertificate([][]byte{leaf}, nil); err != nil {
+		t.Fatalf("expected matching fingerprint to pass: %v", err)
+	}
+	if err := tc.VerifyPeerCertificate([][]byte{[]byte("other")}, nil); err == nil {
+		t.Fatalf("expected mismatching fingerprint to fail")
+	}
+
+	if err := applyTLSSettings(&tls.Config{}, services.SQLServerConfig{CertFingerprint: "zz"}); err == nil {
+		t.Fatalf("expected invalid fingerprint to be rejected")
+	}
+	if err := applyTLSSettings(&tls.Config{}, services.SQLServerConfig{CACertificate: "not a pem"}); err == nil {
+		t.Fatalf("expected invalid CA bundle to be rejected")
+	}
+}
+
+func TestOpenDB_rejectsCABundleWithoutValidation(t *testing.T) {
+	cfg := services.SQLServerConfig{Server: "host", Port: "1433", Encrypt: "true", TrustServerCertificate: true, CACertificate: "-----BEGIN CERTIFICATE-----"}
+	if _, err := openDB(buildDSN(cfg), cfg); !errors.Is(err, services.ErrCAWithoutValidation) {
+		t.Fatalf("expected a CA bundle without validation to be rejected, got %v", err)
+	}
+}
@@ -2,8 +2,14 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultJWTSecret is used when JWT_SECRET is not set (development only)
+const DefaultJWTSecret = "change-me-in-production"
+
 // Config holds basic configuration for the application
 type Config struct {
 	ServerPort string
@@ -11,6 +17,101 @@ type Config struct {
 	JWTSecret  string
 	EncKey     string
 	LogLevel   string
+	// Keyring for credential encryption: EncKeys is "id:key,id:key", EncKeyID the current id.
+	// When EncKeys is empty the ring only holds EncKey (id "default").
+	EncKeys  string
+	EncKeyID string
+	// Access tokens are short-lived; refresh tokens rotate on every use
+	JWTAccessTTL  time.Duration
+	JWTRefreshTTL time.Duration
+	// Token signing: JWTAlg is HS256 (JWTSecret) or RS256/EdDSA with PEM keys
+	// from JWTKeys ("kid:path,kid:path"); JWTKeyID signs, the rest verify for JWTKeyOverlap
+	// from the first start they were rotated out (JWT_ALG is case-insensitive)
+	JWTAlg        string
+	JWTKeys       string
+	JWTKeyID      string
+	JWTKeyOverlap time.Duration
+	// How long the auth middleware trusts a cached session state
+	SessionCacheTTL time.Duration
+	// Concurrent session policy "mode[:N]" (reject | replace_oldest) and per-role overrides
+	SessionPolicy       string
+	SessionRolePolicies string
+	// How long a session still counts against the policy after its access token expired unrefreshed
+	SessionIdleTimeout time.Duration
+	// Brute-force protection on login (0 failures disables the lockout)
+	LoginMaxUserFailures int
+	LoginMaxIPFailures   int
+	LoginFailureWindow   time.Duration
+	LoginLockoutDuration time.Duration
+	LoginDelayBase       time.Duration
+	LoginDelayMax        time.Duration
+	// Two-factor authentication: roles that must use TOTP (comma separated)
+	MFARequiredRoles string
+	MFAIssuer        string
+	MFAChallengeTTL  time.Duration
+	// Personal API keys: expiry when none is requested, and the longest allowed
+	APIKeyDefaultTTL time.Duration
+	APIKeyMaxTTL     time.Duration
+	// OpenID Connect single sign-on (disabled when OIDCIssuer is empty)
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       string
+	OIDCGroupsClaim  string
+	OIDCRoleMapping  string // group=role,... first match wins
+	OIDCDefaultRole  string // role when no group matches; empty rejects the login
+	OIDCLinkByEmail  bool
+	// LDAP / Active Directory login (disabled when LDAPURL is empty)
+	LDAPURL               string
+	LDAPStartTLS          bool
+	LDAPCAFile            string
+	LDAPAllowInsecure     bool
+	LDAPBindDN            string
+	LDAPBindPassword      string
+	LDAPBaseDN            string
+	LDAPUserFilter        string // %s is the escaped username
+	LDAPUsernameAttribute string
+	LDAPEmailAttribute    string
+	LDAPNameAttribute     string
+	LDAPIDAttribute       string // stable id (entryUUID, objectGUID); empty uses the DN
+	LDAPGroupAttribute    string
+	LDAPGroupFilter       string // %s is the escaped user DN
+	LDAPGroupBaseDN       string
+	LDAPRoleMapping       string // group CN=role,... first match is the main role
+	LDAPDefaultRole       string
+	LDAPLinkByEmail       bool
+	LDAPTimeout           time.Duration
+	// Just-in-time role requests: longest elevation, roles that can be requested
+	// (comma separated, empty = any) and how often expired grants are removed
+	JITMaxDuration         time.Duration
+	JITRequestableRoles    string
+	RoleGrantSweepInterval time.Duration
+	// RBAC policy (YAML/JSON) seeded at startup; empty uses the embedded default
+	RBACPolicyFile string
+	// Evidence hash chains: Ed25519 PEM key that signs checkpoints (empty derives
+	// one from JWTSecret unless it is the default), its id, the public keys of
+	// previous keys ("kid:path,kid:path") and how often checkpoints are signed
+	EvidenceSigningKey         string
+	EvidenceSigningKeyID       string
+	EvidenceTrustedKeys        string
+	EvidenceCheckpointInterval time.Duration
+	// API activity log: how long events are kept (0 = forever), how often old
+	// ones are purged, and whether successful reads (GET) are recorded too
+	ActivityRetention     time.Duration
+	ActivityPurgeInterval time.Duration
+	ActivityLogReads      bool
+	// SIEM export of activity events over syslog (disabled when SIEMAddress is empty)
+	SIEMAddress      string
+	SIEMNetwork      string // udp, tcp or tls
+	SIEMFormat       string // rfc5424 or cef
+	SIEMFacility     string // syslog facility name or number
+	SIEMAppName      string
+	SIEMCategories   string // comma-separated activity categories; "all" exports every event
+	SIEMBufferSize   int
+	SIEMMaxRetries   int
+	SIEMRetryBackoff time.Duration
+	SIEMTLSCAFile    string
 	// MSSQL settings
 	MssqlHost string
 	MssqlPort string
@@ -23,6 +124,23 @@ type Config struct {
 	MysqlUser string
 	MysqlPass string
 	MysqlDB   string
+	// SQL Server pool manager settings
+	SQLPoolMaxPools        int
+	SQLPoolIdleTTL         time.Duration
+	SQLPoolMaxOpenConns    int
+	SQLPoolMaxIdleConns    int
+	SQLPoolConnMaxLifetime time.Duration
+	// Active connection monitor
+	ConnHealthInterval time.Duration
+	ConnIdleTimeout    time.Duration
+	// Credential storage for active connections (db | file | vault)
+	SecretBackend   string
+	SecretFilePath  string
+	SecretFileKey   string
+	VaultAddr       string
+	VaultToken      string
+	VaultMount      string
+	VaultPathPrefix string
 }
 
 // LoadConfig loads configuration from environment variables with sensible defaults
@@ -37,7 +155,7 @@ func LoadConfig() Config {
 	}
 	jwt := os.Getenv("JWT_SECRET")
 	if jwt == "" {
-		jwt = "change-me-in-production"
+		jwt = DefaultJWTSecret
 	}
 	enc := os.Getenv("ENCRYPTION_KEY")
 	if enc == "" {
@@ -54,6 +172,8 @@ func LoadConfig() Config {
 		JWTSecret:  jwt,
 		EncKey:     enc,
 		LogLevel:   loglevel,
+		EncKeys:    os.Getenv("ENCRYPTION_KEYS"),
+		EncKeyID:   os.Getenv("ENCRYPTION_KEY_ID"),
 		MssqlHost:  os.Getenv("MSSQL_HOST"),
 		MssqlPort:  os.Getenv("MSSQL_PORT"),
 		MssqlUser:  os.Getenv("MSSQL_USER"),
@@ -64,7 +184,146 @@ func LoadConfig() Config {
 		MysqlUser:  os.Getenv("MYSQL_USER"),
 		MysqlPass:  os.Getenv("MYSQL_PASSWORD"),
 		MysqlDB:    os.Getenv("MYSQL_DATABASE"),
+
+		SQLPoolMaxPools:        getEnvInt("SQL_POOL_MAX_POOLS", 50),
+		SQLPoolIdleTTL:         getEnvDuration("SQL_POOL_IDLE_TTL", 15*time.Minute),
+		SQLPoolMaxOpenConns:    getEnvInt("SQL_POOL_MAX_OPEN_CONNS", 10),
+		SQLPoolMaxIdleConns:    getEnvInt("SQL_POOL_MAX_IDLE_CONNS", 5),
+		SQLPoolConnMaxLifetime: getEnvDuration("SQL_POOL_CONN_MAX_LIFETIME", time.Hour),
+
+		ConnHealthInterval: getEnvDuration("CONN_HEALTH_INTERVAL", time.Minute),
+		ConnIdleTimeout:    getEnvDuration("CONN_IDLE_TIMEOUT", 30*time.Minute),
+
+		// JWT_ACCESS_TTL / JWT_REFRESH_TTL are Go durations (e.g. 15m, 168h)
+		JWTAccessTTL:  getEnvDuration("JWT_ACCESS_TTL", 15*time.Minute),
+		JWTRefreshTTL: getEnvDuration("JWT_REFRESH_TTL", 7*24*time.Hour),
+		JWTAlg:        getEnv("JWT_ALG", "HS256"),
+		JWTKeys:       os.Getenv("JWT_KEYS"),
+		JWTKeyID:      os.Getenv("JWT_KEY_ID"),
+		JWTKeyOverlap: getEnvDuration("JWT_KEY_OVERLAP", 24*time.Hour),
+		// SESSION_CACHE_TTL bounds revocation delay across instances (local revocations are immediate)
+		SessionCacheTTL: getEnvDuration("SESSION_CACHE_TTL", 30*time.Second),
+		// SESSION_POLICY=reject:1 is the strict single-session login
+		SessionPolicy:       getEnv("SESSION_POLICY", "replace_oldest:3"),
+		SessionRolePolicies: os.Getenv("SESSION_ROLE_POLICIES"),
+		SessionIdleTimeout:  getEnvDuration("SESSION_IDLE_TIMEOUT", 30*time.Minute),
+
+		LoginMaxUserFailures: getEnvInt("LOGIN_MAX_USER_FAILURES", 5),
+		LoginMaxIPFailures:   getEnvInt("LOGIN_MAX_IP_FAILURES", 20),
+		LoginFailureWindow:   getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutDuration: getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginDelayBase:       getEnvDuration("LOGIN_DELAY_BASE", 250*time.Millisecond),
+		LoginDelayMax:        getEnvDuration("LOGIN_DELAY_MAX", 5*time.Second),
+
+		// MFA_REQUIRED_ROLES= (empty) makes 2FA optional for everyone
+		MFARequiredRoles: unlessNone(getEnv("MFA_REQUIRED_ROLES", "admin")),
+		MFAIssuer:        getEnv("MFA_ISSUER", "MicroSQL AGo"),
+		MFAChallengeTTL:  getEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+
+		APIKeyDefaultTTL: getEnvDuration("API_KEY_DEFAULT_TTL", 90*24*time.Hour),
+		APIKeyMaxTTL:     getEnvDuration("API_KEY_MAX_TTL", 365*24*time.Hour),
+
+		OIDCIssuer:       os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:       getEnv("OIDC_SCOPES", "openid profile email"),
+		OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:  os.Getenv("OIDC_ROLE_MAPPING"),
+		// OIDC_DEFAULT_ROLE= (empty) only lets in users whose groups are mapped
+		OIDCDefaultRole: unlessNone(getEnv("OIDC_DEFAULT_ROLE", "user")),
+		OIDCLinkByEmail: getEnv("OIDC_LINK_BY_EMAIL", "false") == "true",
+
+		LDAPURL:               os.Getenv("LDAP_URL"),
+		LDAPStartTLS:          getEnv("LDAP_START_TLS", "false") == "true",
+		LDAPCAFile:            os.Getenv("LDAP_CA_FILE"),
+		LDAPAllowInsecure:     getEnv("LDAP_ALLOW_INSECURE", "false") == "true",
+		LDAPBindDN:            os.Getenv("LDAP_BIND_DN"),
+		LDAPBindPassword:      os.Getenv("LDAP_BIND_PASSWORD"),
+		LDAPBaseDN:            os.Getenv("LDAP_BASE_DN"),
+		LDAPUserFilter:        getEnv("LDAP_USER_FILTER", "(uid=%s)"),
+		LDAPUsernameAttribute: getEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+		LDAPEmailAttribute:    getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		LDAPNameAttribute:     getEnv("LDAP_NAME_ATTRIBUTE", "displayName"),
+		LDAPIDAttribute:       os.Getenv("LDAP_ID_ATTRIBUTE"),
+		LDAPGroupAttribute:    unlessNone(getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf")),
+		LDAPGroupFilter:       os.Getenv("LDAP_GROUP_FILTER"),
+		LDAPGroupBaseDN:       os.Getenv("LDAP_GROUP_BASE_DN"),
+		LDAPRoleMapping:       os.Getenv("LDAP_ROLE_MAPPING"),
+		// LDAP_DEFAULT_ROLE= (empty) only lets in users whose groups are mapped
+		LDAPDefaultRole: unlessNone(getEnv("LDAP_DEFAULT_ROLE", "user")),
+		LDAPLinkByEmail: getEnv("LDAP_LINK_BY_EMAIL", "false") == "true",
+		LDAPTimeout:     getEnvDuration("LDAP_TIMEOUT", 10*time.Second),
+
+		JITMaxDuration:         getEnvDuration("JIT_MAX_DURATION", 8*time.Hour),
+		JITRequestableRoles:    os.Getenv("JIT_REQUESTABLE_ROLES"),
+		RoleGrantSweepInterval: getEnvDuration("ROLE_GRANT_SWEEP_INTERVAL", time.Minute),
+		RBACPolicyFile:         os.Getenv("RBAC_POLICY_FILE"),
+
+		EvidenceSigningKey:         os.Getenv("EVIDENCE_SIGNING_KEY"),
+		EvidenceSigningKeyID:       os.Getenv("EVIDENCE_SIGNING_KEY_ID"),
+		EvidenceTrustedKeys:        os.Getenv("EVIDENCE_TRUSTED_KEYS"),
+		EvidenceCheckpointInterval: getEnvDuration("EVIDENCE_CHECKPOINT_INTERVAL", time.Hour),
+
+		ActivityRetention:     getEnvDuration("ACTIVITY_RETENTION", 90*24*time.Hour),
+		ActivityPurgeInterval: getEnvDuration("ACTIVITY_PURGE_INTERVAL", time.Hour),
+		ActivityLogReads:      getEnv("ACTIVITY_LOG_READS", "false") == "true",
+		SIEMAddress:           os.Getenv("SIEM_ADDRESS"),
+		SIEMNetwork:           getEnv("SIEM_NETWORK", "udp"),
+		SIEMFormat:            getEnv("SIEM_FORMAT", "rfc5424"),
+		SIEMFacility:          getEnv("SIEM_FACILITY", "authpriv"),
+		SIEMAppName:           getEnv("SIEM_APP_NAME", "microsql-ago"),
+		SIEMCategories:        getEnv("SIEM_CATEGORIES", "auth,admin,connection,audit,report"),
+		SIEMBufferSize:        getEnvInt("SIEM_BUFFER_SIZE", 1000),
+		SIEMMaxRetries:        getEnvInt("SIEM_MAX_RETRIES", 3),
+		SIEMRetryBackoff:      getEnvDuration("SIEM_RETRY_BACKOFF", time.Second),
+		SIEMTLSCAFile:         os.Getenv("SIEM_TLS_CA_FILE"),
+
+		SecretBackend:   getEnv("SECRET_BACKEND", "db"),
+		SecretFilePath:  getEnv("SECRET_FILE_PATH", "./secrets.keystore"),
+		SecretFileKey:   os.Getenv("SECRET_FILE_KEY"),
+		VaultAddr:       os.Getenv("VAULT_ADDR"),
+		VaultToken:      os.Getenv("VAULT_TOKEN"),
+		VaultMount:      getEnv("VAULT_KV_MOUNT", "secret"),
+		VaultPathPrefix: getEnv("VAULT_PATH_PREFIX", "microsql"),
+	}
+}
+
+// getEnv reads a string env var, falling back to def when unset
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// unlessNone turns the value "none" into empty, for settings whose default is
+// on and that can be switched off (an empty env var falls back to the default)
+func unlessNone(v string) string {
+	if strings.EqualFold(strings.TrimSpace(v), "none") {
+		return ""
+	}
+	return v
+}
+
+// getEnvInt reads an integer env var, falling back to def when unset or invalid
+func getEnvInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// getEnvDuration reads a Go duration (e.g. "15m") env var, falling back to def when unset or invalid
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
 	}
+	return def
 }
 
 // NewGormDB simplified helper - placed here for quick access
@@ -0,0 +1,20 @@
+package config
+
+import (
+	_ "embed"
+	"os"
+)
+
+// DefaultRBACPolicy is the policy seeded at startup when RBAC_POLICY_FILE is empty
+//
+//go:embed rbac_policy.yaml
+var DefaultRBACPolicy []byte
+
+// LoadRBACPolicy reads the policy document at path (YAML or JSON), or returns
+// the embedded default policy when path is empty
+func LoadRBACPolicy(path string) ([]byte, error) {
+	if path == "" {
+		return DefaultRBACPolicy, nil
+	}
+	return os.ReadFile(path)
+}
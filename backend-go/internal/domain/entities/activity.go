@@ -0,0 +1,77 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Activity event actions: what happened, independently of the HTTP route
+const (
+	ActivityLogin           = "auth.login"
+	ActivityLogout          = "auth.logout"
+	ActivityLockout         = "auth.lockout"
+	ActivityUnlock          = "auth.unlock"
+	ActivityConnectionOpen  = "connection.open"
+	ActivityConnectionClose = "connection.close"
+	ActivityAuditExecute    = "audit.execute"
+	ActivityReportDownload  = "report.download"
+	// ActivityAPIRequest is recorded by the middleware for requests no hook described
+	ActivityAPIRequest = "api.request"
+	// admin actions are recorded as "admin." + the RBAC log action (admin.role.create, ...)
+	ActivityAdminPrefix = "admin."
+)
+
+// Activity event outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied" // rejected by authentication, authorization or a lockout
+)
+
+// Activity actor types
+const (
+	ActorUser      = "user"
+	ActorAPIKey    = "api_key"
+	ActorAnonymous = "anonymous"
+	ActorSystem    = "system"
+)
+
+// ActivityEvent is one entry of the API activity log: who did what, from where,
+// on which resource and with which outcome. Request fields are empty for events
+// raised outside a request (background jobs).
+type ActivityEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	OccurredAt time.Time `gorm:"not null;index" json:"occurred_at"`
+	Action     string    `gorm:"size:100;not null;index" json:"action"`
+	Category   string    `gorm:"size:50;not null;index" json:"category"` // prefix of Action: auth, connection, audit, report, api, admin
+	Outcome    string    `gorm:"size:20;not null;index" json:"outcome"`
+
+	ActorType string `gorm:"size:20;not null" json:"actor_type"`
+	ActorID   *uint  `gorm:"index" json:"actor_id,omitempty"`
+	ActorName string `gorm:"size:150;index" json:"actor_name,omitempty"` // as typed on failed logins
+	APIKeyID  *uint  `json:"api_key_id,omitempty"`
+
+	IPAddress string `gorm:"size:64;index" json:"ip_address,omitempty"`
+	UserAgent string `gorm:"size:255" json:"user_agent,omitempty"`
+	RequestID string `gorm:"size:64;index" json:"request_id,omitempty"`
+	Method    string `gorm:"size:10" json:"method,omitempty"`
+	Path      string `gorm:"size:255" json:"path,omitempty"`
+	Status    int    `json:"status,omitempty"`
+
+	ResourceType string `gorm:"size:50;index" json:"resource_type,omitempty"` // e.g. connection, audit_run, connection_history, role
+	ResourceID   string `gorm:"size:100;index" json:"resource_id,omitempty"`
+	// Details is a JSON object with action-specific fields (server, database, error, ...)
+	Details string `gorm:"type:text" json:"details,omitempty"`
+}
+
+// ActivityDetails encodes the action-specific fields of an event as its Details
+func ActivityDetails(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
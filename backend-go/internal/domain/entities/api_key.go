@@ -0,0 +1,35 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey is a personal credential for automation (CI jobs) sent as
+// "Authorization: ApiKey <key>". Only the SHA-256 of the key is stored; Prefix
+// lets the owner tell keys apart. Scopes restrict the key to a subset of the
+// owner's permissions.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"type:text" json:"-"` // comma-separated permission names
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"size:64" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ScopeList returns the permission names the key was restricted to
+func (k *APIKey) ScopeList() []string {
+	var out []string
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
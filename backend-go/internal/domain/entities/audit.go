@@ -1,12 +1,17 @@
 package entities
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
-// AuditRun represents a single audit execution (batch of control scripts)
+// AuditRun represents a single audit execution (batch of control scripts). It
+// joins the evidence hash chain once it is finished.
 type AuditRun struct {
 	ID         uint       `gorm:"primaryKey" json:"id"`
 	UserID     uint       `gorm:"not null;index" json:"user_id"`
 	Mode       string     `gorm:"size:20;not null;default:'partial'" json:"mode"` // partial|full
+	Server     string     `gorm:"size:255;index" json:"server"`
 	Database   string     `gorm:"size:255" json:"database"`
 	Total      int        `json:"total"`
 	Passed     int        `json:"passed"`
@@ -15,6 +20,17 @@ type AuditRun struct {
 	Controls   string     `gorm:"type:text" json:"controls"` // JSON array of control IDs (optional)
 	StartedAt  time.Time  `gorm:"autoCreateTime" json:"started_at"`
 	FinishedAt *time.Time `gorm:"column:finished_at" json:"finished_at"`
+	ChainLink  `gorm:"embedded"`
+}
+
+// ChainContent implements ChainedRecord
+func (r *AuditRun) ChainContent() []byte {
+	var finished interface{}
+	if r.FinishedAt != nil {
+		finished = r.FinishedAt.Unix()
+	}
+	b, _ := json.Marshal([]interface{}{r.ID, r.UserID, r.Mode, r.Server, r.Database, r.Total, r.Passed, r.Failed, r.Status, r.Controls, r.StartedAt.Unix(), finished})
+	return b
 }
 
 // AuditScriptResult represents result of executing one control script inside an audit run.
@@ -29,4 +45,11 @@ type AuditScriptResult struct {
 	DurationMs int64     `json:"duration_ms"`
 	Rows       int64     `json:"rows"`
 	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ChainLink  `gorm:"embedded"`
+}
+
+// ChainContent implements ChainedRecord
+func (r *AuditScriptResult) ChainContent() []byte {
+	b, _ := json.Marshal([]interface{}{r.ID, r.AuditRunID, r.ScriptID, r.ControlID, r.QuerySQL, r.Passed, r.Error, r.DurationMs, r.Rows, r.CreatedAt.Unix()})
+	return b
 }
@@ -0,0 +1,36 @@
+package entities
+
+import "time"
+
+// Auth audit events
+const (
+	AuthEventLoginSuccess  = "login.success"
+	AuthEventLoginFailure  = "login.failure"
+	AuthEventLoginBlocked  = "login.blocked" // attempt while the account or IP was locked
+	AuthEventAccountLocked = "account.locked"
+	AuthEventIPLocked      = "ip.locked"
+	AuthEventUnlocked      = "account.unlocked"
+)
+
+// AuthAuditLog is an append-only record of authentication events
+type AuthAuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Event     string    `gorm:"size:50;not null;index" json:"event"`
+	Username  string    `gorm:"size:150;index" json:"username,omitempty"` // as typed, even if no such user exists
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty"`
+	IPAddress string    `gorm:"size:64;index" json:"ip_address,omitempty"`
+	UserAgent string    `gorm:"size:255" json:"user_agent,omitempty"`
+	Details   string    `gorm:"type:text" json:"details,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
+
+// LoginThrottle counts recent failed logins for one subject ("user:<name>" or "ip:<addr>")
+type LoginThrottle struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Subject       string     `gorm:"size:200;not null;uniqueIndex" json:"subject"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	// RetryAt is the progressive delay: attempts before it are rejected without checking the password
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+}
@@ -11,5 +11,14 @@ type ConnectionLog struct {
 	Server    string    `gorm:"not null" json:"server"`
 	DBUser    string    `gorm:"column:db_user;not null" json:"db_user"`
 	Timestamp time.Time `gorm:"autoCreateTime;index" json:"timestamp"`
-	Status    string    `gorm:"not null;index" json:"status"` // connected, disconnected, reconnected
+	Status    string    `gorm:"not null;index" json:"status"` // connected, disconnected, reconnected, failed, expired
 }
+
+// ConnectionLog statuses
+const (
+	ConnectionStatusConnected    = "connected"
+	ConnectionStatusDisconnected = "disconnected"
+	ConnectionStatusReconnected  = "reconnected"
+	ConnectionStatusFailed       = "failed"
+	ConnectionStatusExpired      = "expired"
+)
@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Cadenas de evidencia: cada tabla de auditoría es una cadena de hashes propia
+const (
+	ChainAdminActions = "admin_action_logs"
+	ChainAuditRuns    = "audit_runs"
+	ChainAuditResults = "audit_script_results"
+)
+
+// EvidenceChains son todas las cadenas, en el orden en que se verifican
+var EvidenceChains = []string{ChainAdminActions, ChainAuditRuns, ChainAuditResults}
+
+// ChainLink es el eslabón de un registro: su posición en la cadena, el hash del
+// anterior y el hash de ambos con su propio contenido. ChainSeq 0 = aún no
+// encadenado (registros anteriores a la cadena o auditorías en curso).
+type ChainLink struct {
+	ChainSeq uint64 `gorm:"index" json:"chain_seq,omitempty"`
+	PrevHash string `gorm:"size:64" json:"prev_hash,omitempty"`
+	Hash     string `gorm:"size:64" json:"hash,omitempty"`
+}
+
+// Link da acceso al eslabón desde el registro que lo embebe
+func (l *ChainLink) Link() *ChainLink { return l }
+
+// ChainedRecord es un registro que forma parte de una cadena de evidencia
+type ChainedRecord interface {
+	Link() *ChainLink
+	// ChainContent serializa de forma estable los campos protegidos por el hash
+	ChainContent() []byte
+}
+
+// ChainHash calcula el hash de un eslabón
+func ChainHash(chain string, seq uint64, prevHash string, content []byte) string {
+	h := sha256.New()
+	h.Write([]byte(chain + "\n" + strconv.FormatUint(seq, 10) + "\n" + prevHash + "\n"))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainCheckpoint es una firma periódica del último eslabón de una cadena: si
+// se borran registros del final, el checkpoint deja de encajar
+type ChainCheckpoint struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Chain     string    `gorm:"size:50;not null;index" json:"chain"`
+	Seq       uint64    `gorm:"not null" json:"seq"`
+	Hash      string    `gorm:"size:64;not null" json:"hash"`
+	KeyID     string    `gorm:"size:100" json:"key_id"`
+	Signature string    `gorm:"size:255" json:"signature"` // base64
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignedPayload es lo que firma el checkpoint
+func (c *ChainCheckpoint) SignedPayload() []byte {
+	return []byte(fmt.Sprintf("%s\n%d\n%s\n%d", c.Chain, c.Seq, c.Hash, c.CreatedAt.Unix()))
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// JWTKeyRetirement records when a rotated-out JWT signing key stops verifying
+// tokens, so a restart does not start its overlap period again
+type JWTKeyRetirement struct {
+	KeyID     string    `gorm:"primaryKey;size:100" json:"kid"`
+	RetireAt  time.Time `gorm:"not null" json:"retire_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (JWTKeyRetirement) TableName() string { return "jwt_key_retirements" }
@@ -0,0 +1,44 @@
+package entities
+
+import "time"
+
+// UserTOTP holds a user's TOTP secret. The secret is encrypted with the
+// credential keyring; it stays disabled until the first code is confirmed.
+type UserTOTP struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Secret       string     `gorm:"size:500;not null" json:"-"`
+	Enabled      bool       `gorm:"default:false" json:"enabled"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `gorm:"default:0" json:"-"` // last accepted time step; codes cannot be replayed
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// RecoveryCode is a single-use 2FA fallback code; only its SHA-256 is stored
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null;index" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Login challenge purposes
+const (
+	ChallengeVerify = "verify" // user has 2FA: submit a code to finish the login
+	ChallengeEnroll = "enroll" // role requires 2FA: enroll before the login completes
+)
+
+// LoginChallenge is issued by Login when a second factor is needed; the client
+// exchanges it (once) for the access/refresh tokens
+type LoginChallenge struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Purpose   string     `gorm:"size:20;not null" json:"purpose"`
+	Device    string     `gorm:"size:100" json:"device,omitempty"`
+	Attempts  int        `gorm:"default:0" json:"attempts"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
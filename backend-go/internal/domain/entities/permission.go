@@ -13,3 +13,21 @@ type Permission struct {
 func (p *Permission) PermissionKey() string {
 	return p.Resource + ":" + p.Action
 }
+
+// Permisos que declaran las rutas de la API (ver config/rbac_policy.yaml)
+const (
+	PermUsersRead          = "users:read"
+	PermUsersUpdate        = "users:update"
+	PermConnectionsManage  = "connections:manage"
+	PermConnectionsViewAll = "connections:view_all"
+	PermAuditsExecute      = "audits:execute"
+	PermAuditsView         = "audits:view"
+	PermRolesManage        = "roles:manage"
+	PermPermissionsManage  = "permissions:manage"
+	PermSessionsManage     = "sessions:manage"
+	PermAPIKeysManage      = "api_keys:manage"
+	PermAuditLogsView      = "audit_logs:view"
+	PermMetricsView        = "metrics:view"
+	PermEncryptionRotate   = "encryption:rotate"
+	PermRoleRequestsReview = "role_requests:review"
+)
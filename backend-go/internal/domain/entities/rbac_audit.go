@@ -1,8 +1,12 @@
 package entities
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
-// AdminActionLog represents an append-only audit record for admin/RBAC changes
+// AdminActionLog represents an append-only audit record for admin/RBAC changes.
+// Each record is linked to the previous one by a hash chain (ChainLink).
 type AdminActionLog struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
 	ActorID    uint      `gorm:"index;not null" json:"actor_id"` // who performed the action
@@ -13,4 +17,11 @@ type AdminActionLog struct {
 	TargetName string    `gorm:"size:255" json:"target_name,omitempty"` // human friendly name
 	Details    string    `gorm:"type:text" json:"details,omitempty"`    // JSON or free text with extra context
 	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ChainLink  `gorm:"embedded"`
+}
+
+// ChainContent implements ChainedRecord
+func (l *AdminActionLog) ChainContent() []byte {
+	b, _ := json.Marshal([]interface{}{l.ID, l.ActorID, l.ActorName, l.Action, l.TargetType, l.TargetID, l.TargetName, l.Details, l.CreatedAt.Unix()})
+	return b
 }
@@ -0,0 +1,32 @@
+package entities
+
+// RBACPolicyVersion es la versión del formato de la política declarativa
+const RBACPolicyVersion = 1
+
+// PolicyAllPermissions en la lista de permisos de un rol concede todos los permisos
+const PolicyAllPermissions = "*"
+
+// RBACPolicy describe roles, permisos y sus vínculos como documento versionable
+// (YAML o JSON). Las asignaciones de usuarios y los scopes no forman parte de él.
+type RBACPolicy struct {
+	Version     int                `json:"version" yaml:"version"`
+	Permissions []PolicyPermission `json:"permissions" yaml:"permissions"`
+	Roles       []PolicyRole       `json:"roles" yaml:"roles"`
+}
+
+// PolicyPermission es un permiso de la política; Resource y Action se deducen
+// del nombre "resource:action" si se omiten
+type PolicyPermission struct {
+	Name        string `json:"name" yaml:"name"`
+	Resource    string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Action      string `json:"action,omitempty" yaml:"action,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// PolicyRole es un rol de la política con su padre y sus permisos directos
+type PolicyRole struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Parent      string   `json:"parent,omitempty" yaml:"parent,omitempty"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+}
@@ -0,0 +1,151 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// ServerGroup agrupa servidores SQL por patrones de host ("sql-prod-*", "10.0.1.*")
+// para poder limitar permisos a todo el grupo
+type ServerGroup struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Servers     string    `gorm:"type:text;not null" json:"-"` // patrones separados por comas
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ServerList devuelve los patrones de host del grupo
+func (g *ServerGroup) ServerList() []string {
+	var out []string
+	for _, s := range strings.Split(g.Servers, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// PermissionScope restringe un permiso de un rol a ciertos servidores y bases de
+// datos. Un permiso de rol sin scopes vale para cualquier recurso; con scopes,
+// solo para los que encajan con alguno de ellos.
+type PermissionScope struct {
+	ID           uint `gorm:"primaryKey" json:"id"`
+	RoleID       uint `gorm:"not null;index:idx_scope_role_perm" json:"role_id"`
+	PermissionID uint `gorm:"not null;index:idx_scope_role_perm" json:"permission_id"`
+	// ServerGroupID limita el permiso a los servidores del grupo
+	ServerGroupID *uint        `gorm:"index" json:"server_group_id,omitempty"`
+	ServerGroup   *ServerGroup `json:"server_group,omitempty"`
+	// Server y Database son patrones (* como comodín); vacío = cualquiera
+	Server    string    `gorm:"size:255" json:"server,omitempty"`
+	Database  string    `gorm:"size:255" json:"database,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccessScope es el conjunto de recursos sobre los que un usuario tiene un permiso
+type AccessScope struct {
+	// Global: algún rol concede el permiso sin restricciones
+	Global bool
+	Rules  []ScopeRule
+}
+
+// ScopeRule es un scope ya resuelto: los patrones del grupo y del servidor deben
+// cumplirse a la vez
+type ScopeRule struct {
+	ServerSets [][]string // cada conjunto exige encajar con alguno de sus patrones
+	Database   string
+}
+
+// Any indica si el permiso vale en algún recurso
+func (s *AccessScope) Any() bool {
+	return s != nil && (s.Global || len(s.Rules) > 0)
+}
+
+// AllowsServer comprueba el permiso a nivel de servidor, p.ej. para abrir una
+// conexión o ver su historial; basta con que alguna base de datos del servidor esté permitida
+func (s *AccessScope) AllowsServer(server string) bool {
+	if s == nil {
+		return false
+	}
+	if s.Global {
+		return true
+	}
+	for _, r := range s.Rules {
+		if r.matchesServer(server) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows comprueba el permiso sobre una base de datos concreta de un servidor.
+// Una base de datos vacía (la predeterminada del login) solo encaja con scopes sin base de datos.
+func (s *AccessScope) Allows(server, database string) bool {
+	if s == nil {
+		return false
+	}
+	if s.Global {
+		return true
+	}
+	for _, r := range s.Rules {
+		if r.matchesServer(server) && (r.Database == "" || MatchPattern(r.Database, database)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ScopeRule) matchesServer(server string) bool {
+	for _, set := range r.ServerSets {
+		ok := false
+		for _, p := range set {
+			if MatchPattern(p, server) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchPattern compara sin distinguir mayúsculas; * encaja con cualquier secuencia.
+// Un valor vacío solo encaja con "*".
+func MatchPattern(pattern, value string) bool {
+	pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+// Rule resuelve el scope en patrones; un grupo eliminado no encaja con nada
+func (s *PermissionScope) Rule() ScopeRule {
+	r := ScopeRule{Database: s.Database}
+	if s.ServerGroupID != nil {
+		var patterns []string
+		if s.ServerGroup != nil {
+			patterns = s.ServerGroup.ServerList()
+		}
+		r.ServerSets = append(r.ServerSets, patterns)
+	}
+	if s.Server != "" {
+		r.ServerSets = append(r.ServerSets, []string{s.Server})
+	}
+	return r
+}
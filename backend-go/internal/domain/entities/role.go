@@ -1,10 +1,13 @@
 package entities
 
+import "time"
+
 // Role representa un rol de usuario en el sistema
 type Role struct {
 	ID          uint         `json:"id" gorm:"primaryKey"`
 	Name        string       `json:"name" gorm:"unique;not null"`
 	Description string       `json:"description"`
+	ParentID    *uint        `json:"parent_id,omitempty" gorm:"index"` // hereda los permisos del padre y sus ancestros
 	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
 }
 
@@ -18,8 +21,21 @@ func (r *Role) HasPermission(permission string) bool {
 	return false
 }
 
-// UserRole representa la relación entre usuarios y roles
+// UserRole representa la relación entre usuarios y roles. Con ValidFrom /
+// ValidUntil la asignación es temporal: fuera de esa ventana no concede nada.
 type UserRole struct {
-	UserID uint `json:"user_id" gorm:"primaryKey"`
-	RoleID uint `json:"role_id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"primaryKey"`
+	RoleID     uint       `json:"role_id" gorm:"primaryKey"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" gorm:"index"`
+	GrantedBy  *uint      `json:"granted_by,omitempty"`
+	Reason     string     `json:"reason,omitempty" gorm:"size:500"`
+}
+
+// Active indica si la asignación está vigente en now
+func (ur *UserRole) Active(now time.Time) bool {
+	if ur.ValidFrom != nil && now.Before(*ur.ValidFrom) {
+		return false
+	}
+	return ur.ValidUntil == nil || now.Before(*ur.ValidUntil)
 }
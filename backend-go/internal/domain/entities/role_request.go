@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// Estados de una solicitud de elevación
+const (
+	RoleRequestPending  = "pending"
+	RoleRequestApproved = "approved"
+	RoleRequestRejected = "rejected"
+)
+
+// RoleRequest es una solicitud de elevación just-in-time: un usuario pide un
+// rol durante un tiempo, con un motivo, y otro usuario la aprueba o rechaza.
+// Al aprobarla se crea una asignación temporal en user_roles.
+type RoleRequest struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	RoleID          uint       `gorm:"not null" json:"role_id"`
+	Role            *Role      `json:"role,omitempty"`
+	Reason          string     `gorm:"size:500;not null" json:"reason"`
+	DurationMinutes int        `gorm:"not null" json:"duration_minutes"`
+	Status          string     `gorm:"size:20;not null;index;default:'pending'" json:"status"`
+	DecidedBy       *uint      `json:"decided_by,omitempty"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty"`
+	DecisionNote    string     `gorm:"size:500" json:"decision_note,omitempty"`
+	ValidUntil      *time.Time `json:"valid_until,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
@@ -11,4 +11,18 @@ type Session struct {
 	ExpiresAt *time.Time `gorm:"index" json:"expires_at"`
 	IsActive  bool       `gorm:"default:true;index" json:"is_active"`
 	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+
+	// Refresh token rotation: every refresh creates a new row in the same family.
+	// Only the SHA-256 of the refresh token is stored.
+	RefreshTokenHash string     `gorm:"size:64;index" json:"-"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+	FamilyID         string     `gorm:"size:64;index" json:"family_id,omitempty"`
+	RotatedAt        *time.Time `json:"rotated_at,omitempty"` // refresh token already exchanged
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+
+	// Where the login came from; kept across refreshes (IP/user agent show the latest refresh)
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Device    string     `gorm:"size:100" json:"device,omitempty"`
+	IPAddress string     `gorm:"size:64" json:"ip_address,omitempty"`
+	UserAgent string     `gorm:"size:255" json:"user_agent,omitempty"`
 }
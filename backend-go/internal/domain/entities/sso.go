@@ -0,0 +1,27 @@
+package entities
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect provider
+// or directory (Issuer "ldap:<base dn>")
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Issuer      string     `gorm:"size:255;not null;uniqueIndex:idx_identity_subject" json:"issuer"`
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_identity_subject" json:"subject"`
+	Email       string     `gorm:"size:254" json:"email,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCLoginState keeps the state, nonce and PKCE verifier of an SSO login between
+// the redirect to the provider and the callback. Only the hash of state is stored.
+type OIDCLoginState struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	StateHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Nonce        string     `gorm:"size:64;not null" json:"-"`
+	CodeVerifier string     `gorm:"size:128;not null" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -24,10 +24,41 @@ type ActiveConnection struct {
 	Driver           string     `gorm:"size:255;not null" json:"driver"`
 	Server           string     `gorm:"size:255;not null" json:"server"`
 	DBUser           string     `gorm:"column:db_user;size:255;not null" json:"db_user"`
-	Password         string     `gorm:"size:500;not null" json:"-"` // encrypted
+	Password         string     `gorm:"size:500;not null" json:"-"` // secret reference (ciphertext or file:/vault: path)
 	IsConnected      bool       `gorm:"default:false;index" json:"is_connected"`
 	LastConnected    time.Time  `json:"last_connected"`
 	LastDisconnected *time.Time `json:"last_disconnected"` // nullable
+
+	// Driver/TLS settings captured on /open and reused on every reconnect
+	// (audits, health checks) through services.NewSQLServerConfig.
+	Port                  string `gorm:"size:10;default:'1433'" json:"port"`
+	Encrypt               string `gorm:"size:10;default:'true'" json:"encrypt"` // disable|false|true
+	ValidateCertificate   bool   `gorm:"default:false" json:"validate_certificate"`
+	CACertificate         string `gorm:"type:text" json:"-"`                         // optional PEM bundle
+	CertFingerprint       string `gorm:"size:128" json:"cert_fingerprint,omitempty"` // optional pinned SHA-256 (hex)
+	HostNameInCertificate string `gorm:"size:255" json:"host_name_in_certificate,omitempty"`
+	AppName               string `gorm:"size:128" json:"app_name,omitempty"`
+
+	// Health state maintained by the background connection monitor
+	HealthStatus    string     `gorm:"size:20;default:'unknown'" json:"health_status"` // unknown|healthy|unhealthy
+	LastHealthCheck *time.Time `json:"last_health_check"`
+	LastLatencyMs   int64      `json:"last_latency_ms"`
+	LastUsedAt      *time.Time `json:"last_used_at"` // last audit run; drives idle expiry
+}
+
+// Health states for ActiveConnection.HealthStatus
+const (
+	HealthUnknown   = "unknown"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// IdleSince returns the last moment the connection was opened or used
+func (c *ActiveConnection) IdleSince() time.Time {
+	if c.LastUsedAt != nil && c.LastUsedAt.After(c.LastConnected) {
+		return *c.LastUsedAt
+	}
+	return c.LastConnected
 }
 
 // ControlsInformation minimal entity for migration
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// ActivityRepository persists the API activity log
+type ActivityRepository interface {
+	Create(ev *entities.ActivityEvent) error
+	// CreateBatch stores several events in one statement
+	CreateBatch(evs []entities.ActivityEvent) error
+	// List returns a page of events matching the filter, newest first, plus the total number of matches
+	List(filter ActivityFilter) ([]entities.ActivityEvent, int64, error)
+	// DeleteBefore removes the events older than t and returns how many were removed
+	DeleteBefore(t time.Time) (int64, error)
+}
+
+// ActivityFilter para filtrar eventos de actividad; los campos nil no filtran
+type ActivityFilter struct {
+	Action       *string
+	Category     *string
+	Outcome      *string
+	ActorID      *uint
+	ActorName    *string
+	IPAddress    *string
+	RequestID    *string
+	ResourceType *string
+	ResourceID   *string
+	From         *time.Time // inclusive
+	To           *time.Time // exclusive
+	Limit        int
+	Offset       int
+}
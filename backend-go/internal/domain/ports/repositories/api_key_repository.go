@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// APIKeyRepository persists personal API keys
+type APIKeyRepository interface {
+	Create(k *entities.APIKey) error
+	// GetByHash returns the key with the given SHA-256 hash (nil, nil if none)
+	GetByHash(hash string) (*entities.APIKey, error)
+	// GetByID returns a key by primary key (nil, nil if none)
+	GetByID(id uint) (*entities.APIKey, error)
+	// List returns keys newest first, revoked ones included; userID filters by owner
+	List(userID *uint) ([]entities.APIKey, error)
+	// Revoke marks a key as revoked; false if it was already revoked
+	Revoke(id uint, at time.Time) (bool, error)
+	// TouchLastUsed records when and from where the key was last used
+	TouchLastUsed(id uint, at time.Time, ip string) error
+}
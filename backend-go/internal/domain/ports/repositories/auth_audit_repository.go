@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// AuthAuditRepository persists AuthAuditLog records
+type AuthAuditRepository interface {
+	Create(log *entities.AuthAuditLog) error
+	// List returns auth events matching optional filters (event, username, ip), newest first
+	List(event, username, ip *string, limit int, offset int) ([]entities.AuthAuditLog, error)
+}
+
+// LoginThrottleRepository tracks failed login attempts per user and per IP
+type LoginThrottleRepository interface {
+	// Get returns the counters for a key (nil, nil if none)
+	Get(key string) (*entities.LoginThrottle, error)
+	// RecordFailure adds one failure, restarting the count when the previous failure
+	// is older than window or the previous lock has expired
+	RecordFailure(key string, at time.Time, window time.Duration) (*entities.LoginThrottle, error)
+	Lock(key string, until time.Time) error
+	// Delay rejects the key's attempts until the given time
+	Delay(key string, until time.Time) error
+	// Reset clears the counters and any lock for a key
+	Reset(key string) error
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 )
 
@@ -11,29 +13,48 @@ type ConnectionRepository interface {
 	GetActiveByUserID(userID uint) (*entities.ActiveConnection, error)
 	// GetActiveByUserIDAndManager returns active connection for specific user and manager (gestor)
 	GetActiveByUserIDAndManager(userID uint, manager string) (*entities.ActiveConnection, error)
-	UpdateActive(conn *entities.ActiveConnection) error
+	// FindActiveByUserAndManager returns the active connection record for a user and
+	// manager whatever its health (used to close connections the monitor marked as down)
+	FindActiveByUserAndManager(userID uint, manager string) (*entities.ActiveConnection, error)
+	// UpdateActiveHealth writes only the health columns maintained by the monitor,
+	// so it never overwrites the password, last use or other concurrent changes
+	UpdateActiveHealth(conn *entities.ActiveConnection) error
 	// DeleteActive removes all active connections for a user (used to close all)
 	DeleteActive(userID uint) error
 	// DeleteActiveByUserAndManager removes a specific active connection for a user and manager
 	DeleteActiveByUserAndManager(userID uint, manager string) error
 	ListActive() ([]*entities.ActiveConnection, error)
-	// ListActiveByUser returns all active connections for a given user across drivers
+	// ListActiveByUser returns all active connections for a given user across drivers,
+	// including the ones the health monitor marked as disconnected
 	ListActiveByUser(userID uint) ([]*entities.ActiveConnection, error)
+	// TouchActive records that the connection was used (resets idle expiry)
+	TouchActive(id uint, at time.Time) error
+	// SwapActivePassword replaces the stored password only if it still equals oldRef;
+	// returns false when the row changed or disappeared meanwhile
+	SwapActivePassword(id uint, oldRef, newRef string) (bool, error)
 
 	// Historial de conexiones
 	LogConnection(log *entities.ConnectionLog) error
 	GetLogsByUserID(userID uint, limit, offset int) ([]*entities.ConnectionLog, error)
 	GetLogByID(id uint) (*entities.ConnectionLog, error)
 	CountLogsByUserID(userID uint) (int64, error)
+	// ListLogs returns a page of logs matching the filter plus the total number of matches
+	ListLogs(filter ConnectionLogFilter) ([]*entities.ConnectionLog, int64, error)
+	// StreamLogs calls fn for every log matching the filter (Limit/Offset ignored),
+	// newest first, without loading the whole result set in memory
+	StreamLogs(filter ConnectionLogFilter, fn func(*entities.ConnectionLog) error) error
 }
 
 // ConnectionLogFilter para filtrar logs de conexión
 type ConnectionLogFilter struct {
 	UserID    *uint
-	StartDate *string
-	EndDate   *string
+	StartDate *time.Time // inclusive
+	EndDate   *time.Time // exclusive
 	Status    *string
 	Server    *string
-	Limit     int
-	Offset    int
+	Manager   *string
+	// ServerScope limita los logs a los servidores permitidos (nil = sin límite)
+	ServerScope *entities.AccessScope
+	Limit       int
+	Offset      int
 }
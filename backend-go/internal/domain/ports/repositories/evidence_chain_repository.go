@@ -0,0 +1,28 @@
+package repositories
+
+import "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+
+// ChainRecord is one sealed record of an evidence chain, ready to be verified
+type ChainRecord struct {
+	RecordID uint
+	entities.ChainLink
+	Content []byte
+}
+
+// EvidenceChainRepository reads the hash chains of the audit tables and stores
+// their signed checkpoints. The repositories that write those tables seal each
+// record when it is created (audit runs when they finish).
+type EvidenceChainRepository interface {
+	// SealPending links the records that are not in the chain yet (written
+	// before the chain existed or whose sealing failed), in id order
+	SealPending(chain string) (int, error)
+	// ListLinks returns up to limit sealed records with chain_seq > afterSeq, in chain order
+	ListLinks(chain string, afterSeq uint64, limit int) ([]ChainRecord, error)
+	// LastLink returns the newest sealed record; seq 0 when the chain is empty
+	LastLink(chain string) (seq uint64, hash string, err error)
+	// CountUnsealed counts the records outside the chain (unfinished audit runs included)
+	CountUnsealed(chain string) (int64, error)
+	CreateCheckpoint(cp *entities.ChainCheckpoint) error
+	// ListCheckpoints returns the checkpoints of a chain by sequence
+	ListCheckpoints(chain string) ([]entities.ChainCheckpoint, error)
+}
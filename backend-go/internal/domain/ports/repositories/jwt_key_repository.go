@@ -0,0 +1,12 @@
+package repositories
+
+import "time"
+
+// JWTKeyRepository persists the end of the overlap period of rotated JWT keys
+type JWTKeyRepository interface {
+	// RetireAt returns the stored retire time of kid; the first time a key is
+	// seen as rotated it stores proposed and returns it
+	RetireAt(kid string, proposed time.Time) (time.Time, error)
+	// Forget drops the retire time of kid, e.g. when it signs again after a rollback
+	Forget(kid string) error
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// MFARepository stores TOTP secrets, recovery codes and login challenges
+type MFARepository interface {
+	// GetTOTP returns the user's TOTP enrollment (nil, nil if none)
+	GetTOTP(userID uint) (*entities.UserTOTP, error)
+	// SaveTOTP creates or replaces the user's enrollment
+	SaveTOTP(t *entities.UserTOTP) error
+	// MarkTOTPUsed records the accepted time step; false if that step (or a later one) was already used
+	MarkTOTPUsed(userID uint, step int64) (bool, error)
+	// ResetUser deletes the enrollment, recovery codes and pending challenges of a user
+	ResetUser(userID uint) error
+
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	// UseRecoveryCode consumes an unused code; false if there is none with that hash
+	UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+
+	CreateChallenge(c *entities.LoginChallenge) error
+	// GetChallenge returns a challenge by token hash (nil, nil if none)
+	GetChallenge(tokenHash string) (*entities.LoginChallenge, error)
+	IncrementChallengeAttempts(id uint) error
+	// ConsumeChallenge marks the challenge used; false if it was already used
+	ConsumeChallenge(id uint, at time.Time) (bool, error)
+}
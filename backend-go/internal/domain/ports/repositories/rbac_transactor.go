@@ -0,0 +1,8 @@
+package repositories
+
+// RBACTransactor runs a set of role, permission and scope changes as one unit:
+// fn gets repositories bound to a transaction that is committed when it returns
+// nil and rolled back otherwise
+type RBACTransactor interface {
+	InTransaction(fn func(roles RoleRepository, perms PermissionRepository, scopes ResourceScopeRepository) error) error
+}
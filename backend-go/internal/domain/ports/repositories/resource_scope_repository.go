@@ -0,0 +1,27 @@
+package repositories
+
+import "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+
+// ResourceScopeRepository persists server groups and the scopes that restrict
+// role permissions to servers and databases
+type ResourceScopeRepository interface {
+	ListServerGroups() ([]entities.ServerGroup, error)
+	// GetServerGroup returns nil, nil when the group does not exist
+	GetServerGroup(id uint) (*entities.ServerGroup, error)
+	CreateServerGroup(g *entities.ServerGroup) error
+	UpdateServerGroup(g *entities.ServerGroup) error
+	DeleteServerGroup(id uint) error
+	// CountScopesByServerGroup tells whether a group is still referenced
+	CountScopesByServerGroup(groupID uint) (int64, error)
+
+	// ListScopesByRoles returns the scopes of the roles with their server group loaded
+	ListScopesByRoles(roleIDs []uint) ([]entities.PermissionScope, error)
+	ListScopes(roleID, permissionID uint) ([]entities.PermissionScope, error)
+	// ReplaceScopes sets the scopes of a role permission in one transaction; an
+	// empty list makes the permission global again
+	ReplaceScopes(roleID, permissionID uint, scopes []entities.PermissionScope) error
+	// DeleteScopesByRole removes the scopes of every permission of a role
+	DeleteScopesByRole(roleID uint) error
+	// DeleteScopesByPermission removes the scopes of a permission in every role
+	DeleteScopesByPermission(permissionID uint) error
+}
@@ -1,6 +1,10 @@
 package repositories
 
-import "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
 
 // RoleRepository define las operaciones para gestionar roles
 type RoleRepository interface {
@@ -10,7 +14,7 @@ type RoleRepository interface {
 	// Actualizar un rol existente
 	Update(role *entities.Role) error
 
-	// Eliminar un rol por ID
+	// Eliminar un rol por ID junto con sus asignaciones
 	Delete(id uint) error
 
 	// Obtener un rol por ID
@@ -22,12 +26,33 @@ type RoleRepository interface {
 	// Listar todos los roles
 	List() ([]entities.Role, error)
 
-	// Asignar rol a usuario
+	// Asignar rol a usuario de forma permanente (sustituye una asignación temporal)
 	AssignToUser(userID uint, roleID uint) error
 
+	// Asignar rol con ventana de validez, sustituyendo la asignación anterior
+	GrantToUser(grant *entities.UserRole) error
+
 	// Revocar rol de usuario
 	RevokeFromUser(userID uint, roleID uint) error
 
-	// Obtener roles de usuario
+	// Obtener los roles vigentes del usuario, ordenados por ID
 	GetUserRoles(userID uint) ([]entities.Role, error)
+
+	// Listar los usuarios con una asignación (vigente o no) del rol
+	ListRoleUsers(roleID uint) ([]uint, error)
+
+	// Listar las asignaciones del usuario, incluidas las futuras
+	ListUserGrants(userID uint) ([]entities.UserRole, error)
+
+	// Listar las asignaciones temporales ya vencidas en now
+	ListExpiredGrants(now time.Time) ([]entities.UserRole, error)
+
+	// Borrar una asignación si sigue vencida en now (una renovación la conserva)
+	DeleteExpiredGrant(userID, roleID uint, now time.Time) (bool, error)
+
+	// Cambiar el rol padre (nil = sin padre)
+	SetParent(roleID uint, parentID *uint) error
+
+	// Listar los roles hijos directos
+	ListChildren(roleID uint) ([]entities.Role, error)
 }
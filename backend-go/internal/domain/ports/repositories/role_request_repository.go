@@ -0,0 +1,14 @@
+package repositories
+
+import "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+
+// RoleRequestRepository persiste las solicitudes de elevación just-in-time
+type RoleRequestRepository interface {
+	Create(req *entities.RoleRequest) error
+	// GetByID devuelve nil, nil si no existe
+	GetByID(id uint) (*entities.RoleRequest, error)
+	// List filtra por usuario y estado (opcionales), las más recientes primero
+	List(userID *uint, status string, limit, offset int) ([]entities.RoleRequest, error)
+	// Decide guarda la decisión solo si la solicitud sigue pendiente
+	Decide(req *entities.RoleRequest) (bool, error)
+}
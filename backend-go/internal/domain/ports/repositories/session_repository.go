@@ -1,13 +1,43 @@
 package repositories
 
-import "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
 
 // SessionRepository defines operations to manage user sessions
 type SessionRepository interface {
 	CreateSession(s *entities.Session) error
 	GetActiveByUserID(userID uint) (*entities.Session, error)
+	// GetByToken returns the session issued for an access token (nil, nil if none)
 	GetByToken(token string) (*entities.Session, error)
 	DeactivateByToken(token string) error
 	// ListActiveSessions returns all currently active (non-deactivated, non-expired) sessions
 	ListActiveSessions() ([]entities.Session, error)
+	// GetByRefreshHash returns the session issued with the given refresh token hash (nil, nil if none)
+	GetByRefreshHash(hash string) (*entities.Session, error)
+	// MarkRotated flags the session's refresh token as used; false if it was already used
+	MarkRotated(id uint, at time.Time) (bool, error)
+	// RevokeFamily deactivates every session of a refresh token family
+	RevokeFamily(familyID string, at time.Time) error
+	// GetByID returns a session by primary key (nil, nil if none)
+	GetByID(id uint) (*entities.Session, error)
+	// RevokeByID deactivates a single session row
+	RevokeByID(id uint, at time.Time) error
+	// RevokeByUser deactivates every session of a user and returns how many were active
+	RevokeByUser(userID uint, at time.Time) (int64, error)
+	// ExpireAccessByUser ends the access token of every live session of a user
+	// while keeping its refresh token, so the client has to refresh
+	ExpireAccessByUser(userID uint, at time.Time) (int64, error)
+	// RevokeAll deactivates every session and returns how many were active
+	RevokeAll(at time.Time) (int64, error)
+	// ListLiveByUser returns the current row of every login of the user that can
+	// still be used or refreshed, oldest login first
+	ListLiveByUser(userID uint, now time.Time) ([]entities.Session, error)
+	// CreateWithinLimit inserts s in one transaction with the session limit
+	// check: decide gets the user's active logins (refreshable and with an access
+	// token that expired after activeSince), oldest first, and returns the ones to
+	// revoke or an error that aborts the login. It returns the revoked sessions.
+	CreateWithinLimit(s *entities.Session, now, activeSince time.Time, decide func(active []entities.Session) ([]entities.Session, error)) ([]entities.Session, error)
 }
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// SSORepository persists external identities (OIDC and LDAP) and pending SSO logins
+type SSORepository interface {
+	CreateState(s *entities.OIDCLoginState) error
+	// ConsumeState marks an unused, unexpired state as used and returns it (nil, nil if none)
+	ConsumeState(hash string, now time.Time) (*entities.OIDCLoginState, error)
+	// GetIdentity returns the link for an issuer and subject (nil, nil if none)
+	GetIdentity(issuer, subject string) (*entities.UserIdentity, error)
+	CreateIdentity(i *entities.UserIdentity) error
+	TouchIdentity(id uint, email string, at time.Time) error
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// ActivityRecorder records an event in the API activity log. Request details
+// (IP, user agent, request id) are taken from ctx when the event leaves them
+// empty. Recording never fails the caller's operation.
+type ActivityRecorder interface {
+	Record(ctx context.Context, ev *entities.ActivityEvent)
+}
+
+// ActivityQueue records events in the background, in batches. It suits events
+// anyone can trigger, like rejected requests, that must not cost a database
+// write each; an event is dropped when the queue is full.
+type ActivityQueue interface {
+	Enqueue(ctx context.Context, ev *entities.ActivityEvent)
+}
+
+// ActivitySink recibe cada evento del log de actividad después de guardarlo
+// (p.ej. para exportarlo a un SIEM). Publish no debe bloquear.
+type ActivitySink interface {
+	Publish(ev entities.ActivityEvent)
+}
+
+// SIEMStatsProvider expone las métricas del exportador de eventos (endpoint de admin)
+type SIEMStatsProvider interface {
+	SIEMStats() SIEMStats
+}
+
+// SIEMStats describe el estado del exportador: cola, enviados, reintentos y descartes
+type SIEMStats struct {
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Format   string `json:"format"`
+	Queued   int    `json:"queued"`
+	Capacity int    `json:"capacity"`
+	Sent     uint64 `json:"sent"`
+	Retries  uint64 `json:"retries"`
+	// Dropped son los eventos descartados con la cola llena
+	Dropped uint64 `json:"dropped"`
+	// Failed son los eventos descartados tras agotar los reintentos
+	Failed      uint64     `json:"failed"`
+	Connects    uint64     `json:"connects"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
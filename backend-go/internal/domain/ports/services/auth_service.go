@@ -1,6 +1,10 @@
 package services
 
-import "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
 
 // AuthService defines methods for authentication and token management
 type AuthService interface {
@@ -9,3 +13,19 @@ type AuthService interface {
 	GenerateToken(user *entities.User) (string, error)
 	ValidateToken(token string) (uint, error) // returns user ID
 }
+
+// TokenIssuer signs short-lived access tokens and reports token lifetimes
+type TokenIssuer interface {
+	GenerateToken(userID uint, username string, role string) (string, error)
+	AccessExpiry() time.Duration
+	RefreshExpiry() time.Duration
+}
+
+// SessionInvalidator drops cached session state after a revocation so the
+// auth middleware stops accepting the affected access tokens immediately
+type SessionInvalidator interface {
+	InvalidateToken(token string)
+	InvalidateFamily(familyID string)
+	InvalidateUser(userID uint)
+	InvalidateAll()
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"errors"
+)
+
+// Directory errors
+var (
+	// ErrDirectoryUserNotFound means the directory has no entry for the username,
+	// so the login may fall back to local accounts
+	ErrDirectoryUserNotFound = errors.New("user not found in directory")
+	// ErrDirectoryInvalidCredentials means the entry exists but the password was rejected
+	ErrDirectoryInvalidCredentials = errors.New("invalid directory credentials")
+)
+
+// DirectoryAuthenticator checks a username and password against a corporate
+// directory (LDAP / Active Directory) and returns the entry as an external identity.
+// Issuer identifies the directory and Subject the entry; Groups are group names.
+type DirectoryAuthenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*ExternalIdentity, error)
+}
@@ -5,3 +5,11 @@ type EncryptionService interface {
 	Encrypt(plain string) (string, error)
 	Decrypt(encrypted string) (string, error)
 }
+
+// KeyRotator re-encrypts stored ciphertexts with the current key (see encryption.Keyring)
+type KeyRotator interface {
+	CurrentKeyID() string
+	// NeedsRotation is false for values written with the current key or that are not ciphertexts
+	NeedsRotation(ciphertext string) bool
+	Reencrypt(ciphertext string) (string, error)
+}
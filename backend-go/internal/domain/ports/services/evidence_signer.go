@@ -0,0 +1,14 @@
+package services
+
+// EvidenceSigner signs the checkpoints of the evidence hash chains. The public
+// key can be handed to auditors so they can check checkpoints independently.
+type EvidenceSigner interface {
+	KeyID() string
+	Sign(payload []byte) ([]byte, error)
+	// Knows tells whether keyID is the signing key or a trusted previous one
+	Knows(keyID string) bool
+	// Verify checks a signature made with the key keyID
+	Verify(keyID string, payload, signature []byte) bool
+	// PublicKey is the base64 verification key
+	PublicKey() string
+}
@@ -0,0 +1,24 @@
+package services
+
+import "context"
+
+// ExternalIdentity is the verified identity returned by an OpenID Connect provider
+// or a directory
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string // preferred_username
+	Name          string
+	Groups        []string
+}
+
+// IdentityProvider runs the authorization-code + PKCE flow against an external IdP
+type IdentityProvider interface {
+	// AuthCodeURL builds the authorization request URL (S256 code challenge)
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems the code with the PKCE verifier and returns the identity
+	// from the ID token after checking its signature, issuer, audience, expiry and nonce
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error)
+}
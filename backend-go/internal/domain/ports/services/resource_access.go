@@ -0,0 +1,18 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// ErrResourceAccessDenied means the user has the permission, but not on this
+// server or database
+var ErrResourceAccessDenied = errors.New("permission not granted on this server or database")
+
+// ResourceAccess tells on which servers and databases a user holds a permission
+type ResourceAccess interface {
+	// Scope returns where the user holds any of the permissions; an empty scope
+	// (Any() == false) when none of them is granted
+	Scope(userID uint, permissions ...string) (*entities.AccessScope, error)
+}
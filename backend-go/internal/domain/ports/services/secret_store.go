@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// SecretStore guarda credenciales de conexión. Put devuelve la referencia que se
+// persiste en la fila (ActiveConnection.Password); según el backend es el propio
+// texto cifrado (DB-AES) o un puntero a un almacén externo ("file:...", "vault:...").
+type SecretStore interface {
+	Put(ctx context.Context, key, secret string) (ref string, err error)
+	Get(ctx context.Context, ref string) (string, error)
+	Delete(ctx context.Context, ref string) error
+}
+
+// CredentialKey es la clave lógica bajo la que se guarda la contraseña de una conexión activa
+func CredentialKey(userID uint, manager string) string {
+	return fmt.Sprintf("connections/%d/%s", userID, manager)
+}
@@ -3,6 +3,11 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 )
 
 // SQLServerService define las operaciones para interactuar con SQL Server
@@ -18,10 +23,36 @@ type SQLServerService interface {
 
 	// Close cierra una conexión y libera recursos
 	Close(db *sql.DB) error
+
+	// Release cierra todos los pools abiertos por un usuario para un gestor (usado en /close)
+	Release(userID uint, manager string) error
+}
+
+// PoolStatsProvider expone estadísticas de los pools vivos (endpoint de admin)
+type PoolStatsProvider interface {
+	PoolStats() []PoolStats
+}
+
+// PoolStats describe un pool vivo. La identidad no contiene secretos.
+type PoolStats struct {
+	UserID   uint        `json:"user_id"`
+	Manager  string      `json:"manager"`
+	Server   string      `json:"server"`
+	Port     string      `json:"port"`
+	DBUser   string      `json:"db_user"`
+	Database string      `json:"database"`
+	Created  time.Time   `json:"created_at"`
+	LastUsed time.Time   `json:"last_used"`
+	Stats    sql.DBStats `json:"stats"`
 }
 
 // SQLServerConfig encapsula la configuración de conexión
 type SQLServerConfig struct {
+	// Identidad del dueño de la conexión; junto con servidor/usuario/base
+	// forma la clave del pool (nunca la contraseña)
+	UserID  uint
+	Manager string
+
 	Driver   string
 	Server   string
 	Port     string
@@ -29,6 +60,73 @@ type SQLServerConfig struct {
 	Password string
 	Database string
 	Options  map[string]string // Opciones adicionales como encrypt=true
+
+	// TLS settings. CACertificate and CertFingerprint cannot be expressed in the
+	// DSN and are applied by the adapter on the driver's tls.Config.
+	Encrypt                string // disable|false|true
+	TrustServerCertificate bool
+	CACertificate          string // PEM bundle used as RootCAs
+	CertFingerprint        string // pinned SHA-256 of the leaf certificate (hex)
+	HostNameInCertificate  string
+	AppName                string
+}
+
+// Defaults applied when a connection does not specify them
+const (
+	DefaultSQLServerPort    = "1433"
+	DefaultSQLServerEncrypt = "true"
+	DefaultSQLServerAppName = "MicroSQL-AGo"
+)
+
+// NewSQLServerConfig builds the driver configuration for a stored connection.
+// It is the single place that maps ActiveConnection settings to driver options,
+// so /open, audits and health checks always connect the same way.
+func NewSQLServerConfig(conn *entities.ActiveConnection, password, database string) SQLServerConfig {
+	port := conn.Port
+	if port == "" {
+		port = DefaultSQLServerPort
+	}
+	encrypt := strings.ToLower(strings.TrimSpace(conn.Encrypt))
+	if encrypt == "" {
+		encrypt = DefaultSQLServerEncrypt
+	}
+	appName := conn.AppName
+	if appName == "" {
+		appName = DefaultSQLServerAppName
+	}
+
+	return SQLServerConfig{
+		UserID:   conn.UserID,
+		Manager:  conn.Manager,
+		Driver:   conn.Driver,
+		Server:   conn.Server,
+		Port:     port,
+		User:     conn.DBUser,
+		Password: password,
+		Database: database,
+		Options:  map[string]string{},
+
+		Encrypt: encrypt,
+		// a pinned fingerprint replaces chain validation, so the driver must not
+		// reject self-signed certificates before our own check runs
+		TrustServerCertificate: !conn.ValidateCertificate || conn.CertFingerprint != "",
+		CACertificate:          conn.CACertificate,
+		CertFingerprint:        conn.CertFingerprint,
+		HostNameInCertificate:  conn.HostNameInCertificate,
+		AppName:                appName,
+	}
+}
+
+// ErrCAWithoutValidation: el bundle de CA solo se usa validando la cadena del
+// certificado; sin validación (o con un fingerprint fijado) se ignoraría
+var ErrCAWithoutValidation = errors.New("ca_certificate requires validate_certificate=true and no cert_fingerprint")
+
+// ValidateTLS rechaza combinaciones de TLS en las que un ajuste no tendría efecto
+func (c SQLServerConfig) ValidateTLS() error {
+	if c.CACertificate != "" && c.TrustServerCertificate {
+		return ErrCAWithoutValidation
+	}
+	return nil
 }
 
 // ConnectionError representa errores específicos de conexión
@@ -0,0 +1,279 @@
+package activity
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// Límites de paginación de la consulta del log
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// writeBatch es cuántos eventos de la cola se guardan como mucho de una vez
+const writeBatch = 100
+
+// RequestInfo son los datos de la petición HTTP en curso. El middleware la
+// guarda en el contexto; los casos de uso no la tocan, Record completa con ella
+// los eventos que registran.
+type RequestInfo struct {
+	RequestID string
+	IPAddress string
+	UserAgent string
+	Method    string
+	Path      string
+	// actor autenticado, lo pone el middleware de autenticación (SetActor)
+	userID   *uint
+	username string
+	apiKeyID *uint
+	recorded int32
+}
+
+// SetActor anota quién hace la petición una vez autenticada (apiKeyID nil con un token de sesión)
+func (r *RequestInfo) SetActor(userID uint, username string, apiKeyID *uint) {
+	r.userID, r.username, r.apiKeyID = &userID, username, apiKeyID
+}
+
+// Recorded indica si algún caso de uso ya registró un evento para esta petición
+func (r *RequestInfo) Recorded() bool {
+	return atomic.LoadInt32(&r.recorded) > 0
+}
+
+type requestKey struct{}
+
+// WithRequest devuelve un contexto con los datos de la petición
+func WithRequest(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestKey{}, info)
+}
+
+// RequestFrom devuelve los datos de la petición del contexto (nil fuera de una petición)
+func RequestFrom(ctx context.Context) *RequestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(requestKey{}).(*RequestInfo)
+	return info
+}
+
+// Options configura el log de actividad
+type Options struct {
+	// Retention es cuánto se conservan los eventos (0 = para siempre)
+	Retention time.Duration
+	// PurgeInterval es cada cuánto se borran los eventos vencidos (1h por defecto)
+	PurgeInterval time.Duration
+	// QueueSize es el tamaño de la cola de Enqueue (1000 por defecto)
+	QueueSize int
+}
+
+// ActivityLogUseCase registra y consulta el log de actividad de la API
+// (logins, conexiones, auditorías, descargas, cambios de administración y el
+// resto de peticiones que modifican algo o son rechazadas) y aplica su retención.
+// Implementa services.ActivityRecorder y services.ActivityQueue.
+type ActivityLogUseCase struct {
+	repo   repositories.ActivityRepository
+	opts   Options
+	now    func() time.Time
+	sink   services.ActivitySink
+	logger *zap.Logger
+	// queue la vacía Run; dropped cuenta los eventos que no cupieron
+	queue   chan entities.ActivityEvent
+	dropped uint64
+}
+
+func NewActivityLogUseCase(repo repositories.ActivityRepository, opts Options) *ActivityLogUseCase {
+	if opts.PurgeInterval <= 0 {
+		opts.PurgeInterval = time.Hour
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	return &ActivityLogUseCase{repo: repo, opts: opts, now: time.Now, logger: zap.NewNop(), queue: make(chan entities.ActivityEvent, opts.QueueSize)}
+}
+
+// WithLogger anota los eventos que no se pudieron guardar y las purgas
+func (uc *ActivityLogUseCase) WithLogger(l *zap.Logger) *ActivityLogUseCase {
+	uc.logger = l
+	return uc
+}
+
+// WithSink reenvía cada evento registrado a sink (p.ej. el exportador al SIEM)
+func (uc *ActivityLogUseCase) WithSink(sink services.ActivitySink) *ActivityLogUseCase {
+	uc.sink = sink
+	return uc
+}
+
+// Record completa el evento (fecha, categoría, tipo de actor y datos de la
+// petición), lo guarda y lo reenvía al sink si hay uno. Un error se anota pero
+// no interrumpe la operación; el evento se reenvía aunque no se haya podido guardar.
+func (uc *ActivityLogUseCase) Record(ctx context.Context, ev *entities.ActivityEvent) {
+	uc.complete(ctx, ev)
+	if err := uc.repo.Create(ev); err != nil {
+		uc.logger.Error("failed to record activity event", zap.String("action", ev.Action), zap.Error(err))
+	}
+	if uc.sink != nil {
+		uc.sink.Publish(*ev)
+	}
+}
+
+// Enqueue completa el evento como Record y lo deja en la cola para que Run lo
+// guarde por lotes y lo reenvíe al sink. Con la cola llena el evento se descarta.
+func (uc *ActivityLogUseCase) Enqueue(ctx context.Context, ev *entities.ActivityEvent) {
+	uc.complete(ctx, ev)
+	select {
+	case uc.queue <- *ev:
+	default:
+		atomic.AddUint64(&uc.dropped, 1)
+	}
+}
+
+// complete rellena los campos que el evento deja vacíos y marca la petición como registrada
+func (uc *ActivityLogUseCase) complete(ctx context.Context, ev *entities.ActivityEvent) {
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = uc.now()
+	}
+	if ev.Category == "" {
+		ev.Category = strings.SplitN(ev.Action, ".", 2)[0]
+	}
+	if ev.Outcome == "" {
+		ev.Outcome = entities.OutcomeSuccess
+	}
+	info := RequestFrom(ctx)
+	// el actor autenticado de la petición completa nombre y clave de API
+	if info != nil && info.userID != nil && ev.ActorID != nil && *ev.ActorID == *info.userID && ev.ActorType == "" {
+		if ev.ActorName == "" {
+			ev.ActorName = info.username
+		}
+		if ev.APIKeyID == nil {
+			ev.APIKeyID = info.apiKeyID
+		}
+	}
+	if ev.ActorType == "" {
+		switch {
+		case ev.APIKeyID != nil:
+			ev.ActorType = entities.ActorAPIKey
+		case ev.ActorID != nil:
+			ev.ActorType = entities.ActorUser
+		default:
+			ev.ActorType = entities.ActorAnonymous
+		}
+	}
+	if info != nil {
+		atomic.AddInt32(&info.recorded, 1)
+		if ev.RequestID == "" {
+			ev.RequestID = info.RequestID
+		}
+		if ev.IPAddress == "" {
+			ev.IPAddress = info.IPAddress
+		}
+		if ev.UserAgent == "" {
+			ev.UserAgent = info.UserAgent
+		}
+		if ev.Method == "" {
+			ev.Method = info.Method
+		}
+		if ev.Path == "" {
+			ev.Path = info.Path
+		}
+	}
+	ev.ActorName = truncate(ev.ActorName, 150)
+	ev.IPAddress = truncate(ev.IPAddress, 64)
+	ev.UserAgent = truncate(ev.UserAgent, 255)
+	ev.Path = truncate(ev.Path, 255)
+	ev.ResourceID = truncate(ev.ResourceID, 100)
+}
+
+// List devuelve una página del log con el total de coincidencias
+func (uc *ActivityLogUseCase) List(filter repositories.ActivityFilter) ([]entities.ActivityEvent, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit > MaxListLimit {
+		filter.Limit = MaxListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return uc.repo.List(filter)
+}
+
+// Retention devuelve cuánto se conservan los eventos (0 = para siempre)
+func (uc *ActivityLogUseCase) Retention() time.Duration {
+	return uc.opts.Retention
+}
+
+// Purge borra los eventos más antiguos que la retención
+func (uc *ActivityLogUseCase) Purge() (int64, error) {
+	if uc.opts.Retention <= 0 {
+		return 0, nil
+	}
+	return uc.repo.DeleteBefore(uc.now().Add(-uc.opts.Retention))
+}
+
+// Run guarda los eventos de la cola y aplica la retención cada PurgeInterval
+// hasta que ctx se cancele; antes de volver guarda lo que quede en la cola
+func (uc *ActivityLogUseCase) Run(ctx context.Context) {
+	var purge <-chan time.Time
+	if uc.opts.Retention > 0 {
+		ticker := time.NewTicker(uc.opts.PurgeInterval)
+		defer ticker.Stop()
+		purge = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			for len(uc.queue) > 0 {
+				uc.flush(<-uc.queue)
+			}
+			return
+		case ev := <-uc.queue:
+			uc.flush(ev)
+		case <-purge:
+			if n, err := uc.Purge(); err != nil {
+				uc.logger.Error("activity log purge failed", zap.Error(err))
+			} else if n > 0 {
+				uc.logger.Info("activity log purged", zap.Int64("events", n), zap.Duration("retention", uc.opts.Retention))
+			}
+		}
+	}
+}
+
+// flush guarda first y los eventos que ya esperan en la cola, hasta writeBatch
+func (uc *ActivityLogUseCase) flush(first entities.ActivityEvent) {
+	batch := []entities.ActivityEvent{first}
+collect:
+	for len(batch) < writeBatch {
+		select {
+		case ev := <-uc.queue:
+			batch = append(batch, ev)
+		default:
+			break collect
+		}
+	}
+	if err := uc.repo.CreateBatch(batch); err != nil {
+		uc.logger.Error("failed to record activity events", zap.Int("events", len(batch)), zap.Error(err))
+	}
+	if n := atomic.SwapUint64(&uc.dropped, 0); n > 0 {
+		uc.logger.Warn("activity log queue full, events dropped", zap.Uint64("events", n))
+	}
+	if uc.sink != nil {
+		for _, ev := range batch {
+			uc.sink.Publish(ev)
+		}
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
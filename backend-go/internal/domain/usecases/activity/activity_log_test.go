@@ -0,0 +1,136 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestActivityLog_filtersAndRetention(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ActivityEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	uc := NewActivityLogUseCase(repo.NewGormActivityRepository(db), Options{Retention: 24 * time.Hour})
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	uc.now = func() time.Time { return now }
+
+	alice, bob := uint(1), uint(2)
+	info := &RequestInfo{RequestID: "req-1", IPAddress: "10.0.0.7", UserAgent: "browser", Method: "POST", Path: "/api/auth/login"}
+	ctx := WithRequest(context.Background(), info)
+	uc.Record(ctx, &entities.ActivityEvent{Action: entities.ActivityLogin, Outcome: entities.OutcomeFailure, ActorName: "alice"})
+	if !info.Recorded() {
+		t.Fatalf("expected the request to be marked as described by a hook")
+	}
+	uc.Record(context.Background(), &entities.ActivityEvent{Action: entities.ActivityConnectionOpen, ActorID: &alice, ResourceType: "connection", ResourceID: "4", OccurredAt: now.Add(-time.Minute)})
+	uc.Record(context.Background(), &entities.ActivityEvent{Action: entities.ActivityReportDownload, ActorID: &bob, OccurredAt: now.Add(-48 * time.Hour)})
+
+	events, total, _ := uc.List(repositories.ActivityFilter{})
+	if total != 3 {
+		t.Fatalf("expected 3 events, got %d", total)
+	}
+	login := events[0]
+	if login.Category != "auth" || login.ActorType != entities.ActorAnonymous || login.RequestID != "req-1" || login.IPAddress != "10.0.0.7" || login.Path != "/api/auth/login" {
+		t.Fatalf("expected the request details to be filled in, got %+v", login)
+	}
+	if events[1].ActorType != entities.ActorUser || events[1].Outcome != entities.OutcomeSuccess || events[1].IPAddress != "" {
+		t.Fatalf("unexpected defaults outside a request: %+v", events[1])
+	}
+
+	category, outcome := "connection", entities.OutcomeFailure
+	if list, n, _ := uc.List(repositories.ActivityFilter{Category: &category, ActorID: &alice}); n != 1 || list[0].ResourceID != "4" {
+		t.Fatalf("expected alice's connection, got %+v", list)
+	}
+	if _, n, _ := uc.List(repositories.ActivityFilter{Outcome: &outcome, ActorID: &alice}); n != 0 {
+		t.Fatalf("expected no failures for alice, got %d", n)
+	}
+	from := now.Add(-time.Hour)
+	if _, n, _ := uc.List(repositories.ActivityFilter{From: &from}); n != 2 {
+		t.Fatalf("expected 2 recent events, got %d", n)
+	}
+	if list, n, _ := uc.List(repositories.ActivityFilter{Limit: 1, Offset: 1}); n != 3 || len(list) != 1 {
+		t.Fatalf("expected one event of a page with the full total, got %d %d", len(list), n)
+	}
+
+	if n, err := uc.Purge(); err != nil || n != 1 {
+		t.Fatalf("expected the 2 day old event to be purged, got %d %v", n, err)
+	}
+	if _, n, _ := uc.List(repositories.ActivityFilter{}); n != 2 {
+		t.Fatalf("expected 2 events after the purge, got %d", n)
+	}
+	uc.opts.Retention = 0
+	if n, _ := uc.Purge(); n != 0 {
+		t.Fatalf("a zero retention keeps everything, purged %d", n)
+	}
+}
+
+type sinkFunc func(entities.ActivityEvent)
+
+func (f sinkFunc) Publish(ev entities.ActivityEvent) { f(ev) }
+
+func TestActivityLog_forwardsToSink(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ActivityEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var published []entities.ActivityEvent
+	uc := NewActivityLogUseCase(repo.NewGormActivityRepository(db), Options{}).
+		WithSink(sinkFunc(func(ev entities.ActivityEvent) { published = append(published, ev) }))
+
+	ctx := WithRequest(context.Background(), &RequestInfo{RequestID: "req-9", IPAddress: "10.0.0.8"})
+	uc.Record(ctx, &entities.ActivityEvent{Action: entities.ActivityLockout, ActorType: entities.ActorSystem})
+	if len(published) != 1 {
+		t.Fatalf("expected one published event, got %d", len(published))
+	}
+	if ev := published[0]; ev.ID == 0 || ev.Category != "auth" || ev.RequestID != "req-9" || ev.IPAddress != "10.0.0.8" {
+		t.Fatalf("expected the stored and completed event to be published, got %+v", ev)
+	}
+}
+
+func TestActivityLog_queueWritesInBatchesAndDropsWhenFull(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ActivityEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var published []entities.ActivityEvent
+	uc := NewActivityLogUseCase(repo.NewGormActivityRepository(db), Options{QueueSize: 3}).
+		WithSink(sinkFunc(func(ev entities.ActivityEvent) { published = append(published, ev) }))
+	ctx := WithRequest(context.Background(), &RequestInfo{RequestID: "req-1", IPAddress: "10.0.0.9"})
+	for i := 0; i < 5; i++ {
+		uc.Enqueue(ctx, &entities.ActivityEvent{Action: entities.ActivityAPIRequest, Outcome: entities.OutcomeDenied})
+	}
+	if _, total, _ := uc.List(repositories.ActivityFilter{}); total != 0 {
+		t.Fatalf("expected queued events to wait for the writer, got %d stored", total)
+	}
+
+	// the writer stores what fits in the queue and flushes it when stopped
+	stop, cancel := context.WithCancel(context.Background())
+	cancel()
+	uc.Run(stop)
+	events, total, err := uc.List(repositories.ActivityFilter{})
+	if err != nil || total != 3 {
+		t.Fatalf("expected the 3 queued events and the rest dropped, got %d %v", total, err)
+	}
+	if ev := events[0]; ev.RequestID != "req-1" || ev.IPAddress != "10.0.0.9" || ev.Category != "api" {
+		t.Fatalf("expected queued events to be completed from the request, got %+v", ev)
+	}
+	if len(published) != 3 || published[0].ID == 0 {
+		t.Fatalf("expected the stored events to be published, got %+v", published)
+	}
+}
@@ -15,68 +15,122 @@ import (
 type ConnectToServerUseCase struct {
 	connRepo   repositories.ConnectionRepository
 	sqlService services.SQLServerService
-	encryptSvc services.EncryptionService
+	secrets    services.SecretStore
+	// access, si está configurado, limita los servidores a los que puede conectarse cada usuario
+	access services.ResourceAccess
+	// activity registra cada intento en el log de actividad (opcional)
+	activity services.ActivityRecorder
 }
 
 func NewConnectToServerUseCase(
 	cr repositories.ConnectionRepository,
 	ss services.SQLServerService,
-	es services.EncryptionService,
+	st services.SecretStore,
 ) *ConnectToServerUseCase {
 	return &ConnectToServerUseCase{
 		connRepo:   cr,
 		sqlService: ss,
-		encryptSvc: es,
+		secrets:    st,
 	}
 }
 
+// WithAccess activa la comprobación de scopes por servidor
+func (uc *ConnectToServerUseCase) WithAccess(a services.ResourceAccess) *ConnectToServerUseCase {
+	uc.access = a
+	return uc
+}
+
+// WithActivity registra las aperturas de conexión en el log de actividad
+func (uc *ConnectToServerUseCase) WithActivity(r services.ActivityRecorder) *ConnectToServerUseCase {
+	uc.activity = r
+	return uc
+}
+
 // Execute intenta establecer una conexión a SQL Server
 func (uc *ConnectToServerUseCase) Execute(ctx context.Context, userID uint, req ConnectRequest) (*entities.ActiveConnection, error) {
+	conn, err := uc.connect(ctx, userID, req)
+	if uc.activity != nil {
+		ev := connectionEvent(entities.ActivityConnectionOpen, userID, req.Manager, req.Driver, req.Server, req.DBUser, err)
+		if conn != nil {
+			ev.ResourceID = fmt.Sprint(conn.ID)
+		}
+		uc.activity.Record(ctx, ev)
+	}
+	return conn, err
+}
+
+func (uc *ConnectToServerUseCase) connect(ctx context.Context, userID uint, req ConnectRequest) (*entities.ActiveConnection, error) {
+	// El permiso de la ruta puede estar limitado a ciertos servidores
+	if uc.access != nil {
+		scope, err := uc.access.Scope(userID, connectionPermissions...)
+		if err != nil {
+			return nil, err
+		}
+		if !scope.AllowsServer(req.Server) {
+			return nil, services.ErrResourceAccessDenied
+		}
+	}
+
 	// Verificar si ya existe una conexión activa para este gestor (manager)
 	if active, _ := uc.connRepo.GetActiveByUserIDAndManager(userID, req.Manager); active != nil {
 		return nil, errors.New("user already has an active connection for this manager")
 	}
 
-	// Encriptar contraseña antes de almacenar
-	encryptedPass, err := uc.encryptSvc.Encrypt(req.Password)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encrypt password: %w", err)
+	// Crear registro de conexión activa; sus ajustes de puerto/TLS se reutilizan
+	// en cada reconexión (auditorías, health checks)
+	conn := &entities.ActiveConnection{
+		UserID:                userID,
+		Manager:               req.Manager,
+		Driver:                req.Driver,
+		Server:                req.Server,
+		DBUser:                req.DBUser,
+		Port:                  req.Port,
+		Encrypt:               req.Encrypt,
+		ValidateCertificate:   req.ValidateCertificate,
+		CACertificate:         req.CACertificate,
+		CertFingerprint:       req.CertFingerprint,
+		HostNameInCertificate: req.HostNameInCertificate,
+		AppName:               req.AppName,
 	}
 
-	// Intentar conectar a SQL Server
-	cfg := services.SQLServerConfig{
-		Driver:   req.Driver,
-		Server:   req.Server,
-		Port:     req.Port,
-		User:     req.DBUser,
-		Password: req.Password,
-		Database: "master", // Siempre conectamos a master primero
-		Options: map[string]string{
-			"encrypt": "true",
-			// use `yes` explicitly (driver accepts yes/no) so connection will trust server certificate
-			"TrustServerCertificate": "true",
-		},
+	// Intentar conectar a SQL Server (siempre conectamos a master primero)
+	cfg := services.NewSQLServerConfig(conn, req.Password, "master")
+	if err := cfg.ValidateTLS(); err != nil {
+		return nil, err
 	}
+	conn.Port = cfg.Port
+	conn.Encrypt = cfg.Encrypt
+	conn.AppName = cfg.AppName
 
 	db, err := uc.sqlService.Connect(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("connection failed: %w", err)
 	}
 
-	// Crear registro de conexión activa
-	conn := &entities.ActiveConnection{
-		UserID:        userID,
-		Manager:       req.Manager,
-		Driver:        req.Driver,
-		Server:        req.Server,
-		DBUser:        req.DBUser,
-		Password:      encryptedPass,
-		IsConnected:   true,
-		LastConnected: time.Now(),
+	now := time.Now()
+	conn.IsConnected = true
+	conn.LastConnected = now
+	conn.HealthStatus = entities.HealthHealthy
+	conn.LastHealthCheck = &now
+
+	// Una conexión marcada como caída por el monitor no cuenta como activa;
+	// se reemplaza su registro para poder reabrirla
+	if err := uc.connRepo.DeleteActiveByUserAndManager(userID, req.Manager); err != nil {
+		uc.sqlService.Close(db)
+		return nil, fmt.Errorf("failed to replace stale connection: %w", err)
 	}
 
+	// Guardar la contraseña en el almacén de secretos; la fila solo conserva la referencia
+	ref, err := uc.secrets.Put(ctx, services.CredentialKey(userID, req.Manager), req.Password)
+	if err != nil {
+		uc.sqlService.Close(db)
+		return nil, fmt.Errorf("failed to store password: %w", err)
+	}
+	conn.Password = ref
+
 	if err := uc.connRepo.CreateActive(conn); err != nil {
 		uc.sqlService.Close(db)
+		_ = uc.secrets.Delete(ctx, ref)
 		return nil, fmt.Errorf("failed to save connection: %w", err)
 	}
 
@@ -88,7 +142,7 @@ func (uc *ConnectToServerUseCase) Execute(ctx context.Context, userID uint, req
 		Server:    req.Server,
 		DBUser:    req.DBUser,
 		Timestamp: time.Now(),
-		Status:    "connected",
+		Status:    entities.ConnectionStatusConnected,
 	}
 
 	if err := uc.connRepo.LogConnection(log); err != nil {
@@ -99,6 +153,21 @@ func (uc *ConnectToServerUseCase) Execute(ctx context.Context, userID uint, req
 	return conn, nil
 }
 
+// connectionEvent describe una apertura o cierre de conexión para el log de actividad
+func connectionEvent(action string, userID uint, manager, driver, server, dbUser string, err error) *entities.ActivityEvent {
+	fields := map[string]interface{}{"manager": manager, "driver": driver, "server": server, "db_user": dbUser}
+	ev := &entities.ActivityEvent{Action: action, ActorID: &userID, ResourceType: "connection"}
+	switch {
+	case errors.Is(err, services.ErrResourceAccessDenied):
+		ev.Outcome = entities.OutcomeDenied
+	case err != nil:
+		ev.Outcome = entities.OutcomeFailure
+		fields["error"] = err.Error()
+	}
+	ev.Details = entities.ActivityDetails(fields)
+	return ev
+}
+
 // ConnectRequest representa los datos necesarios para conectar
 type ConnectRequest struct {
 	Manager  string
@@ -107,4 +176,12 @@ type ConnectRequest struct {
 	Port     string
 	DBUser   string
 	Password string
+
+	// Ajustes TLS opcionales (ver entities.ActiveConnection)
+	Encrypt               string
+	ValidateCertificate   bool
+	CACertificate         string
+	CertFingerprint       string
+	HostNameInCertificate string
+	AppName               string
 }
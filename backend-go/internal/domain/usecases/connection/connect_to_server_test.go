@@ -0,0 +1,76 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/mocks"
+)
+
+func TestConnectToServer_storesOnlySecretReference(t *testing.T) {
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(nil, nil)
+	mconn.On("DeleteActiveByUserAndManager", uint(6), "mssql").Return(nil)
+	mconn.On("CreateActive", mock.MatchedBy(func(c *entities.ActiveConnection) bool {
+		return c.Password == "vault:microsql/connections/6/mssql"
+	})).Return(nil)
+	mconn.On("LogConnection", mock.Anything).Return(nil)
+
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Connect", mock.Anything, mock.Anything).Return((*sql.DB)(nil), nil)
+
+	mstore := &mocks.MockSecretStore{}
+	mstore.On("Put", "connections/6/mssql", "S3cret!").Return("vault:microsql/connections/6/mssql", nil)
+
+	uc := NewConnectToServerUseCase(mconn, msql, mstore)
+	conn, err := uc.Execute(context.Background(), 6, ConnectRequest{
+		Manager: "mssql", Driver: "mssql", Server: "host", DBUser: "sa", Password: "S3cret!",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "vault:microsql/connections/6/mssql", conn.Password)
+	mconn.AssertExpectations(t)
+	mstore.AssertExpectations(t)
+}
+
+type fixedScope entities.AccessScope
+
+func (s *fixedScope) Scope(userID uint, permissions ...string) (*entities.AccessScope, error) {
+	return (*entities.AccessScope)(s), nil
+}
+
+func TestConnectToServer_refusesServersOutsideTheScope(t *testing.T) {
+	mconn := &mocks.MockConnectionRepository{}
+	msql := &mocks.MockSQLServerService{}
+	scope := &fixedScope{Rules: []entities.ScopeRule{{ServerSets: [][]string{{"sql-prod-*"}}}}}
+
+	uc := NewConnectToServerUseCase(mconn, msql, &mocks.MockSecretStore{}).WithAccess(scope)
+	_, err := uc.Execute(context.Background(), 6, ConnectRequest{
+		Manager: "mssql", Driver: "mssql", Server: "sql-dev-01", DBUser: "sa", Password: "S3cret!",
+	})
+
+	assert.ErrorIs(t, err, services.ErrResourceAccessDenied)
+	msql.AssertNotCalled(t, "Connect", mock.Anything, mock.Anything)
+	mconn.AssertNotCalled(t, "CreateActive", mock.Anything)
+}
+
+func TestConnectToServer_rejectsCABundleWithoutValidation(t *testing.T) {
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(nil, nil)
+	msql := &mocks.MockSQLServerService{}
+
+	uc := NewConnectToServerUseCase(mconn, msql, &mocks.MockSecretStore{})
+	_, err := uc.Execute(context.Background(), 6, ConnectRequest{
+		Manager: "mssql", Driver: "mssql", Server: "host", DBUser: "sa", Password: "S3cret!",
+		CACertificate: "-----BEGIN CERTIFICATE-----", ValidateCertificate: false,
+	})
+
+	assert.ErrorIs(t, err, services.ErrCAWithoutValidation)
+	msql.AssertNotCalled(t, "Connect", mock.Anything, mock.Anything)
+}
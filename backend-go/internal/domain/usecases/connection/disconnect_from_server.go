@@ -15,34 +15,75 @@ import (
 type DisconnectFromServerUseCase struct {
 	connRepo   repositories.ConnectionRepository
 	sqlService services.SQLServerService
+	secrets    services.SecretStore
+	// activity registra cada cierre en el log de actividad (opcional)
+	activity services.ActivityRecorder
 }
 
 func NewDisconnectFromServerUseCase(
 	cr repositories.ConnectionRepository,
 	ss services.SQLServerService,
+	st services.SecretStore,
 ) *DisconnectFromServerUseCase {
 	return &DisconnectFromServerUseCase{
 		connRepo:   cr,
 		sqlService: ss,
+		secrets:    st,
 	}
 }
 
+// WithActivity registra los cierres de conexión en el log de actividad
+func (uc *DisconnectFromServerUseCase) WithActivity(r services.ActivityRecorder) *DisconnectFromServerUseCase {
+	uc.activity = r
+	return uc
+}
+
 // Execute realiza la desconexión de SQL Server
 func (uc *DisconnectFromServerUseCase) Execute(ctx context.Context, userID uint, manager string) error {
-	// Obtener conexión activa para el gestor indicado
-	active, err := uc.connRepo.GetActiveByUserIDAndManager(userID, manager)
+	active, err := uc.disconnect(ctx, userID, manager)
+	if uc.activity != nil {
+		if active == nil {
+			active = &entities.ActiveConnection{}
+		}
+		ev := connectionEvent(entities.ActivityConnectionClose, userID, manager, active.Driver, active.Server, active.DBUser, err)
+		if active.ID != 0 {
+			ev.ResourceID = fmt.Sprint(active.ID)
+		}
+		uc.activity.Record(ctx, ev)
+	}
+	return err
+}
+
+// disconnect cierra la conexión y devuelve el registro que estaba activo
+func (uc *DisconnectFromServerUseCase) disconnect(ctx context.Context, userID uint, manager string) (*entities.ActiveConnection, error) {
+	// Obtener la conexión del gestor indicado, también si el monitor la marcó como caída
+	active, err := uc.connRepo.FindActiveByUserAndManager(userID, manager)
 	if err != nil {
-		return fmt.Errorf("failed to get active connection: %w", err)
+		return nil, fmt.Errorf("failed to get active connection: %w", err)
 	}
 
 	if active == nil {
-		return errors.New("no active connection found for this driver")
+		return nil, errors.New("no active connection found for this driver")
 	}
 
 	// Eliminar el registro de conexión activa para que el usuario pueda crear otra conexión
 	// con el mismo manager posteriormente
 	if err := uc.connRepo.DeleteActiveByUserAndManager(userID, manager); err != nil {
-		return fmt.Errorf("failed to delete active connection: %w", err)
+		return active, fmt.Errorf("failed to delete active connection: %w", err)
+	}
+
+	// Cerrar los pools SQL asociados a esta conexión para no dejar sesiones abiertas en el servidor
+	if uc.sqlService != nil {
+		if err := uc.sqlService.Release(userID, manager); err != nil {
+			fmt.Printf("failed to release sql pools: %v\n", err)
+		}
+	}
+
+	// Borrar la contraseña del almacén externo (no-op en modo DB-AES)
+	if uc.secrets != nil {
+		if err := uc.secrets.Delete(ctx, active.Password); err != nil {
+			fmt.Printf("failed to delete stored password: %v\n", err)
+		}
 	}
 
 	// Registrar en el historial
@@ -53,7 +94,7 @@ func (uc *DisconnectFromServerUseCase) Execute(ctx context.Context, userID uint,
 		Server:    active.Server,
 		DBUser:    active.DBUser,
 		Timestamp: time.Now(),
-		Status:    "disconnected",
+		Status:    entities.ConnectionStatusDisconnected,
 	}
 
 	if err := uc.connRepo.LogConnection(log); err != nil {
@@ -61,5 +102,5 @@ func (uc *DisconnectFromServerUseCase) Execute(ctx context.Context, userID uint,
 		fmt.Printf("failed to log disconnection: %v\n", err)
 	}
 
-	return nil
+	return active, nil
 }
@@ -28,29 +28,37 @@ func TestDisconnectFromServer_DeletesActiveConnection(t *testing.T) {
 
 	mconn := &mocks.MockConnectionRepository{}
 	// expect GetActive to find it
-	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(conn, nil)
+	mconn.On("FindActiveByUserAndManager", uint(6), "mssql").Return(conn, nil)
 	// expect DeleteActiveByUserAndManager to be called
 	mconn.On("DeleteActiveByUserAndManager", uint(6), "mssql").Return(nil)
 	// expect log to be called
 	mconn.On("LogConnection", mock.Anything).Return(nil)
 
 	msql := &mocks.MockSQLServerService{}
+	// expect the user's pools for this manager to be closed
+	msql.On("Release", uint(6), "mssql").Return(nil)
 
-	uc := NewDisconnectFromServerUseCase(mconn, msql)
+	// expect the stored password to be removed
+	mstore := &mocks.MockSecretStore{}
+	mstore.On("Delete", "enc").Return(nil)
+
+	uc := NewDisconnectFromServerUseCase(mconn, msql, mstore)
 
 	err := uc.Execute(context.Background(), 6, "mssql")
 	assert.NoError(t, err)
 
 	mconn.AssertExpectations(t)
+	msql.AssertExpectations(t)
+	mstore.AssertExpectations(t)
 }
 
 func TestDisconnectFromServer_NoActiveReturnsError(t *testing.T) {
 	mconn := &mocks.MockConnectionRepository{}
-	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(nil, nil)
+	mconn.On("FindActiveByUserAndManager", uint(6), "mssql").Return(nil, nil)
 
 	msql := &mocks.MockSQLServerService{}
 
-	uc := NewDisconnectFromServerUseCase(mconn, msql)
+	uc := NewDisconnectFromServerUseCase(mconn, msql, &mocks.MockSecretStore{})
 
 	err := uc.Execute(context.Background(), 6, "mssql")
 	assert.Error(t, err)
@@ -58,3 +66,22 @@ func TestDisconnectFromServer_NoActiveReturnsError(t *testing.T) {
 
 	mconn.AssertExpectations(t)
 }
+
+func TestDisconnectFromServer_closesUnhealthyConnection(t *testing.T) {
+	conn := &entities.ActiveConnection{
+		ID: 2, UserID: 6, Manager: "mssql", Password: "enc", IsConnected: false, HealthStatus: entities.HealthUnhealthy,
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("FindActiveByUserAndManager", uint(6), "mssql").Return(conn, nil)
+	mconn.On("DeleteActiveByUserAndManager", uint(6), "mssql").Return(nil)
+	mconn.On("LogConnection", mock.Anything).Return(nil)
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Release", uint(6), "mssql").Return(nil)
+	mstore := &mocks.MockSecretStore{}
+	mstore.On("Delete", "enc").Return(nil)
+
+	err := NewDisconnectFromServerUseCase(mconn, msql, mstore).Execute(context.Background(), 6, "mssql")
+	assert.NoError(t, err)
+	mconn.AssertExpectations(t)
+}
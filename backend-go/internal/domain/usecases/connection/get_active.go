@@ -5,11 +5,23 @@ import (
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
 )
 
+// connectionPermissions son los permisos de las rutas de conexión; sus scopes
+// deciden a qué servidores puede conectarse el usuario y cuáles ve
+var connectionPermissions = []string{entities.PermConnectionsManage, entities.PermAuditsExecute}
+
 // GetActiveConnectionUseCase maneja la obtención de la conexión activa
 type GetActiveConnectionUseCase struct {
 	connRepo repositories.ConnectionRepository
+	access   services.ResourceAccess
+}
+
+// WithAccess oculta las conexiones a servidores que el usuario ya no puede usar
+func (uc *GetActiveConnectionUseCase) WithAccess(a services.ResourceAccess) *GetActiveConnectionUseCase {
+	uc.access = a
+	return uc
 }
 
 func NewGetActiveConnectionUseCase(
@@ -20,12 +32,27 @@ func NewGetActiveConnectionUseCase(
 
 // Execute obtiene la conexión activa del usuario para el driver/gestor indicado
 func (uc *GetActiveConnectionUseCase) Execute(ctx context.Context, userID uint, manager string) (*entities.ActiveConnection, error) {
-	return uc.connRepo.GetActiveByUserIDAndManager(userID, manager)
+	conn, err := uc.connRepo.GetActiveByUserIDAndManager(userID, manager)
+	if err != nil || conn == nil || uc.access == nil {
+		return conn, err
+	}
+	visible, err := filterByServer(uc.access, userID, []*entities.ActiveConnection{conn})
+	if err != nil || len(visible) == 0 {
+		return nil, err
+	}
+	return conn, nil
 }
 
 // ListActiveConnectionsUseCase lista todas las conexiones activas de un usuario (todos los gestores)
 type ListActiveConnectionsUseCase struct {
 	connRepo repositories.ConnectionRepository
+	access   services.ResourceAccess
+}
+
+// WithAccess oculta las conexiones a servidores que el usuario ya no puede usar
+func (uc *ListActiveConnectionsUseCase) WithAccess(a services.ResourceAccess) *ListActiveConnectionsUseCase {
+	uc.access = a
+	return uc
 }
 
 func NewListActiveConnectionsUseCase(cr repositories.ConnectionRepository) *ListActiveConnectionsUseCase {
@@ -33,5 +60,23 @@ func NewListActiveConnectionsUseCase(cr repositories.ConnectionRepository) *List
 }
 
 func (uc *ListActiveConnectionsUseCase) Execute(ctx context.Context, userID uint) ([]*entities.ActiveConnection, error) {
-	return uc.connRepo.ListActiveByUser(userID)
+	conns, err := uc.connRepo.ListActiveByUser(userID)
+	if err != nil || uc.access == nil {
+		return conns, err
+	}
+	return filterByServer(uc.access, userID, conns)
+}
+
+func filterByServer(access services.ResourceAccess, userID uint, conns []*entities.ActiveConnection) ([]*entities.ActiveConnection, error) {
+	scope, err := access.Scope(userID, connectionPermissions...)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*entities.ActiveConnection, 0, len(conns))
+	for _, c := range conns {
+		if scope.AllowsServer(c.Server) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
 }
@@ -0,0 +1,177 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// HealthMonitor revisa periódicamente las conexiones activas: hace ping con
+// ValidateConnection, registra caídas/reconexiones en el historial y cierra
+// las conexiones que llevan demasiado tiempo sin usarse.
+type HealthMonitor struct {
+	connRepo    repositories.ConnectionRepository
+	sqlService  services.SQLServerService
+	secrets     services.SecretStore
+	interval    time.Duration
+	idleTimeout time.Duration
+	// activity registra los cierres por inactividad en el log de actividad (opcional)
+	activity services.ActivityRecorder
+	now      func() time.Time
+}
+
+// NewHealthMonitor crea el monitor. idleTimeout <= 0 desactiva la expiración por inactividad.
+func NewHealthMonitor(
+	cr repositories.ConnectionRepository,
+	ss services.SQLServerService,
+	st services.SecretStore,
+	interval time.Duration,
+	idleTimeout time.Duration,
+) *HealthMonitor {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &HealthMonitor{
+		connRepo:    cr,
+		sqlService:  ss,
+		secrets:     st,
+		interval:    interval,
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+	}
+}
+
+// WithActivity registra los cierres por inactividad en el log de actividad
+func (m *HealthMonitor) WithActivity(r services.ActivityRecorder) *HealthMonitor {
+	m.activity = r
+	return m
+}
+
+// Run ejecuta CheckAll cada intervalo hasta que ctx se cancele
+func (m *HealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.CheckAll(ctx); err != nil {
+				fmt.Printf("connection health check failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// CheckAll revisa todas las conexiones activas una vez
+func (m *HealthMonitor) CheckAll(ctx context.Context) error {
+	conns, err := m.connRepo.ListActive()
+	if err != nil {
+		return fmt.Errorf("failed to list active connections: %w", err)
+	}
+	for _, conn := range conns {
+		if m.expired(conn) {
+			m.expire(conn)
+			continue
+		}
+		m.check(ctx, conn)
+	}
+	return nil
+}
+
+func (m *HealthMonitor) expired(conn *entities.ActiveConnection) bool {
+	return m.idleTimeout > 0 && m.now().Sub(conn.IdleSince()) > m.idleTimeout
+}
+
+// expire cierra una conexión sin uso: libera sus pools y elimina el registro activo
+func (m *HealthMonitor) expire(conn *entities.ActiveConnection) {
+	if err := m.sqlService.Release(conn.UserID, conn.Manager); err != nil {
+		fmt.Printf("failed to release sql pools: %v\n", err)
+	}
+	if err := m.connRepo.DeleteActiveByUserAndManager(conn.UserID, conn.Manager); err != nil {
+		fmt.Printf("failed to delete expired connection: %v\n", err)
+		return
+	}
+	if err := m.secrets.Delete(context.Background(), conn.Password); err != nil {
+		fmt.Printf("failed to delete stored password: %v\n", err)
+	}
+	m.log(conn, entities.ConnectionStatusExpired)
+	if m.activity != nil {
+		m.activity.Record(context.Background(), &entities.ActivityEvent{
+			Action:       entities.ActivityConnectionClose,
+			ActorType:    entities.ActorSystem,
+			ResourceType: "connection",
+			ResourceID:   fmt.Sprint(conn.ID),
+			Details: entities.ActivityDetails(map[string]interface{}{
+				"reason": "idle", "user_id": conn.UserID, "manager": conn.Manager, "driver": conn.Driver, "server": conn.Server, "db_user": conn.DBUser,
+			}),
+		})
+	}
+}
+
+// check hace ping a la conexión y persiste estado, última revisión y latencia
+func (m *HealthMonitor) check(ctx context.Context, conn *entities.ActiveConnection) {
+	wasHealthy := conn.IsConnected && conn.HealthStatus != entities.HealthUnhealthy
+
+	start := m.now()
+	err := m.ping(ctx, conn)
+	checked := m.now()
+	latency := checked.Sub(start)
+
+	conn.LastHealthCheck = &checked
+	if err != nil {
+		conn.IsConnected = false
+		conn.HealthStatus = entities.HealthUnhealthy
+		conn.LastLatencyMs = 0
+		if conn.LastDisconnected == nil || wasHealthy {
+			conn.LastDisconnected = &checked
+		}
+	} else {
+		conn.IsConnected = true
+		conn.HealthStatus = entities.HealthHealthy
+		conn.LastLatencyMs = latency.Milliseconds()
+	}
+
+	if uerr := m.connRepo.UpdateActiveHealth(conn); uerr != nil {
+		fmt.Printf("failed to update connection health: %v\n", uerr)
+	}
+
+	switch {
+	case err != nil && wasHealthy:
+		m.log(conn, entities.ConnectionStatusFailed)
+	case err == nil && !wasHealthy:
+		m.log(conn, entities.ConnectionStatusReconnected)
+	}
+}
+
+func (m *HealthMonitor) ping(ctx context.Context, conn *entities.ActiveConnection) error {
+	password, err := m.secrets.Get(ctx, conn.Password)
+	if err != nil {
+		return fmt.Errorf("failed to read stored password: %w", err)
+	}
+	db, err := m.sqlService.Connect(ctx, services.NewSQLServerConfig(conn, password, "master"))
+	if err != nil {
+		return err
+	}
+	return m.sqlService.ValidateConnection(ctx, db)
+}
+
+func (m *HealthMonitor) log(conn *entities.ActiveConnection, status string) {
+	log := &entities.ConnectionLog{
+		UserID:    conn.UserID,
+		Manager:   conn.Manager,
+		Driver:    conn.Driver,
+		Server:    conn.Server,
+		DBUser:    conn.DBUser,
+		Timestamp: m.now(),
+		Status:    status,
+	}
+	if err := m.connRepo.LogConnection(log); err != nil {
+		// Solo loggeamos el error, no fallamos la operación
+		fmt.Printf("failed to log connection %s: %v\n", status, err)
+	}
+}
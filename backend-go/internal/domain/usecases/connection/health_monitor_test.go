@@ -0,0 +1,110 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/mocks"
+)
+
+func logWithStatus(status string) interface{} {
+	return mock.MatchedBy(func(l *entities.ConnectionLog) bool { return l.Status == status })
+}
+
+func TestHealthMonitor_marksFailedAndReconnected(t *testing.T) {
+	conn := &entities.ActiveConnection{
+		ID: 1, UserID: 6, Manager: "mssql", Driver: "mssql", Server: "host", DBUser: "sa",
+		Password: "enc", IsConnected: true, HealthStatus: entities.HealthHealthy, LastConnected: time.Now(),
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("ListActive").Return([]*entities.ActiveConnection{conn}, nil)
+	mconn.On("UpdateActiveHealth", conn).Return(nil)
+	mconn.On("LogConnection", logWithStatus(entities.ConnectionStatusFailed)).Return(nil).Once()
+	mconn.On("LogConnection", logWithStatus(entities.ConnectionStatusReconnected)).Return(nil).Once()
+
+	mstore := &mocks.MockSecretStore{}
+	mstore.On("Get", "enc").Return("plain", nil)
+
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Connect", mock.Anything, mock.Anything).Return((*sql.DB)(nil), nil)
+	msql.On("ValidateConnection", mock.Anything, (*sql.DB)(nil)).Return(errors.New("server gone")).Once()
+	msql.On("ValidateConnection", mock.Anything, (*sql.DB)(nil)).Return(nil).Once()
+
+	m := NewHealthMonitor(mconn, msql, mstore, time.Minute, time.Hour)
+
+	// first pass: server is down
+	assert.NoError(t, m.CheckAll(context.Background()))
+	assert.False(t, conn.IsConnected)
+	assert.Equal(t, entities.HealthUnhealthy, conn.HealthStatus)
+	assert.NotNil(t, conn.LastHealthCheck)
+	assert.NotNil(t, conn.LastDisconnected)
+
+	// second pass: server is back
+	assert.NoError(t, m.CheckAll(context.Background()))
+	assert.True(t, conn.IsConnected)
+	assert.Equal(t, entities.HealthHealthy, conn.HealthStatus)
+
+	mconn.AssertExpectations(t)
+	msql.AssertExpectations(t)
+}
+
+func TestHealthMonitor_expiresIdleConnections(t *testing.T) {
+	conn := &entities.ActiveConnection{
+		ID: 1, UserID: 6, Manager: "mssql", Driver: "mssql", Server: "host", DBUser: "sa",
+		Password: "enc", IsConnected: true, LastConnected: time.Now().Add(-2 * time.Hour),
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("ListActive").Return([]*entities.ActiveConnection{conn}, nil)
+	mconn.On("DeleteActiveByUserAndManager", uint(6), "mssql").Return(nil)
+	mconn.On("LogConnection", logWithStatus(entities.ConnectionStatusExpired)).Return(nil)
+
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Release", uint(6), "mssql").Return(nil)
+
+	// the stored password is removed together with the row
+	mstore := &mocks.MockSecretStore{}
+	mstore.On("Delete", "enc").Return(nil)
+
+	m := NewHealthMonitor(mconn, msql, mstore, time.Minute, 30*time.Minute)
+
+	assert.NoError(t, m.CheckAll(context.Background()))
+
+	mconn.AssertExpectations(t)
+	msql.AssertExpectations(t)
+	mstore.AssertExpectations(t)
+}
+
+func TestHealthMonitor_recentUseKeepsConnectionAlive(t *testing.T) {
+	used := time.Now().Add(-time.Minute)
+	conn := &entities.ActiveConnection{
+		ID: 1, UserID: 6, Manager: "mssql", Password: "enc", IsConnected: true,
+		HealthStatus: entities.HealthHealthy, LastConnected: time.Now().Add(-2 * time.Hour), LastUsedAt: &used,
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("ListActive").Return([]*entities.ActiveConnection{conn}, nil)
+	mconn.On("UpdateActiveHealth", conn).Return(nil)
+
+	mstore := &mocks.MockSecretStore{}
+	mstore.On("Get", "enc").Return("plain", nil)
+
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Connect", mock.Anything, mock.Anything).Return((*sql.DB)(nil), nil)
+	msql.On("ValidateConnection", mock.Anything, (*sql.DB)(nil)).Return(nil)
+
+	m := NewHealthMonitor(mconn, msql, mstore, time.Minute, 30*time.Minute)
+
+	assert.NoError(t, m.CheckAll(context.Background()))
+	// healthy -> healthy: no history entry, no expiry
+	mconn.AssertNotCalled(t, "LogConnection", mock.Anything)
+	mconn.AssertNotCalled(t, "DeleteActiveByUserAndManager", mock.Anything, mock.Anything)
+}
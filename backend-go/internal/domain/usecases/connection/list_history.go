@@ -2,15 +2,26 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// Pagination limits for the history endpoints
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 500
 )
 
 // ListConnectionHistoryUseCase maneja la obtención del historial de conexiones
 type ListConnectionHistoryUseCase struct {
 	connRepo repositories.ConnectionRepository
+	access   services.ResourceAccess
+	// activity registra las exportaciones en el log de actividad (opcional)
+	activity services.ActivityRecorder
 }
 
 func NewListConnectionHistoryUseCase(
@@ -21,15 +32,179 @@ func NewListConnectionHistoryUseCase(
 	}
 }
 
+// WithAccess limita el historial a los servidores donde quien consulta tiene el permiso
+func (uc *ListConnectionHistoryUseCase) WithAccess(a services.ResourceAccess) *ListConnectionHistoryUseCase {
+	uc.access = a
+	return uc
+}
+
+// WithActivity registra las exportaciones del historial en el log de actividad
+func (uc *ListConnectionHistoryUseCase) WithActivity(r services.ActivityRecorder) *ListConnectionHistoryUseCase {
+	uc.activity = r
+	return uc
+}
+
+// HistoryPage es una página del historial con el total de coincidencias
+type HistoryPage struct {
+	Logs   []*entities.ConnectionLog `json:"logs"`
+	Total  int64                     `json:"total"`
+	Limit  int                       `json:"limit"`
+	Offset int                       `json:"offset"`
+}
+
 // Execute obtiene el historial de conexiones del usuario
-func (uc *ListConnectionHistoryUseCase) Execute(ctx context.Context, userID uint, filters HistoryFilters) ([]*entities.ConnectionLog, error) {
-	return uc.connRepo.GetLogsByUserID(userID, filters.Limit, filters.Offset)
+func (uc *ListConnectionHistoryUseCase) Execute(ctx context.Context, userID uint, filters HistoryFilters) (*HistoryPage, error) {
+	filters.UserID = &userID
+	return uc.list(filters, userID, ownHistoryPermissions)
+}
+
+// ExecuteAll obtiene el historial de todos los usuarios (admin); filters.UserID es opcional
+func (uc *ListConnectionHistoryUseCase) ExecuteAll(ctx context.Context, filters HistoryFilters) (*HistoryPage, error) {
+	return uc.list(filters, filters.ViewerID, allHistoryPermissions)
+}
+
+func (uc *ListConnectionHistoryUseCase) list(filters HistoryFilters, viewer uint, perms []string) (*HistoryPage, error) {
+	f := filters.toRepositoryFilter()
+	if err := uc.restrict(&f, viewer, perms); err != nil {
+		return nil, err
+	}
+	logs, total, err := uc.connRepo.ListLogs(f)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryPage{Logs: logs, Total: total, Limit: f.Limit, Offset: f.Offset}, nil
+}
+
+// Export recorre todo el historial del usuario que coincide con los filtros (sin paginar)
+func (uc *ListConnectionHistoryUseCase) Export(ctx context.Context, userID uint, filters HistoryFilters, fn func(*entities.ConnectionLog) error) error {
+	filters.UserID = &userID
+	return uc.export(ctx, filters, userID, ownHistoryPermissions, "own", fn)
+}
+
+// ExportAll recorre el historial de todos los usuarios (admin) que coincide con los filtros
+func (uc *ListConnectionHistoryUseCase) ExportAll(ctx context.Context, filters HistoryFilters, fn func(*entities.ConnectionLog) error) error {
+	return uc.export(ctx, filters, filters.ViewerID, allHistoryPermissions, "all", fn)
+}
+
+// export recorre el historial y registra la descarga; scope es "own" o "all"
+func (uc *ListConnectionHistoryUseCase) export(ctx context.Context, filters HistoryFilters, viewer uint, perms []string, scope string, fn func(*entities.ConnectionLog) error) error {
+	rows := 0
+	err := uc.exportLogs(ctx, filters, viewer, perms, func(l *entities.ConnectionLog) error {
+		rows++
+		return fn(l)
+	})
+	if uc.activity != nil {
+		fields := map[string]interface{}{"scope": scope, "rows": rows, "filters": filters.describe()}
+		ev := &entities.ActivityEvent{Action: entities.ActivityReportDownload, ResourceType: "connection_history"}
+		if viewer != 0 {
+			ev.ActorID = &viewer
+		}
+		switch {
+		case errors.Is(err, services.ErrResourceAccessDenied):
+			ev.Outcome = entities.OutcomeDenied
+		case err != nil:
+			ev.Outcome = entities.OutcomeFailure
+			fields["error"] = err.Error()
+		}
+		ev.Details = entities.ActivityDetails(fields)
+		uc.activity.Record(ctx, ev)
+	}
+	return err
+}
+
+func (uc *ListConnectionHistoryUseCase) exportLogs(ctx context.Context, filters HistoryFilters, viewer uint, perms []string, fn func(*entities.ConnectionLog) error) error {
+	f := filters.toRepositoryFilter()
+	if err := uc.restrict(&f, viewer, perms); err != nil {
+		return err
+	}
+	return uc.connRepo.StreamLogs(f, func(l *entities.ConnectionLog) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(l)
+	})
+}
+
+// Permisos cuyos scopes limitan los servidores visibles en cada historial
+var (
+	ownHistoryPermissions = []string{entities.PermConnectionsManage, entities.PermAuditsView}
+	allHistoryPermissions = []string{entities.PermConnectionsViewAll}
+)
+
+// restrict añade al filtro los servidores donde viewer tiene alguno de los permisos
+func (uc *ListConnectionHistoryUseCase) restrict(f *repositories.ConnectionLogFilter, viewer uint, perms []string) error {
+	if uc.access == nil || viewer == 0 {
+		return nil
+	}
+	scope, err := uc.access.Scope(viewer, perms...)
+	if err != nil {
+		return err
+	}
+	f.ServerScope = scope
+	return nil
 }
 
 // HistoryFilters contiene los filtros para obtener el historial
 type HistoryFilters struct {
-	StartDate time.Time
-	EndDate   time.Time
+	UserID *uint
+	// ViewerID es quien consulta el historial global; sus scopes limitan los servidores
+	ViewerID  uint
+	StartDate time.Time // zero = sin límite
+	EndDate   time.Time // zero = sin límite (exclusivo)
+	Status    string
+	Server    string
+	Manager   string
 	Limit     int
 	Offset    int
 }
+
+// describe devuelve los filtros usados, para el log de actividad
+func (f HistoryFilters) describe() map[string]interface{} {
+	out := map[string]interface{}{}
+	if f.UserID != nil {
+		out["user_id"] = *f.UserID
+	}
+	if !f.StartDate.IsZero() {
+		out["start_date"] = f.StartDate.UTC().Format(time.RFC3339)
+	}
+	if !f.EndDate.IsZero() {
+		out["end_date"] = f.EndDate.UTC().Format(time.RFC3339)
+	}
+	for k, v := range map[string]string{"status": f.Status, "server": f.Server, "manager": f.Manager} {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (f HistoryFilters) toRepositoryFilter() repositories.ConnectionLogFilter {
+	out := repositories.ConnectionLogFilter{UserID: f.UserID, Limit: f.Limit, Offset: f.Offset}
+	if !f.StartDate.IsZero() {
+		start := f.StartDate
+		out.StartDate = &start
+	}
+	if !f.EndDate.IsZero() {
+		end := f.EndDate
+		out.EndDate = &end
+	}
+	if f.Status != "" {
+		out.Status = &f.Status
+	}
+	if f.Server != "" {
+		out.Server = &f.Server
+	}
+	if f.Manager != "" {
+		out.Manager = &f.Manager
+	}
+	if out.Limit <= 0 {
+		out.Limit = DefaultHistoryLimit
+	}
+	if out.Limit > MaxHistoryLimit {
+		out.Limit = MaxHistoryLimit
+	}
+	if out.Offset < 0 {
+		out.Offset = 0
+	}
+	return out
+}
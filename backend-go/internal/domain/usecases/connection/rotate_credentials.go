@@ -0,0 +1,89 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// RotateCredentialsUseCase re-cifra con la clave actual las contraseñas de
+// active_connections guardadas con claves antiguas (o sin id de clave).
+// Las referencias a almacenes externos (file:, vault:) no se tocan.
+type RotateCredentialsUseCase struct {
+	connRepo repositories.ConnectionRepository
+	rotator  services.KeyRotator
+}
+
+func NewRotateCredentialsUseCase(
+	cr repositories.ConnectionRepository,
+	kr services.KeyRotator,
+) *RotateCredentialsUseCase {
+	return &RotateCredentialsUseCase{
+		connRepo: cr,
+		rotator:  kr,
+	}
+}
+
+// RotationReport resume una ejecución del job
+type RotationReport struct {
+	KeyID   string          `json:"key_id"`
+	DryRun  bool            `json:"dry_run"`
+	Scanned int             `json:"scanned"`
+	Rotated int             `json:"rotated"`
+	Skipped int             `json:"skipped"`
+	Failed  int             `json:"failed"`
+	Errors  []RotationError `json:"errors,omitempty"`
+}
+
+// RotationError identifica la fila que no pudo re-cifrarse (sin exponer el secreto)
+type RotationError struct {
+	ConnectionID uint   `json:"connection_id"`
+	Error        string `json:"error"`
+}
+
+// Execute recorre todas las conexiones activas. Con dryRun solo cuenta las que se rotarían.
+func (uc *RotateCredentialsUseCase) Execute(ctx context.Context, dryRun bool) (*RotationReport, error) {
+	conns, err := uc.connRepo.ListActive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active connections: %w", err)
+	}
+
+	report := &RotationReport{KeyID: uc.rotator.CurrentKeyID(), DryRun: dryRun}
+	for _, conn := range conns {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Scanned++
+
+		if !uc.rotator.NeedsRotation(conn.Password) {
+			report.Skipped++
+			continue
+		}
+		if dryRun {
+			report.Rotated++
+			continue
+		}
+
+		rotated, err := uc.rotator.Reencrypt(conn.Password)
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, RotationError{ConnectionID: conn.ID, Error: err.Error()})
+			continue
+		}
+		// compare-and-swap: si la conexión se reabrió mientras tanto ya tiene un valor nuevo
+		swapped, err := uc.connRepo.SwapActivePassword(conn.ID, conn.Password, rotated)
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, RotationError{ConnectionID: conn.ID, Error: err.Error()})
+			continue
+		}
+		if !swapped {
+			report.Skipped++
+			continue
+		}
+		report.Rotated++
+	}
+	return report, nil
+}
@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/encryption"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/mocks"
+	repo "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestRotateCredentials_reencryptsOldKeysOnly(t *testing.T) {
+	const k1 = "0123456789abcdef0123456789abcdef"
+	const k2 = "fedcba9876543210fedcba9876543210"
+
+	oldRing, _ := encryption.ParseKeyring("k1", "k1:"+k1, "")
+	ring, err := encryption.ParseKeyring("k2", "k1:"+k1+",k2:"+k2, "")
+	assert.NoError(t, err)
+
+	old, _ := oldRing.Encrypt("old-pass")
+	current, _ := ring.Encrypt("current-pass")
+	conns := []*entities.ActiveConnection{
+		{ID: 1, Password: old},
+		{ID: 2, Password: current},
+		{ID: 3, Password: "vault:microsql/connections/3/mssql"},
+		{ID: 4, Password: "v1:gone:AAAA"},
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("ListActive").Return(conns, nil)
+	mconn.On("SwapActivePassword", uint(1), old, mock.MatchedBy(func(n string) bool {
+		pt, err := ring.Decrypt(n)
+		return strings.HasPrefix(n, "v1:k2:") && err == nil && pt == "old-pass"
+	})).Return(true, nil)
+
+	uc := NewRotateCredentialsUseCase(mconn, ring)
+
+	// dry run reports without writing
+	report, err := uc.Execute(context.Background(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Rotated)
+	mconn.AssertNotCalled(t, "SwapActivePassword", mock.Anything, mock.Anything, mock.Anything)
+
+	report, err = uc.Execute(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", report.KeyID)
+	assert.Equal(t, 4, report.Scanned)
+	assert.Equal(t, 1, report.Rotated)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, uint(4), report.Errors[0].ConnectionID)
+	mconn.AssertExpectations(t)
+}
+
+// The monitor and a key rotation work on copies of the same rows: a rotation
+// that runs while a health pass is in progress must keep its new ciphertext
+func TestRotateCredentials_duringHealthChecksKeepsRotatedPasswords(t *testing.T) {
+	const k1 = "0123456789abcdef0123456789abcdef"
+	const k2 = "fedcba9876543210fedcba9876543210"
+	oldRing, _ := encryption.ParseKeyring("k1", "k1:"+k1, "")
+	ring, _ := encryption.ParseKeyring("k2", "k1:"+k1+",k2:"+k2, "")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ActiveConnection{}, &entities.ConnectionLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	connRepo := repo.NewGormConnectionRepository(db)
+	for i := 0; i < 3; i++ {
+		old, _ := oldRing.Encrypt("pass")
+		_ = connRepo.CreateActive(&entities.ActiveConnection{UserID: uint(i + 1), Manager: "mssql", Server: "host", Password: old, IsConnected: true, LastConnected: time.Now()})
+	}
+
+	rotate := NewRotateCredentialsUseCase(connRepo, ring)
+	var once sync.Once
+	var report *RotationReport
+	mstore := &mocks.MockSecretStore{}
+	// the monitor has already listed the rows when it reads the first password
+	mstore.On("Get", mock.Anything).Run(func(mock.Arguments) {
+		once.Do(func() { report, _ = rotate.Execute(context.Background(), false) })
+	}).Return("pass", nil)
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Connect", mock.Anything, mock.Anything).Return((*sql.DB)(nil), nil)
+	msql.On("ValidateConnection", mock.Anything, mock.Anything).Return(errors.New("server gone"))
+	monitor := NewHealthMonitor(connRepo, msql, mstore, time.Minute, 0)
+
+	assert.NoError(t, monitor.CheckAll(context.Background()))
+	assert.Equal(t, 3, report.Rotated)
+
+	conns, _ := connRepo.ListActive()
+	for _, c := range conns {
+		assert.True(t, strings.HasPrefix(c.Password, "v1:k2:"), "connection %d lost its rotated password: %s", c.ID, c.Password)
+		assert.Equal(t, entities.HealthUnhealthy, c.HealthStatus)
+	}
+}
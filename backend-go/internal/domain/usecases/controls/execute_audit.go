@@ -2,6 +2,7 @@ package controls
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -11,6 +12,9 @@ import (
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
 )
 
+// ErrAuditRunForbidden: la auditoría pedida es de otro usuario
+var ErrAuditRunForbidden = errors.New("forbidden")
+
 // ExecuteAuditUseCase ejecuta scripts de control predefinidos (auditorías completas o parciales)
 type ExecuteAuditUseCase struct {
 	controlRepo repositories.ControlRepository
@@ -18,7 +22,11 @@ type ExecuteAuditUseCase struct {
 	queryExec   services.QueryExecutor
 	connRepo    repositories.ConnectionRepository
 	auditRepo   repositories.AuditRepository
-	encryptSvc  services.EncryptionService
+	secrets     services.SecretStore
+	// access, si está configurado, limita servidores y bases de datos por usuario
+	access services.ResourceAccess
+	// activity registra ejecuciones y consultas de resultados en el log de actividad (opcional)
+	activity services.ActivityRecorder
 }
 
 // NewExecuteAuditUseCase crea una nueva instancia con todas las dependencias
@@ -28,7 +36,7 @@ func NewExecuteAuditUseCase(
 	qe services.QueryExecutor,
 	conn repositories.ConnectionRepository,
 	ar repositories.AuditRepository,
-	enc services.EncryptionService,
+	st services.SecretStore,
 ) *ExecuteAuditUseCase {
 	return &ExecuteAuditUseCase{
 		controlRepo: cr,
@@ -36,10 +44,36 @@ func NewExecuteAuditUseCase(
 		queryExec:   qe,
 		connRepo:    conn,
 		auditRepo:   ar,
-		encryptSvc:  enc,
+		secrets:     st,
 	}
 }
 
+// WithAccess activa la comprobación de scopes: audits:execute para ejecutar y
+// audits:view para consultar resultados
+func (uc *ExecuteAuditUseCase) WithAccess(a services.ResourceAccess) *ExecuteAuditUseCase {
+	uc.access = a
+	return uc
+}
+
+// WithActivity registra las ejecuciones de auditorías y las descargas de sus
+// resultados en el log de actividad
+func (uc *ExecuteAuditUseCase) WithActivity(r services.ActivityRecorder) *ExecuteAuditUseCase {
+	uc.activity = r
+	return uc
+}
+
+// allowed comprueba si el usuario tiene el permiso sobre la base de datos del servidor
+func (uc *ExecuteAuditUseCase) allowed(userID uint, permission, server, database string) (bool, error) {
+	if uc.access == nil {
+		return true, nil
+	}
+	scope, err := uc.access.Scope(userID, permission)
+	if err != nil {
+		return false, err
+	}
+	return scope.Allows(server, database), nil
+}
+
 // AuditRequest representa la petición para ejecutar una auditoría
 type AuditRequest struct {
 	ControlIDs []uint `json:"control_ids,omitempty"`
@@ -70,6 +104,30 @@ type AuditResult struct {
 
 // Execute ejecuta una auditoría con controles o scripts indicados
 func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager string, req AuditRequest) (*AuditResult, error) {
+	res, run, err := uc.execute(ctx, userID, manager, req)
+	if uc.activity != nil {
+		fields := map[string]interface{}{"manager": manager, "server": run.Server, "database": req.Database, "mode": run.Mode, "controls": run.Controls}
+		ev := &entities.ActivityEvent{Action: entities.ActivityAuditExecute, ActorID: &userID, ResourceType: "audit_run"}
+		if run.ID != 0 {
+			ev.ResourceID = fmt.Sprint(run.ID)
+		}
+		switch {
+		case errors.Is(err, services.ErrResourceAccessDenied):
+			ev.Outcome = entities.OutcomeDenied
+		case err != nil:
+			ev.Outcome = entities.OutcomeFailure
+			fields["error"] = err.Error()
+		default:
+			fields["total"], fields["passed"], fields["failed"] = res.Total, res.Passed, res.Failed
+		}
+		ev.Details = entities.ActivityDetails(fields)
+		uc.activity.Record(ctx, ev)
+	}
+	return res, err
+}
+
+// execute hace el trabajo de Execute y devuelve también el AuditRun creado
+func (uc *ExecuteAuditUseCase) execute(ctx context.Context, userID uint, manager string, req AuditRequest) (*AuditResult, *entities.AuditRun, error) {
 	// Prepare and persist AuditRun (mode: partial|full)
 	mode := "partial"
 	if req.FullAudit {
@@ -94,23 +152,23 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 
 	if uc.auditRepo != nil {
 		if err := uc.auditRepo.CreateAuditRun(run); err != nil {
-			return nil, err
+			return nil, run, err
 		}
 	}
 	// Verificar conexión activa — preferir la conexión activa para el gestor/driver pedido
 	// Intentar obtener la conexión activa específica por user+driver
 	conn, err := uc.connRepo.GetActiveByUserIDAndManager(userID, manager)
 	if err != nil {
-		return nil, err
+		return nil, run, err
 	}
 	// Si no existe conexión específica, buscar en todas las activas y aplicar heurísticas
 	if conn == nil {
 		conns, err := uc.connRepo.ListActiveByUser(userID)
 		if err != nil {
-			return nil, err
+			return nil, run, err
 		}
 		if len(conns) == 0 {
-			return nil, fmt.Errorf("no active connection")
+			return nil, run, fmt.Errorf("no active connection")
 		}
 
 		// Preferir conexiones que parezcan apuntar a SQL Server (driver contiene sql/mssql/odbc)
@@ -144,11 +202,25 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 			}
 		}
 		if latestConn == nil {
-			return nil, fmt.Errorf("no active connection")
+			return nil, run, fmt.Errorf("no active connection")
 		}
 		// assign selected connection to outer variable
 		conn = latestConn
 	}
+	run.Server = conn.Server
+	ok, err := uc.allowed(userID, entities.PermAuditsExecute, conn.Server, req.Database)
+	if err != nil {
+		return nil, run, err
+	}
+	if !ok {
+		// la ejecución rechazada queda registrada
+		if uc.auditRepo != nil {
+			now := time.Now()
+			run.Status, run.FinishedAt = "denied", &now
+			_ = uc.auditRepo.UpdateAuditRun(run)
+		}
+		return nil, run, services.ErrResourceAccessDenied
+	}
 	// Recolectar scripts desde full audit OR controlIDs/scriptIDs
 	// If FullAudit==true we ignore control_ids/script_ids and load all scripts
 	// from the control repository.
@@ -157,7 +229,7 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 	if req.FullAudit {
 		all, err := uc.controlRepo.GetAllScripts()
 		if err != nil {
-			return nil, err
+			return nil, run, err
 		}
 		for _, sc := range all {
 			scriptsMap[sc.ID] = sc
@@ -168,7 +240,7 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 		for _, cid := range req.ControlIDs {
 			s, err := uc.controlRepo.GetControlScripts(cid)
 			if err != nil {
-				return nil, err
+				return nil, run, err
 			}
 			for _, sc := range s {
 				scriptsMap[sc.ID] = sc
@@ -179,7 +251,7 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 	if !req.FullAudit && len(req.ScriptIDs) > 0 {
 		s, err := uc.controlRepo.GetScriptsByIDs(req.ScriptIDs)
 		if err != nil {
-			return nil, err
+			return nil, run, err
 		}
 		for _, sc := range s {
 			scriptsMap[sc.ID] = sc
@@ -188,32 +260,25 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 
 	// Si no hubo scripts obtenidos, devolver error
 	if len(scriptsMap) == 0 {
-		return nil, fmt.Errorf("no scripts found for given control_ids or script_ids")
+		return nil, run, fmt.Errorf("no scripts found for given control_ids or script_ids")
 	}
 
 	// Conectar a SQL Server usando la conexión activa
-	// Desencriptar contraseña si está cifrada (fallback: usarla tal cual)
-	password := conn.Password
-	if uc.encryptSvc != nil {
-		if dec, derr := uc.encryptSvc.Decrypt(conn.Password); derr == nil && dec != "" {
-			password = dec
-		}
+	// Resolver la contraseña en el almacén de secretos. Un fallo es un error:
+	// nunca se envía el texto cifrado al servidor como si fuera la contraseña.
+	password, err := uc.secrets.Get(ctx, conn.Password)
+	if err != nil {
+		return nil, run, fmt.Errorf("failed to read stored password: %w", err)
 	}
 
-	cfg := services.SQLServerConfig{
-		Driver:   conn.Driver,
-		Server:   conn.Server,
-		Port:     "1433",
-		User:     conn.DBUser,
-		Password: password,
-		Database: req.Database,
-		Options:  map[string]string{"TrustServerCertificate": "true"},
-	}
+	cfg := services.NewSQLServerConfig(conn, password, req.Database)
 
 	db, err := uc.sqlService.Connect(ctx, cfg)
 	if err != nil {
-		return nil, err
+		return nil, run, err
 	}
+	// mark the connection as used so the health monitor does not expire it
+	_ = uc.connRepo.TouchActive(conn.ID, time.Now())
 
 	// Ejecutar scripts y recolectar resultados
 	res := &AuditResult{}
@@ -323,11 +388,30 @@ func (uc *ExecuteAuditUseCase) Execute(ctx context.Context, userID uint, manager
 		res.AuditRunID = run.ID
 	}
 
-	return res, nil
+	return res, run, nil
 }
 
-// GetAuditRun fetches an audit run and its script results if the user has access
+// GetAuditRun fetches an audit run and its script results if the user has access.
+// Reading the results is recorded as a report download.
 func (uc *ExecuteAuditUseCase) GetAuditRun(ctx context.Context, userID uint, auditID uint) (*AuditResult, *entities.AuditRun, error) {
+	res, run, err := uc.getAuditRun(userID, auditID)
+	if uc.activity != nil {
+		ev := &entities.ActivityEvent{Action: entities.ActivityReportDownload, ActorID: &userID, ResourceType: "audit_run", ResourceID: fmt.Sprint(auditID)}
+		switch {
+		case errors.Is(err, services.ErrResourceAccessDenied) || errors.Is(err, ErrAuditRunForbidden):
+			ev.Outcome = entities.OutcomeDenied
+		case err != nil:
+			ev.Outcome = entities.OutcomeFailure
+			ev.Details = entities.ActivityDetails(map[string]interface{}{"error": err.Error()})
+		default:
+			ev.Details = entities.ActivityDetails(map[string]interface{}{"server": run.Server, "database": run.Database, "total": res.Total})
+		}
+		uc.activity.Record(ctx, ev)
+	}
+	return res, run, err
+}
+
+func (uc *ExecuteAuditUseCase) getAuditRun(userID uint, auditID uint) (*AuditResult, *entities.AuditRun, error) {
 	if uc.auditRepo == nil {
 		return nil, nil, fmt.Errorf("audit repository not configured")
 	}
@@ -339,7 +423,15 @@ func (uc *ExecuteAuditUseCase) GetAuditRun(ctx context.Context, userID uint, aud
 
 	// ensure the requesting user is owner (simple authorization)
 	if run.UserID != userID {
-		return nil, nil, fmt.Errorf("forbidden")
+		return nil, nil, ErrAuditRunForbidden
+	}
+	// and still allowed to view results for this server and database
+	ok, err := uc.allowed(userID, entities.PermAuditsView, run.Server, run.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, services.ErrResourceAccessDenied
 	}
 
 	// load script results
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/encryption"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/secrets"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
@@ -84,6 +85,8 @@ func TestExecuteAudit_usesDecryptedPasswordAndLatestConnection(t *testing.T) {
 	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(nil, nil)
 	mconn.On("ListActiveByUser", uint(6)).Return([]*entities.ActiveConnection{conn}, nil)
 
+	mconn.On("TouchActive", uint(1), mock.Anything).Return(nil)
+
 	msql := &mocks.MockSQLServerService{}
 	// Connect should be called with decrypted password
 	msql.On("Connect", mock.Anything, mock.MatchedBy(func(cfg services.SQLServerConfig) bool {
@@ -96,7 +99,7 @@ func TestExecuteAudit_usesDecryptedPasswordAndLatestConnection(t *testing.T) {
 
 	aud := &fakeAuditRepo{}
 
-	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, mq, mconn, aud, enc)
+	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, mq, mconn, aud, secrets.NewDBStore(enc))
 
 	res, err := uc.Execute(context.Background(), 6, "mssql", AuditRequest{ScriptIDs: []uint{1}, Database: "master"})
 	assert.NoError(t, err)
@@ -112,6 +115,8 @@ func TestExecuteAudit_usesDecryptedPasswordAndLatestConnection(t *testing.T) {
 func TestExecuteAudit_fullAudit_executesAllScripts(t *testing.T) {
 	// prepare encryption
 	enc := encryption.NewAESGCMService("your-32-byte-encryption-key-here")
+	encrypted, err := enc.Encrypt("plain")
+	assert.NoError(t, err)
 
 	// prepare active connection for user 6
 	conn := &entities.ActiveConnection{
@@ -120,7 +125,7 @@ func TestExecuteAudit_fullAudit_executesAllScripts(t *testing.T) {
 		Driver:        "mssql",
 		Server:        "host.docker.internal",
 		DBUser:        "sa",
-		Password:      "plain",
+		Password:      encrypted,
 		IsConnected:   true,
 		LastConnected: time.Now(),
 	}
@@ -128,6 +133,8 @@ func TestExecuteAudit_fullAudit_executesAllScripts(t *testing.T) {
 	mconn := &mocks.MockConnectionRepository{}
 	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(conn, nil)
 
+	mconn.On("TouchActive", uint(1), mock.Anything).Return(nil)
+
 	msql := &mocks.MockSQLServerService{}
 	msql.On("Connect", mock.Anything, mock.Anything).Return((*sql.DB)(nil), nil)
 	msql.On("ExecuteQuery", mock.Anything, (*sql.DB)(nil), "SELECT 1").Return(true, nil)
@@ -137,7 +144,7 @@ func TestExecuteAudit_fullAudit_executesAllScripts(t *testing.T) {
 
 	aud := &fakeAuditRepo{}
 
-	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, mq, mconn, aud, enc)
+	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, mq, mconn, aud, secrets.NewDBStore(enc))
 
 	res, err := uc.Execute(context.Background(), 6, "mssql", AuditRequest{FullAudit: true, Database: "master"})
 	assert.NoError(t, err)
@@ -160,6 +167,8 @@ func TestExecuteAudit_fullAudit_executesAllScripts(t *testing.T) {
 func TestExecuteAudit_manualScripts_areMarkedPassed(t *testing.T) {
 	// prepare encryption
 	enc := encryption.NewAESGCMService("your-32-byte-encryption-key-here")
+	encrypted, err := enc.Encrypt("plain")
+	assert.NoError(t, err)
 
 	// prepare active connection for user 6
 	conn := &entities.ActiveConnection{
@@ -168,7 +177,7 @@ func TestExecuteAudit_manualScripts_areMarkedPassed(t *testing.T) {
 		Driver:        "mssql",
 		Server:        "host.docker.internal",
 		DBUser:        "sa",
-		Password:      "plain",
+		Password:      encrypted,
 		IsConnected:   true,
 		LastConnected: time.Now(),
 	}
@@ -178,6 +187,8 @@ func TestExecuteAudit_manualScripts_areMarkedPassed(t *testing.T) {
 
 	// make control repo return one manual and one automatic (see manualRepo implementation)
 
+	mconn.On("TouchActive", uint(1), mock.Anything).Return(nil)
+
 	msql := &mocks.MockSQLServerService{}
 	msql.On("Connect", mock.Anything, mock.Anything).Return((*sql.DB)(nil), nil)
 	msql.On("ExecuteQuery", mock.Anything, (*sql.DB)(nil), "SELECT 1").Return(true, nil)
@@ -187,7 +198,7 @@ func TestExecuteAudit_manualScripts_areMarkedPassed(t *testing.T) {
 
 	aud := &fakeAuditRepo{}
 
-	uc := NewExecuteAuditUseCase(&manualRepo{}, msql, mq, mconn, aud, enc)
+	uc := NewExecuteAuditUseCase(&manualRepo{}, msql, mq, mconn, aud, secrets.NewDBStore(enc))
 
 	// Request with both control IDs (one manual) and script IDs
 	req := AuditRequest{ControlIDs: []uint{100}, ScriptIDs: []uint{1}, Database: "master"}
@@ -201,3 +212,125 @@ func TestExecuteAudit_manualScripts_areMarkedPassed(t *testing.T) {
 	assert.Equal(t, 2, res.Passed)
 	assert.Equal(t, 1, res.Manual)
 }
+
+func TestExecuteAudit_reusesStoredPortAndTLSSettings(t *testing.T) {
+	enc := encryption.NewAESGCMService("your-32-byte-encryption-key-here")
+	encrypted, err := enc.Encrypt("secret")
+	assert.NoError(t, err)
+
+	conn := &entities.ActiveConnection{
+		ID:                    1,
+		UserID:                6,
+		Manager:               "mssql",
+		Driver:                "mssql",
+		Server:                "sql.internal",
+		DBUser:                "sa",
+		Password:              encrypted,
+		IsConnected:           true,
+		LastConnected:         time.Now(),
+		Port:                  "14330",
+		Encrypt:               "true",
+		ValidateCertificate:   true,
+		HostNameInCertificate: "sql.example.com",
+		AppName:               "ci-audits",
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(conn, nil)
+
+	mconn.On("TouchActive", uint(1), mock.Anything).Return(nil)
+
+	msql := &mocks.MockSQLServerService{}
+	msql.On("Connect", mock.Anything, mock.MatchedBy(func(cfg services.SQLServerConfig) bool {
+		return cfg.Port == "14330" &&
+			cfg.Encrypt == "true" &&
+			!cfg.TrustServerCertificate &&
+			cfg.HostNameInCertificate == "sql.example.com" &&
+			cfg.AppName == "ci-audits" &&
+			cfg.Database == "master"
+	})).Return((*sql.DB)(nil), nil)
+	msql.On("ExecuteQuery", mock.Anything, (*sql.DB)(nil), "SELECT 1").Return(true, nil)
+
+	mq := &mocks.MockQueryExecutor{}
+	mq.On("ValidateQuery", "SELECT 1").Return(nil)
+
+	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, mq, mconn, &fakeAuditRepo{}, secrets.NewDBStore(enc))
+
+	_, err = uc.Execute(context.Background(), 6, "mssql", AuditRequest{ScriptIDs: []uint{1}, Database: "master"})
+	assert.NoError(t, err)
+	msql.AssertExpectations(t)
+}
+
+func TestExecuteAudit_undecryptablePasswordIsAnError(t *testing.T) {
+	// ciphertext written with a key that is no longer configured
+	other := encryption.NewAESGCMService("another-32-byte-encryption-key!!")
+	encrypted, err := other.Encrypt("secret")
+	assert.NoError(t, err)
+
+	conn := &entities.ActiveConnection{
+		ID: 1, UserID: 6, Manager: "mssql", Driver: "mssql", Server: "host", DBUser: "sa",
+		Password: encrypted, IsConnected: true, LastConnected: time.Now(),
+	}
+
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(conn, nil)
+
+	msql := &mocks.MockSQLServerService{}
+	mq := &mocks.MockQueryExecutor{}
+	mq.On("ValidateQuery", "SELECT 1").Return(nil)
+
+	enc := encryption.NewAESGCMService("your-32-byte-encryption-key-here")
+	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, mq, mconn, &fakeAuditRepo{}, secrets.NewDBStore(enc))
+
+	_, err = uc.Execute(context.Background(), 6, "mssql", AuditRequest{ScriptIDs: []uint{1}, Database: "master"})
+	assert.Error(t, err)
+	// the ciphertext must never be sent to the server as the password
+	msql.AssertNotCalled(t, "Connect", mock.Anything, mock.Anything)
+}
+
+// staticAccess grants each permission on fixed scopes
+type staticAccess map[string]*entities.AccessScope
+
+func (a staticAccess) Scope(userID uint, permissions ...string) (*entities.AccessScope, error) {
+	out := &entities.AccessScope{}
+	for _, p := range permissions {
+		if s := a[p]; s != nil {
+			if s.Global {
+				return s, nil
+			}
+			out.Rules = append(out.Rules, s.Rules...)
+		}
+	}
+	return out, nil
+}
+
+func TestExecuteAudit_scopedPermissionsLimitServersAndDatabases(t *testing.T) {
+	conn := &entities.ActiveConnection{
+		ID: 1, UserID: 6, Manager: "mssql", Driver: "mssql", Server: "sql-dev-01", DBUser: "sa",
+		Password: "x", IsConnected: true, LastConnected: time.Now(),
+	}
+	mconn := &mocks.MockConnectionRepository{}
+	mconn.On("GetActiveByUserIDAndManager", uint(6), "mssql").Return(conn, nil)
+	msql := &mocks.MockSQLServerService{}
+	aud := &fakeAuditRepo{}
+	access := staticAccess{
+		entities.PermAuditsExecute: {Rules: []entities.ScopeRule{{ServerSets: [][]string{{"sql-prod-*"}}}}},
+		entities.PermAuditsView:    {Rules: []entities.ScopeRule{{Database: "sales"}}},
+	}
+	enc := encryption.NewAESGCMService("your-32-byte-encryption-key-here")
+	uc := NewExecuteAuditUseCase(&fakeControlRepo{}, msql, &mocks.MockQueryExecutor{}, mconn, aud, secrets.NewDBStore(enc)).WithAccess(access)
+
+	_, err := uc.Execute(context.Background(), 6, "mssql", AuditRequest{ScriptIDs: []uint{1}, Database: "master"})
+	assert.ErrorIs(t, err, services.ErrResourceAccessDenied)
+	msql.AssertNotCalled(t, "Connect", mock.Anything, mock.Anything)
+	// the refused attempt stays in the audit history
+	assert.Equal(t, "denied", aud.createdRun.Status)
+	assert.Equal(t, "sql-dev-01", aud.createdRun.Server)
+
+	aud.createdRun.Database = "hr"
+	_, _, err = uc.GetAuditRun(context.Background(), 6, 0)
+	assert.ErrorIs(t, err, services.ErrResourceAccessDenied)
+	aud.createdRun.Database = "sales"
+	_, _, err = uc.GetAuditRun(context.Background(), 6, 0)
+	assert.NoError(t, err)
+}
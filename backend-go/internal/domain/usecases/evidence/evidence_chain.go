@@ -0,0 +1,264 @@
+package evidence
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// ErrUnknownChain: la cadena pedida no existe
+var ErrUnknownChain = errors.New("unknown evidence chain")
+
+// Estado de una verificación: tampered si algo no cuadra; unverifiable si la
+// cadena cuadra pero hay checkpoints firmados con claves que no se conocen
+const (
+	EvidenceOK           = "ok"
+	EvidenceTampered     = "tampered"
+	EvidenceUnverifiable = "unverifiable"
+)
+
+// verifyBatch es cuántos eslabones se leen de cada vez al verificar
+const verifyBatch = 500
+
+// BrokenLink es el primer punto donde la cadena deja de cuadrar
+type BrokenLink struct {
+	Seq          uint64 `json:"seq"`
+	RecordID     uint   `json:"record_id,omitempty"`
+	CheckpointID uint   `json:"checkpoint_id,omitempty"`
+	Reason       string `json:"reason"`
+}
+
+// UnverifiableCheckpoint es un checkpoint firmado con una clave desconocida
+type UnverifiableCheckpoint struct {
+	CheckpointID uint   `json:"checkpoint_id"`
+	Seq          uint64 `json:"seq"`
+	KeyID        string `json:"key_id"`
+}
+
+// ChainReport es el resultado de verificar una cadena
+type ChainReport struct {
+	Chain    string `json:"chain"`
+	OK       bool   `json:"ok"`
+	Status   string `json:"status"`
+	Records  int    `json:"records"`
+	LastSeq  uint64 `json:"last_seq"`
+	LastHash string `json:"last_hash,omitempty"`
+	// Unsealed son registros fuera de la cadena: anteriores a ella, auditorías
+	// en curso o sellados pendientes; no están protegidos hasta el próximo sellado
+	Unsealed       int64                     `json:"unsealed"`
+	Checkpoints    int                       `json:"checkpoints"`
+	LastCheckpoint *entities.ChainCheckpoint `json:"last_checkpoint,omitempty"`
+	FirstBroken    *BrokenLink               `json:"first_broken,omitempty"`
+	// Unverifiable no cuentan como manipulación, pero tampoco protegen la cadena
+	Unverifiable []UnverifiableCheckpoint `json:"unverifiable,omitempty"`
+}
+
+// VerifyReport reúne las cadenas verificadas
+type VerifyReport struct {
+	OK        bool          `json:"ok"`
+	Status    string        `json:"status"`
+	KeyID     string        `json:"key_id"`
+	PublicKey string        `json:"public_key"`
+	Chains    []ChainReport `json:"chains"`
+}
+
+// EvidenceChainUseCase verifica las cadenas de hashes de AdminActionLog,
+// AuditRun y AuditScriptResult y firma checkpoints periódicos de su último eslabón
+type EvidenceChainUseCase struct {
+	repo     repositories.EvidenceChainRepository
+	signer   services.EvidenceSigner
+	interval time.Duration
+	now      func() time.Time
+	logger   *zap.Logger
+}
+
+func NewEvidenceChainUseCase(repo repositories.EvidenceChainRepository, signer services.EvidenceSigner, interval time.Duration) *EvidenceChainUseCase {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &EvidenceChainUseCase{repo: repo, signer: signer, interval: interval, now: time.Now, logger: zap.NewNop()}
+}
+
+// WithLogger registra los checkpoints periódicos que fallan
+func (uc *EvidenceChainUseCase) WithLogger(l *zap.Logger) *EvidenceChainUseCase {
+	uc.logger = l
+	return uc
+}
+
+// Run sella lo pendiente y firma un checkpoint cada intervalo hasta que ctx se cancele
+func (uc *EvidenceChainUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := uc.Checkpoint(); err != nil {
+				uc.logger.Error("evidence checkpoint failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Checkpoint encadena los registros pendientes y firma el último eslabón de
+// cada cadena que haya avanzado desde su último checkpoint
+func (uc *EvidenceChainUseCase) Checkpoint() ([]entities.ChainCheckpoint, error) {
+	var out []entities.ChainCheckpoint
+	for _, chain := range entities.EvidenceChains {
+		if _, err := uc.repo.SealPending(chain); err != nil {
+			return out, fmt.Errorf("seal %s: %w", chain, err)
+		}
+		seq, hash, err := uc.repo.LastLink(chain)
+		if err != nil {
+			return out, err
+		}
+		if seq == 0 {
+			continue
+		}
+		existing, err := uc.repo.ListCheckpoints(chain)
+		if err != nil {
+			return out, err
+		}
+		if n := len(existing); n > 0 && existing[n-1].Seq == seq && existing[n-1].Hash == hash {
+			continue
+		}
+		cp := entities.ChainCheckpoint{Chain: chain, Seq: seq, Hash: hash, KeyID: uc.signer.KeyID(), CreatedAt: uc.now().UTC().Truncate(time.Second)}
+		sig, err := uc.signer.Sign(cp.SignedPayload())
+		if err != nil {
+			return out, err
+		}
+		cp.Signature = base64.StdEncoding.EncodeToString(sig)
+		if err := uc.repo.CreateCheckpoint(&cp); err != nil {
+			return out, err
+		}
+		out = append(out, cp)
+	}
+	return out, nil
+}
+
+// Verify comprueba una cadena ("" = todas) y devuelve el primer eslabón roto de cada una
+func (uc *EvidenceChainUseCase) Verify(chain string) (*VerifyReport, error) {
+	chains := entities.EvidenceChains
+	if chain != "" {
+		known := false
+		for _, c := range entities.EvidenceChains {
+			known = known || c == chain
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownChain, chain)
+		}
+		chains = []string{chain}
+	}
+	out := &VerifyReport{Status: EvidenceOK, KeyID: uc.signer.KeyID(), PublicKey: uc.signer.PublicKey()}
+	for _, c := range chains {
+		rep, err := uc.verifyChain(c)
+		if err != nil {
+			return nil, err
+		}
+		if rep.Status == EvidenceTampered || out.Status == EvidenceOK {
+			out.Status = rep.Status
+		}
+		out.Chains = append(out.Chains, *rep)
+	}
+	out.OK = out.Status == EvidenceOK
+	return out, nil
+}
+
+func (uc *EvidenceChainUseCase) verifyChain(chain string) (*ChainReport, error) {
+	rep := &ChainReport{Chain: chain}
+	checkpoints, err := uc.repo.ListCheckpoints(chain)
+	if err != nil {
+		return nil, err
+	}
+	rep.Checkpoints = len(checkpoints)
+	if n := len(checkpoints); n > 0 {
+		rep.LastCheckpoint = &checkpoints[n-1]
+	}
+	if rep.Unsealed, err = uc.repo.CountUnsealed(chain); err != nil {
+		return nil, err
+	}
+	// hashes of the sequences a checkpoint refers to
+	wanted := map[uint64]string{}
+	for _, cp := range checkpoints {
+		wanted[cp.Seq] = ""
+	}
+
+	var prevHash string
+	var expect uint64 = 1
+walk:
+	for {
+		links, err := uc.repo.ListLinks(chain, expect-1, verifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			switch {
+			case l.ChainSeq != expect:
+				rep.FirstBroken = &BrokenLink{Seq: expect, Reason: fmt.Sprintf("record with chain_seq %d is missing (deleted)", expect)}
+			case l.PrevHash != prevHash:
+				rep.FirstBroken = &BrokenLink{Seq: l.ChainSeq, RecordID: l.RecordID, Reason: "prev_hash does not match the previous record (records removed, inserted or reordered)"}
+			case entities.ChainHash(chain, l.ChainSeq, l.PrevHash, l.Content) != l.Hash:
+				rep.FirstBroken = &BrokenLink{Seq: l.ChainSeq, RecordID: l.RecordID, Reason: "record content does not match its hash (modified)"}
+			}
+			if rep.FirstBroken != nil {
+				break walk
+			}
+			if _, ok := wanted[l.ChainSeq]; ok {
+				wanted[l.ChainSeq] = l.Hash
+			}
+			rep.Records++
+			rep.LastSeq, rep.LastHash, prevHash = l.ChainSeq, l.Hash, l.Hash
+			expect++
+		}
+		if len(links) < verifyBatch {
+			break
+		}
+	}
+
+	// a checkpoint before the first broken link catches what the walk cannot:
+	// records deleted from the end of the chain or a rewritten chain
+	for i := range checkpoints {
+		cp := &checkpoints[i]
+		if rep.FirstBroken != nil && cp.Seq >= rep.FirstBroken.Seq {
+			break
+		}
+		if !uc.signer.Knows(cp.KeyID) {
+			// a retired key that is no longer trusted: nothing to check it against
+			rep.Unverifiable = append(rep.Unverifiable, UnverifiableCheckpoint{CheckpointID: cp.ID, Seq: cp.Seq, KeyID: cp.KeyID})
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+		var issue string
+		switch {
+		case err != nil || !uc.signer.Verify(cp.KeyID, cp.SignedPayload(), sig):
+			issue = fmt.Sprintf("checkpoint signature is invalid (key %s)", cp.KeyID)
+		case cp.Seq > rep.LastSeq:
+			issue = fmt.Sprintf("chain ends at seq %d but a checkpoint covers seq %d (records deleted)", rep.LastSeq, cp.Seq)
+		case wanted[cp.Seq] != cp.Hash:
+			issue = "record hash differs from the signed checkpoint (chain rewritten)"
+		}
+		if issue != "" {
+			rep.FirstBroken = &BrokenLink{Seq: cp.Seq, CheckpointID: cp.ID, Reason: issue}
+			break
+		}
+	}
+	switch {
+	case rep.FirstBroken != nil:
+		rep.Status = EvidenceTampered
+	case len(rep.Unverifiable) > 0:
+		rep.Status = EvidenceUnverifiable
+	default:
+		rep.Status = EvidenceOK
+	}
+	rep.OK = rep.Status == EvidenceOK
+	return rep, nil
+}
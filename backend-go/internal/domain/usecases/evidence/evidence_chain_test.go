@@ -0,0 +1,180 @@
+package evidence
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestEvidenceChain_detectsEditsDeletionsAndTruncation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.AdminActionLog{}, &entities.AuditRun{}, &entities.AuditScriptResult{}, &entities.ChainCheckpoint{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// a record written before the chain existed is sealed by the next checkpoint
+	db.Create(&entities.AdminActionLog{ActorName: "legacy", Action: "role.create", TargetType: "role"})
+
+	logs := repositories.NewGormAdminAuditRepository(db)
+	for _, action := range []string{"role.assign", "role.revoke", "permission.delete"} {
+		if err := logs.Create(&entities.AdminActionLog{ActorID: 1, ActorName: "admin", Action: action, TargetType: "role"}); err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+	audits := repositories.NewGormAuditRepository(db)
+	run := &entities.AuditRun{UserID: 1, Mode: "partial", Status: "running"}
+	audits.CreateAuditRun(run)
+	audits.CreateScriptResult(&entities.AuditScriptResult{AuditRunID: run.ID, ScriptID: 1, ControlID: 1, QuerySQL: "SELECT 1", Passed: true})
+	running := &entities.AuditRun{UserID: 2, Mode: "full", Status: "running"}
+	audits.CreateAuditRun(running)
+	now := time.Now()
+	run.Status, run.Passed, run.Total, run.FinishedAt = "completed", 1, 1, &now
+	if err := audits.UpdateAuditRun(run); err != nil {
+		t.Fatalf("finish run: %v", err)
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	uc := NewEvidenceChainUseCase(repositories.NewGormEvidenceChainRepository(db), security.NewEvidenceSigner("test", ed25519.NewKeyFromSeed(seed)), time.Hour)
+	created, err := uc.Checkpoint()
+	if err != nil || len(created) != 3 {
+		t.Fatalf("expected a checkpoint per chain, got %+v %v", created, err)
+	}
+	if again, _ := uc.Checkpoint(); len(again) != 0 {
+		t.Fatalf("expected no checkpoint when nothing changed, got %+v", again)
+	}
+	report, err := uc.Verify("")
+	if err != nil || !report.OK {
+		t.Fatalf("expected intact chains, got %+v %v", report, err)
+	}
+	for _, c := range report.Chains {
+		if c.Chain == entities.ChainAdminActions && c.Records != 4 {
+			t.Fatalf("expected the legacy log to be sealed too, got %+v", c)
+		}
+		if c.Chain == entities.ChainAuditRuns && (c.Records != 1 || c.Unsealed != 1) {
+			t.Fatalf("expected the running audit to stay outside the chain, got %+v", c)
+		}
+	}
+
+	// an edited row breaks its own hash
+	var target entities.AdminActionLog
+	db.Where("action = ?", "role.revoke").First(&target)
+	db.Exec("UPDATE admin_action_logs SET details = ? WHERE id = ?", "nothing to see", target.ID)
+	report, _ = uc.Verify(entities.ChainAdminActions)
+	if broken := report.Chains[0].FirstBroken; report.OK || broken == nil || broken.RecordID != target.ID || !strings.Contains(broken.Reason, "modified") {
+		t.Fatalf("expected the edited record to be reported, got %+v", report.Chains[0])
+	}
+	db.Exec("UPDATE admin_action_logs SET details = '' WHERE id = ?", target.ID)
+
+	// a deleted row in the middle leaves a gap
+	db.Delete(&entities.AdminActionLog{}, target.ID)
+	report, _ = uc.Verify(entities.ChainAdminActions)
+	if broken := report.Chains[0].FirstBroken; broken == nil || broken.Seq != target.ChainSeq || !strings.Contains(broken.Reason, "missing") {
+		t.Fatalf("expected the deleted record to be reported, got %+v", report.Chains[0])
+	}
+
+	// deleting the newest script result can only be caught by the signed checkpoint
+	db.Exec("DELETE FROM audit_script_results")
+	report, _ = uc.Verify(entities.ChainAuditResults)
+	if broken := report.Chains[0].FirstBroken; broken == nil || broken.CheckpointID == 0 || !strings.Contains(broken.Reason, "deleted") {
+		t.Fatalf("expected the truncated chain to be caught by its checkpoint, got %+v", report.Chains[0])
+	}
+
+	// a forged checkpoint does not verify
+	db.Exec("UPDATE chain_checkpoints SET hash = ? WHERE chain = ?", strings.Repeat("0", 64), entities.ChainAuditRuns)
+	report, _ = uc.Verify(entities.ChainAuditRuns)
+	if broken := report.Chains[0].FirstBroken; broken == nil || !strings.Contains(broken.Reason, "signature") {
+		t.Fatalf("expected the forged checkpoint to be rejected, got %+v", report.Chains[0])
+	}
+
+	if _, err := uc.Verify("users"); err == nil {
+		t.Fatalf("expected an unknown chain to be rejected")
+	}
+}
+
+func TestEvidenceChain_checkpointsOfUntrustedKeysAreUnverifiable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.AdminActionLog{}, &entities.AuditRun{}, &entities.AuditScriptResult{}, &entities.ChainCheckpoint{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repositories.NewGormAdminAuditRepository(db).Create(&entities.AdminActionLog{ActorID: 1, ActorName: "admin", Action: "role.assign", TargetType: "role"})
+	chains := repositories.NewGormEvidenceChainRepository(db)
+	old := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	if _, err := NewEvidenceChainUseCase(chains, security.NewEvidenceSigner("old", old), time.Hour).Checkpoint(); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+
+	// the key was rotated and the old one is not trusted
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	signer := security.NewEvidenceSigner("new", ed25519.NewKeyFromSeed(seed))
+	uc := NewEvidenceChainUseCase(chains, signer, time.Hour)
+	report, _ := uc.Verify(entities.ChainAdminActions)
+	c := report.Chains[0]
+	if report.OK || report.Status != EvidenceUnverifiable || c.FirstBroken != nil || len(c.Unverifiable) != 1 || c.Unverifiable[0].KeyID != "old" {
+		t.Fatalf("expected the old checkpoint to be unverifiable, not tampered, got %+v", c)
+	}
+
+	if err := signer.Trust("old", old.Public()); err != nil {
+		t.Fatalf("trust: %v", err)
+	}
+	if report, _ = uc.Verify(entities.ChainAdminActions); !report.OK || report.Status != EvidenceOK {
+		t.Fatalf("expected a trusted key to verify its checkpoints, got %+v", report.Chains[0])
+	}
+
+	// a checkpoint claiming a trusted key it was not signed with is tampering
+	db.Exec("UPDATE chain_checkpoints SET key_id = ?", "new")
+	if report, _ = uc.Verify(entities.ChainAdminActions); report.Status != EvidenceTampered {
+		t.Fatalf("expected a bad signature to be tampering, got %+v", report.Chains[0])
+	}
+}
+
+func TestEvidenceChain_ephemeralKeysAreUnverifiableAfterARestart(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.AdminActionLog{}, &entities.AuditRun{}, &entities.AuditScriptResult{}, &entities.ChainCheckpoint{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repositories.NewGormAdminAuditRepository(db).Create(&entities.AdminActionLog{ActorID: 1, ActorName: "admin", Action: "role.assign", TargetType: "role"})
+	chains := repositories.NewGormEvidenceChainRepository(db)
+
+	// no key file and the default JWT secret: development falls back to a key of this process
+	if _, err := security.LoadEvidenceSigner("", "", "", ""); !errors.Is(err, security.ErrNoEvidenceKey) {
+		t.Fatalf("expected ErrNoEvidenceKey, got %v", err)
+	}
+	first, err := security.NewEphemeralEvidenceSigner("")
+	if err != nil {
+		t.Fatalf("ephemeral signer: %v", err)
+	}
+	uc := NewEvidenceChainUseCase(chains, first, time.Hour)
+	if _, err := uc.Checkpoint(); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if report, _ := uc.Verify(entities.ChainAdminActions); report.Status != EvidenceOK {
+		t.Fatalf("expected the process to verify its own checkpoints, got %+v", report.Chains[0])
+	}
+
+	second, _ := security.NewEphemeralEvidenceSigner("")
+	if second.KeyID() == first.KeyID() {
+		t.Fatalf("expected a new key id after a restart")
+	}
+	report, _ := NewEvidenceChainUseCase(chains, second, time.Hour).Verify(entities.ChainAdminActions)
+	if report.Status != EvidenceUnverifiable {
+		t.Fatalf("expected checkpoints of a previous ephemeral key to be unverifiable, got %+v", report.Chains[0])
+	}
+}
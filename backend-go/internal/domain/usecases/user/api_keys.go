@@ -0,0 +1,221 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/randtoken"
+	"go.uber.org/zap"
+)
+
+// API key errors
+var (
+	ErrInvalidAPIKey      = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrAPIKeyScope        = errors.New("scope is not a permission of the user")
+	ErrAPIKeyNoScopes     = errors.New("at least one scope is required")
+	ErrAPIKeyTTLTooLong   = errors.New("api key expiry exceeds the allowed maximum")
+	ErrAPIKeyNameRequired = errors.New("api key name is required")
+)
+
+const (
+	// APIKeyPrefix identifica las claves en logs y escáneres de secretos
+	APIKeyPrefix = "msk_"
+	// apiKeyTouchInterval evita escribir last_used_at en cada petición
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyOptions limita la caducidad de las claves
+type APIKeyOptions struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// CreatedAPIKey lleva la clave en claro; solo se muestra al crearla
+type CreatedAPIKey struct {
+	Key    *entities.APIKey
+	Secret string
+}
+
+// APIKeyPrincipal es el usuario autenticado con una clave y los permisos
+// efectivos de la clave (los scopes que el usuario sigue teniendo)
+type APIKeyPrincipal struct {
+	KeyID  uint
+	User   *entities.User
+	Scopes []string
+}
+
+// APIKeysUseCase gestiona las claves personales para automatizaciones. Una clave
+// solo puede restringirse a permisos que el usuario tiene, y deja de valer para
+// los permisos que el usuario pierda después.
+type APIKeysUseCase struct {
+	keys   repositories.APIKeyRepository
+	users  repositories.UserRepository
+	roles  repositories.RoleRepository
+	opts   APIKeyOptions
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+func NewAPIKeysUseCase(kr repositories.APIKeyRepository, ur repositories.UserRepository, rr repositories.RoleRepository, opts APIKeyOptions) *APIKeysUseCase {
+	if opts.DefaultTTL <= 0 {
+		opts.DefaultTTL = 90 * 24 * time.Hour
+	}
+	if opts.MaxTTL > 0 && opts.DefaultTTL > opts.MaxTTL {
+		opts.DefaultTTL = opts.MaxTTL
+	}
+	return &APIKeysUseCase{keys: kr, users: ur, roles: rr, opts: opts, now: time.Now, logger: zap.NewNop()}
+}
+
+// WithLogger registra los fallos que no impiden autenticar (last_used_at)
+func (uc *APIKeysUseCase) WithLogger(l *zap.Logger) *APIKeysUseCase {
+	uc.logger = l
+	return uc
+}
+
+// Create genera una clave con nombre, scopes y caducidad (ttl 0 = DefaultTTL)
+func (uc *APIKeysUseCase) Create(userID uint, name string, scopes []string, ttl time.Duration) (*CreatedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrAPIKeyNameRequired
+	}
+	if ttl <= 0 {
+		ttl = uc.opts.DefaultTTL
+	}
+	if uc.opts.MaxTTL > 0 && ttl > uc.opts.MaxTTL {
+		return nil, ErrAPIKeyTTLTooLong
+	}
+
+	user, err := uc.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := uc.permissions(user)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var clean []string
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		if !granted[s] {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyScope, s)
+		}
+		seen[s] = true
+		clean = append(clean, s)
+	}
+	if len(clean) == 0 {
+		return nil, ErrAPIKeyNoScopes
+	}
+	sort.Strings(clean)
+
+	random, err := randtoken.New(32)
+	if err != nil {
+		return nil, err
+	}
+	secret := APIKeyPrefix + random
+	k := &entities.APIKey{
+		UserID:    userID,
+		Name:      truncate(name, 100),
+		Prefix:    secret[:len(APIKeyPrefix)+8],
+		KeyHash:   HashRefreshToken(secret),
+		Scopes:    strings.Join(clean, ","),
+		ExpiresAt: uc.now().Add(ttl),
+	}
+	if err := uc.keys.Create(k); err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{Key: k, Secret: secret}, nil
+}
+
+// ListForUser devuelve las claves del usuario (también las revocadas y caducadas)
+func (uc *APIKeysUseCase) ListForUser(userID uint) ([]entities.APIKey, error) {
+	return uc.keys.List(&userID)
+}
+
+// List devuelve las claves de todos los usuarios, o de uno si userID no es nil
+func (uc *APIKeysUseCase) List(userID *uint) ([]entities.APIKey, error) {
+	return uc.keys.List(userID)
+}
+
+// RevokeForUser revoca una clave propia; las ajenas se tratan como inexistentes
+func (uc *APIKeysUseCase) RevokeForUser(userID, keyID uint) error {
+	k, err := uc.keys.GetByID(keyID)
+	if err != nil {
+		return err
+	}
+	if k == nil || k.UserID != userID || k.RevokedAt != nil {
+		return ErrAPIKeyNotFound
+	}
+	_, err = uc.keys.Revoke(k.ID, uc.now())
+	return err
+}
+
+// Revoke revoca cualquier clave (acción de administrador)
+func (uc *APIKeysUseCase) Revoke(keyID uint) (*entities.APIKey, error) {
+	k, err := uc.keys.GetByID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	ok, err := uc.keys.Revoke(k.ID, uc.now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return k, nil
+}
+
+// Authenticate valida una clave presentada en una petición y registra su uso
+func (uc *APIKeysUseCase) Authenticate(secret, ip string) (*APIKeyPrincipal, error) {
+	if !strings.HasPrefix(secret, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	k, err := uc.keys.GetByHash(HashRefreshToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	now := uc.now()
+	if k == nil || k.RevokedAt != nil || !now.Before(k.ExpiresAt) {
+		return nil, ErrInvalidAPIKey
+	}
+	user, err := uc.users.FindByID(k.UserID)
+	if err != nil || user == nil || !user.IsActive {
+		return nil, ErrInvalidAPIKey
+	}
+
+	granted, err := uc.permissions(user)
+	if err != nil {
+		return nil, err
+	}
+	p := &APIKeyPrincipal{KeyID: k.ID, User: user}
+	for _, s := range k.ScopeList() {
+		if granted[s] {
+			p.Scopes = append(p.Scopes, s)
+		}
+	}
+
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= apiKeyTouchInterval || k.LastUsedIP != ip {
+		if err := uc.keys.TouchLastUsed(k.ID, now, truncate(ip, 64)); err != nil {
+			uc.logger.Warn("failed to record use of API key", zap.Uint("key_id", k.ID), zap.Error(err))
+		}
+	}
+	return p, nil
+}
+
+// permissions reúne los permisos de los roles asignados al usuario y de su rol principal
+func (uc *APIKeysUseCase) permissions(user *entities.User) (map[string]bool, error) {
+	return effectivePermissions(uc.roles, user)
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestAPIKeys_scopesExpiryAndRevocation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.APIKey{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	execute := entities.Permission{Name: "audits:execute", Resource: "audits", Action: "execute"}
+	view := entities.Permission{Name: "audits:view", Resource: "audits", Action: "view"}
+	role := entities.Role{Name: "auditor", Permissions: []entities.Permission{execute, view}}
+	db.Create(&role)
+	u := &entities.User{Username: "ci", Email: "ci@e", Password: "x", Role: "user", IsActive: true}
+	db.Omit("last_login").Create(u)
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: role.ID})
+
+	uc := NewAPIKeysUseCase(repositories.NewGormAPIKeyRepository(db), persistence.NewUserRepository(db), repositories.NewGormRoleRepository(db), APIKeyOptions{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour})
+	now := time.Now()
+	uc.now = func() time.Time { return now }
+
+	if _, err := uc.Create(u.ID, "ci", []string{"roles:manage"}, 0); !errors.Is(err, ErrAPIKeyScope) {
+		t.Fatalf("expected a scope the user lacks to be rejected, got %v", err)
+	}
+	if _, err := uc.Create(u.ID, "ci", []string{"audits:view"}, 48*time.Hour); !errors.Is(err, ErrAPIKeyTTLTooLong) {
+		t.Fatalf("expected expiry above the maximum to be rejected, got %v", err)
+	}
+	created, err := uc.Create(u.ID, "ci", []string{"audits:view", "audits:execute", "audits:view"}, 0)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Key.Scopes != "audits:execute,audits:view" || !created.Key.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected key %+v", created.Key)
+	}
+	var stored entities.APIKey
+	db.First(&stored, created.Key.ID)
+	if stored.KeyHash == created.Secret || stored.KeyHash != HashRefreshToken(created.Secret) {
+		t.Fatalf("expected only the hash of the key to be stored")
+	}
+
+	p, err := uc.Authenticate(created.Secret, "10.0.0.1")
+	if err != nil || p.User.ID != u.ID || len(p.Scopes) != 2 {
+		t.Fatalf("authenticate: %+v %v", p, err)
+	}
+	db.First(&stored, created.Key.ID)
+	if stored.LastUsedAt == nil || stored.LastUsedIP != "10.0.0.1" {
+		t.Fatalf("expected last use to be recorded, got %+v", stored)
+	}
+	if _, err := uc.Authenticate(created.Secret+"x", ""); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected unknown key to fail, got %v", err)
+	}
+
+	// the key loses the permissions its owner loses
+	db.Model(&role).Association("Permissions").Delete(&role.Permissions[0])
+	if p, _ := uc.Authenticate(created.Secret, "10.0.0.1"); len(p.Scopes) != 1 || p.Scopes[0] != "audits:view" {
+		t.Fatalf("expected only audits:view to remain, got %v", p.Scopes)
+	}
+
+	if err := uc.RevokeForUser(u.ID+1, created.Key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected other users' keys to be hidden, got %v", err)
+	}
+	if err := uc.RevokeForUser(u.ID, created.Key.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := uc.Authenticate(created.Secret, ""); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected revoked key to fail, got %v", err)
+	}
+
+	other, _ := uc.Create(u.ID, "nightly", []string{"audits:view"}, 0)
+	now = now.Add(2 * time.Hour)
+	if _, err := uc.Authenticate(other.Secret, ""); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected expired key to fail, got %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+)
+
+// ErrUserNotFound: el usuario por el que se pregunta no existe
+var ErrUserNotFound = errors.New("user not found")
+
+// AuthzResource es el recurso de la pregunta: sin base de datos se comprueba a
+// nivel de servidor (conectar, historial), con ella a nivel de base de datos (auditorías)
+type AuthzResource struct {
+	Server   string `json:"server"`
+	Database string `json:"database,omitempty"`
+}
+
+// AuthzGrant es un camino por el que un usuario recibe un permiso: el rol
+// asignado, la cadena de herencia hasta el rol que tiene el permiso, la
+// ventana de la asignación y los scopes de ese permiso en el rol
+type AuthzGrant struct {
+	Permission   string `json:"permission"`
+	Role         string `json:"role"`
+	RoleID       uint   `json:"role_id"`
+	AssignedRole string `json:"assigned_role"`
+	// Chain va del rol asignado al rol que tiene el permiso
+	Chain      []string                   `json:"chain"`
+	Inherited  bool                       `json:"inherited"`
+	ValidFrom  *time.Time                 `json:"valid_from,omitempty"`
+	ValidUntil *time.Time                 `json:"valid_until,omitempty"`
+	Global     bool                       `json:"global"`
+	Scopes     []entities.PermissionScope `json:"scopes,omitempty"`
+	// Matches indica si cubre el recurso preguntado (solo si se pregunta por uno)
+	Matches *bool `json:"matches,omitempty"`
+}
+
+// AuthzDecision explica si un usuario tiene un permiso. InactiveGrants son los
+// caminos de asignaciones fuera de su ventana (vencidas o futuras), que no cuentan.
+type AuthzDecision struct {
+	UserID         uint           `json:"user_id"`
+	Username       string         `json:"username"`
+	UserActive     bool           `json:"user_active"`
+	Permission     string         `json:"permission"`
+	Resource       *AuthzResource `json:"resource,omitempty"`
+	Allowed        bool           `json:"allowed"`
+	Reason         string         `json:"reason"`
+	Grants         []AuthzGrant   `json:"grants"`
+	InactiveGrants []AuthzGrant   `json:"inactive_grants"`
+}
+
+// PermissionGrants es un permiso efectivo con los caminos que lo conceden
+type PermissionGrants struct {
+	Permission string       `json:"permission"`
+	Global     bool         `json:"global"`
+	Grants     []AuthzGrant `json:"grants"`
+}
+
+// UserCapabilities es lo que un usuario puede hacer: sus permisos efectivos
+// (los mismos que comprueba el middleware) con su origen
+type UserCapabilities struct {
+	UserID         uint               `json:"user_id"`
+	Username       string             `json:"username"`
+	UserActive     bool               `json:"user_active"`
+	Permissions    []PermissionGrants `json:"permissions"`
+	InactiveGrants []AuthzGrant       `json:"inactive_grants"`
+}
+
+// Explain resuelve un permiso como el middleware (y, con resource, como los
+// casos de uso que comprueban scopes) y devuelve la decisión con su origen
+func (uc *PermissionsUseCase) Explain(userID uint, permission string, resource *AuthzResource) (*AuthzDecision, error) {
+	user, err := uc.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	out := &AuthzDecision{UserID: user.ID, Username: user.Username, UserActive: user.IsActive, Permission: permission, Resource: resource}
+	out.Grants, out.InactiveGrants, err = uc.grants(user, permission)
+	if err != nil {
+		return nil, err
+	}
+	if resource != nil {
+		for i := range out.Grants {
+			g := &out.Grants[i]
+			scope := &entities.AccessScope{Global: g.Global}
+			for j := range g.Scopes {
+				scope.Rules = append(scope.Rules, g.Scopes[j].Rule())
+			}
+			ok := scope.AllowsServer(resource.Server)
+			if resource.Database != "" {
+				ok = scope.Allows(resource.Server, resource.Database)
+			}
+			g.Matches = &ok
+		}
+	}
+	switch {
+	case !user.IsActive:
+		out.Reason = "user is inactive: an inactive user has no permissions"
+	case len(out.Grants) == 0 && len(out.InactiveGrants) > 0:
+		out.Reason = fmt.Sprintf("not granted: only role %s grants %s and its assignment is outside its validity window", out.InactiveGrants[0].AssignedRole, permission)
+	case len(out.Grants) == 0:
+		out.Reason = fmt.Sprintf("no role assigned to the user grants %s", permission)
+	default:
+		for _, g := range out.Grants {
+			if g.Matches == nil || *g.Matches {
+				out.Allowed = true
+				out.Reason = "granted by role " + g.Role
+				if g.Inherited {
+					out.Reason += " (inherited through " + g.AssignedRole + ")"
+				}
+				if resource != nil && !g.Global {
+					out.Reason += " on a matching scope"
+				}
+				break
+			}
+		}
+		if !out.Allowed {
+			out.Reason = fmt.Sprintf("%s is granted only with scopes that do not cover this resource", permission)
+		}
+	}
+	return out, nil
+}
+
+// Capabilities lista los permisos efectivos del usuario con los roles, la
+// herencia, las ventanas y los scopes que los conceden
+func (uc *PermissionsUseCase) Capabilities(userID uint) (*UserCapabilities, error) {
+	user, err := uc.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	out := &UserCapabilities{UserID: user.ID, Username: user.Username, UserActive: user.IsActive, Permissions: []PermissionGrants{}}
+	active, inactive, err := uc.grants(user, "")
+	if err != nil {
+		return nil, err
+	}
+	out.InactiveGrants = inactive
+	if !user.IsActive {
+		return out, nil
+	}
+	byPerm := map[string]*PermissionGrants{}
+	for _, g := range active {
+		pg, ok := byPerm[g.Permission]
+		if !ok {
+			pg = &PermissionGrants{Permission: g.Permission}
+			byPerm[g.Permission] = pg
+		}
+		pg.Global = pg.Global || g.Global
+		pg.Grants = append(pg.Grants, g)
+	}
+	for _, name := range sortedKeys(byPerm) {
+		out.Permissions = append(out.Permissions, *byPerm[name])
+	}
+	return out, nil
+}
+
+// grants recorre las cadenas de roles del usuario (las mismas de Effective) y,
+// aparte, las de sus asignaciones fuera de ventana. permission "" = todos.
+func (uc *PermissionsUseCase) grants(user *entities.User, permission string) (active, inactive []AuthzGrant, err error) {
+	active, inactive = []AuthzGrant{}, []AuthzGrant{}
+	if !user.IsActive {
+		return active, inactive, nil
+	}
+	chains, err := userRoleChains(uc.roles, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignments, err := uc.roles.ListUserGrants(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	windows := map[uint]entities.UserRole{}
+	var dormant []entities.Role
+	now := time.Now()
+	for _, a := range assignments {
+		windows[a.RoleID] = a
+		if a.Active(now) {
+			continue
+		}
+		role, err := uc.roles.GetByID(a.RoleID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if role != nil {
+			dormant = append(dormant, *role)
+		}
+	}
+	dormantChains, err := roleChains(uc.roles, dormant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type rolePermission struct{ role, perm uint }
+	scoped := map[rolePermission][]entities.PermissionScope{}
+	if uc.scopes != nil {
+		var ids []uint
+		for _, list := range [][][]entities.Role{chains, dormantChains} {
+			for _, chain := range list {
+				for _, r := range chain {
+					ids = append(ids, r.ID)
+				}
+			}
+		}
+		if len(ids) > 0 {
+			list, err := uc.scopes.ListScopesByRoles(ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, sc := range list {
+				k := rolePermission{sc.RoleID, sc.PermissionID}
+				scoped[k] = append(scoped[k], sc)
+			}
+		}
+	}
+	walk := func(chains [][]entities.Role) []AuthzGrant {
+		out := []AuthzGrant{}
+		for _, chain := range chains {
+			window := windows[chain[0].ID]
+			names := make([]string, 0, len(chain))
+			for i, r := range chain {
+				names = append(names, r.Name)
+				for _, p := range r.Permissions {
+					if permission != "" && p.Name != permission {
+						continue
+					}
+					list := scoped[rolePermission{r.ID, p.ID}]
+					out = append(out, AuthzGrant{
+						Permission: p.Name, Role: r.Name, RoleID: r.ID, AssignedRole: chain[0].Name,
+						Chain: append([]string(nil), names...), Inherited: i > 0,
+						ValidFrom: window.ValidFrom, ValidUntil: window.ValidUntil,
+						Global: len(list) == 0, Scopes: list,
+					})
+				}
+			}
+		}
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Permission < out[j].Permission })
+		return out
+	}
+	return walk(chains), walk(dormantChains), nil
+}
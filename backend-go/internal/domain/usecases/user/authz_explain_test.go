@@ -0,0 +1,93 @@
+package user
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestPermissions_explainFollowsRolesScopesAndWindows(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.ServerGroup{}, &entities.PermissionScope{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	execute := entities.Permission{Name: entities.PermAuditsExecute, Resource: "audits", Action: "execute"}
+	view := entities.Permission{Name: entities.PermAuditsView, Resource: "audits", Action: "view"}
+	manage := entities.Permission{Name: entities.PermRolesManage, Resource: "roles", Action: "manage"}
+	db.Create(&execute)
+	db.Create(&view)
+	db.Create(&manage)
+	auditor := entities.Role{Name: "auditor", Permissions: []entities.Permission{execute, view}}
+	db.Create(&auditor)
+	lead := entities.Role{Name: "auditor-lead", ParentID: &auditor.ID}
+	oncall := entities.Role{Name: "oncall", Permissions: []entities.Permission{manage}}
+	db.Create(&lead)
+	db.Create(&oncall)
+	u := &entities.User{Username: "ana", Email: "ana@e", Password: "x", Role: "auditor-lead", IsActive: true}
+	db.Omit("last_login").Create(u)
+	expired := time.Now().Add(-time.Hour)
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: lead.ID})
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: oncall.ID, ValidUntil: &expired})
+
+	scopeRepo := repositories.NewGormResourceScopeRepository(db)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	perms := NewPermissionsUseCase(persistence.NewUserRepository(db), roleRepo, scopeRepo)
+	if _, _, err := NewResourceScopesUseCase(scopeRepo, roleRepo).SetScopes(auditor.ID, execute.ID, []ScopeInput{{Server: "sql-prod-*"}}); err != nil {
+		t.Fatalf("set scopes: %v", err)
+	}
+
+	d, err := perms.Explain(u.ID, entities.PermAuditsView, nil)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	if !d.Allowed || len(d.Grants) != 1 || !d.Grants[0].Inherited || strings.Join(d.Grants[0].Chain, ">") != "auditor-lead>auditor" {
+		t.Fatalf("expected audits:view inherited from auditor through auditor-lead, got %+v", d)
+	}
+
+	d, _ = perms.Explain(u.ID, entities.PermAuditsExecute, &AuthzResource{Server: "sql-dev-01"})
+	if d.Allowed || !strings.Contains(d.Reason, "scopes") || d.Grants[0].Matches == nil || *d.Grants[0].Matches {
+		t.Fatalf("expected execution outside the scope to be denied, got %+v", d)
+	}
+	if d, _ = perms.Explain(u.ID, entities.PermAuditsExecute, &AuthzResource{Server: "sql-prod-01", Database: "sales"}); !d.Allowed {
+		t.Fatalf("expected execution on a matching server, got %+v", d)
+	}
+
+	d, _ = perms.Explain(u.ID, entities.PermRolesManage, nil)
+	if d.Allowed || len(d.InactiveGrants) != 1 || d.InactiveGrants[0].ValidUntil == nil || !strings.Contains(d.Reason, "oncall") {
+		t.Fatalf("expected the expired oncall grant to explain the denial, got %+v", d)
+	}
+
+	// the capabilities are exactly what the middleware resolves
+	caps, err := perms.Capabilities(u.ID)
+	if err != nil {
+		t.Fatalf("capabilities: %v", err)
+	}
+	effective, _ := perms.Effective(u.ID)
+	if len(caps.Permissions) != len(effective) {
+		t.Fatalf("expected %d permissions, got %+v", len(effective), caps.Permissions)
+	}
+	for _, p := range caps.Permissions {
+		if !effective[p.Permission] {
+			t.Fatalf("capability %s is not an effective permission", p.Permission)
+		}
+		if p.Permission == entities.PermAuditsExecute && p.Global {
+			t.Fatalf("expected audits:execute to be reported as scoped")
+		}
+	}
+
+	u.IsActive = false
+	db.Save(u)
+	if d, _ = perms.Explain(u.ID, entities.PermAuditsView, nil); d.Allowed || !strings.Contains(d.Reason, "inactive") {
+		t.Fatalf("expected an inactive user to have nothing, got %+v", d)
+	}
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// DirectoryLoginUseCase autentica usuario y contraseña contra el directorio
+// corporativo (LDAP / Active Directory). El primer login da de alta al usuario
+// local y cada login sincroniza sus roles con los grupos del directorio.
+type DirectoryLoginUseCase struct {
+	dir      services.DirectoryAuthenticator
+	accounts *externalAccounts
+}
+
+func NewDirectoryLoginUseCase(dir services.DirectoryAuthenticator, sr repositories.SSORepository, ur repositories.UserRepository, rr repositories.RoleRepository, opts ExternalAccountOptions) *DirectoryLoginUseCase {
+	return &DirectoryLoginUseCase{
+		dir:      dir,
+		accounts: &externalAccounts{links: sr, users: ur, roles: rr, opts: opts, source: "ldap", now: time.Now, logger: zap.NewNop()},
+	}
+}
+
+// WithAssignments renueva los tokens de los usuarios cuyos roles cambian al entrar
+func (uc *DirectoryLoginUseCase) WithAssignments(a *RoleAssignmentsUseCase) *DirectoryLoginUseCase {
+	uc.accounts.assignments = a
+	return uc
+}
+
+// WithLogger registra los enlaces de identidades y los cambios de rol al entrar
+func (uc *DirectoryLoginUseCase) WithLogger(l *zap.Logger) *DirectoryLoginUseCase {
+	uc.accounts.logger = l
+	return uc
+}
+
+// Login devuelve el usuario local. services.ErrDirectoryUserNotFound indica que
+// el directorio no conoce al usuario y el login puede seguir con las cuentas locales.
+func (uc *DirectoryLoginUseCase) Login(ctx context.Context, username, password string) (*entities.User, error) {
+	id, err := uc.dir.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return uc.accounts.resolve(id)
+}
@@ -0,0 +1,324 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/randtoken"
+)
+
+// Errores del alta de usuarios externos (SSO y directorio)
+var (
+	ErrNoMappedRole         = errors.New("none of the user's groups is mapped to a role")
+	ErrExternalUserInactive = errors.New("user account is inactive")
+)
+
+// RoleMapping asigna un rol a los miembros de un grupo del proveedor de identidad
+type RoleMapping struct {
+	Group string
+	Role  string
+}
+
+// ParseRoleMappings lee "grupo=rol,grupo=rol". El orden es la prioridad: la
+// primera regla cuyo grupo tenga el usuario da su rol principal.
+func ParseRoleMappings(spec string) ([]RoleMapping, error) {
+	var out []RoleMapping
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid role mapping %q, expected group=role", entry)
+		}
+		out = append(out, RoleMapping{Group: strings.TrimSpace(kv[0]), Role: strings.TrimSpace(kv[1])})
+	}
+	return out, nil
+}
+
+// ExternalAccountOptions configura el alta y la asignación de roles de los
+// usuarios que se autentican fuera (OIDC, LDAP)
+type ExternalAccountOptions struct {
+	RoleMappings []RoleMapping
+	// DefaultRole se asigna si ningún grupo coincide; vacío rechaza el login
+	DefaultRole string
+	// LinkByEmail enlaza la identidad con un usuario local con el mismo email verificado
+	LinkByEmail bool
+	// PrivilegedRoles nunca se enlazan por email: un email del proveedor no basta
+	// para entrar en esas cuentas, que se crean aparte
+	PrivilegedRoles []string
+}
+
+// externalAccounts resuelve una identidad externa a un usuario local: lo busca
+// por el enlace emisor+sujeto, lo enlaza por email o lo crea, y sincroniza sus
+// roles con los grupos externos en cada login
+type externalAccounts struct {
+	links repositories.SSORepository
+	users repositories.UserRepository
+	roles repositories.RoleRepository
+	opts  ExternalAccountOptions
+	// source identifica el origen en los logs ("sso", "ldap")
+	source string
+	now    func() time.Time
+	// assignments renueva los tokens si cambian los roles (opcional)
+	assignments *RoleAssignmentsUseCase
+	logger      *zap.Logger
+}
+
+func (a *externalAccounts) resolve(id *services.ExternalIdentity) (*entities.User, error) {
+	primary, roles := a.MapRoles(id.Groups)
+	if primary == "" {
+		return nil, ErrNoMappedRole
+	}
+
+	link, err := a.links.GetIdentity(id.Issuer, id.Subject)
+	if err != nil {
+		return nil, err
+	}
+	var user *entities.User
+	if link != nil {
+		if user, err = a.users.FindByID(link.UserID); err != nil {
+			return nil, err
+		}
+	} else {
+		if a.opts.LinkByEmail && id.EmailVerified && id.Email != "" {
+			user, _ = a.users.FindByEmail(id.Email)
+			if user != nil {
+				privileged, err := a.privileged(user)
+				if err != nil {
+					return nil, err
+				}
+				if privileged {
+					user = nil
+				}
+			}
+		}
+		if user == nil {
+			if user, err = a.createUser(id, primary); err != nil {
+				return nil, err
+			}
+		}
+		link = &entities.UserIdentity{UserID: user.ID, Issuer: id.Issuer, Subject: id.Subject, Email: id.Email}
+		if err := a.links.CreateIdentity(link); err != nil {
+			return nil, err
+		}
+		a.logger.Info("external identity linked", zap.String("source", a.source), zap.String("issuer", id.Issuer), zap.String("subject", id.Subject), zap.Uint("user_id", user.ID))
+	}
+
+	if !user.IsActive {
+		return nil, ErrExternalUserInactive
+	}
+	if err := a.syncRoles(user, primary, roles); err != nil {
+		return nil, err
+	}
+	if err := a.links.TouchIdentity(link.ID, truncate(id.Email, 254), a.now()); err != nil {
+		a.logger.Warn("failed to record external login", zap.String("source", a.source), zap.Uint("identity_id", link.ID), zap.Error(err))
+	}
+	return user, nil
+}
+
+// privileged indica si el usuario tiene, como principal o asignado, un rol de
+// PrivilegedRoles o un rol que hereda de uno de ellos
+func (a *externalAccounts) privileged(user *entities.User) (bool, error) {
+	protected := map[string]bool{}
+	for _, r := range a.opts.PrivilegedRoles {
+		protected[r] = true
+	}
+	if protected[user.Role] {
+		return true, nil
+	}
+	roles, err := userRoles(a.roles, user)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if protected[r.Name] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MapRoles aplica las reglas grupo=rol. Devuelve el rol principal (primera regla
+// que coincide, o DefaultRole) y todos los roles que dan los grupos del usuario.
+// Un rol principal vacío significa que el usuario no tiene acceso.
+func (a *externalAccounts) MapRoles(groups []string) (string, []string) {
+	has := map[string]bool{}
+	for _, g := range groups {
+		has[g] = true
+	}
+	var roles []string
+	seen := map[string]bool{}
+	for _, m := range a.opts.RoleMappings {
+		if has[m.Group] && !seen[m.Role] {
+			seen[m.Role] = true
+			roles = append(roles, m.Role)
+		}
+	}
+	if len(roles) == 0 {
+		if a.opts.DefaultRole == "" {
+			return "", nil
+		}
+		roles = []string{a.opts.DefaultRole}
+	}
+	return roles[0], roles
+}
+
+// createUser da de alta al usuario con una contraseña aleatoria: solo entra por
+// su proveedor externo
+func (a *externalAccounts) createUser(id *services.ExternalIdentity, role string) (*entities.User, error) {
+	password, err := randtoken.New(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	email := id.Email
+	if email != "" {
+		// un email no verificado no puede quedarse con el de otra cuenta
+		if taken, _ := a.users.FindByEmail(email); taken != nil {
+			email = ""
+		}
+	}
+	if email == "" {
+		email = a.source + "-" + HashRefreshToken(id.Issuer + "|" + id.Subject)[:16] + "@users.invalid"
+	}
+	username, err := a.freeUsername(id)
+	if err != nil {
+		return nil, err
+	}
+	first, last, _ := strings.Cut(strings.TrimSpace(id.Name), " ")
+	u := &entities.User{
+		Username:  username,
+		Email:     truncate(email, 254),
+		Password:  string(hash),
+		FirstName: truncate(first, 150),
+		LastName:  truncate(strings.TrimSpace(last), 150),
+		Role:      role,
+		IsActive:  true,
+	}
+	if err := a.users.Create(u); err != nil {
+		return nil, fmt.Errorf("create %s user: %w", a.source, err)
+	}
+	return u, nil
+}
+
+// freeUsername deriva el nombre del usuario externo o de su email y le añade un
+// sufijo si ya existe
+func (a *externalAccounts) freeUsername(id *services.ExternalIdentity) (string, error) {
+	base := id.Username
+	if base == "" {
+		base, _, _ = strings.Cut(id.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, base)
+	if base == "" {
+		base = a.source + "-" + HashRefreshToken(id.Subject)[:8]
+	}
+	base = truncate(base, 140)
+	name := base
+	for i := 2; i < 100; i++ {
+		if u, _ := a.users.FindByUsername(name); u == nil {
+			return name, nil
+		}
+		name = base + "-" + strconv.Itoa(i)
+	}
+	suffix, err := randtoken.New(6)
+	if err != nil {
+		return "", err
+	}
+	return base + "-" + suffix, nil
+}
+
+// syncRoles deja el rol principal del usuario igual al que dan sus grupos y
+// refleja en user_roles todos los roles mapeados. Se retiran los roles que
+// proceden de las reglas y el usuario ya no tiene; los asignados a mano por un
+// administrador se conservan. Si algo cambia, las demás sesiones del usuario
+// tienen que renovar su token.
+func (a *externalAccounts) syncRoles(user *entities.User, primary string, roles []string) error {
+	changed := user.Role != primary
+	if changed {
+		old := user.Role
+		user.Role = primary
+		if err := a.users.Update(user); err != nil {
+			return err
+		}
+		a.logger.Info("primary role changed", zap.String("source", a.source), zap.Uint("user_id", user.ID), zap.String("from", old), zap.String("to", primary))
+	}
+
+	assigned, err := a.roles.GetUserRoles(user.ID)
+	if err != nil {
+		return err
+	}
+	want := map[string]bool{}
+	for _, r := range roles {
+		want[r] = true
+	}
+	has := map[string]bool{}
+	for _, r := range assigned {
+		has[r.Name] = true
+		if !want[r.Name] && a.managedRole(r.Name) {
+			if err := a.roles.RevokeFromUser(user.ID, r.ID); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+	for _, name := range roles {
+		if has[name] {
+			continue
+		}
+		r, err := a.roles.GetByName(name)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			a.logger.Warn("mapped role does not exist, skipping", zap.String("source", a.source), zap.String("role", name))
+			continue
+		}
+		if err := a.roles.AssignToUser(user.ID, r.ID); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed || a.assignments == nil {
+		return nil
+	}
+	if err := a.assignments.Changed(user.ID); err != nil {
+		return err
+	}
+	// el principal puede no existir como rol; manda lo que quede asignado
+	if u, _ := a.users.FindByID(user.ID); u != nil {
+		user.Role = u.Role
+	}
+	return nil
+}
+
+func (a *externalAccounts) managedRole(role string) bool {
+	if role == a.opts.DefaultRole {
+		return true
+	}
+	for _, m := range a.opts.RoleMappings {
+		if m.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// LockoutPolicy define los umbrales de protección contra fuerza bruta en el login
+type LockoutPolicy struct {
+	MaxUserFailures int           // fallos por usuario antes de bloquearlo (0 = sin bloqueo)
+	MaxIPFailures   int           // fallos por IP antes de bloquearla (0 = sin bloqueo)
+	FailureWindow   time.Duration // los fallos más antiguos que esto no cuentan
+	LockoutDuration time.Duration
+	BaseDelay       time.Duration // retardo tras el primer fallo; se duplica en cada fallo
+	MaxDelay        time.Duration
+}
+
+// LoginAttempt identifica un intento de login
+type LoginAttempt struct {
+	Username  string
+	UserID    *uint // nil si el usuario no existe
+	IPAddress string
+	UserAgent string
+}
+
+// LoginGuard cuenta los fallos de login por usuario y por IP, aplica retardos
+// progresivos y bloqueos temporales, y deja constancia en el log de auditoría de auth.
+// No duerme: el retardo se guarda y los intentos anteriores a él se rechazan, para
+// que el handler conteste 429 con Retry-After sin ocupar la petición.
+// Los usuarios se identifican por el nombre tecleado, exista o no, para que un
+// usuario inexistente y una cuenta bloqueada se comporten igual.
+type LoginGuard struct {
+	throttle repositories.LoginThrottleRepository
+	audit    repositories.AuthAuditRepository
+	activity services.ActivityRecorder
+	policy   LockoutPolicy
+	now      func() time.Time
+}
+
+func NewLoginGuard(tr repositories.LoginThrottleRepository, ar repositories.AuthAuditRepository, policy LockoutPolicy) *LoginGuard {
+	return &LoginGuard{
+		throttle: tr,
+		audit:    ar,
+		policy:   policy,
+		now:      time.Now,
+	}
+}
+
+// WithActivity copia los eventos de autenticación al log de actividad
+func (g *LoginGuard) WithActivity(r services.ActivityRecorder) *LoginGuard {
+	g.activity = r
+	return g
+}
+
+func userKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// RetryAfter devuelve cuánto falta para que el usuario o la IP del intento puedan
+// volver a intentarlo (cero si pueden ya): hasta el fin del bloqueo o del retardo
+// progresivo. Un intento rechazado se registra, pero no alarga la espera.
+// ctx es el de la petición: enlaza los eventos del log de actividad con ella.
+func (g *LoginGuard) RetryAfter(ctx context.Context, a LoginAttempt) (time.Duration, error) {
+	now := g.now()
+	for _, key := range []string{userKey(a.Username), ipKey(a.IPAddress)} {
+		t, err := g.throttle.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if t == nil {
+			continue
+		}
+		if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+			g.record(ctx, entities.AuthEventLoginBlocked, a, fmt.Sprintf("%s locked until %s", key, t.LockedUntil.UTC().Format(time.RFC3339)))
+			return t.LockedUntil.Sub(now), nil
+		}
+		if t.RetryAt != nil && now.Before(*t.RetryAt) {
+			g.record(ctx, entities.AuthEventLoginBlocked, a, fmt.Sprintf("%s delayed until %s", key, t.RetryAt.UTC().Format(time.RFC3339Nano)))
+			return t.RetryAt.Sub(now), nil
+		}
+	}
+	return 0, nil
+}
+
+// Failure registra un intento fallido, bloquea el usuario o la IP al superar el
+// umbral y devuelve el retardo progresivo antes del siguiente intento
+func (g *LoginGuard) Failure(ctx context.Context, a LoginAttempt) (time.Duration, error) {
+	now := g.now()
+	g.record(ctx, entities.AuthEventLoginFailure, a, "")
+
+	ut, err := g.throttle.RecordFailure(userKey(a.Username), now, g.policy.FailureWindow)
+	if err != nil {
+		return 0, err
+	}
+	it, err := g.throttle.RecordFailure(ipKey(a.IPAddress), now, g.policy.FailureWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	until := now.Add(g.policy.LockoutDuration)
+	if g.policy.MaxUserFailures > 0 && ut.Failures >= g.policy.MaxUserFailures {
+		if err := g.throttle.Lock(ut.Subject, until); err != nil {
+			return 0, err
+		}
+		g.record(ctx, entities.AuthEventAccountLocked, a, fmt.Sprintf("%d failed attempts; locked for %s", ut.Failures, g.policy.LockoutDuration))
+	}
+	if g.policy.MaxIPFailures > 0 && it.Failures >= g.policy.MaxIPFailures {
+		if err := g.throttle.Lock(it.Subject, until); err != nil {
+			return 0, err
+		}
+		g.record(ctx, entities.AuthEventIPLocked, a, fmt.Sprintf("%d failed attempts; locked for %s", it.Failures, g.policy.LockoutDuration))
+	}
+
+	// cada contador retrasa su propia clave: un usuario que se equivoca no frena
+	// al resto de su IP, pero probar muchos usuarios desde una IP sí la frena
+	var wait time.Duration
+	for _, t := range []*entities.LoginThrottle{ut, it} {
+		delay := g.delayFor(t.Failures)
+		if delay <= 0 {
+			continue
+		}
+		if err := g.throttle.Delay(t.Subject, now.Add(delay)); err != nil {
+			return 0, err
+		}
+		if delay > wait {
+			wait = delay
+		}
+	}
+	return wait, nil
+}
+
+// Success limpia los fallos del usuario. Los de la IP se mantienen: un atacante
+// con una cuenta válida no debe poder reiniciar el contador de su IP.
+func (g *LoginGuard) Success(ctx context.Context, a LoginAttempt) error {
+	g.record(ctx, entities.AuthEventLoginSuccess, a, "")
+	return g.throttle.Reset(userKey(a.Username))
+}
+
+// Unlock levanta el bloqueo de un usuario (acción de administrador)
+func (g *LoginGuard) Unlock(ctx context.Context, username string, userID uint, actor string) error {
+	if err := g.throttle.Reset(userKey(username)); err != nil {
+		return err
+	}
+	g.record(ctx, entities.AuthEventUnlocked, LoginAttempt{Username: username, UserID: &userID}, "unlocked by "+actor)
+	return nil
+}
+
+// delayFor devuelve BaseDelay * 2^(failures-1), con MaxDelay como tope
+func (g *LoginGuard) delayFor(failures int) time.Duration {
+	if failures <= 0 || g.policy.BaseDelay <= 0 {
+		return 0
+	}
+	d := g.policy.BaseDelay
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if g.policy.MaxDelay > 0 && d >= g.policy.MaxDelay {
+			return g.policy.MaxDelay
+		}
+	}
+	if g.policy.MaxDelay > 0 && d > g.policy.MaxDelay {
+		return g.policy.MaxDelay
+	}
+	return d
+}
+
+func (g *LoginGuard) record(ctx context.Context, event string, a LoginAttempt, details string) {
+	g.recordActivity(ctx, event, a, details)
+	if g.audit == nil {
+		return
+	}
+	log := &entities.AuthAuditLog{
+		Event:     event,
+		Username:  truncate(a.Username, 150),
+		UserID:    a.UserID,
+		IPAddress: truncate(a.IPAddress, 64),
+		UserAgent: truncate(a.UserAgent, 255),
+		Details:   details,
+	}
+	if err := g.audit.Create(log); err != nil {
+		fmt.Printf("auth audit: failed to record %s for %q: %v\n", event, a.Username, err)
+	}
+}
+
+// activityOf traduce un evento de autenticación al log de actividad: acción y
+// resultado. El desbloqueo no está: lo registra el handler de administración.
+var activityOf = map[string][2]string{
+	entities.AuthEventLoginSuccess:  {entities.ActivityLogin, entities.OutcomeSuccess},
+	entities.AuthEventLoginFailure:  {entities.ActivityLogin, entities.OutcomeFailure},
+	entities.AuthEventLoginBlocked:  {entities.ActivityLogin, entities.OutcomeDenied},
+	entities.AuthEventAccountLocked: {entities.ActivityLockout, entities.OutcomeSuccess},
+	entities.AuthEventIPLocked:      {entities.ActivityLockout, entities.OutcomeSuccess},
+}
+
+func (g *LoginGuard) recordActivity(ctx context.Context, event string, a LoginAttempt, details string) {
+	mapped, ok := activityOf[event]
+	if g.activity == nil || !ok {
+		return
+	}
+	fields := map[string]interface{}{"event": event}
+	if details != "" {
+		fields["details"] = details
+	}
+	ev := &entities.ActivityEvent{
+		Action:       mapped[0],
+		Outcome:      mapped[1],
+		ActorID:      a.UserID,
+		ActorName:    a.Username,
+		IPAddress:    a.IPAddress,
+		UserAgent:    a.UserAgent,
+		ResourceType: "user",
+		ResourceID:   a.Username,
+		Details:      entities.ActivityDetails(fields),
+	}
+	if mapped[0] == entities.ActivityLockout {
+		// el bloqueo lo decide el sistema, no quien tecleó la contraseña
+		ev.ActorType, ev.ActorID, ev.ActorName = entities.ActorSystem, nil, ""
+		if event == entities.AuthEventIPLocked {
+			ev.ResourceType, ev.ResourceID = "ip", a.IPAddress
+		}
+	}
+	g.activity.Record(ctx, ev)
+}
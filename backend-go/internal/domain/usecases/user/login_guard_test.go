@@ -0,0 +1,165 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func setupLoginGuard(t *testing.T, policy LockoutPolicy) (*LoginGuard, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.LoginThrottle{}, &entities.AuthAuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	g := NewLoginGuard(repositories.NewGormLoginThrottleRepository(db), repositories.NewGormAuthAuditRepository(db), policy)
+	return g, db
+}
+
+func locked(g *LoginGuard, a LoginAttempt) bool {
+	wait, _ := g.RetryAfter(context.Background(), a)
+	return wait > 0
+}
+
+func TestLoginGuard_locksUserAfterFailuresWithProgressiveDelay(t *testing.T) {
+	ctx := context.Background()
+	g, db := setupLoginGuard(t, LockoutPolicy{
+		MaxUserFailures: 3,
+		FailureWindow:   time.Minute,
+		LockoutDuration: 10 * time.Minute,
+		BaseDelay:       100 * time.Millisecond,
+		MaxDelay:        300 * time.Millisecond,
+	})
+	a := LoginAttempt{Username: "Alice", IPAddress: "10.0.0.1"}
+
+	start := time.Now()
+	g.now = func() time.Time { return start }
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i, d := range want {
+		if locked(g, a) {
+			t.Fatalf("locked too early at attempt %d", i+1)
+		}
+		wait, err := g.Failure(ctx, a)
+		if err != nil {
+			t.Fatalf("failure: %v", err)
+		}
+		if wait != d {
+			t.Fatalf("delay %d: want %s, got %s", i, d, wait)
+		}
+		if i < 2 {
+			// a retry within the delay is rejected without sleeping
+			if got, _ := g.RetryAfter(ctx, a); got != d {
+				t.Fatalf("retry after %d: want %s, got %s", i, d, got)
+			}
+			start = start.Add(d)
+		}
+	}
+
+	// usernames are case-insensitive, so "alice" is locked too
+	if wait, _ := g.RetryAfter(ctx, LoginAttempt{Username: "alice", IPAddress: "10.0.0.2"}); wait != 10*time.Minute {
+		t.Fatalf("expected user to be locked for the lockout duration, got %s", wait)
+	}
+
+	// the lock expires on its own
+	g.now = func() time.Time { return start.Add(11 * time.Minute) }
+	if locked(g, a) {
+		t.Fatalf("expected lock to expire")
+	}
+	g.now = time.Now
+
+	var events []string
+	db.Model(&entities.AuthAuditLog{}).Order("id").Pluck("event", &events)
+	if events[5] != entities.AuthEventAccountLocked || events[len(events)-1] != entities.AuthEventLoginBlocked {
+		t.Fatalf("unexpected audit trail: %v", events)
+	}
+}
+
+func TestLoginGuard_ipLockSuccessAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	g, _ := setupLoginGuard(t, LockoutPolicy{
+		MaxUserFailures: 3,
+		MaxIPFailures:   4,
+		FailureWindow:   time.Minute,
+		LockoutDuration: time.Hour,
+	})
+
+	// spraying different usernames from one IP locks the IP, not the users
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, _ = g.Failure(ctx, LoginAttempt{Username: name, IPAddress: "10.0.0.9"})
+	}
+	if !locked(g, LoginAttempt{Username: "e", IPAddress: "10.0.0.9"}) {
+		t.Fatalf("expected IP to be locked")
+	}
+	if locked(g, LoginAttempt{Username: "a", IPAddress: "10.0.0.10"}) {
+		t.Fatalf("user a must not be locked from another IP")
+	}
+
+	// a success clears the user's counter (no IP limit here so only the user counts)
+	g, _ = setupLoginGuard(t, LockoutPolicy{MaxUserFailures: 3, FailureWindow: time.Minute, LockoutDuration: time.Hour})
+	bob := LoginAttempt{Username: "bob", IPAddress: "10.0.0.20"}
+	_, _ = g.Failure(ctx, bob)
+	_, _ = g.Failure(ctx, bob)
+	_ = g.Success(ctx, bob)
+	_, _ = g.Failure(ctx, bob)
+	_, _ = g.Failure(ctx, bob)
+	if locked(g, bob) {
+		t.Fatalf("success should have reset the failure count")
+	}
+
+	// and an admin unlock clears a lock
+	_, _ = g.Failure(ctx, bob)
+	if !locked(g, bob) {
+		t.Fatalf("expected bob to be locked")
+	}
+	if err := g.Unlock(ctx, "bob", 7, "admin"); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if locked(g, bob) {
+		t.Fatalf("expected bob to be unlocked")
+	}
+}
+
+type recordedActivity []entities.ActivityEvent
+
+func (r *recordedActivity) Record(ctx context.Context, ev *entities.ActivityEvent) {
+	*r = append(*r, *ev)
+}
+
+func TestLoginGuard_copiesEventsToActivityLog(t *testing.T) {
+	ctx := context.Background()
+	g, _ := setupLoginGuard(t, LockoutPolicy{MaxUserFailures: 2, FailureWindow: time.Minute, LockoutDuration: time.Hour})
+	var events recordedActivity
+	g.WithActivity(&events)
+	a := LoginAttempt{Username: "carol", IPAddress: "10.0.0.30", UserAgent: "curl"}
+	_, _ = g.Failure(ctx, a)
+	_, _ = g.Failure(ctx, a)
+	_, _ = g.RetryAfter(ctx, a)
+	_ = g.Unlock(ctx, "carol", 5, "admin")
+
+	want := []struct{ action, outcome, actor string }{
+		{entities.ActivityLogin, entities.OutcomeFailure, ""},
+		{entities.ActivityLogin, entities.OutcomeFailure, ""},
+		{entities.ActivityLockout, entities.OutcomeSuccess, entities.ActorSystem},
+		{entities.ActivityLogin, entities.OutcomeDenied, ""},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d activity events (unlock is logged by the admin handler), got %+v", len(want), events)
+	}
+	for i, w := range want {
+		ev := events[i]
+		if ev.Action != w.action || ev.Outcome != w.outcome || ev.ActorType != w.actor {
+			t.Fatalf("event %d: expected %+v, got %+v", i, w, ev)
+		}
+	}
+	if events[0].ActorName != "carol" || events[0].IPAddress != "10.0.0.30" || events[0].ResourceID != "carol" {
+		t.Fatalf("expected the attempt on the failure event, got %+v", events[0])
+	}
+}
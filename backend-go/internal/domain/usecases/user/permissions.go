@@ -0,0 +1,163 @@
+package user
+
+import (
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+)
+
+// PermissionsUseCase calcula los permisos efectivos de un usuario para la
+// autorización por ruta y, con scopes, sobre qué servidores y bases de datos valen
+type PermissionsUseCase struct {
+	users  repositories.UserRepository
+	roles  repositories.RoleRepository
+	scopes repositories.ResourceScopeRepository
+}
+
+// NewPermissionsUseCase; con sr nil todos los permisos son globales
+func NewPermissionsUseCase(ur repositories.UserRepository, rr repositories.RoleRepository, sr repositories.ResourceScopeRepository) *PermissionsUseCase {
+	return &PermissionsUseCase{users: ur, roles: rr, scopes: sr}
+}
+
+// Effective devuelve los permisos del usuario; uno inexistente o desactivado no
+// tiene ninguno aunque su token siga vigente
+func (uc *PermissionsUseCase) Effective(userID uint) (map[string]bool, error) {
+	user, err := uc.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return map[string]bool{}, nil
+	}
+	return effectivePermissions(uc.roles, user)
+}
+
+// Scope implementa services.ResourceAccess: reúne los scopes de todos los roles
+// que conceden alguno de los permisos. Basta un rol que lo conceda sin scopes
+// para que el permiso sea global.
+func (uc *PermissionsUseCase) Scope(userID uint, permissions ...string) (*entities.AccessScope, error) {
+	out := &entities.AccessScope{}
+	user, err := uc.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return out, nil
+	}
+	roles, err := userRoles(uc.roles, user)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, p := range permissions {
+		wanted[p] = true
+	}
+	type rolePermission struct{ role, perm uint }
+	scoped := map[rolePermission][]entities.PermissionScope{}
+	if uc.scopes != nil {
+		ids := make([]uint, 0, len(roles))
+		for _, r := range roles {
+			ids = append(ids, r.ID)
+		}
+		list, err := uc.scopes.ListScopesByRoles(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range list {
+			k := rolePermission{sc.RoleID, sc.PermissionID}
+			scoped[k] = append(scoped[k], sc)
+		}
+	}
+	for _, r := range roles {
+		for _, p := range r.Permissions {
+			if !wanted[p.Name] {
+				continue
+			}
+			list := scoped[rolePermission{r.ID, p.ID}]
+			if len(list) == 0 {
+				return &entities.AccessScope{Global: true}, nil
+			}
+			for i := range list {
+				out.Rules = append(out.Rules, list[i].Rule())
+			}
+		}
+	}
+	return out, nil
+}
+
+// effectivePermissions reúne los permisos de los roles asignados al usuario y de su rol principal
+func effectivePermissions(roles repositories.RoleRepository, user *entities.User) (map[string]bool, error) {
+	assigned, err := userRoles(roles, user)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]bool{}
+	for _, r := range assigned {
+		for _, p := range r.Permissions {
+			out[p.Name] = true
+		}
+	}
+	return out, nil
+}
+
+// userRoles devuelve los roles asignados al usuario (user_roles es la única
+// fuente; users.role es solo el principal derivado de ellos) con sus
+// ancestros: un rol hereda los permisos (y los scopes) de sus padres
+func userRoles(roles repositories.RoleRepository, user *entities.User) ([]entities.Role, error) {
+	chains, err := userRoleChains(roles, user)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[uint]bool{}
+	var out []entities.Role
+	for _, chain := range chains {
+		for _, r := range chain {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				out = append(out, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+// userRoleNames devuelve el rol principal y los roles vigentes del usuario con
+// sus ancestros, para las políticas que se configuran por nombre de rol (2FA
+// obligatoria, sesiones). Sin repositorio de roles solo cuenta el principal.
+func userRoleNames(roles repositories.RoleRepository, user *entities.User) ([]string, error) {
+	names := []string{user.Role}
+	if roles == nil {
+		return names, nil
+	}
+	list, err := userRoles(roles, user)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range list {
+		if r.Name != user.Role {
+			names = append(names, r.Name)
+		}
+	}
+	return names, nil
+}
+
+// userRoleChains devuelve, por cada rol vigente del usuario, ese rol seguido de
+// sus ancestros; Explain lo usa para mostrar de dónde viene cada permiso
+func userRoleChains(roles repositories.RoleRepository, user *entities.User) ([][]entities.Role, error) {
+	assigned, err := roles.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return roleChains(roles, assigned)
+}
+
+func roleChains(roles repositories.RoleRepository, assigned []entities.Role) ([][]entities.Role, error) {
+	out := make([][]entities.Role, 0, len(assigned))
+	for i := range assigned {
+		chain, err := ancestors(roles, &assigned[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, append([]entities.Role{assigned[i]}, chain...))
+	}
+	return out, nil
+}
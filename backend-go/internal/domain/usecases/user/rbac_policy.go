@@ -0,0 +1,634 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+)
+
+// Errores de la política RBAC como código
+var (
+	ErrInvalidPolicy = errors.New("invalid RBAC policy")
+	ErrPolicyLockout = errors.New("the policy would leave no assigned role with " + entities.PermPermissionsManage)
+)
+
+// Acciones de un plan, en el orden en que se aplican
+const (
+	PolicyCreatePermission = "create_permission"
+	PolicyUpdatePermission = "update_permission"
+	PolicyCreateRole       = "create_role"
+	PolicyUpdateRole       = "update_role"
+	PolicySetParent        = "set_parent"
+	PolicyGrant            = "grant"
+	PolicyRevoke           = "revoke"
+	PolicyDeleteRole       = "delete_role"
+	PolicyDeletePermission = "delete_permission"
+)
+
+// PolicyChange es un paso del plan
+type PolicyChange struct {
+	Action     string `json:"action"`
+	Role       string `json:"role,omitempty"`
+	Permission string `json:"permission,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// PolicyPlan es la diferencia entre la política y el estado actual
+type PolicyPlan struct {
+	Prune   bool           `json:"prune"`
+	Applied bool           `json:"applied"`
+	Changes []PolicyChange `json:"changes"`
+}
+
+// Summary cuenta los cambios por acción ("create_role=1 grant=3")
+func (p *PolicyPlan) Summary() string {
+	if len(p.Changes) == 0 {
+		return "no changes"
+	}
+	counts := map[string]int{}
+	var order []string
+	for _, c := range p.Changes {
+		if counts[c.Action] == 0 {
+			order = append(order, c.Action)
+		}
+		counts[c.Action]++
+	}
+	parts := make([]string, 0, len(order))
+	for _, a := range order {
+		parts = append(parts, fmt.Sprintf("%s=%d", a, counts[a]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// RBACPolicyUseCase exporta, compara y aplica la política declarativa de roles y
+// permisos. Para los roles y permisos que declara, la política es el estado
+// exacto (descripción, padre y vínculos); con prune se borran además los que no
+// aparecen en ella.
+type RBACPolicyUseCase struct {
+	roles repositories.RoleRepository
+	perms repositories.PermissionRepository
+	// scopes se limpian al revocar un vínculo o borrar un rol (opcional)
+	scopes repositories.ResourceScopeRepository
+	// assignments actualiza a los usuarios de los roles borrados (opcional)
+	assignments *RoleAssignmentsUseCase
+	// tx aplica los planes en una transacción (opcional)
+	tx repositories.RBACTransactor
+}
+
+func NewRBACPolicyUseCase(rr repositories.RoleRepository, pr repositories.PermissionRepository, sr repositories.ResourceScopeRepository) *RBACPolicyUseCase {
+	return &RBACPolicyUseCase{roles: rr, perms: pr, scopes: sr}
+}
+
+// WithAssignments renueva el rol principal y los tokens de los usuarios de los roles podados
+func (uc *RBACPolicyUseCase) WithAssignments(a *RoleAssignmentsUseCase) *RBACPolicyUseCase {
+	uc.assignments = a
+	return uc
+}
+
+// WithTransactions aplica cada plan entero o nada: si un paso falla se deshacen los anteriores
+func (uc *RBACPolicyUseCase) WithTransactions(t repositories.RBACTransactor) *RBACPolicyUseCase {
+	uc.tx = t
+	return uc
+}
+
+// ParsePolicy lee una política en YAML o JSON (JSON también es YAML válido).
+// Los campos desconocidos son un error para no ignorar erratas.
+func ParsePolicy(data []byte) (*entities.RBACPolicy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var p entities.RBACPolicy
+	if err := dec.Decode(&p); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: empty document", ErrInvalidPolicy)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	return &p, nil
+}
+
+// MarshalPolicy serializa la política como "yaml" o "json"
+func MarshalPolicy(p *entities.RBACPolicy, format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(p, "", "  ")
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(p); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Export devuelve el estado actual como política, ordenada por nombre
+func (uc *RBACPolicyUseCase) Export() (*entities.RBACPolicy, error) {
+	st, err := uc.load()
+	if err != nil {
+		return nil, err
+	}
+	out := &entities.RBACPolicy{Version: entities.RBACPolicyVersion, Permissions: []entities.PolicyPermission{}, Roles: []entities.PolicyRole{}}
+	for _, name := range sortedKeys(st.perms) {
+		p := st.perms[name]
+		out.Permissions = append(out.Permissions, entities.PolicyPermission{Name: p.Name, Resource: p.Resource, Action: p.Action, Description: p.Description})
+	}
+	for _, name := range sortedKeys(st.roles) {
+		r := st.roles[name]
+		perms := sortedKeys(st.bindings(name))
+		if perms == nil {
+			perms = []string{}
+		}
+		out.Roles = append(out.Roles, entities.PolicyRole{Name: r.Name, Description: r.Description, Parent: st.parent(name), Permissions: perms})
+	}
+	return out, nil
+}
+
+// Plan calcula los cambios sin aplicarlos
+func (uc *RBACPolicyUseCase) Plan(p *entities.RBACPolicy, prune bool) (*PolicyPlan, error) {
+	st, err := uc.load()
+	if err != nil {
+		return nil, err
+	}
+	return diffPolicy(st, p, prune)
+}
+
+// Apply aplica el plan paso a paso. La política se valida entera antes del
+// primer cambio; si un paso falla, el plan devuelto indica lo que había y, con
+// WithTransactions, no queda aplicado ninguno de sus pasos. Los usuarios de los
+// roles borrados se actualizan después de confirmar los cambios.
+func (uc *RBACPolicyUseCase) Apply(p *entities.RBACPolicy, prune bool) (*PolicyPlan, error) {
+	var plan *PolicyPlan
+	var st *rbacState
+	run := func(pc *RBACPolicyUseCase) error {
+		var err error
+		if st, err = pc.load(); err != nil {
+			return err
+		}
+		if plan, err = diffPolicy(st, p, prune); err != nil {
+			return err
+		}
+		perms, roles := policyIndex(p)
+		for _, ch := range plan.Changes {
+			if err := pc.apply(st, perms, roles, ch); err != nil {
+				return fmt.Errorf("%s %s%s: %w", ch.Action, ch.Role, ch.Permission, err)
+			}
+		}
+		return nil
+	}
+	var err error
+	if uc.tx == nil {
+		err = run(uc)
+	} else {
+		err = uc.tx.InTransaction(func(rr repositories.RoleRepository, pr repositories.PermissionRepository, sr repositories.ResourceScopeRepository) error {
+			in := &RBACPolicyUseCase{roles: rr, perms: pr}
+			if uc.scopes != nil {
+				in.scopes = sr
+			}
+			return run(in)
+		})
+		if err != nil && st != nil {
+			// la transacción se deshizo: ningún rol llegó a borrarse
+			st.orphans = nil
+		}
+	}
+	if st != nil && len(st.orphans) > 0 && uc.assignments != nil {
+		if aerr := uc.assignments.RoleDeleted(st.orphans); aerr != nil && err == nil {
+			err = fmt.Errorf("update users of deleted roles: %w", aerr)
+		}
+	}
+	if err != nil {
+		return plan, err
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// Seed aplica la política por defecto al arrancar sin deshacer cambios de los
+// administradores: crea los permisos y roles que faltan, da a los roles con "*"
+// los permisos nuevos y solo rellena los vínculos y el padre de un rol recién
+// creado o sin permisos. Nunca borra nada.
+func (uc *RBACPolicyUseCase) Seed(p *entities.RBACPolicy) error {
+	if err := normalizePolicy(p); err != nil {
+		return err
+	}
+	st, err := uc.load()
+	if err != nil {
+		return err
+	}
+	perms, _ := policyIndex(p)
+	for _, pp := range p.Permissions {
+		if _, ok := st.perms[pp.Name]; !ok {
+			if err := uc.apply(st, perms, nil, PolicyChange{Action: PolicyCreatePermission, Permission: pp.Name}); err != nil {
+				return fmt.Errorf("create permission %s: %w", pp.Name, err)
+			}
+		}
+	}
+	universe := map[string]bool{}
+	for name := range st.perms {
+		universe[name] = true
+	}
+	fresh := map[string]bool{}
+	for _, r := range p.Roles {
+		if _, ok := st.roles[r.Name]; ok {
+			continue
+		}
+		role := entities.Role{Name: r.Name, Description: r.Description}
+		if err := uc.roles.Create(&role); err != nil {
+			return fmt.Errorf("create role %s: %w", r.Name, err)
+		}
+		st.roles[r.Name] = role
+		st.roleNames[role.ID] = r.Name
+		fresh[r.Name] = true
+	}
+	for _, r := range p.Roles {
+		want, err := expandPermissions(r, universe)
+		if err != nil {
+			return err
+		}
+		have := st.bindings(r.Name)
+		all := false
+		for _, name := range r.Permissions {
+			all = all || name == entities.PolicyAllPermissions
+		}
+		if !fresh[r.Name] && len(have) > 0 && !all {
+			continue
+		}
+		role := st.roles[r.Name]
+		for _, name := range sortedKeys(want) {
+			if have[name] {
+				continue
+			}
+			if err := uc.perms.AssignToRole(role.ID, st.perms[name].ID); err != nil {
+				return fmt.Errorf("assign %s to %s: %w", name, r.Name, err)
+			}
+		}
+		if fresh[r.Name] && r.Parent != "" {
+			parent, ok := st.roles[r.Parent]
+			if !ok {
+				return fmt.Errorf("%w: role %q has unknown parent %q", ErrInvalidPolicy, r.Name, r.Parent)
+			}
+			if err := uc.roles.SetParent(role.ID, &parent.ID); err != nil {
+				return fmt.Errorf("set parent of %s: %w", r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (uc *RBACPolicyUseCase) apply(st *rbacState, perms map[string]entities.PolicyPermission, roles map[string]entities.PolicyRole, ch PolicyChange) error {
+	switch ch.Action {
+	case PolicyCreatePermission:
+		pp := perms[ch.Permission]
+		perm := entities.Permission{Name: pp.Name, Resource: pp.Resource, Action: pp.Action, Description: pp.Description}
+		if err := uc.perms.Create(&perm); err != nil {
+			return err
+		}
+		st.perms[perm.Name] = perm
+	case PolicyUpdatePermission:
+		pp, perm := perms[ch.Permission], st.perms[ch.Permission]
+		perm.Resource, perm.Action, perm.Description = pp.Resource, pp.Action, pp.Description
+		if err := uc.perms.Update(&perm); err != nil {
+			return err
+		}
+		st.perms[perm.Name] = perm
+	case PolicyCreateRole:
+		role := entities.Role{Name: ch.Role, Description: roles[ch.Role].Description}
+		if err := uc.roles.Create(&role); err != nil {
+			return err
+		}
+		st.roles[role.Name] = role
+		st.roleNames[role.ID] = role.Name
+	case PolicyUpdateRole:
+		// sin Permissions para que Save no toque los vínculos
+		role := st.roles[ch.Role]
+		role.Description, role.Permissions = roles[ch.Role].Description, nil
+		if err := uc.roles.Update(&role); err != nil {
+			return err
+		}
+	case PolicySetParent:
+		var parentID *uint
+		if name := roles[ch.Role].Parent; name != "" {
+			id := st.roles[name].ID
+			parentID = &id
+		}
+		return uc.roles.SetParent(st.roles[ch.Role].ID, parentID)
+	case PolicyGrant:
+		return uc.perms.AssignToRole(st.roles[ch.Role].ID, st.perms[ch.Permission].ID)
+	case PolicyRevoke:
+		roleID, permID := st.roles[ch.Role].ID, st.perms[ch.Permission].ID
+		if err := uc.perms.RevokeFromRole(roleID, permID); err != nil {
+			return err
+		}
+		if uc.scopes != nil {
+			return uc.scopes.ReplaceScopes(roleID, permID, nil)
+		}
+	case PolicyDeleteRole:
+		id := st.roles[ch.Role].ID
+		holders, err := uc.roles.ListRoleUsers(id)
+		if err != nil {
+			return err
+		}
+		if err := uc.roles.Delete(id); err != nil {
+			return err
+		}
+		if uc.scopes != nil {
+			if err := uc.scopes.DeleteScopesByRole(id); err != nil {
+				return err
+			}
+		}
+		st.orphans = append(st.orphans, holders...)
+	case PolicyDeletePermission:
+		id := st.perms[ch.Permission].ID
+		if err := uc.perms.Delete(id); err != nil {
+			return err
+		}
+		if uc.scopes != nil {
+			return uc.scopes.DeleteScopesByPermission(id)
+		}
+	}
+	return nil
+}
+
+// rbacState es el estado actual indexado por nombre
+type rbacState struct {
+	perms     map[string]entities.Permission
+	roles     map[string]entities.Role
+	roleNames map[uint]string
+	permNames map[uint]string
+	holders   map[string]int
+	// orphans son los usuarios de los roles borrados por el plan
+	orphans []uint
+}
+
+func (uc *RBACPolicyUseCase) load() (*rbacState, error) {
+	perms, err := uc.perms.List()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := uc.roles.List()
+	if err != nil {
+		return nil, err
+	}
+	st := &rbacState{perms: map[string]entities.Permission{}, roles: map[string]entities.Role{}, roleNames: map[uint]string{}, permNames: map[uint]string{}, holders: map[string]int{}}
+	for _, p := range perms {
+		st.perms[p.Name] = p
+		st.permNames[p.ID] = p.Name
+	}
+	for _, r := range roles {
+		st.roles[r.Name] = r
+		st.roleNames[r.ID] = r.Name
+		users, err := uc.roles.ListRoleUsers(r.ID)
+		if err != nil {
+			return nil, err
+		}
+		st.holders[r.Name] = len(users)
+	}
+	return st, nil
+}
+
+func (st *rbacState) bindings(role string) map[string]bool {
+	out := map[string]bool{}
+	for _, p := range st.roles[role].Permissions {
+		out[p.Name] = true
+	}
+	return out
+}
+
+func (st *rbacState) parent(role string) string {
+	if id := st.roles[role].ParentID; id != nil {
+		return st.roleNames[*id]
+	}
+	return ""
+}
+
+// diffPolicy valida la política contra el estado y devuelve los cambios ordenados
+func diffPolicy(st *rbacState, p *entities.RBACPolicy, prune bool) (*PolicyPlan, error) {
+	if err := normalizePolicy(p); err != nil {
+		return nil, err
+	}
+	perms, roles := policyIndex(p)
+	plan := &PolicyPlan{Prune: prune, Changes: []PolicyChange{}}
+	add := func(action, role, perm, detail string) {
+		plan.Changes = append(plan.Changes, PolicyChange{Action: action, Role: role, Permission: perm, Detail: detail})
+	}
+
+	// permisos que existirán después de aplicar
+	universe := map[string]bool{}
+	for name := range perms {
+		universe[name] = true
+	}
+	for name := range st.perms {
+		if !prune {
+			universe[name] = true
+		}
+	}
+	// roles que existirán después de aplicar y sus permisos y padres finales
+	finalPerms := map[string]map[string]bool{}
+	finalParent := map[string]string{}
+	if !prune {
+		for name := range st.roles {
+			finalPerms[name] = st.bindings(name)
+			finalParent[name] = st.parent(name)
+		}
+	}
+	for name, r := range roles {
+		want, err := expandPermissions(r, universe)
+		if err != nil {
+			return nil, err
+		}
+		finalPerms[name] = want
+		finalParent[name] = r.Parent
+	}
+	for name, parent := range finalParent {
+		if parent == "" {
+			continue
+		}
+		if _, ok := finalPerms[parent]; !ok {
+			return nil, fmt.Errorf("%w: role %q has unknown parent %q", ErrInvalidPolicy, name, parent)
+		}
+		seen := map[string]bool{name: true}
+		for cur := parent; cur != ""; cur = finalParent[cur] {
+			if seen[cur] {
+				return nil, fmt.Errorf("%w: role %q is part of a parent cycle", ErrInvalidPolicy, name)
+			}
+			seen[cur] = true
+		}
+	}
+	// alguien tiene que poder seguir administrando permisos: un rol que sobrevive
+	// al plan, tiene usuarios y da permissions:manage directamente o por un
+	// ancestro. Sin ningún rol asignado (instalación nueva) basta con que exista.
+	assigned := false
+	for _, n := range st.holders {
+		if n > 0 {
+			assigned = true
+		}
+	}
+	locked := true
+	for name := range finalPerms {
+		if assigned && st.holders[name] == 0 {
+			continue
+		}
+		for cur := name; cur != ""; cur = finalParent[cur] {
+			if finalPerms[cur][entities.PermPermissionsManage] {
+				locked = false
+				break
+			}
+		}
+	}
+	if locked {
+		return nil, ErrPolicyLockout
+	}
+
+	for _, name := range sortedKeys(perms) {
+		pp := perms[name]
+		cur, ok := st.perms[name]
+		switch {
+		case !ok:
+			add(PolicyCreatePermission, "", name, "")
+		case cur.Resource != pp.Resource || cur.Action != pp.Action || cur.Description != pp.Description:
+			add(PolicyUpdatePermission, "", name, "")
+		}
+	}
+	for _, name := range sortedKeys(roles) {
+		cur, ok := st.roles[name]
+		if !ok {
+			add(PolicyCreateRole, name, "", "")
+		} else if cur.Description != roles[name].Description {
+			add(PolicyUpdateRole, name, "", "")
+		}
+	}
+	for _, name := range sortedKeys(roles) {
+		from, to := st.parent(name), roles[name].Parent
+		if from != to {
+			add(PolicySetParent, name, "", fmt.Sprintf("%q -> %q", from, to))
+		}
+	}
+	for _, name := range sortedKeys(roles) {
+		have, want := st.bindings(name), finalPerms[name]
+		for _, perm := range sortedKeys(want) {
+			if !have[perm] {
+				add(PolicyGrant, name, perm, "")
+			}
+		}
+		for _, perm := range sortedKeys(have) {
+			if !want[perm] {
+				add(PolicyRevoke, name, perm, "")
+			}
+		}
+	}
+	if prune {
+		for _, name := range sortedKeys(st.roles) {
+			if _, ok := roles[name]; !ok {
+				add(PolicyDeleteRole, name, "", fmt.Sprintf("%d users assigned", st.holders[name]))
+			}
+		}
+		for _, name := range sortedKeys(st.perms) {
+			if _, ok := perms[name]; !ok {
+				add(PolicyDeletePermission, "", name, "")
+			}
+		}
+	}
+	return plan, nil
+}
+
+// normalizePolicy recorta nombres, deduce resource/action y rechaza duplicados
+func normalizePolicy(p *entities.RBACPolicy) error {
+	if p.Version != entities.RBACPolicyVersion {
+		return fmt.Errorf("%w: unsupported version %d (expected %d)", ErrInvalidPolicy, p.Version, entities.RBACPolicyVersion)
+	}
+	seen := map[string]bool{}
+	for i := range p.Permissions {
+		pp := &p.Permissions[i]
+		pp.Name = strings.TrimSpace(pp.Name)
+		if pp.Name == "" || pp.Name == entities.PolicyAllPermissions {
+			return fmt.Errorf("%w: permission #%d has no valid name", ErrInvalidPolicy, i+1)
+		}
+		if seen[pp.Name] {
+			return fmt.Errorf("%w: permission %q is declared twice", ErrInvalidPolicy, pp.Name)
+		}
+		seen[pp.Name] = true
+		if res, act, ok := strings.Cut(pp.Name, ":"); ok {
+			if pp.Resource == "" {
+				pp.Resource = res
+			}
+			if pp.Action == "" {
+				pp.Action = act
+			}
+		}
+		if pp.Resource == "" || pp.Action == "" {
+			return fmt.Errorf("%w: permission %q needs a resource and an action", ErrInvalidPolicy, pp.Name)
+		}
+	}
+	seen = map[string]bool{}
+	for i := range p.Roles {
+		r := &p.Roles[i]
+		r.Name, r.Parent = strings.TrimSpace(r.Name), strings.TrimSpace(r.Parent)
+		if r.Name == "" {
+			return fmt.Errorf("%w: role #%d has no name", ErrInvalidPolicy, i+1)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("%w: role %q is declared twice", ErrInvalidPolicy, r.Name)
+		}
+		seen[r.Name] = true
+		if r.Parent == r.Name {
+			return fmt.Errorf("%w: role %q cannot be its own parent", ErrInvalidPolicy, r.Name)
+		}
+		for j := range r.Permissions {
+			r.Permissions[j] = strings.TrimSpace(r.Permissions[j])
+		}
+	}
+	return nil
+}
+
+func policyIndex(p *entities.RBACPolicy) (map[string]entities.PolicyPermission, map[string]entities.PolicyRole) {
+	perms := map[string]entities.PolicyPermission{}
+	for _, pp := range p.Permissions {
+		perms[pp.Name] = pp
+	}
+	roles := map[string]entities.PolicyRole{}
+	for _, r := range p.Roles {
+		roles[r.Name] = r
+	}
+	return perms, roles
+}
+
+// expandPermissions resuelve "*" y comprueba que cada permiso vaya a existir
+func expandPermissions(r entities.PolicyRole, universe map[string]bool) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, name := range r.Permissions {
+		if name == entities.PolicyAllPermissions {
+			for p := range universe {
+				out[p] = true
+			}
+			continue
+		}
+		if !universe[name] {
+			return nil, fmt.Errorf("%w: role %q references unknown permission %q", ErrInvalidPolicy, r.Name, name)
+		}
+		out[name] = true
+	}
+	return out, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
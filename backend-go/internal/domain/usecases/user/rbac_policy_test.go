@@ -0,0 +1,276 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/config"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func newPolicyTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.ServerGroup{}, &entities.PermissionScope{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestRBACPolicy_seedKeepsAdministratorChanges(t *testing.T) {
+	db := newPolicyTestDB(t)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	permRepo := repositories.NewGormPermissionRepository(db)
+	uc := NewRBACPolicyUseCase(roleRepo, permRepo, nil)
+	policy, err := ParsePolicy(config.DefaultRBACPolicy)
+	if err != nil {
+		t.Fatalf("parse default policy: %v", err)
+	}
+	if err := uc.Seed(policy); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	all, _ := permRepo.List()
+	admin, _ := roleRepo.GetByName("admin")
+	if len(all) == 0 || len(admin.Permissions) != len(all) {
+		t.Fatalf("expected admin to get every permission, got %d of %d", len(admin.Permissions), len(all))
+	}
+
+	// an administrator trims the user role; a restart must not undo it
+	userRole, _ := roleRepo.GetByName("user")
+	view, _ := permRepo.GetByName(entities.PermAuditsView)
+	permRepo.RevokeFromRole(userRole.ID, view.ID)
+	policy, _ = ParsePolicy(config.DefaultRBACPolicy)
+	if err := uc.Seed(policy); err != nil {
+		t.Fatalf("second seed: %v", err)
+	}
+	if userRole, _ = roleRepo.GetByName("user"); userRole.HasPermission(entities.PermAuditsView) {
+		t.Fatalf("expected the seed to keep the administrator's change, got %+v", userRole.Permissions)
+	}
+}
+
+func TestRBACPolicy_planApplyAndExport(t *testing.T) {
+	db := newPolicyTestDB(t)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	permRepo := repositories.NewGormPermissionRepository(db)
+	uc := NewRBACPolicyUseCase(roleRepo, permRepo, repositories.NewGormResourceScopeRepository(db))
+	policy, _ := ParsePolicy(config.DefaultRBACPolicy)
+	if err := uc.Seed(policy); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	legacy := entities.Role{Name: "legacy"}
+	roleRepo.Create(&legacy)
+	u := &entities.User{Username: "ana", Email: "ana@e", Password: "x", Role: "legacy", IsActive: true}
+	db.Omit("last_login").Create(u)
+	roleRepo.AssignToUser(u.ID, legacy.ID)
+	root := &entities.User{Username: "root", Email: "root@e", Password: "x", Role: "admin", IsActive: true}
+	db.Omit("last_login").Create(root)
+	adminRole, _ := roleRepo.GetByName("admin")
+	roleRepo.AssignToUser(root.ID, adminRole.ID)
+	var cache invalidatedUsers
+	uc.WithAssignments(NewRoleAssignmentsUseCase(persistence.NewUserRepository(db), roleRepo, nil, &cache))
+
+	doc := []byte(`
+version: 1
+permissions:
+  - name: permissions:manage
+  - name: audits:view
+    description: View audit results
+  - name: reports:export
+roles:
+  - name: admin
+    description: Full system administrator
+    permissions: ["*"]
+  - name: auditor
+    description: Can run and view audits
+    permissions: [audits:view]
+  - name: reporter
+    parent: auditor
+    permissions: [reports:export]
+`)
+	p, err := ParsePolicy(doc)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	plan, err := uc.Plan(p, true)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if plan.Applied {
+		t.Fatalf("a plan must not be applied")
+	}
+	if r, _ := roleRepo.GetByName("reporter"); r != nil {
+		t.Fatalf("plan must not change anything")
+	}
+	// 16 seeded permissions: 2 kept, 1 new; admin loses 14 and auditor audits:execute
+	for _, want := range []string{"create_permission=1", "create_role=1", "set_parent=1", "grant=2", "revoke=15", "delete_role=2", "delete_permission=14"} {
+		if !strings.Contains(plan.Summary()+" ", want+" ") {
+			t.Fatalf("expected %s in %q", want, plan.Summary())
+		}
+	}
+
+	p, _ = ParsePolicy(doc)
+	plan, err = uc.Apply(p, true)
+	if err != nil || !plan.Applied {
+		t.Fatalf("apply: %v", err)
+	}
+	if r, _ := roleRepo.GetByName("legacy"); r != nil {
+		t.Fatalf("expected prune to delete roles missing from the policy")
+	}
+	if len(cache) == 0 {
+		t.Fatalf("expected the users of a pruned role to be updated")
+	}
+	admin, _ := roleRepo.GetByName("admin")
+	if len(admin.Permissions) != 3 {
+		t.Fatalf("expected admin to hold exactly the declared permissions, got %+v", admin.Permissions)
+	}
+	if gone, _ := permRepo.GetByName("users:create"); gone != nil {
+		t.Fatalf("expected prune to delete permissions missing from the policy")
+	}
+
+	// the export is the policy itself: planning it again changes nothing
+	exported, err := uc.Export()
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var reporter entities.PolicyRole
+	for _, r := range exported.Roles {
+		if r.Name == "reporter" {
+			reporter = r
+		}
+	}
+	if reporter.Parent != "auditor" || len(reporter.Permissions) != 1 {
+		t.Fatalf("unexpected exported role %+v", reporter)
+	}
+	out, _ := MarshalPolicy(exported, "yaml")
+	again, err := ParsePolicy(out)
+	if err != nil {
+		t.Fatalf("parse export: %v", err)
+	}
+	if plan, err = uc.Plan(again, true); err != nil || len(plan.Changes) != 0 {
+		t.Fatalf("expected an exported policy to be up to date, got %+v (%v)", plan, err)
+	}
+}
+
+func TestRBACPolicy_applyIsAllOrNothingAndDropsScopes(t *testing.T) {
+	db := newPolicyTestDB(t)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	permRepo := repositories.NewGormPermissionRepository(db)
+	uc := NewRBACPolicyUseCase(roleRepo, permRepo, repositories.NewGormResourceScopeRepository(db)).
+		WithTransactions(repositories.NewGormRBACTransactor(db))
+	policy, _ := ParsePolicy(config.DefaultRBACPolicy)
+	if err := uc.Seed(policy); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// a scope left on a permission the role no longer holds
+	auditor, _ := roleRepo.GetByName("auditor")
+	create, _ := permRepo.GetByName("users:create")
+	db.Create(&entities.PermissionScope{RoleID: auditor.ID, PermissionID: create.ID, Server: "sql-*"})
+
+	doc := []byte(`
+version: 1
+permissions:
+  - name: permissions:manage
+  - name: audits:view
+roles:
+  - name: admin
+    permissions: ["*"]
+  - name: auditor
+    permissions: [audits:view]
+`)
+	p, _ := ParsePolicy(doc)
+	if _, err := uc.Apply(p, true); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	var left int64
+	db.Model(&entities.PermissionScope{}).Where("permission_id = ?", create.ID).Count(&left)
+	if left != 0 {
+		t.Fatalf("expected the scopes of a deleted permission to be deleted, %d left", left)
+	}
+
+	// the last step fails: the earlier ones must be rolled back
+	db.Create(&entities.Role{Name: "legacy"})
+	if err := db.Exec("CREATE TRIGGER keep_roles BEFORE DELETE ON roles BEGIN SELECT RAISE(ABORT, 'roles are read-only'); END").Error; err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+	doc = append(doc, []byte("  - name: reporter\n    permissions: [audits:view]\n")...)
+	p, _ = ParsePolicy(doc)
+	plan, err := uc.Apply(p, true)
+	if err == nil || plan == nil || plan.Applied {
+		t.Fatalf("expected the delete of legacy to fail, got %+v (%v)", plan, err)
+	}
+	if r, _ := roleRepo.GetByName("reporter"); r != nil {
+		t.Fatalf("expected a failed plan to leave no change behind")
+	}
+}
+
+func TestRBACPolicy_lockoutNeedsAHolderOfPermissionsManage(t *testing.T) {
+	db := newPolicyTestDB(t)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	uc := NewRBACPolicyUseCase(roleRepo, repositories.NewGormPermissionRepository(db), nil)
+	policy, _ := ParsePolicy(config.DefaultRBACPolicy)
+	if err := uc.Seed(policy); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// only an auditor holds a role; admin keeps permissions:manage but nobody has it
+	u := &entities.User{Username: "ana", Email: "ana@e", Password: "x", Role: "auditor", IsActive: true}
+	db.Omit("last_login").Create(u)
+	auditor, _ := roleRepo.GetByName("auditor")
+	roleRepo.AssignToUser(u.ID, auditor.ID)
+
+	cases := map[string]struct {
+		doc  string
+		want error
+	}{
+		"manage on an unassigned role": {"version: 1\npermissions: [{name: permissions:manage}, {name: audits:view}]\nroles:\n  - {name: admin, permissions: ['*']}\n  - {name: auditor, permissions: [audits:view]}\n", ErrPolicyLockout},
+		"manage on the held role":      {"version: 1\npermissions: [{name: permissions:manage}, {name: audits:view}]\nroles:\n  - {name: admin, permissions: ['*']}\n  - {name: auditor, permissions: ['*']}\n", nil},
+		"manage through a parent":      {"version: 1\npermissions: [{name: permissions:manage}, {name: audits:view}]\nroles:\n  - {name: admin, permissions: ['*']}\n  - {name: auditor, parent: admin, permissions: [audits:view]}\n", nil},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParsePolicy([]byte(tc.doc))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if _, err := uc.Plan(p, true); !errors.Is(err, tc.want) && (tc.want != nil || err != nil) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestRBACPolicy_rejectsInvalidPolicies(t *testing.T) {
+	db := newPolicyTestDB(t)
+	uc := NewRBACPolicyUseCase(repositories.NewGormRoleRepository(db), repositories.NewGormPermissionRepository(db), nil)
+
+	cases := map[string]struct {
+		doc  string
+		want error
+	}{
+		"unknown field":   {"version: 1\nrole: []\n", ErrInvalidPolicy},
+		"wrong version":   {"version: 2\n", ErrInvalidPolicy},
+		"unknown perm":    {"version: 1\nroles:\n  - name: a\n    permissions: [x:y]\n", ErrInvalidPolicy},
+		"parent cycle":    {"version: 1\npermissions: [{name: permissions:manage}]\nroles:\n  - {name: a, parent: b, permissions: ['*']}\n  - {name: b, parent: a, permissions: []}\n", ErrInvalidPolicy},
+		"no admin left":   {"version: 1\npermissions: [{name: audits:view}]\nroles:\n  - {name: a, permissions: [audits:view]}\n", ErrPolicyLockout},
+		"json is allowed": {`{"version": 1, "permissions": [{"name": "permissions:manage"}], "roles": [{"name": "a", "permissions": ["*"]}]}`, nil},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := ParsePolicy([]byte(tc.doc))
+			if err == nil {
+				_, err = uc.Plan(p, true)
+			}
+			if !errors.Is(err, tc.want) && (tc.want != nil || err != nil) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
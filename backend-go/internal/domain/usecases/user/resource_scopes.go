@@ -0,0 +1,178 @@
+package user
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+)
+
+// Errores de grupos de servidores y scopes
+var (
+	ErrServerGroupNotFound  = errors.New("server group not found")
+	ErrServerGroupInUse     = errors.New("server group is used by permission scopes")
+	ErrServerGroupInvalid   = errors.New("server group needs a name and at least one server pattern")
+	ErrScopeRoleNotFound    = errors.New("role not found")
+	ErrScopePermissionUnset = errors.New("the role does not have this permission")
+	ErrScopeEmpty           = errors.New("a scope needs a server group, a server or a database")
+)
+
+// ScopeInput describe un scope: todos los campos indicados deben cumplirse
+type ScopeInput struct {
+	ServerGroupID *uint  `json:"server_group_id"`
+	Server        string `json:"server"`
+	Database      string `json:"database"`
+}
+
+// ResourceScopesUseCase administra los grupos de servidores y los scopes que
+// limitan los permisos de un rol a ciertos servidores y bases de datos
+type ResourceScopesUseCase struct {
+	scopes repositories.ResourceScopeRepository
+	roles  repositories.RoleRepository
+}
+
+func NewResourceScopesUseCase(sr repositories.ResourceScopeRepository, rr repositories.RoleRepository) *ResourceScopesUseCase {
+	return &ResourceScopesUseCase{scopes: sr, roles: rr}
+}
+
+func (uc *ResourceScopesUseCase) ListGroups() ([]entities.ServerGroup, error) {
+	return uc.scopes.ListServerGroups()
+}
+
+// CreateGroup crea un grupo con sus patrones de host
+func (uc *ResourceScopesUseCase) CreateGroup(name, description string, servers []string) (*entities.ServerGroup, error) {
+	g := &entities.ServerGroup{Name: strings.TrimSpace(name), Description: strings.TrimSpace(description), Servers: joinPatterns(servers)}
+	if g.Name == "" || g.Servers == "" {
+		return nil, ErrServerGroupInvalid
+	}
+	if err := uc.scopes.CreateServerGroup(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// UpdateGroup reemplaza nombre, descripción y patrones; afecta de inmediato a los scopes que lo usan
+func (uc *ResourceScopesUseCase) UpdateGroup(id uint, name, description string, servers []string) (*entities.ServerGroup, error) {
+	g, err := uc.scopes.GetServerGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, ErrServerGroupNotFound
+	}
+	g.Name, g.Description, g.Servers = strings.TrimSpace(name), strings.TrimSpace(description), joinPatterns(servers)
+	if g.Name == "" || g.Servers == "" {
+		return nil, ErrServerGroupInvalid
+	}
+	if err := uc.scopes.UpdateServerGroup(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// DeleteGroup borra un grupo que ningún scope usa; devuelve el grupo borrado
+func (uc *ResourceScopesUseCase) DeleteGroup(id uint) (*entities.ServerGroup, error) {
+	g, err := uc.scopes.GetServerGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, ErrServerGroupNotFound
+	}
+	n, err := uc.scopes.CountScopesByServerGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		return nil, ErrServerGroupInUse
+	}
+	return g, uc.scopes.DeleteServerGroup(id)
+}
+
+// Scopes devuelve los scopes de un permiso de un rol (vacío = global)
+func (uc *ResourceScopesUseCase) Scopes(roleID, permissionID uint) ([]entities.PermissionScope, error) {
+	if _, err := uc.rolePermission(roleID, permissionID); err != nil {
+		return nil, err
+	}
+	return uc.scopes.ListScopes(roleID, permissionID)
+}
+
+// SetScopes sustituye los scopes de un permiso de un rol; una lista vacía lo
+// vuelve global. Devuelve el nombre del permiso para el log de auditoría.
+func (uc *ResourceScopesUseCase) SetScopes(roleID, permissionID uint, in []ScopeInput) ([]entities.PermissionScope, string, error) {
+	perm, err := uc.rolePermission(roleID, permissionID)
+	if err != nil {
+		return nil, "", err
+	}
+	scopes := make([]entities.PermissionScope, 0, len(in))
+	for _, s := range in {
+		sc := entities.PermissionScope{
+			ServerGroupID: s.ServerGroupID,
+			Server:        strings.TrimSpace(s.Server),
+			Database:      strings.TrimSpace(s.Database),
+		}
+		if sc.ServerGroupID == nil && sc.Server == "" && sc.Database == "" {
+			return nil, "", ErrScopeEmpty
+		}
+		if sc.ServerGroupID != nil {
+			g, err := uc.scopes.GetServerGroup(*sc.ServerGroupID)
+			if err != nil {
+				return nil, "", err
+			}
+			if g == nil {
+				return nil, "", ErrServerGroupNotFound
+			}
+		}
+		scopes = append(scopes, sc)
+	}
+	if err := uc.scopes.ReplaceScopes(roleID, permissionID, scopes); err != nil {
+		return nil, "", err
+	}
+	out, err := uc.scopes.ListScopes(roleID, permissionID)
+	return out, perm.Name, err
+}
+
+// ClearRole borra los scopes de un rol eliminado
+func (uc *ResourceScopesUseCase) ClearRole(roleID uint) error {
+	return uc.scopes.DeleteScopesByRole(roleID)
+}
+
+// ClearPermission borra los scopes de un permiso eliminado en todos los roles
+func (uc *ResourceScopesUseCase) ClearPermission(permissionID uint) error {
+	return uc.scopes.DeleteScopesByPermission(permissionID)
+}
+
+// ClearRolePermission borra los scopes de un permiso revocado, para que no
+// reaparezcan si se vuelve a asignar
+func (uc *ResourceScopesUseCase) ClearRolePermission(roleID, permissionID uint) error {
+	return uc.scopes.ReplaceScopes(roleID, permissionID, nil)
+}
+
+func (uc *ResourceScopesUseCase) rolePermission(roleID, permissionID uint) (*entities.Permission, error) {
+	role, err := uc.roles.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrScopeRoleNotFound
+	}
+	for i := range role.Permissions {
+		if role.Permissions[i].ID == permissionID {
+			return &role.Permissions[i], nil
+		}
+	}
+	return nil, ErrScopePermissionUnset
+}
+
+func joinPatterns(in []string) string {
+	var out []string
+	for _, s := range in {
+		for _, p := range strings.Split(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,102 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestResourceScopes_limitPermissionsToServersAndDatabases(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.ServerGroup{}, &entities.PermissionScope{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	execute := entities.Permission{Name: entities.PermAuditsExecute, Resource: "audits", Action: "execute"}
+	view := entities.Permission{Name: entities.PermAuditsView, Resource: "audits", Action: "view"}
+	db.Create(&execute)
+	db.Create(&view)
+	auditor := entities.Role{Name: "auditor", Permissions: []entities.Permission{execute, view}}
+	db.Create(&auditor)
+	u := &entities.User{Username: "ana", Email: "ana@e", Password: "x", Role: "auditor", IsActive: true}
+	db.Omit("last_login").Create(u)
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: auditor.ID})
+
+	scopeRepo := repositories.NewGormResourceScopeRepository(db)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	perms := NewPermissionsUseCase(persistence.NewUserRepository(db), roleRepo, scopeRepo)
+	admin := NewResourceScopesUseCase(scopeRepo, roleRepo)
+
+	scope, err := perms.Scope(u.ID, entities.PermAuditsExecute)
+	if err != nil || !scope.Global {
+		t.Fatalf("expected an unscoped permission to be global, got %+v %v", scope, err)
+	}
+
+	if _, err := admin.CreateGroup("prod", "", nil); !errors.Is(err, ErrServerGroupInvalid) {
+		t.Fatalf("expected a group without servers to be rejected, got %v", err)
+	}
+	prod, err := admin.CreateGroup("prod", "production", []string{"sql-prod-*, 10.0.1.*"})
+	if err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if _, _, err := admin.SetScopes(auditor.ID, execute.ID, []ScopeInput{{ServerGroupID: &prod.ID}}); err != nil {
+		t.Fatalf("set scopes: %v", err)
+	}
+	if _, _, err := admin.SetScopes(auditor.ID, view.ID, []ScopeInput{{Database: "sales"}}); err != nil {
+		t.Fatalf("set scopes: %v", err)
+	}
+	if _, _, err := admin.SetScopes(auditor.ID, view.ID, []ScopeInput{{}}); !errors.Is(err, ErrScopeEmpty) {
+		t.Fatalf("expected an empty scope to be rejected, got %v", err)
+	}
+
+	scope, _ = perms.Scope(u.ID, entities.PermAuditsExecute)
+	if scope.Global || !scope.AllowsServer("SQL-PROD-01") || !scope.Allows("10.0.1.7", "master") || scope.AllowsServer("sql-dev-01") {
+		t.Fatalf("expected execution only on the prod group, got %+v", scope)
+	}
+	scope, _ = perms.Scope(u.ID, entities.PermAuditsView)
+	if !scope.Allows("any-host", "sales") || scope.Allows("any-host", "hr") || scope.Allows("any-host", "") {
+		t.Fatalf("expected results only for the sales database, got %+v", scope)
+	}
+
+	// another role granting the permission without scopes makes it global again
+	other := entities.Role{Name: "viewer", Permissions: []entities.Permission{view}}
+	db.Create(&other)
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: other.ID})
+	if scope, _ = perms.Scope(u.ID, entities.PermAuditsView); !scope.Global {
+		t.Fatalf("expected an unscoped grant from another role to win, got %+v", scope)
+	}
+
+	if _, err := admin.DeleteGroup(prod.ID); !errors.Is(err, ErrServerGroupInUse) {
+		t.Fatalf("expected a group used by scopes to be kept, got %v", err)
+	}
+	// patterns edited on the group apply to the scopes at once
+	if _, err := admin.UpdateGroup(prod.ID, "prod", "", []string{"sql-dev-*"}); err != nil {
+		t.Fatalf("update group: %v", err)
+	}
+	if scope, _ = perms.Scope(u.ID, entities.PermAuditsExecute); !scope.AllowsServer("sql-dev-01") || scope.AllowsServer("sql-prod-01") {
+		t.Fatalf("expected the updated group patterns, got %+v", scope)
+	}
+
+	// revoking the permission clears its scopes; without any grant nothing is allowed
+	if err := admin.ClearRolePermission(auditor.ID, execute.ID); err != nil {
+		t.Fatalf("clear scopes: %v", err)
+	}
+	db.Model(&auditor).Association("Permissions").Delete(&execute)
+	if scope, _ = perms.Scope(u.ID, entities.PermAuditsExecute); scope.Any() {
+		t.Fatalf("expected no access after the permission is revoked, got %+v", scope)
+	}
+	if _, err := admin.DeleteGroup(prod.ID); err != nil {
+		t.Fatalf("expected an unused group to be deleted, got %v", err)
+	}
+	if _, _, err := admin.SetScopes(auditor.ID, execute.ID, nil); !errors.Is(err, ErrScopePermissionUnset) {
+		t.Fatalf("expected scopes to require the permission on the role, got %v", err)
+	}
+}
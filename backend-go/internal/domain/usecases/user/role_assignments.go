@@ -0,0 +1,108 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	"go.uber.org/zap"
+)
+
+// RoleAssignmentsUseCase mantiene user_roles como única fuente de los roles de
+// un usuario. users.role solo guarda el rol principal derivado de sus
+// asignaciones (lo que va en el JWT y eligen la política de sesiones y la 2FA);
+// tras cada cambio se recalcula y se caducan los access tokens vigentes, para
+// que el cliente los renueve con claims actualizados.
+type RoleAssignmentsUseCase struct {
+	users    repositories.UserRepository
+	roles    repositories.RoleRepository
+	sessions repositories.SessionRepository
+	// cache drops middleware session state once the tokens are expired (optional)
+	cache  services.SessionInvalidator
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+func NewRoleAssignmentsUseCase(ur repositories.UserRepository, rr repositories.RoleRepository, sr repositories.SessionRepository, inv services.SessionInvalidator) *RoleAssignmentsUseCase {
+	return &RoleAssignmentsUseCase{users: ur, roles: rr, sessions: sr, cache: inv, now: time.Now, logger: zap.NewNop()}
+}
+
+// WithLogger registra los cambios de rol principal
+func (uc *RoleAssignmentsUseCase) WithLogger(l *zap.Logger) *RoleAssignmentsUseCase {
+	uc.logger = l
+	return uc
+}
+
+// Assign asigna un rol de forma permanente
+func (uc *RoleAssignmentsUseCase) Assign(userID, roleID uint) error {
+	if err := uc.roles.AssignToUser(userID, roleID); err != nil {
+		return err
+	}
+	return uc.Changed(userID)
+}
+
+// Revoke retira un rol del usuario
+func (uc *RoleAssignmentsUseCase) Revoke(userID, roleID uint) error {
+	if err := uc.roles.RevokeFromUser(userID, roleID); err != nil {
+		return err
+	}
+	return uc.Changed(userID)
+}
+
+// Changed se llama después de cualquier cambio en las asignaciones del usuario
+func (uc *RoleAssignmentsUseCase) Changed(userID uint) error {
+	user, err := uc.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	assigned, err := uc.roles.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+	if primary := PrimaryRole(user.Role, assigned); primary != user.Role {
+		uc.logger.Info("primary role changed", zap.Uint("user_id", userID), zap.String("from", user.Role), zap.String("to", primary))
+		user.Role = primary
+		if err := uc.users.Update(user); err != nil {
+			return err
+		}
+	}
+	if uc.sessions != nil {
+		if _, err := uc.sessions.ExpireAccessByUser(userID, uc.now()); err != nil {
+			return fmt.Errorf("failed to expire access tokens: %w", err)
+		}
+	}
+	if uc.cache != nil {
+		uc.cache.InvalidateUser(userID)
+	}
+	return nil
+}
+
+// RoleDeleted actualiza a los usuarios que tenían el rol; userIDs se obtiene
+// con ListRoleUsers antes de borrarlo
+func (uc *RoleAssignmentsUseCase) RoleDeleted(userIDs []uint) error {
+	for _, id := range userIDs {
+		if err := uc.Changed(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrimaryRole conserva el rol principal actual mientras siga asignado; si no,
+// toma el primer rol vigente (por ID). Sin roles devuelve "".
+func PrimaryRole(current string, assigned []entities.Role) string {
+	for _, r := range assigned {
+		if r.Name == current {
+			return current
+		}
+	}
+	if len(assigned) == 0 {
+		return ""
+	}
+	return assigned[0].Name
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+type invalidatedUsers []uint
+
+func (i *invalidatedUsers) InvalidateToken(string)     {}
+func (i *invalidatedUsers) InvalidateFamily(string)    {}
+func (i *invalidatedUsers) InvalidateUser(userID uint) { *i = append(*i, userID) }
+func (i *invalidatedUsers) InvalidateAll()             {}
+
+func TestRoleAssignments_derivePrimaryRoleAndExpireTokens(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.Session{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	view := entities.Permission{Name: entities.PermAuditsView, Resource: "audits", Action: "view"}
+	db.Create(&view)
+	admin := entities.Role{Name: "admin", Permissions: []entities.Permission{view}}
+	auditor := entities.Role{Name: "auditor", Permissions: []entities.Permission{view}}
+	db.Create(&admin)
+	db.Create(&auditor)
+	u := &entities.User{Username: "ana", Email: "ana@e", Password: "x", Role: "auditor", IsActive: true}
+	db.Omit("last_login").Create(u)
+
+	users := persistence.NewUserRepository(db)
+	roleRepo := repositories.NewGormRoleRepository(db)
+	sessionRepo := repositories.NewGormSessionRepository(db)
+	perms := NewPermissionsUseCase(users, roleRepo, nil)
+
+	// users.role alone no longer grants anything
+	if got, _ := perms.Effective(u.ID); len(got) != 0 {
+		t.Fatalf("expected no permissions without a user_roles assignment, got %v", got)
+	}
+
+	now := time.Now()
+	accessExp, refreshExp := now.Add(15*time.Minute), now.Add(24*time.Hour)
+	live := &entities.Session{UserID: u.ID, Token: "access-1", ExpiresAt: &accessExp, IsActive: true, RefreshTokenHash: "r1", RefreshExpiresAt: &refreshExp, FamilyID: "f1"}
+	sessionRepo.CreateSession(live)
+
+	var cache invalidatedUsers
+	uc := NewRoleAssignmentsUseCase(users, roleRepo, sessionRepo, &cache)
+	uc.now = func() time.Time { return now }
+
+	if err := uc.Assign(u.ID, admin.ID); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	got, _ := users.FindByID(u.ID)
+	if got.Role != "admin" {
+		t.Fatalf("expected the primary role to follow the assignments, got %q", got.Role)
+	}
+	s, _ := sessionRepo.GetByID(live.ID)
+	if s.ExpiresAt == nil || s.ExpiresAt.After(now) || !s.IsActive || s.RevokedAt != nil {
+		t.Fatalf("expected the access token to expire but the session to stay refreshable, got %+v", s)
+	}
+	if left, _ := sessionRepo.ListLiveByUser(u.ID, now); len(left) != 1 {
+		t.Fatalf("expected the refresh token to remain usable, got %+v", left)
+	}
+	if len(cache) != 1 || cache[0] != u.ID {
+		t.Fatalf("expected the session cache of the user to be dropped, got %v", cache)
+	}
+
+	// a second role keeps the primary role while it is still assigned
+	if err := uc.Assign(u.ID, auditor.ID); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if got, _ = users.FindByID(u.ID); got.Role != "admin" {
+		t.Fatalf("expected the primary role to be kept, got %q", got.Role)
+	}
+	if err := uc.Revoke(u.ID, admin.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if got, _ = users.FindByID(u.ID); got.Role != "auditor" {
+		t.Fatalf("expected the remaining role to become primary, got %q", got.Role)
+	}
+
+	holders, _ := roleRepo.ListRoleUsers(auditor.ID)
+	if err := roleRepo.Delete(auditor.ID); err != nil {
+		t.Fatalf("delete role: %v", err)
+	}
+	if err := uc.RoleDeleted(holders); err != nil {
+		t.Fatalf("role deleted: %v", err)
+	}
+	if got, _ = users.FindByID(u.ID); got.Role != "" {
+		t.Fatalf("expected no primary role once every role is gone, got %q", got.Role)
+	}
+	if left, _ := roleRepo.ListUserGrants(u.ID); len(left) != 0 {
+		t.Fatalf("expected the assignments of the deleted role to be removed, got %+v", left)
+	}
+}
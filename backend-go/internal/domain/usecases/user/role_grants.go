@@ -0,0 +1,381 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+)
+
+// Errores de asignaciones temporales y solicitudes de elevación
+var (
+	ErrGrantWindowInvalid    = errors.New("valid_until must be in the future and after valid_from")
+	ErrGrantReasonRequired   = errors.New("a reason is required")
+	ErrGrantTooLong          = errors.New("requested duration exceeds the maximum allowed")
+	ErrRoleNotRequestable    = errors.New("this role cannot be requested")
+	ErrRoleAlreadyGranted    = errors.New("the role is already assigned")
+	ErrRoleGrantedPermanent  = errors.New("the role is already assigned permanently; revoke it before granting it with a window")
+	ErrRoleRequestPending    = errors.New("there is already a pending request for this role")
+	ErrRoleRequestNotFound   = errors.New("role request not found")
+	ErrRoleRequestDecided    = errors.New("role request is no longer pending")
+	ErrRoleRequestSelfReview = errors.New("a request cannot be approved or rejected by its requester")
+)
+
+// RoleGrantOptions configura la elevación just-in-time
+type RoleGrantOptions struct {
+	// MaxDuration limita la duración de una elevación (y su valor por defecto)
+	MaxDuration time.Duration
+	// RequestableRoles son los roles que se pueden solicitar; vacío = cualquiera
+	RequestableRoles []string
+}
+
+// RoleGrantsUseCase gestiona asignaciones de rol con ventana de validez y el
+// flujo solicitar/aprobar de la elevación just-in-time
+type RoleGrantsUseCase struct {
+	roles    repositories.RoleRepository
+	requests repositories.RoleRequestRepository
+	opts     RoleGrantOptions
+	now      func() time.Time
+	// assignments recalcula el rol principal y renueva los tokens (opcional)
+	assignments *RoleAssignmentsUseCase
+}
+
+func NewRoleGrantsUseCase(rr repositories.RoleRepository, qr repositories.RoleRequestRepository, opts RoleGrantOptions) *RoleGrantsUseCase {
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 8 * time.Hour
+	}
+	return &RoleGrantsUseCase{roles: rr, requests: qr, opts: opts, now: time.Now}
+}
+
+// WithAssignments avisa de cada asignación nueva para renovar los tokens del usuario
+func (uc *RoleGrantsUseCase) WithAssignments(a *RoleAssignmentsUseCase) *RoleGrantsUseCase {
+	uc.assignments = a
+	return uc
+}
+
+// ParseRoleList separa una lista de roles por comas (JIT_REQUESTABLE_ROLES)
+func ParseRoleList(v string) []string {
+	var out []string
+	for _, r := range strings.Split(v, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Grant asigna un rol con ventana de validez (administración); sustituye la
+// asignación temporal anterior del mismo rol, pero no una permanente: acortarla
+// exige revocarla antes. Las fechas se guardan en UTC.
+func (uc *RoleGrantsUseCase) Grant(actorID, userID, roleID uint, from, until *time.Time, reason string) (*entities.UserRole, error) {
+	role, err := uc.roles.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	from, until = utcTime(from), utcTime(until)
+	if until != nil && (!until.After(uc.now()) || (from != nil && !until.After(*from))) {
+		return nil, ErrGrantWindowInvalid
+	}
+	if from != nil || until != nil {
+		current, err := uc.grant(userID, roleID)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && current.ValidFrom == nil && current.ValidUntil == nil {
+			return nil, ErrRoleGrantedPermanent
+		}
+	}
+	grant := &entities.UserRole{UserID: userID, RoleID: roleID, ValidFrom: from, ValidUntil: until, GrantedBy: &actorID, Reason: strings.TrimSpace(reason)}
+	if err := uc.roles.GrantToUser(grant); err != nil {
+		return nil, err
+	}
+	if err := uc.changed(userID); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// Request crea una solicitud de elevación; duration 0 usa el máximo permitido
+func (uc *RoleGrantsUseCase) Request(userID, roleID uint, reason string, duration time.Duration) (*entities.RoleRequest, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrGrantReasonRequired
+	}
+	if duration <= 0 {
+		duration = uc.opts.MaxDuration
+	}
+	if duration > uc.opts.MaxDuration {
+		return nil, ErrGrantTooLong
+	}
+	role, err := uc.roles.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	if !uc.requestable(role.Name) {
+		return nil, ErrRoleNotRequestable
+	}
+	current, err := uc.grant(userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.ValidUntil == nil && current.Active(uc.now()) {
+		return nil, ErrRoleAlreadyGranted
+	}
+	pending, err := uc.requests.List(&userID, entities.RoleRequestPending, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pending {
+		if p.RoleID == roleID {
+			return nil, ErrRoleRequestPending
+		}
+	}
+	req := &entities.RoleRequest{
+		UserID:          userID,
+		RoleID:          roleID,
+		Reason:          reason,
+		DurationMinutes: int((duration + time.Minute - 1) / time.Minute),
+		Status:          entities.RoleRequestPending,
+	}
+	if err := uc.requests.Create(req); err != nil {
+		return nil, err
+	}
+	req.Role = role
+	return req, nil
+}
+
+// ListRequests lista solicitudes, opcionalmente de un usuario y en un estado
+func (uc *RoleGrantsUseCase) ListRequests(userID *uint, status string, limit, offset int) ([]entities.RoleRequest, error) {
+	return uc.requests.List(userID, status, limit, offset)
+}
+
+// Approve concede el rol desde ahora y durante el tiempo pedido. Una
+// asignación permanente o más larga que ya tenga el usuario se conserva.
+func (uc *RoleGrantsUseCase) Approve(requestID, approverID uint, note string) (*entities.RoleRequest, error) {
+	req, err := uc.decide(requestID, approverID, entities.RoleRequestApproved, note)
+	if err != nil {
+		return nil, err
+	}
+	current, err := uc.grant(req.UserID, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && current.Active(*req.DecidedAt) && (current.ValidUntil == nil || current.ValidUntil.After(*req.ValidUntil)) {
+		return req, nil
+	}
+	reason := fmt.Sprintf("role request #%d: %s", req.ID, req.Reason)
+	if err := uc.roles.GrantToUser(&entities.UserRole{UserID: req.UserID, RoleID: req.RoleID, ValidUntil: req.ValidUntil, GrantedBy: &approverID, Reason: reason}); err != nil {
+		return nil, err
+	}
+	if err := uc.changed(req.UserID); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Reject rechaza una solicitud pendiente
+func (uc *RoleGrantsUseCase) Reject(requestID, approverID uint, note string) (*entities.RoleRequest, error) {
+	return uc.decide(requestID, approverID, entities.RoleRequestRejected, note)
+}
+
+// decide valida la solicitud y la reserva (pasa de pending al nuevo estado)
+// para que dos aprobadores no actúen a la vez
+func (uc *RoleGrantsUseCase) decide(requestID, approverID uint, status, note string) (*entities.RoleRequest, error) {
+	req, err := uc.requests.GetByID(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, ErrRoleRequestNotFound
+	}
+	if req.Status != entities.RoleRequestPending {
+		return nil, ErrRoleRequestDecided
+	}
+	if req.UserID == approverID {
+		return nil, ErrRoleRequestSelfReview
+	}
+	now := uc.now().UTC()
+	req.Status, req.DecidedBy, req.DecidedAt, req.DecisionNote = status, &approverID, &now, strings.TrimSpace(note)
+	if status == entities.RoleRequestApproved {
+		until := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		req.ValidUntil = &until
+	}
+	ok, err := uc.requests.Decide(req)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRoleRequestDecided
+	}
+	return req, nil
+}
+
+// utcTime copia t en UTC; las ventanas se comparan en SQL y un desfase mezclado
+// en la columna rompería el orden
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func (uc *RoleGrantsUseCase) changed(userID uint) error {
+	if uc.assignments == nil {
+		return nil
+	}
+	return uc.assignments.Changed(userID)
+}
+
+func (uc *RoleGrantsUseCase) grant(userID, roleID uint) (*entities.UserRole, error) {
+	grants, err := uc.roles.ListUserGrants(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range grants {
+		if grants[i].RoleID == roleID {
+			return &grants[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (uc *RoleGrantsUseCase) requestable(role string) bool {
+	if len(uc.opts.RequestableRoles) == 0 {
+		return true
+	}
+	for _, r := range uc.opts.RequestableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleGrantSweeper borra las asignaciones temporales vencidas y deja constancia
+// en el log de auditoría RBAC y en el log de actividad. La autorización ya las
+// ignora al vencer; el barrido solo limpia y registra.
+type RoleGrantSweeper struct {
+	roles    repositories.RoleRepository
+	audit    repositories.AdminAuditRepository
+	interval time.Duration
+	now      func() time.Time
+	logger   *zap.Logger
+	// assignments recalcula el rol principal y renueva los tokens (opcional)
+	assignments *RoleAssignmentsUseCase
+	// activity registra los vencimientos en el log de actividad, y con él en el SIEM (opcional)
+	activity services.ActivityRecorder
+}
+
+func NewRoleGrantSweeper(rr repositories.RoleRepository, ar repositories.AdminAuditRepository, interval time.Duration) *RoleGrantSweeper {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &RoleGrantSweeper{roles: rr, audit: ar, interval: interval, now: time.Now, logger: zap.NewNop()}
+}
+
+// WithAssignments avisa de cada asignación vencida para renovar los tokens del usuario
+func (s *RoleGrantSweeper) WithAssignments(a *RoleAssignmentsUseCase) *RoleGrantSweeper {
+	s.assignments = a
+	return s
+}
+
+// WithActivity registra cada vencimiento como admin.role.expire en el log de actividad
+func (s *RoleGrantSweeper) WithActivity(r services.ActivityRecorder) *RoleGrantSweeper {
+	s.activity = r
+	return s
+}
+
+// WithLogger anota los barridos que fallan
+func (s *RoleGrantSweeper) WithLogger(l *zap.Logger) *RoleGrantSweeper {
+	s.logger = l
+	return s
+}
+
+// Run ejecuta Sweep cada intervalo hasta que ctx se cancele
+func (s *RoleGrantSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil {
+				s.logger.Error("role grant sweep failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Sweep elimina las asignaciones vencidas y devuelve cuántas ha registrado
+func (s *RoleGrantSweeper) Sweep(ctx context.Context) (int, error) {
+	now := s.now().UTC()
+	expired, err := s.roles.ListExpiredGrants(now)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range expired {
+		g := expired[i]
+		// si se renovó entre la lista y el borrado, no se toca
+		deleted, err := s.roles.DeleteExpiredGrant(g.UserID, g.RoleID, now)
+		if err != nil {
+			return n, err
+		}
+		if !deleted {
+			continue
+		}
+		n++
+		if s.assignments != nil {
+			if err := s.assignments.Changed(g.UserID); err != nil {
+				s.logger.Warn("role grant sweep: failed updating user", zap.Uint("user_id", g.UserID), zap.Error(err))
+			}
+		}
+		roleName := fmt.Sprintf("role_id=%d", g.RoleID)
+		if r, _ := s.roles.GetByID(g.RoleID); r != nil {
+			roleName = r.Name
+		}
+		details := fmt.Sprintf("role %s of user_id=%d expired at %s", roleName, g.UserID, g.ValidUntil.UTC().Format(time.RFC3339))
+		if g.Reason != "" {
+			details += " (" + g.Reason + ")"
+		}
+		targetName := fmt.Sprintf("user:%d", g.UserID)
+		if s.activity != nil {
+			s.activity.Record(ctx, &entities.ActivityEvent{
+				Action:       entities.ActivityAdminPrefix + "role.expire",
+				ActorType:    entities.ActorSystem,
+				ResourceType: "user_role",
+				ResourceID:   fmt.Sprint(g.UserID),
+				Details:      entities.ActivityDetails(map[string]interface{}{"target_name": targetName, "role": roleName, "details": details}),
+			})
+		}
+		if s.audit == nil {
+			continue
+		}
+		if err := s.audit.Create(&entities.AdminActionLog{
+			ActorName:  "system",
+			Action:     "role.expire",
+			TargetType: "user_role",
+			TargetID:   &g.UserID,
+			TargetName: targetName,
+			Details:    details,
+		}); err != nil {
+			s.logger.Warn("role grant sweep: failed recording expiry", zap.Error(err))
+		}
+	}
+	return n, nil
+}
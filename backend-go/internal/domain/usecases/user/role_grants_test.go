@@ -0,0 +1,177 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestRoleGrants_justInTimeRequestsExpire(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.RoleRequest{}, &entities.AdminActionLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	execute := entities.Permission{Name: entities.PermAuditsExecute, Resource: "audits", Action: "execute"}
+	db.Create(&execute)
+	auditor := entities.Role{Name: "auditor", Permissions: []entities.Permission{execute}}
+	db.Create(&auditor)
+	db.Create(&entities.Role{Name: "admin"})
+	oncall := &entities.User{Username: "oncall", Email: "oncall@e", Password: "x", Role: "user", IsActive: true}
+	lead := &entities.User{Username: "lead", Email: "lead@e", Password: "x", Role: "user", IsActive: true}
+	db.Omit("last_login").Create(oncall)
+	db.Omit("last_login").Create(lead)
+
+	roleRepo := repositories.NewGormRoleRepository(db)
+	grants := NewRoleGrantsUseCase(roleRepo, repositories.NewGormRoleRequestRepository(db), RoleGrantOptions{MaxDuration: 4 * time.Hour, RequestableRoles: []string{"auditor"}})
+	perms := NewPermissionsUseCase(persistence.NewUserRepository(db), roleRepo, nil)
+
+	var admin entities.Role
+	db.Where("name = ?", "admin").First(&admin)
+	if _, err := grants.Request(oncall.ID, admin.ID, "incident", time.Hour); !errors.Is(err, ErrRoleNotRequestable) {
+		t.Fatalf("expected admin to not be requestable, got %v", err)
+	}
+	if _, err := grants.Request(oncall.ID, auditor.ID, " ", time.Hour); !errors.Is(err, ErrGrantReasonRequired) {
+		t.Fatalf("expected a reason to be required, got %v", err)
+	}
+	if _, err := grants.Request(oncall.ID, auditor.ID, "incident", 5*time.Hour); !errors.Is(err, ErrGrantTooLong) {
+		t.Fatalf("expected the maximum duration to apply, got %v", err)
+	}
+	req, err := grants.Request(oncall.ID, auditor.ID, "INC-42 audit", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	if _, err := grants.Request(oncall.ID, auditor.ID, "again", time.Hour); !errors.Is(err, ErrRoleRequestPending) {
+		t.Fatalf("expected a second pending request to be refused, got %v", err)
+	}
+	if got, _ := perms.Effective(oncall.ID); got[entities.PermAuditsExecute] {
+		t.Fatalf("a pending request must not grant anything")
+	}
+
+	if _, err := grants.Approve(req.ID, oncall.ID, ""); !errors.Is(err, ErrRoleRequestSelfReview) {
+		t.Fatalf("expected self approval to be refused, got %v", err)
+	}
+	now := time.Now()
+	grants.now = func() time.Time { return now }
+	approved, err := grants.Approve(req.ID, lead.ID, "ok")
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if approved.Status != entities.RoleRequestApproved || !approved.ValidUntil.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("unexpected approved request %+v", approved)
+	}
+	if _, err := grants.Reject(req.ID, lead.ID, ""); !errors.Is(err, ErrRoleRequestDecided) {
+		t.Fatalf("expected a decided request to stay decided, got %v", err)
+	}
+	if got, _ := perms.Effective(oncall.ID); !got[entities.PermAuditsExecute] {
+		t.Fatalf("expected the approved role to grant its permissions, got %v", got)
+	}
+
+	// the window closing is enough for authorization, before any sweep
+	past := time.Now().Add(-time.Minute)
+	db.Model(&entities.UserRole{}).Where("user_id = ?", oncall.ID).Update("valid_until", past)
+	if got, _ := perms.Effective(oncall.ID); got[entities.PermAuditsExecute] {
+		t.Fatalf("expected the expired grant to be ignored, got %v", got)
+	}
+
+	// a grant that has not started yet is ignored too, and is not swept
+	from, until := time.Now().Add(time.Hour), time.Now().Add(3*time.Hour)
+	if _, err := grants.Grant(lead.ID, lead.ID, auditor.ID, &from, &until, "weekend on-call"); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if got, _ := perms.Effective(lead.ID); got[entities.PermAuditsExecute] {
+		t.Fatalf("expected a future grant to be inactive, got %v", got)
+	}
+	if _, err := grants.Grant(lead.ID, lead.ID, auditor.ID, nil, &past, ""); !errors.Is(err, ErrGrantWindowInvalid) {
+		t.Fatalf("expected a grant ending in the past to be rejected, got %v", err)
+	}
+
+	audit := repositories.NewGormAdminAuditRepository(db)
+	var events recordedActivity
+	sweeper := NewRoleGrantSweeper(roleRepo, audit, time.Minute).WithActivity(&events)
+	n, err := sweeper.Sweep(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("expected one expired grant to be swept, got %d %v", n, err)
+	}
+	action := "role.expire"
+	logs, _ := audit.List(nil, nil, &action, 10, 0)
+	if len(logs) != 1 || logs[0].TargetID == nil || *logs[0].TargetID != oncall.ID || !strings.Contains(logs[0].Details, "auditor") {
+		t.Fatalf("expected the expiry in the admin log, got %+v", logs)
+	}
+	// the activity log, and with it the SIEM, gets the expiry too
+	if len(events) != 1 || events[0].Action != entities.ActivityAdminPrefix+"role.expire" || events[0].ActorType != entities.ActorSystem || events[0].ResourceID != fmt.Sprint(oncall.ID) {
+		t.Fatalf("expected the expiry in the activity log, got %+v", events)
+	}
+	if left, _ := roleRepo.ListUserGrants(oncall.ID); len(left) != 0 {
+		t.Fatalf("expected the expired grant to be removed, got %+v", left)
+	}
+	if left, _ := roleRepo.ListUserGrants(lead.ID); len(left) != 1 {
+		t.Fatalf("expected the future grant to be kept, got %+v", left)
+	}
+}
+
+func TestRoleGrants_windowsAreStoredInUTCAndKeepPermanentGrants(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.RoleRequest{}, &entities.AdminActionLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	auditor := entities.Role{Name: "auditor"}
+	admin := entities.Role{Name: "admin"}
+	db.Create(&auditor)
+	db.Create(&admin)
+	u := &entities.User{Username: "oncall", Email: "oncall@e", Password: "x", Role: "user", IsActive: true}
+	db.Omit("last_login").Create(u)
+
+	roleRepo := repositories.NewGormRoleRepository(db)
+	grants := NewRoleGrantsUseCase(roleRepo, repositories.NewGormRoleRequestRepository(db), RoleGrantOptions{})
+	sweeper := NewRoleGrantSweeper(roleRepo, nil, time.Minute)
+
+	// a window sent with a +05:00 offset is stored in UTC, so it closes at the
+	// right instant instead of hours later
+	plus5 := time.FixedZone("UTC+5", 5*60*60)
+	now := time.Now()
+	until := now.Add(time.Hour).In(plus5)
+	grant, err := grants.Grant(1, u.ID, auditor.ID, nil, &until, "INC-7")
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if grant.ValidUntil.Location() != time.UTC || !grant.ValidUntil.Equal(until) {
+		t.Fatalf("expected valid_until in UTC, got %v", grant.ValidUntil)
+	}
+	var stored entities.UserRole
+	db.Where("user_id = ? AND role_id = ?", u.ID, auditor.ID).First(&stored)
+	if !stored.ValidUntil.Equal(until) {
+		t.Fatalf("stored %v, want %v", stored.ValidUntil, until)
+	}
+	sweeper.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if n, err := sweeper.Sweep(context.Background()); err != nil || n != 1 {
+		t.Fatalf("expected the grant to expire after its window, got %d %v", n, err)
+	}
+
+	// a window never shortens a permanent assignment
+	if err := roleRepo.AssignToUser(u.ID, admin.ID); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	until = now.Add(time.Hour)
+	if _, err := grants.Grant(1, u.ID, admin.ID, nil, &until, "temporary"); !errors.Is(err, ErrRoleGrantedPermanent) {
+		t.Fatalf("expected a windowed grant over a permanent one to be refused, got %v", err)
+	}
+	if r, _ := roleRepo.GetUserRoles(u.ID); len(r) != 1 || r[0].Name != "admin" {
+		t.Fatalf("expected the permanent admin role to remain, got %v", r)
+	}
+}
@@ -0,0 +1,162 @@
+package user
+
+import (
+	"errors"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+)
+
+// Errores de la jerarquía de roles
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrParentRoleNotFound = errors.New("parent role not found")
+	ErrRoleCycle          = errors.New("the parent role would create a cycle in the hierarchy")
+	ErrRoleHasChildren    = errors.New("role is the parent of other roles")
+)
+
+// InheritedPermission es un permiso que el rol recibe de un ancestro
+type InheritedPermission struct {
+	entities.Permission
+	FromRoleID uint   `json:"from_role_id"`
+	FromRole   string `json:"from_role"`
+}
+
+// RolePermissions separa los permisos propios de un rol de los heredados
+type RolePermissions struct {
+	Role *entities.Role `json:"role"`
+	// Ancestors va del padre directo a la raíz
+	Ancestors []entities.Role       `json:"ancestors"`
+	Direct    []entities.Permission `json:"direct"`
+	Inherited []InheritedPermission `json:"inherited"`
+}
+
+// RoleHierarchyUseCase gestiona los roles padre. La herencia se resuelve al
+// calcular los permisos, así que un cambio en un padre llega a los hijos en la
+// siguiente petición.
+type RoleHierarchyUseCase struct {
+	roles repositories.RoleRepository
+}
+
+func NewRoleHierarchyUseCase(rr repositories.RoleRepository) *RoleHierarchyUseCase {
+	return &RoleHierarchyUseCase{roles: rr}
+}
+
+// CheckParent valida el padre de un rol (roleID 0 = rol nuevo): debe existir y
+// no puede ser el propio rol ni uno de sus descendientes
+func (uc *RoleHierarchyUseCase) CheckParent(roleID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	parent, err := uc.roles.GetByID(*parentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return ErrParentRoleNotFound
+	}
+	if roleID == 0 {
+		return nil
+	}
+	if parent.ID == roleID {
+		return ErrRoleCycle
+	}
+	chain, err := ancestors(uc.roles, parent)
+	if err != nil {
+		return err
+	}
+	for _, a := range chain {
+		if a.ID == roleID {
+			return ErrRoleCycle
+		}
+	}
+	return nil
+}
+
+// SetParent cambia el padre de un rol; nil lo deja sin padre
+func (uc *RoleHierarchyUseCase) SetParent(roleID uint, parentID *uint) (*entities.Role, error) {
+	role, err := uc.roles.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	if err := uc.CheckParent(roleID, parentID); err != nil {
+		return nil, err
+	}
+	if err := uc.roles.SetParent(roleID, parentID); err != nil {
+		return nil, err
+	}
+	role.ParentID = parentID
+	return role, nil
+}
+
+// CheckDelete impide borrar un rol del que dependen otros
+func (uc *RoleHierarchyUseCase) CheckDelete(roleID uint) error {
+	children, err := uc.roles.ListChildren(roleID)
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrRoleHasChildren
+	}
+	return nil
+}
+
+// Permissions devuelve los permisos directos del rol y los heredados; si un
+// permiso llega por varios ancestros se indica el más cercano
+func (uc *RoleHierarchyUseCase) Permissions(roleID uint) (*RolePermissions, error) {
+	role, err := uc.roles.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	chain, err := ancestors(uc.roles, role)
+	if err != nil {
+		return nil, err
+	}
+	out := &RolePermissions{Role: role, Ancestors: chain, Direct: role.Permissions, Inherited: []InheritedPermission{}}
+	if out.Direct == nil {
+		out.Direct = []entities.Permission{}
+	}
+	if out.Ancestors == nil {
+		out.Ancestors = []entities.Role{}
+	}
+	seen := map[uint]bool{}
+	for _, p := range role.Permissions {
+		seen[p.ID] = true
+	}
+	for _, a := range chain {
+		for _, p := range a.Permissions {
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			out.Inherited = append(out.Inherited, InheritedPermission{Permission: p, FromRoleID: a.ID, FromRole: a.Name})
+		}
+	}
+	return out, nil
+}
+
+// ancestors sube desde el padre de role hasta la raíz. Se detiene si encuentra
+// un ciclo (solo posible si se editó la base de datos a mano).
+func ancestors(roles repositories.RoleRepository, role *entities.Role) ([]entities.Role, error) {
+	var out []entities.Role
+	seen := map[uint]bool{role.ID: true}
+	for next := role.ParentID; next != nil && !seen[*next]; {
+		parent, err := roles.GetByID(*next)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			break
+		}
+		seen[parent.ID] = true
+		out = append(out, *parent)
+		next = parent.ParentID
+	}
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package user
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func TestRoleHierarchy_inheritsPermissionsAndRejectsCycles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	view := entities.Permission{Name: entities.PermAuditsView, Resource: "audits", Action: "view"}
+	execute := entities.Permission{Name: entities.PermAuditsExecute, Resource: "audits", Action: "execute"}
+	manage := entities.Permission{Name: entities.PermConnectionsManage, Resource: "connections", Action: "manage"}
+	db.Create(&view)
+	db.Create(&execute)
+	db.Create(&manage)
+	base := entities.Role{Name: "viewer", Permissions: []entities.Permission{view}}
+	db.Create(&base)
+	auditor := entities.Role{Name: "auditor", ParentID: &base.ID, Permissions: []entities.Permission{execute}}
+	db.Create(&auditor)
+	lead := entities.Role{Name: "auditor-lead", ParentID: &auditor.ID}
+	db.Create(&lead)
+	u := &entities.User{Username: "lead", Email: "lead@e", Password: "x", Role: "auditor-lead", IsActive: true}
+	db.Omit("last_login").Create(u)
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: lead.ID})
+
+	roleRepo := repositories.NewGormRoleRepository(db)
+	hierarchy := NewRoleHierarchyUseCase(roleRepo)
+	perms := NewPermissionsUseCase(persistence.NewUserRepository(db), roleRepo, nil)
+
+	got, err := perms.Effective(u.ID)
+	if err != nil || !got[entities.PermAuditsView] || !got[entities.PermAuditsExecute] || got[entities.PermConnectionsManage] {
+		t.Fatalf("expected permissions of every ancestor, got %v %v", got, err)
+	}
+
+	rp, err := hierarchy.Permissions(lead.ID)
+	if err != nil {
+		t.Fatalf("role permissions: %v", err)
+	}
+	if len(rp.Direct) != 0 || len(rp.Inherited) != 2 || len(rp.Ancestors) != 2 || rp.Inherited[0].FromRole != "auditor" || rp.Inherited[1].FromRole != "viewer" {
+		t.Fatalf("unexpected direct/inherited split %+v", rp)
+	}
+
+	// a change on the root reaches the grandchild on the next lookup
+	db.Model(&base).Association("Permissions").Append(&manage)
+	if got, _ = perms.Effective(u.ID); !got[entities.PermConnectionsManage] {
+		t.Fatalf("expected the new parent permission to be inherited, got %v", got)
+	}
+
+	if _, err := hierarchy.SetParent(base.ID, &lead.ID); !errors.Is(err, ErrRoleCycle) {
+		t.Fatalf("expected a cycle to be rejected, got %v", err)
+	}
+	if _, err := hierarchy.SetParent(base.ID, &base.ID); !errors.Is(err, ErrRoleCycle) {
+		t.Fatalf("expected a role to not be its own parent, got %v", err)
+	}
+	missing := uint(999)
+	if err := hierarchy.CheckParent(0, &missing); !errors.Is(err, ErrParentRoleNotFound) {
+		t.Fatalf("expected an unknown parent to be rejected, got %v", err)
+	}
+	if err := hierarchy.CheckDelete(auditor.ID); !errors.Is(err, ErrRoleHasChildren) {
+		t.Fatalf("expected a parent role to be protected from deletion, got %v", err)
+	}
+
+	if _, err := hierarchy.SetParent(lead.ID, nil); err != nil {
+		t.Fatalf("detach: %v", err)
+	}
+	if got, _ = perms.Effective(u.ID); len(got) != 0 {
+		t.Fatalf("expected no permissions after detaching the role, got %v", got)
+	}
+}
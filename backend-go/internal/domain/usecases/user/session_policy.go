@@ -0,0 +1,123 @@
+package user
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Qué hacer cuando un login supera el máximo de sesiones
+const (
+	SessionPolicyReject        = "reject"
+	SessionPolicyReplaceOldest = "replace_oldest"
+)
+
+// SessionPolicy limita las sesiones activas por usuario: logins con refresh token
+// vigente cuyo access token no ha caducado o caducó hace menos de Idle (se usaron
+// hace poco). reject:1 es la sesión única que rechaza logins nuevos; replace_oldest:1
+// cierra la sesión anterior; reject:N / replace_oldest:N permiten N sesiones.
+type SessionPolicy struct {
+	Mode string
+	Max  int
+}
+
+// SessionPolicies es la política global con excepciones por rol
+type SessionPolicies struct {
+	Default SessionPolicy
+	ByRole  map[string]SessionPolicy
+	// Idle es cuánto sigue contando una sesión después de caducar su access token
+	// sin refrescarse; cero usa DefaultSessionIdle
+	Idle time.Duration
+}
+
+// DefaultSessionPolicy permite tres sesiones y cierra la más antigua, para que
+// una sesión abandonada no bloquee los logins nuevos
+var DefaultSessionPolicy = SessionPolicy{Mode: SessionPolicyReplaceOldest, Max: 3}
+
+// DefaultSessionIdle es el Idle por defecto
+const DefaultSessionIdle = 30 * time.Minute
+
+func (p SessionPolicies) idle() time.Duration {
+	if p.Idle <= 0 {
+		return DefaultSessionIdle
+	}
+	return p.Idle
+}
+
+// For devuelve la política aplicable a un rol
+func (p SessionPolicies) For(role string) SessionPolicy {
+	return p.ForRoles([]string{role})
+}
+
+// ForRoles devuelve la política más estricta entre las de los roles (menos
+// sesiones; a igualdad, reject); si ninguno tiene una propia, la global
+func (p SessionPolicies) ForRoles(roles []string) SessionPolicy {
+	var (
+		out   SessionPolicy
+		found bool
+	)
+	for _, r := range roles {
+		rp, ok := p.ByRole[r]
+		if !ok {
+			continue
+		}
+		if !found || rp.Max < out.Max || (rp.Max == out.Max && rp.Mode == SessionPolicyReject) {
+			out, found = rp, true
+		}
+	}
+	if found {
+		return out
+	}
+	if p.Default.Mode == "" {
+		return DefaultSessionPolicy
+	}
+	return p.Default
+}
+
+// ParseSessionPolicy lee "mode[:N]" (p.ej. "reject", "replace_oldest:1", "reject:5");
+// vacío es DefaultSessionPolicy
+func ParseSessionPolicy(spec string) (SessionPolicy, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return DefaultSessionPolicy, nil
+	}
+	parts := strings.SplitN(spec, ":", 2)
+	p := SessionPolicy{Mode: strings.TrimSpace(parts[0]), Max: 1}
+	if p.Mode != SessionPolicyReject && p.Mode != SessionPolicyReplaceOldest {
+		return SessionPolicy{}, fmt.Errorf("invalid session policy mode %q", p.Mode)
+	}
+	if len(parts) == 2 {
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 1 {
+			return SessionPolicy{}, fmt.Errorf("invalid session limit in %q", spec)
+		}
+		p.Max = n
+	}
+	return p, nil
+}
+
+// ParseSessionPolicies lee la política global y las de rol ("admin=reject:1,user=replace_oldest:3")
+func ParseSessionPolicies(def, byRole string) (SessionPolicies, error) {
+	d, err := ParseSessionPolicy(def)
+	if err != nil {
+		return SessionPolicies{}, err
+	}
+	out := SessionPolicies{Default: d, ByRole: map[string]SessionPolicy{}}
+	for _, entry := range strings.Split(byRole, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return SessionPolicies{}, fmt.Errorf("invalid role session policy %q, expected role=mode[:N]", entry)
+		}
+		p, err := ParseSessionPolicy(kv[1])
+		if err != nil {
+			return SessionPolicies{}, err
+		}
+		out.ByRole[strings.TrimSpace(kv[0])] = p
+	}
+	return out, nil
+}
@@ -0,0 +1,333 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/randtoken"
+)
+
+// Refresh errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected; session revoked")
+)
+
+// Session policy errors
+var (
+	ErrSessionLimitReached = errors.New("user already has the maximum number of active sessions")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// SessionMeta describe el cliente que abre o refresca una sesión
+type SessionMeta struct {
+	Device    string
+	IPAddress string
+	UserAgent string
+}
+
+// TokenPair es lo que recibe el cliente tras login o refresh
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+	Session          *entities.Session
+}
+
+// SessionTokensUseCase emite access tokens de vida corta y refresh tokens rotativos.
+// Cada login abre una familia; cada refresh crea una sesión nueva en la familia y
+// marca la anterior como rotada. Presentar un refresh token ya rotado revoca la familia.
+type SessionTokensUseCase struct {
+	sessions repositories.SessionRepository
+	users    repositories.UserRepository
+	tokens   services.TokenIssuer
+	// cache drops middleware session state on revocation (optional)
+	cache services.SessionInvalidator
+	// activity records logouts in the activity log (optional)
+	activity services.ActivityRecorder
+	now      func() time.Time
+
+	policies SessionPolicies
+	// roles aplica la política de todos los roles del usuario, no solo la del principal (opcional)
+	roles repositories.RoleRepository
+}
+
+func NewSessionTokensUseCase(
+	sr repositories.SessionRepository,
+	ur repositories.UserRepository,
+	ti services.TokenIssuer,
+	inv services.SessionInvalidator,
+) *SessionTokensUseCase {
+	return &SessionTokensUseCase{
+		sessions: sr,
+		users:    ur,
+		tokens:   ti,
+		cache:    inv,
+		now:      time.Now,
+	}
+}
+
+// WithPolicies configura la política de sesiones concurrentes (global y por rol)
+func (uc *SessionTokensUseCase) WithPolicies(p SessionPolicies) *SessionTokensUseCase {
+	uc.policies = p
+	return uc
+}
+
+// WithRoles aplica la política más estricta de los roles vigentes del usuario y
+// de sus ancestros
+func (uc *SessionTokensUseCase) WithRoles(rr repositories.RoleRepository) *SessionTokensUseCase {
+	uc.roles = rr
+	return uc
+}
+
+// WithActivity registra los logouts en el log de actividad
+func (uc *SessionTokensUseCase) WithActivity(r services.ActivityRecorder) *SessionTokensUseCase {
+	uc.activity = r
+	return uc
+}
+
+// Start abre una familia de tokens nueva para el usuario ya autenticado,
+// aplicando la política de sesiones de sus roles
+func (uc *SessionTokensUseCase) Start(ctx context.Context, user *entities.User, meta SessionMeta) (*TokenPair, error) {
+	familyID, err := randtoken.Hex(16)
+	if err != nil {
+		return nil, err
+	}
+	names, err := userRoleNames(uc.roles, user)
+	if err != nil {
+		return nil, err
+	}
+	policy := uc.policies.ForRoles(names)
+	now := uc.now()
+	var replaced []entities.Session
+	pair, err := uc.issue(user, familyID, &now, meta, func(s *entities.Session) error {
+		var err error
+		replaced, err = uc.sessions.CreateWithinLimit(s, now, now.Add(-uc.policies.idle()), func(active []entities.Session) ([]entities.Session, error) {
+			return sessionsToReplace(policy, active)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range replaced {
+		uc.forget(s)
+		if uc.activity != nil {
+			uc.activity.Record(ctx, &entities.ActivityEvent{
+				Action:       entities.ActivityLogout,
+				ActorID:      &user.ID,
+				ResourceType: "session",
+				ResourceID:   fmt.Sprint(s.ID),
+				Details:      entities.ActivityDetails(map[string]interface{}{"reason": "session_policy", "replaced_by": pair.Session.ID}),
+			})
+		}
+	}
+	return pair, nil
+}
+
+// sessionsToReplace rechaza el login o elige las sesiones más antiguas que se
+// cierran cuando el usuario ya tiene el máximo permitido
+func sessionsToReplace(policy SessionPolicy, active []entities.Session) ([]entities.Session, error) {
+	excess := len(active) - policy.Max + 1
+	if excess <= 0 {
+		return nil, nil
+	}
+	if policy.Mode != SessionPolicyReplaceOldest {
+		return nil, ErrSessionLimitReached
+	}
+	return active[:excess], nil
+}
+
+// ListForUser devuelve las sesiones vigentes del usuario (una por login)
+func (uc *SessionTokensUseCase) ListForUser(userID uint) ([]entities.Session, error) {
+	return uc.sessions.ListLiveByUser(userID, uc.now())
+}
+
+// TerminateForUser cierra una sesión propia del usuario; las ajenas se tratan como inexistentes
+func (uc *SessionTokensUseCase) TerminateForUser(userID, sessionID uint) error {
+	s, err := uc.sessions.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+	if s == nil || s.UserID != userID || !s.IsActive || s.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+	return uc.revoke(s)
+}
+
+// Refresh canjea un refresh token por un par nuevo
+func (uc *SessionTokensUseCase) Refresh(refreshToken string, meta SessionMeta) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	s, err := uc.sessions.GetByRefreshHash(HashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	now := uc.now()
+	if s.RotatedAt != nil {
+		// el token ya se usó: alguien más lo tiene, se revoca toda la familia
+		if err := uc.RevokeFamily(s.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if !s.IsActive || s.RevokedAt != nil || s.RefreshExpiresAt == nil || !s.RefreshExpiresAt.After(now) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := uc.users.FindByID(s.UserID)
+	if err != nil || user == nil || !user.IsActive {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	swapped, err := uc.sessions.MarkRotated(s.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !swapped {
+		// otra petición canjeó el mismo token a la vez
+		if err := uc.RevokeFamily(s.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	// el access token de la sesión rotada deja de valer ya, no al caducar la caché
+	if uc.cache != nil {
+		uc.cache.InvalidateToken(s.Token)
+	}
+
+	// el dispositivo y el inicio de la sesión se heredan; IP y user agent son los del refresh
+	meta.Device = s.Device
+	started := s.StartedAt
+	if started == nil {
+		started = &s.CreatedAt
+	}
+	return uc.issue(user, s.FamilyID, started, meta, uc.sessions.CreateSession)
+}
+
+// RevokeFamily cierra todas las sesiones de la familia
+func (uc *SessionTokensUseCase) RevokeFamily(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	if err := uc.sessions.RevokeFamily(familyID, uc.now()); err != nil {
+		return err
+	}
+	if uc.cache != nil {
+		uc.cache.InvalidateFamily(familyID)
+	}
+	return nil
+}
+
+// Logout es el logout pedido por el usuario: revoca la sesión y lo registra en
+// el log de actividad
+func (uc *SessionTokensUseCase) Logout(ctx context.Context, s *entities.Session) error {
+	err := uc.revoke(s)
+	if uc.activity != nil {
+		ev := &entities.ActivityEvent{
+			Action:       entities.ActivityLogout,
+			ActorID:      &s.UserID,
+			ResourceType: "session",
+			ResourceID:   fmt.Sprint(s.ID),
+		}
+		if err != nil {
+			ev.Outcome = entities.OutcomeFailure
+			ev.Details = entities.ActivityDetails(map[string]interface{}{"error": err.Error()})
+		}
+		uc.activity.Record(ctx, ev)
+	}
+	return err
+}
+
+// revoke revoca la sesión (y su familia de refresh tokens) con efecto inmediato
+func (uc *SessionTokensUseCase) revoke(s *entities.Session) error {
+	if s.FamilyID != "" {
+		if err := uc.RevokeFamily(s.FamilyID); err != nil {
+			return err
+		}
+	} else if err := uc.sessions.RevokeByID(s.ID, uc.now()); err != nil {
+		// sesiones anteriores a los refresh tokens no tienen familia
+		return err
+	}
+	uc.forget(*s)
+	return nil
+}
+
+// forget descarta de la caché del middleware una sesión ya revocada; la familia
+// solo cubre las entradas cacheadas con ella, así que el token se descarta siempre
+func (uc *SessionTokensUseCase) forget(s entities.Session) {
+	if uc.cache == nil {
+		return
+	}
+	uc.cache.InvalidateFamily(s.FamilyID)
+	uc.cache.InvalidateToken(s.Token)
+}
+
+func (uc *SessionTokensUseCase) issue(user *entities.User, familyID string, startedAt *time.Time, meta SessionMeta, create func(*entities.Session) error) (*TokenPair, error) {
+	access, err := uc.tokens.GenerateToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	refresh, err := randtoken.New(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	accessExp := now.Add(uc.tokens.AccessExpiry())
+	refreshExp := now.Add(uc.tokens.RefreshExpiry())
+	s := &entities.Session{
+		UserID:           user.ID,
+		Token:            access,
+		ExpiresAt:        &accessExp,
+		IsActive:         true,
+		RefreshTokenHash: HashRefreshToken(refresh),
+		RefreshExpiresAt: &refreshExp,
+		FamilyID:         familyID,
+		StartedAt:        startedAt,
+		Device:           truncate(meta.Device, 100),
+		IPAddress:        truncate(meta.IPAddress, 64),
+		UserAgent:        truncate(meta.UserAgent, 255),
+	}
+	if err := create(s); err != nil {
+		if errors.Is(err, ErrSessionLimitReached) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		AccessExpiresAt:  accessExp,
+		RefreshExpiresAt: refreshExp,
+		Session:          s,
+	}, nil
+}
+
+// HashRefreshToken calcula el SHA-256 (hex) con el que se guarda un refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return strings.ToValidUTF8(s[:n], "")
+	}
+	return s
+}
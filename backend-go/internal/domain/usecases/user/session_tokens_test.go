@@ -0,0 +1,320 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/security"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+func setupSessionTokens(t *testing.T) (*SessionTokensUseCase, *gorm.DB, *entities.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Session{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	u := &entities.User{Username: "alice", Email: "a@e", Password: "x", Role: "user", IsActive: true}
+	if err := db.Omit("last_login").Create(u).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	jwt := security.NewJWTServiceWithTTL("secret", 5*time.Minute, time.Hour)
+	uc := NewSessionTokensUseCase(repositories.NewGormSessionRepository(db), persistence.NewUserRepository(db), jwt, nil)
+	return uc, db, u
+}
+
+func TestSessionTokens_refreshRotatesAndStoresOnlyHash(t *testing.T) {
+	uc, db, u := setupSessionTokens(t)
+
+	first, err := uc.Start(context.Background(), u, SessionMeta{})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	var stored entities.Session
+	db.First(&stored, first.Session.ID)
+	if stored.RefreshTokenHash == first.RefreshToken || stored.RefreshTokenHash != HashRefreshToken(first.RefreshToken) {
+		t.Fatalf("expected only the refresh token hash to be stored")
+	}
+
+	second, err := uc.Refresh(first.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Fatalf("expected a new token pair")
+	}
+	if second.Session.FamilyID != first.Session.FamilyID {
+		t.Fatalf("expected rotation to stay in the same family")
+	}
+	db.First(&stored, first.Session.ID)
+	if stored.IsActive || stored.RotatedAt == nil {
+		t.Fatalf("expected previous session to be marked rotated")
+	}
+}
+
+func TestSessionTokens_reuseRevokesWholeFamily(t *testing.T) {
+	uc, db, u := setupSessionTokens(t)
+
+	first, _ := uc.Start(context.Background(), u, SessionMeta{})
+	second, err := uc.Refresh(first.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	// replaying the already-used token is treated as theft
+	if _, err := uc.Refresh(first.RefreshToken, SessionMeta{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected reuse error, got %v", err)
+	}
+	// the legitimate holder's newer token is revoked as well
+	if _, err := uc.Refresh(second.RefreshToken, SessionMeta{}); err == nil {
+		t.Fatalf("expected family to be revoked")
+	}
+	var active int64
+	db.Model(&entities.Session{}).Where("family_id = ? AND is_active = ?", first.Session.FamilyID, true).Count(&active)
+	if active != 0 {
+		t.Fatalf("expected no active sessions in the family, got %d", active)
+	}
+
+	// other logins of the same user are unaffected
+	other, _ := uc.Start(context.Background(), u, SessionMeta{})
+	if _, err := uc.Refresh(other.RefreshToken, SessionMeta{}); err != nil {
+		t.Fatalf("expected unrelated family to keep working: %v", err)
+	}
+}
+
+func TestSessionTokens_expiredOrUnknownRefreshTokenIsRejected(t *testing.T) {
+	uc, _, u := setupSessionTokens(t)
+
+	pair, _ := uc.Start(context.Background(), u, SessionMeta{})
+	uc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := uc.Refresh(pair.RefreshToken, SessionMeta{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected expired refresh token to be rejected, got %v", err)
+	}
+	if _, err := uc.Refresh("not-a-token", SessionMeta{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected unknown token to be rejected, got %v", err)
+	}
+}
+
+func TestSessionTokens_policyRejectReplaceAndConcurrent(t *testing.T) {
+	uc, db, u := setupSessionTokens(t)
+
+	// single session, new logins are rejected
+	uc.WithPolicies(SessionPolicies{Default: SessionPolicy{Mode: SessionPolicyReject, Max: 1}})
+	first, err := uc.Start(context.Background(), u, SessionMeta{Device: "laptop", IPAddress: "10.0.0.1", UserAgent: "ua/1"})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, err := uc.Start(context.Background(), u, SessionMeta{}); !errors.Is(err, ErrSessionLimitReached) {
+		t.Fatalf("expected session limit error, got %v", err)
+	}
+
+	// a refresh keeps the device and login time, and does not count as another session
+	refreshed, err := uc.Refresh(first.RefreshToken, SessionMeta{IPAddress: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if refreshed.Session.Device != "laptop" || refreshed.Session.IPAddress != "10.0.0.2" || refreshed.Session.StartedAt == nil {
+		t.Fatalf("unexpected session metadata after refresh: %+v", refreshed.Session)
+	}
+
+	// per-role override: replace the oldest session
+	policies, err := ParseSessionPolicies("reject", "user=replace_oldest:1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	uc.WithPolicies(policies)
+	second, err := uc.Start(context.Background(), u, SessionMeta{Device: "phone"})
+	if err != nil {
+		t.Fatalf("expected oldest session to be replaced, got %v", err)
+	}
+	var old entities.Session
+	db.First(&old, refreshed.Session.ID)
+	if old.IsActive || old.RevokedAt == nil {
+		t.Fatalf("expected the previous login to be revoked")
+	}
+
+	// N concurrent sessions, rejecting beyond the limit
+	uc.WithPolicies(SessionPolicies{Default: SessionPolicy{Mode: SessionPolicyReject, Max: 2}})
+	if _, err := uc.Start(context.Background(), u, SessionMeta{Device: "tablet"}); err != nil {
+		t.Fatalf("expected a second concurrent session: %v", err)
+	}
+	if _, err := uc.Start(context.Background(), u, SessionMeta{}); !errors.Is(err, ErrSessionLimitReached) {
+		t.Fatalf("expected limit at 2 sessions, got %v", err)
+	}
+	live, _ := uc.ListForUser(u.ID)
+	if len(live) != 2 || live[0].ID != second.Session.ID {
+		t.Fatalf("expected 2 live sessions, oldest first, got %+v", live)
+	}
+
+	// users can only terminate their own sessions
+	if err := uc.TerminateForUser(u.ID+1, second.Session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected not found for another user's session, got %v", err)
+	}
+	if err := uc.TerminateForUser(u.ID, second.Session.ID); err != nil {
+		t.Fatalf("terminate: %v", err)
+	}
+	if live, _ := uc.ListForUser(u.ID); len(live) != 1 {
+		t.Fatalf("expected 1 live session after terminate, got %d", len(live))
+	}
+}
+
+func TestParseSessionPolicies_rejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"kick", "reject:0", "reject:x"} {
+		if _, err := ParseSessionPolicy(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+	if _, err := ParseSessionPolicies("reject", "admin"); err == nil {
+		t.Errorf("expected error for role entry without policy")
+	}
+	p, err := ParseSessionPolicies("", "admin=replace_oldest")
+	if err != nil || p.For("user") != DefaultSessionPolicy || p.For("admin").Mode != SessionPolicyReplaceOldest {
+		t.Fatalf("unexpected policies %+v (%v)", p, err)
+	}
+}
+
+func TestSessionTokens_policyOfAssignedRolesApplies(t *testing.T) {
+	uc, db, u := setupSessionTokens(t)
+	if err := db.AutoMigrate(&entities.Role{}, &entities.Permission{}, &entities.UserRole{}); err != nil {
+		t.Fatalf("migrate roles: %v", err)
+	}
+	admin := entities.Role{Name: "admin"}
+	db.Create(&admin)
+	ops := entities.Role{Name: "ops", ParentID: &admin.ID}
+	db.Create(&ops)
+	db.Create(&entities.UserRole{UserID: u.ID, RoleID: ops.ID})
+
+	// the primary role "user" allows 3 sessions, but ops inherits admin's single session
+	policies, err := ParseSessionPolicies("", "user=replace_oldest:3,admin=reject:1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	uc.WithPolicies(policies).WithRoles(repositories.NewGormRoleRepository(db))
+	if _, err := uc.Start(context.Background(), u, SessionMeta{}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, err := uc.Start(context.Background(), u, SessionMeta{}); !errors.Is(err, ErrSessionLimitReached) {
+		t.Fatalf("expected the stricter policy of an inherited role, got %v", err)
+	}
+
+	if got := policies.ForRoles([]string{"user", "admin"}); got.Mode != SessionPolicyReject || got.Max != 1 {
+		t.Fatalf("expected the strictest policy, got %+v", got)
+	}
+	if got := policies.ForRoles([]string{"auditor"}); got != DefaultSessionPolicy {
+		t.Fatalf("expected the default policy for roles without one, got %+v", got)
+	}
+}
+
+func TestSessionTokens_rotateAndLogoutEvictCachedSession(t *testing.T) {
+	uc, _, u := setupSessionTokens(t)
+	cache := security.NewSessionCache(uc.sessions, time.Hour)
+	uc.cache = cache
+
+	first, err := uc.Start(context.Background(), u, SessionMeta{})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, active, _ := cache.Lookup(first.AccessToken); !active {
+		t.Fatalf("expected the new session to be active")
+	}
+	second, err := uc.Refresh(first.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, active, _ := cache.Lookup(first.AccessToken); active {
+		t.Fatalf("expected the rotated access token to be rejected before the cache TTL")
+	}
+
+	if _, active, _ := cache.Lookup(second.AccessToken); !active {
+		t.Fatalf("expected the rotated-in session to be active")
+	}
+	if err := uc.Logout(context.Background(), second.Session); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, active, _ := cache.Lookup(second.AccessToken); active {
+		t.Fatalf("expected the logged out access token to be rejected before the cache TTL")
+	}
+}
+
+func TestSessionTokens_idleSessionsDoNotCount(t *testing.T) {
+	uc, _, u := setupSessionTokens(t)
+	uc.WithPolicies(SessionPolicies{Default: SessionPolicy{Mode: SessionPolicyReject, Max: 1}, Idle: 10 * time.Minute})
+
+	start := time.Now()
+	uc.now = func() time.Time { return start }
+	abandoned, err := uc.Start(context.Background(), u, SessionMeta{})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	// the access token (5m) expired 4 minutes ago: still in use
+	uc.now = func() time.Time { return start.Add(9 * time.Minute) }
+	if _, err := uc.Start(context.Background(), u, SessionMeta{}); !errors.Is(err, ErrSessionLimitReached) {
+		t.Fatalf("expected a recently used session to count, got %v", err)
+	}
+	// not refreshed for longer than Idle: the login goes through although the
+	// refresh token is still valid
+	uc.now = func() time.Time { return start.Add(16 * time.Minute) }
+	if _, err := uc.Start(context.Background(), u, SessionMeta{}); err != nil {
+		t.Fatalf("expected an idle session not to block the login, got %v", err)
+	}
+	if _, err := uc.Refresh(abandoned.RefreshToken, SessionMeta{}); err != nil {
+		t.Fatalf("expected the idle session to stay refreshable, got %v", err)
+	}
+}
+
+func TestSessionTokens_concurrentLoginsRespectTheLimit(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000", filepath.Join(t.TempDir(), "sessions.db"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Session{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	u := &entities.User{Username: "alice", Email: "a@e", Password: "x", Role: "user", IsActive: true}
+	if err := db.Omit("last_login").Create(u).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	jwt := security.NewJWTServiceWithTTL("secret", 5*time.Minute, time.Hour)
+	uc := NewSessionTokensUseCase(repositories.NewGormSessionRepository(db), persistence.NewUserRepository(db), jwt, nil).
+		WithPolicies(SessionPolicies{Default: SessionPolicy{Mode: SessionPolicyReject, Max: 2}})
+
+	const logins = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, logins)
+	for i := 0; i < logins; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := uc.Start(context.Background(), u, SessionMeta{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	ok := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case !errors.Is(err, ErrSessionLimitReached):
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	live, _ := uc.ListForUser(u.ID)
+	if ok != 2 || len(live) != 2 {
+		t.Fatalf("expected exactly 2 logins under the limit, got %d accepted and %d live", ok, len(live))
+	}
+}
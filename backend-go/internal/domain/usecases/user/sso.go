@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/randtoken"
+)
+
+// SSO errors
+var (
+	ErrInvalidSSOState = errors.New("invalid or expired SSO login")
+	ErrSSONoRole       = ErrNoMappedRole
+	ErrSSOUserInactive = ErrExternalUserInactive
+)
+
+// SSOOptions configura el alta y la asignación de roles de los usuarios SSO
+type SSOOptions struct {
+	ExternalAccountOptions
+	StateTTL time.Duration
+}
+
+// SSOUseCase implementa el login OIDC (authorization code + PKCE): prepara la
+// redirección al proveedor, valida el callback, crea o enlaza el usuario local y
+// sincroniza su rol con los grupos del proveedor en cada login.
+type SSOUseCase struct {
+	idp      services.IdentityProvider
+	sso      repositories.SSORepository
+	accounts *externalAccounts
+	opts     SSOOptions
+	now      func() time.Time
+}
+
+func NewSSOUseCase(idp services.IdentityProvider, sr repositories.SSORepository, ur repositories.UserRepository, rr repositories.RoleRepository, opts SSOOptions) *SSOUseCase {
+	if opts.StateTTL <= 0 {
+		opts.StateTTL = 10 * time.Minute
+	}
+	uc := &SSOUseCase{idp: idp, sso: sr, opts: opts, now: time.Now}
+	uc.accounts = &externalAccounts{links: sr, users: ur, roles: rr, opts: opts.ExternalAccountOptions, source: "sso", now: func() time.Time { return uc.now() }, logger: zap.NewNop()}
+	return uc
+}
+
+// WithAssignments renueva los tokens de los usuarios cuyos roles cambian al entrar
+func (uc *SSOUseCase) WithAssignments(a *RoleAssignmentsUseCase) *SSOUseCase {
+	uc.accounts.assignments = a
+	return uc
+}
+
+// WithLogger registra los enlaces de identidades y los cambios de rol al entrar
+func (uc *SSOUseCase) WithLogger(l *zap.Logger) *SSOUseCase {
+	uc.accounts.logger = l
+	return uc
+}
+
+// SSOLogin es un login SSO iniciado. State debe quedar ligado al navegador
+// (cookie) para que el callback solo lo complete quien lo empezó.
+type SSOLogin struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
+
+// Begin guarda state, nonce y code verifier y devuelve la URL del proveedor
+func (uc *SSOUseCase) Begin(ctx context.Context) (*SSOLogin, error) {
+	state, err := randtoken.New(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randtoken.New(24)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randtoken.New(48)
+	if err != nil {
+		return nil, err
+	}
+	st := &entities.OIDCLoginState{
+		StateHash:    HashRefreshToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    uc.now().Add(uc.opts.StateTTL),
+	}
+	if err := uc.sso.CreateState(st); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(st.CodeVerifier))
+	url, err := uc.idp.AuthCodeURL(ctx, state, st.Nonce, base64.RawURLEncoding.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, err
+	}
+	return &SSOLogin{URL: url, State: state, ExpiresAt: st.ExpiresAt}, nil
+}
+
+// Complete valida el callback y devuelve el usuario local listo para abrir sesión
+func (uc *SSOUseCase) Complete(ctx context.Context, code, state string) (*entities.User, error) {
+	now := uc.now()
+	st, err := uc.sso.ConsumeState(HashRefreshToken(state), now)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || code == "" {
+		return nil, ErrInvalidSSOState
+	}
+	id, err := uc.idp.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return uc.accounts.resolve(id)
+}
@@ -0,0 +1,142 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/oidc/oidctest"
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+)
+
+// ssoLogin runs Begin, the provider redirect and Complete like a browser would
+func ssoLogin(t *testing.T, uc *SSOUseCase) (*entities.User, string, error) {
+	t.Helper()
+	login, err := uc.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	authURL := login.URL
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	state := loc.Query().Get("state")
+	if state != login.State {
+		t.Fatalf("expected the provider to return the state of Begin")
+	}
+	u, err := uc.Complete(context.Background(), loc.Query().Get("code"), state)
+	return u, state, err
+}
+
+func TestSSO_createsLinksAndSyncsRoles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.Role{}, &entities.Permission{}, &entities.UserRole{}, &entities.UserIdentity{}, &entities.OIDCLoginState{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, name := range []string{"admin", "auditor", "user"} {
+		db.Create(&entities.Role{Name: name})
+	}
+	db.Omit("last_login").Create(&entities.User{Username: "bob", Email: "bob@example.com", Password: "x", Role: "user", IsActive: true})
+	db.Omit("last_login").Create(&entities.User{Username: "root", Email: "root@example.com", Password: "x", Role: "admin", IsActive: true})
+
+	mock, srv := oidctest.Start("microsql")
+	defer srv.Close()
+	idp := oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "microsql", RedirectURL: "http://app/callback"})
+	mappings, _ := ParseRoleMappings("idp-admins=admin, dba=auditor")
+	users := persistence.NewUserRepository(db)
+	roles := repositories.NewGormRoleRepository(db)
+	uc := NewSSOUseCase(idp, repositories.NewGormSSORepository(db), users, roles, SSOOptions{ExternalAccountOptions: ExternalAccountOptions{RoleMappings: mappings, DefaultRole: "user", LinkByEmail: true, PrivilegedRoles: []string{"admin"}}})
+
+	// first login creates the user; the first matching rule gives the main role
+	// and every matching group is reflected in user_roles
+	mock.SetUser(oidctest.User{Subject: "s-ana", Email: "ana@example.com", EmailVerified: true, Username: "ana", Name: "Ana Pérez", Groups: []string{"dba", "idp-admins"}})
+	ana, state, err := ssoLogin(t, uc)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if ana.Username != "ana" || ana.Role != "admin" || ana.FirstName != "Ana" || ana.LastName != "Pérez" {
+		t.Fatalf("unexpected user %+v", ana)
+	}
+	if r, _ := roles.GetUserRoles(ana.ID); len(r) != 2 {
+		t.Fatalf("expected admin and auditor in user_roles, got %v", r)
+	}
+	// the callback cannot be replayed
+	if _, err := uc.Complete(context.Background(), "any", state); !errors.Is(err, ErrInvalidSSOState) {
+		t.Fatalf("expected replayed state to fail, got %v", err)
+	}
+
+	// next login is the same user; leaving the admin group demotes them
+	mock.SetUser(oidctest.User{Subject: "s-ana", Email: "ana@example.com", EmailVerified: true, Username: "ana", Groups: []string{"dba"}})
+	again, _, err := ssoLogin(t, uc)
+	if err != nil || again.ID != ana.ID || again.Role != "auditor" {
+		t.Fatalf("second login: %+v %v", again, err)
+	}
+	if r, _ := roles.GetUserRoles(ana.ID); len(r) != 1 || r[0].Name != "auditor" {
+		t.Fatalf("expected the mapped admin role to be revoked, got %v", r)
+	}
+
+	// a verified email links to the existing local account
+	mock.SetUser(oidctest.User{Subject: "s-bob", Email: "bob@example.com", EmailVerified: true, Username: "robert"})
+	bob, _, err := ssoLogin(t, uc)
+	if err != nil || bob.Username != "bob" || bob.Role != "user" {
+		t.Fatalf("expected link to local bob, got %+v %v", bob, err)
+	}
+
+	// privileged accounts are never linked, even with a verified email
+	mock.SetUser(oidctest.User{Subject: "s-root", Email: "root@example.com", EmailVerified: true, Username: "root"})
+	r, _, err := ssoLogin(t, uc)
+	if err != nil || r.Username == "root" || r.Role != "user" || r.Email == "root@example.com" {
+		t.Fatalf("expected a separate account for the admin's email, got %+v %v", r, err)
+	}
+
+	// ...nor are accounts whose assigned role inherits from a privileged one
+	var admin entities.Role
+	db.Where("name = ?", "admin").First(&admin)
+	lead := entities.Role{Name: "dba-lead", ParentID: &admin.ID}
+	db.Create(&lead)
+	db.Omit("last_login").Create(&entities.User{Username: "lead", Email: "lead@example.com", Password: "x", Role: "user", IsActive: true})
+	local, _ := users.FindByUsername("lead")
+	if err := roles.AssignToUser(local.ID, lead.ID); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	mock.SetUser(oidctest.User{Subject: "s-lead", Email: "lead@example.com", EmailVerified: true, Username: "lead"})
+	if r, _, err := ssoLogin(t, uc); err != nil || r.ID == local.ID || r.Email == "lead@example.com" {
+		t.Fatalf("expected a separate account for an inherited privileged role, got %+v %v", r, err)
+	}
+
+	// an unverified email never takes over an account; the username gets a suffix
+	mock.SetUser(oidctest.User{Subject: "s-mallory", Email: "bob@example.com", Username: "bob"})
+	m, _, err := ssoLogin(t, uc)
+	if err != nil || m.ID == bob.ID || m.Username != "bob-2" || m.Email == "bob@example.com" {
+		t.Fatalf("expected a separate account, got %+v %v", m, err)
+	}
+
+	// without a default role only mapped groups get in
+	strict := NewSSOUseCase(idp, repositories.NewGormSSORepository(db), users, roles, SSOOptions{ExternalAccountOptions: ExternalAccountOptions{RoleMappings: mappings}})
+	mock.SetUser(oidctest.User{Subject: "s-eve", Email: "eve@example.com", EmailVerified: true, Groups: []string{"sales"}})
+	if _, _, err := ssoLogin(t, strict); !errors.Is(err, ErrSSONoRole) {
+		t.Fatalf("expected ErrSSONoRole, got %v", err)
+	}
+
+	// disabled accounts stay disabled
+	db.Model(&entities.User{}).Where("id = ?", bob.ID).Update("is_active", false)
+	mock.SetUser(oidctest.User{Subject: "s-bob", Email: "bob@example.com", EmailVerified: true})
+	if _, _, err := ssoLogin(t, uc); !errors.Is(err, ErrSSOUserInactive) {
+		t.Fatalf("expected inactive user to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,451 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/services"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/randtoken"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/totp"
+)
+
+// 2FA errors
+var (
+	ErrInvalidChallenge      = errors.New("invalid or expired login challenge")
+	ErrInvalidTwoFactorCode  = errors.New("invalid two-factor code")
+	ErrTwoFactorNotEnrolled  = errors.New("two-factor authentication is not enrolled")
+	ErrTwoFactorEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorRequired     = errors.New("two-factor authentication is mandatory for this role")
+	ErrTooManyChallengeTries = errors.New("too many invalid codes; log in again")
+)
+
+const (
+	// RecoveryCodeCount es el número de códigos de recuperación por usuario
+	RecoveryCodeCount = 10
+	// maxChallengeAttempts limita los códigos probados con un mismo challenge
+	maxChallengeAttempts = 5
+	// totpSkew acepta el código del paso anterior y del siguiente (desfase de reloj)
+	totpSkew = 1
+)
+
+// TwoFactorOptions configura la política de 2FA
+type TwoFactorOptions struct {
+	Issuer        string   // nombre mostrado por la app de autenticación
+	RequiredRoles []string // roles que no pueden iniciar sesión sin 2FA
+	ChallengeTTL  time.Duration
+}
+
+// LoginChallengeResult es lo que recibe el cliente en lugar de los tokens
+type LoginChallengeResult struct {
+	Token              string
+	ExpiresAt          time.Time
+	EnrollmentRequired bool
+}
+
+// Enrollment es el secreto pendiente de confirmar
+type Enrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+// TwoFactorStatus resume el estado de 2FA de un usuario
+type TwoFactorStatus struct {
+	Enabled       bool
+	Required      bool
+	RecoveryCodes int64
+}
+
+// TwoFactorUseCase gestiona TOTP, códigos de recuperación y el segundo paso del login.
+// Los secretos se guardan cifrados con el keyring de credenciales.
+type TwoFactorUseCase struct {
+	mfa      repositories.MFARepository
+	users    repositories.UserRepository
+	enc      services.EncryptionService
+	opts     TwoFactorOptions
+	required map[string]bool
+	// roles permite aplicar RequiredRoles a todos los roles del usuario (opcional)
+	roles repositories.RoleRepository
+	// guard cuenta los códigos incorrectos como fallos de login (opcional)
+	guard *LoginGuard
+	now   func() time.Time
+}
+
+func NewTwoFactorUseCase(mr repositories.MFARepository, ur repositories.UserRepository, enc services.EncryptionService, opts TwoFactorOptions) *TwoFactorUseCase {
+	if opts.Issuer == "" {
+		opts.Issuer = "MicroSQL AGo"
+	}
+	if opts.ChallengeTTL <= 0 {
+		opts.ChallengeTTL = 5 * time.Minute
+	}
+	required := map[string]bool{}
+	for _, r := range opts.RequiredRoles {
+		if r = strings.TrimSpace(r); r != "" {
+			required[r] = true
+		}
+	}
+	return &TwoFactorUseCase{mfa: mr, users: ur, enc: enc, opts: opts, required: required, now: time.Now}
+}
+
+// WithGuard cuenta cada código incorrecto como un fallo de login del usuario, de
+// modo que los intentos no se reinician al pedir un challenge nuevo
+func (uc *TwoFactorUseCase) WithGuard(g *LoginGuard) *TwoFactorUseCase {
+	uc.guard = g
+	return uc
+}
+
+// WithRoles aplica RequiredRoles a todos los roles vigentes del usuario y a sus
+// ancestros, no solo al principal
+func (uc *TwoFactorUseCase) WithRoles(rr repositories.RoleRepository) *TwoFactorUseCase {
+	uc.roles = rr
+	return uc
+}
+
+// Required indica si alguno de los roles del usuario obliga a usar 2FA
+func (uc *TwoFactorUseCase) Required(user *entities.User) (bool, error) {
+	if len(uc.required) == 0 {
+		return false, nil
+	}
+	names, err := userRoleNames(uc.roles, user)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range names {
+		if uc.required[r] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Status devuelve si el usuario tiene 2FA activo y cuántos códigos de recuperación le quedan
+func (uc *TwoFactorUseCase) Status(user *entities.User) (*TwoFactorStatus, error) {
+	t, err := uc.mfa.GetTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := uc.Required(user)
+	if err != nil {
+		return nil, err
+	}
+	st := &TwoFactorStatus{Enabled: t != nil && t.Enabled, Required: required}
+	if st.Enabled {
+		if st.RecoveryCodes, err = uc.mfa.CountRecoveryCodes(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// BeginLogin se llama tras validar la contraseña. Devuelve nil si el login puede
+// completarse ya; si no, un challenge para verificar el código o para enrolarse.
+func (uc *TwoFactorUseCase) BeginLogin(user *entities.User, device string) (*LoginChallengeResult, error) {
+	t, err := uc.mfa.GetTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	purpose := entities.ChallengeVerify
+	if t == nil || !t.Enabled {
+		required, err := uc.Required(user)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		purpose = entities.ChallengeEnroll
+	}
+
+	token, err := randtoken.New(32)
+	if err != nil {
+		return nil, err
+	}
+	expires := uc.now().Add(uc.opts.ChallengeTTL)
+	c := &entities.LoginChallenge{
+		TokenHash: HashRefreshToken(token),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Device:    truncate(device, 100),
+		ExpiresAt: expires,
+	}
+	if err := uc.mfa.CreateChallenge(c); err != nil {
+		return nil, fmt.Errorf("failed to create login challenge: %w", err)
+	}
+	return &LoginChallengeResult{Token: token, ExpiresAt: expires, EnrollmentRequired: purpose == entities.ChallengeEnroll}, nil
+}
+
+// VerifyChallenge completa el login con un código TOTP o de recuperación.
+// Devuelve el usuario y el dispositivo indicado en el login. attempt aporta la IP
+// y el user agent con los que se cuentan los códigos incorrectos; ctx es el de la petición.
+func (uc *TwoFactorUseCase) VerifyChallenge(ctx context.Context, token, code, recoveryCode string, attempt LoginAttempt) (*entities.User, string, error) {
+	c, user, err := uc.challenge(token, entities.ChallengeVerify)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := uc.checkGuard(ctx, user, &attempt); err != nil {
+		return nil, "", err
+	}
+	if err := uc.checkCode(user.ID, code, recoveryCode); err != nil {
+		if err == ErrInvalidTwoFactorCode {
+			err = uc.codeFailed(ctx, c, attempt)
+		}
+		return nil, "", err
+	}
+	if err := uc.consume(c); err != nil {
+		return nil, "", err
+	}
+	return user, c.Device, nil
+}
+
+// EnrollWithChallenge inicia el enrolamiento obligatorio durante el login
+func (uc *TwoFactorUseCase) EnrollWithChallenge(token string) (*Enrollment, error) {
+	_, user, err := uc.challenge(token, entities.ChallengeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Enroll(user)
+}
+
+// ConfirmWithChallenge activa 2FA con el primer código y completa el login
+func (uc *TwoFactorUseCase) ConfirmWithChallenge(ctx context.Context, token, code string, attempt LoginAttempt) (*entities.User, string, []string, error) {
+	c, user, err := uc.challenge(token, entities.ChallengeEnroll)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if err := uc.checkGuard(ctx, user, &attempt); err != nil {
+		return nil, "", nil, err
+	}
+	codes, err := uc.Confirm(user.ID, code)
+	if err != nil {
+		if err == ErrInvalidTwoFactorCode {
+			err = uc.codeFailed(ctx, c, attempt)
+		}
+		return nil, "", nil, err
+	}
+	if err := uc.consume(c); err != nil {
+		return nil, "", nil, err
+	}
+	return user, c.Device, codes, nil
+}
+
+// Enroll genera un secreto nuevo (pendiente hasta Confirm)
+func (uc *TwoFactorUseCase) Enroll(user *entities.User) (*Enrollment, error) {
+	t, err := uc.mfa.GetTOTP(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil && t.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := uc.enc.Encrypt(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt TOTP secret: %w", err)
+	}
+	if err := uc.mfa.SaveTOTP(&entities.UserTOTP{UserID: user.ID, Secret: encrypted}); err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: secret, ProvisioningURI: totp.ProvisioningURI(uc.opts.Issuer, user.Username, secret)}, nil
+}
+
+// Confirm activa el secreto pendiente y devuelve los códigos de recuperación (solo esta vez)
+func (uc *TwoFactorUseCase) Confirm(userID uint, code string) ([]string, error) {
+	t, err := uc.mfa.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if t.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if err := uc.checkTOTP(t, code); err != nil {
+		return nil, err
+	}
+	now := uc.now()
+	t.Enabled = true
+	t.EnabledAt = &now
+	if err := uc.mfa.SaveTOTP(t); err != nil {
+		return nil, err
+	}
+	return uc.newRecoveryCodes(userID)
+}
+
+// RegenerateRecoveryCodes invalida los códigos anteriores; exige un código TOTP válido
+func (uc *TwoFactorUseCase) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	t, err := uc.enabledTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkTOTP(t, code); err != nil {
+		return nil, err
+	}
+	return uc.newRecoveryCodes(userID)
+}
+
+// Disable quita 2FA al usuario (no permitido si su rol lo exige)
+func (uc *TwoFactorUseCase) Disable(user *entities.User, code string) error {
+	required, err := uc.Required(user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	if err := uc.checkCode(user.ID, code, ""); err != nil {
+		return err
+	}
+	return uc.mfa.ResetUser(user.ID)
+}
+
+// Reset elimina el enrolamiento sin código (administrador; p.ej. dispositivo perdido).
+// Si el rol lo exige, el usuario deberá enrolarse de nuevo en el próximo login.
+func (uc *TwoFactorUseCase) Reset(userID uint) error {
+	return uc.mfa.ResetUser(userID)
+}
+
+func (uc *TwoFactorUseCase) challenge(token, purpose string) (*entities.LoginChallenge, *entities.User, error) {
+	if token == "" {
+		return nil, nil, ErrInvalidChallenge
+	}
+	c, err := uc.mfa.GetChallenge(HashRefreshToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if c == nil || c.Purpose != purpose || c.UsedAt != nil || !uc.now().Before(c.ExpiresAt) {
+		return nil, nil, ErrInvalidChallenge
+	}
+	if c.Attempts >= maxChallengeAttempts {
+		return nil, nil, ErrTooManyChallengeTries
+	}
+	user, err := uc.users.FindByID(c.UserID)
+	if err != nil || user == nil || !user.IsActive {
+		return nil, nil, ErrInvalidChallenge
+	}
+	return c, user, nil
+}
+
+// checkGuard completa el intento con el usuario del challenge y lo rechaza si el
+// usuario o la IP están bloqueados (p.ej. por códigos incorrectos en otros challenges)
+func (uc *TwoFactorUseCase) checkGuard(ctx context.Context, user *entities.User, attempt *LoginAttempt) error {
+	attempt.Username, attempt.UserID = user.Username, &user.ID
+	if uc.guard == nil {
+		return nil
+	}
+	wait, err := uc.guard.RetryAfter(ctx, *attempt)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return ErrTooManyChallengeTries
+	}
+	return nil
+}
+
+// codeFailed cuenta el código incorrecto en el challenge y como fallo de login;
+// devuelve ErrInvalidTwoFactorCode o el error al registrar el fallo
+func (uc *TwoFactorUseCase) codeFailed(ctx context.Context, c *entities.LoginChallenge, attempt LoginAttempt) error {
+	_ = uc.mfa.IncrementChallengeAttempts(c.ID)
+	if uc.guard != nil {
+		if _, err := uc.guard.Failure(ctx, attempt); err != nil {
+			return fmt.Errorf("failed to record invalid 2FA code: %w", err)
+		}
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+func (uc *TwoFactorUseCase) consume(c *entities.LoginChallenge) error {
+	ok, err := uc.mfa.ConsumeChallenge(c.ID, uc.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+func (uc *TwoFactorUseCase) enabledTOTP(userID uint) (*entities.UserTOTP, error) {
+	t, err := uc.mfa.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || !t.Enabled {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	return t, nil
+}
+
+// checkCode acepta un código TOTP o, si se indica, un código de recuperación
+func (uc *TwoFactorUseCase) checkCode(userID uint, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		ok, err := uc.mfa.UseRecoveryCode(userID, hashRecoveryCode(recoveryCode), uc.now())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+	t, err := uc.enabledTOTP(userID)
+	if err != nil {
+		return err
+	}
+	return uc.checkTOTP(t, code)
+}
+
+// checkTOTP valida el código y registra su paso para que no se pueda reutilizar
+func (uc *TwoFactorUseCase) checkTOTP(t *entities.UserTOTP, code string) error {
+	secret, err := uc.enc.Decrypt(t.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to read TOTP secret: %w", err)
+	}
+	step, ok := totp.Verify(secret, code, uc.now(), totpSkew)
+	if !ok || step <= t.LastUsedStep {
+		return ErrInvalidTwoFactorCode
+	}
+	fresh, err := uc.mfa.MarkTOTPUsed(t.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTwoFactorCode
+	}
+	t.LastUsedStep = step
+	return nil
+}
+
+func (uc *TwoFactorUseCase) newRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b)) // 8 caracteres
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := uc.mfa.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode ignora mayúsculas, espacios y guiones al comparar
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return HashRefreshToken(normalized)
+}
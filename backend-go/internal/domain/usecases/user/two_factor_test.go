@@ -0,0 +1,258 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	persistence "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/adapters/secondary/persistence/sqlserver"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/infrastructure/repositories"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/pkg/totp"
+)
+
+// reverseEnc is a stand-in cipher: enough to prove the secret is not stored as is
+type reverseEnc struct{}
+
+func (reverseEnc) Encrypt(p string) (string, error) { return "enc:" + reverse(p), nil }
+func (reverseEnc) Decrypt(c string) (string, error) {
+	return reverse(strings.TrimPrefix(c, "enc:")), nil
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func setupTwoFactor(t *testing.T) (*TwoFactorUseCase, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.User{}, &entities.UserTOTP{}, &entities.RecoveryCode{}, &entities.LoginChallenge{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	uc := NewTwoFactorUseCase(repositories.NewGormMFARepository(db), persistence.NewUserRepository(db), reverseEnc{}, TwoFactorOptions{RequiredRoles: []string{"admin"}})
+	return uc, db
+}
+
+func createUser(t *testing.T, db *gorm.DB, name, role string) *entities.User {
+	u := &entities.User{Username: name, Email: name + "@e", Password: "x", Role: role, IsActive: true}
+	if err := db.Omit("last_login").Create(u).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return u
+}
+
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	code, err := totp.CodeAt(secret, totp.Step(at))
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+	return code
+}
+
+func TestTwoFactor_enrollVerifyReplayAndRecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	uc, db := setupTwoFactor(t)
+	u := createUser(t, db, "alice", "user")
+	now := time.Now()
+	uc.now = func() time.Time { return now }
+
+	if ch, err := uc.BeginLogin(u, ""); err != nil || ch != nil {
+		t.Fatalf("expected no challenge without 2FA, got %v %v", ch, err)
+	}
+
+	e, err := uc.Enroll(u)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if !strings.Contains(e.ProvisioningURI, "secret="+e.Secret) {
+		t.Fatalf("unexpected provisioning uri %s", e.ProvisioningURI)
+	}
+	var stored entities.UserTOTP
+	db.Where("user_id = ?", u.ID).First(&stored)
+	if stored.Secret == e.Secret || stored.Enabled {
+		t.Fatalf("expected an encrypted, pending secret")
+	}
+	codes, err := uc.Confirm(u.ID, codeAt(t, e.Secret, now))
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("confirm: %v (%d codes)", err, len(codes))
+	}
+
+	ch, err := uc.BeginLogin(u, "laptop")
+	if err != nil || ch == nil || ch.EnrollmentRequired {
+		t.Fatalf("expected a verify challenge, got %+v %v", ch, err)
+	}
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, "000000", "", LoginAttempt{}); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected invalid code, got %v", err)
+	}
+	// the code used to confirm cannot be replayed
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now), "", LoginAttempt{}); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected replayed code to fail, got %v", err)
+	}
+	now = now.Add(totp.Period * time.Second)
+	got, device, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now), "", LoginAttempt{})
+	if err != nil || got.ID != u.ID || device != "laptop" {
+		t.Fatalf("verify: %v", err)
+	}
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now), "", LoginAttempt{}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("expected used challenge to be rejected, got %v", err)
+	}
+
+	// recovery codes work once, in any case and without the dash
+	ch, _ = uc.BeginLogin(u, "")
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, "", strings.ToUpper(strings.ReplaceAll(codes[0], "-", "")), LoginAttempt{}); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	ch, _ = uc.BeginLogin(u, "")
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, "", codes[0], LoginAttempt{}); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected used recovery code to fail, got %v", err)
+	}
+	if st, _ := uc.Status(u); !st.Enabled || st.RecoveryCodes != RecoveryCodeCount-1 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	// challenges expire and cap the number of guesses
+	ch, _ = uc.BeginLogin(u, "")
+	for i := 0; i < maxChallengeAttempts; i++ {
+		_, _, _ = uc.VerifyChallenge(ctx, ch.Token, "000000", "", LoginAttempt{})
+	}
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now.Add(time.Minute)), "", LoginAttempt{}); !errors.Is(err, ErrTooManyChallengeTries) {
+		t.Fatalf("expected attempt cap, got %v", err)
+	}
+	ch, _ = uc.BeginLogin(u, "")
+	now = now.Add(10 * time.Minute)
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now), "", LoginAttempt{}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("expected expired challenge, got %v", err)
+	}
+}
+
+func TestTwoFactor_mandatoryEnrollmentForRequiredRoles(t *testing.T) {
+	ctx := context.Background()
+	uc, db := setupTwoFactor(t)
+	admin := createUser(t, db, "root", "admin")
+	now := time.Now()
+	uc.now = func() time.Time { return now }
+
+	ch, err := uc.BeginLogin(admin, "")
+	if err != nil || ch == nil || !ch.EnrollmentRequired {
+		t.Fatalf("expected an enrollment challenge, got %+v %v", ch, err)
+	}
+	// an enrollment challenge cannot be used to skip the second factor
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, "000000", "", LoginAttempt{}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("expected purpose mismatch, got %v", err)
+	}
+
+	e, err := uc.EnrollWithChallenge(ch.Token)
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	got, _, codes, err := uc.ConfirmWithChallenge(ctx, ch.Token, codeAt(t, e.Secret, now), LoginAttempt{})
+	if err != nil || got.ID != admin.ID || len(codes) != RecoveryCodeCount {
+		t.Fatalf("confirm with challenge: %v", err)
+	}
+
+	now = now.Add(totp.Period * time.Second)
+	if err := uc.Disable(admin, codeAt(t, e.Secret, now)); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Fatalf("expected admins to keep 2FA, got %v", err)
+	}
+
+	// an admin reset removes the enrollment; the next login asks to enroll again
+	if err := uc.Reset(admin.ID); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if ch, _ := uc.BeginLogin(admin, ""); ch == nil || !ch.EnrollmentRequired {
+		t.Fatalf("expected enrollment to be required after reset")
+	}
+}
+
+func TestTwoFactor_assignedAndInheritedRolesRequireIt(t *testing.T) {
+	uc, db := setupTwoFactor(t)
+	if err := db.AutoMigrate(&entities.Role{}, &entities.Permission{}, &entities.UserRole{}); err != nil {
+		t.Fatalf("migrate roles: %v", err)
+	}
+	uc.WithRoles(repositories.NewGormRoleRepository(db))
+	admin := entities.Role{Name: "admin"}
+	db.Create(&admin)
+	ops := entities.Role{Name: "ops", ParentID: &admin.ID}
+	db.Create(&ops)
+	until := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Hour)
+
+	// the primary role is "user", but a grant of admin (or of a role under it) still requires 2FA
+	granted := createUser(t, db, "bob", "user")
+	db.Create(&entities.UserRole{UserID: granted.ID, RoleID: admin.ID, ValidUntil: &until})
+	inherited := createUser(t, db, "carol", "user")
+	db.Create(&entities.UserRole{UserID: inherited.ID, RoleID: ops.ID})
+	for _, u := range []*entities.User{granted, inherited} {
+		ch, err := uc.BeginLogin(u, "")
+		if err != nil || ch == nil || !ch.EnrollmentRequired {
+			t.Fatalf("%s: expected an enrollment challenge, got %+v %v", u.Username, ch, err)
+		}
+		if err := uc.Disable(u, "000000"); !errors.Is(err, ErrTwoFactorRequired) {
+			t.Fatalf("%s: expected 2FA to be mandatory, got %v", u.Username, err)
+		}
+	}
+
+	// an expired grant no longer counts
+	lapsed := createUser(t, db, "dave", "user")
+	db.Create(&entities.UserRole{UserID: lapsed.ID, RoleID: admin.ID, ValidUntil: &expired})
+	if ch, err := uc.BeginLogin(lapsed, ""); err != nil || ch != nil {
+		t.Fatalf("expected no challenge after the grant expired, got %+v %v", ch, err)
+	}
+}
+
+func TestTwoFactor_invalidCodesCountAsLoginFailures(t *testing.T) {
+	ctx := context.Background()
+	uc, db := setupTwoFactor(t)
+	if err := db.AutoMigrate(&entities.LoginThrottle{}, &entities.AuthAuditLog{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	guard := NewLoginGuard(repositories.NewGormLoginThrottleRepository(db), repositories.NewGormAuthAuditRepository(db), LockoutPolicy{
+		MaxUserFailures: 3, FailureWindow: time.Hour, LockoutDuration: time.Hour,
+	})
+	uc.WithGuard(guard)
+	u := createUser(t, db, "alice", "user")
+	now := time.Now()
+	uc.now = func() time.Time { return now }
+	e, _ := uc.Enroll(u)
+	if _, err := uc.Confirm(u.ID, codeAt(t, e.Secret, now)); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	attempt := LoginAttempt{IPAddress: "10.0.0.1"}
+
+	// a correct code clears the user's failures...
+	ch, _ := uc.BeginLogin(u, "")
+	_, _, _ = uc.VerifyChallenge(ctx, ch.Token, "000000", "", attempt)
+	now = now.Add(totp.Period * time.Second)
+	ch, _ = uc.BeginLogin(u, "")
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now), "", attempt); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	_ = guard.Success(ctx, LoginAttempt{Username: "alice"})
+
+	// ...while wrong codes add up across challenges, so asking for a new one
+	// does not reset the count
+	for i := 0; i < 3; i++ {
+		ch, _ = uc.BeginLogin(u, "")
+		if _, _, err := uc.VerifyChallenge(ctx, ch.Token, "000000", "", attempt); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d: expected invalid code, got %v", i+1, err)
+		}
+	}
+	if wait, _ := guard.RetryAfter(ctx, LoginAttempt{Username: "alice", IPAddress: "10.0.0.2"}); wait <= 0 {
+		t.Fatalf("expected the user to be locked after 3 invalid codes")
+	}
+	ch, _ = uc.BeginLogin(u, "")
+	if _, _, err := uc.VerifyChallenge(ctx, ch.Token, codeAt(t, e.Secret, now.Add(totp.Period*time.Second)), "", attempt); !errors.Is(err, ErrTooManyChallengeTries) {
+		t.Fatalf("expected a locked user to be rejected even with a valid code, got %v", err)
+	}
+}
@@ -1,8 +1,11 @@
 package mocks
 
 import (
+	"time"
+
 	"github.com/stretchr/testify/mock"
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
 )
 
 type MockConnectionRepository struct {
@@ -31,7 +34,15 @@ func (m *MockConnectionRepository) GetActiveByUserIDAndManager(userID uint, mana
 	return args.Get(0).(*entities.ActiveConnection), args.Error(1)
 }
 
-func (m *MockConnectionRepository) UpdateActive(conn *entities.ActiveConnection) error {
+func (m *MockConnectionRepository) FindActiveByUserAndManager(userID uint, manager string) (*entities.ActiveConnection, error) {
+	args := m.Called(userID, manager)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.ActiveConnection), args.Error(1)
+}
+
+func (m *MockConnectionRepository) UpdateActiveHealth(conn *entities.ActiveConnection) error {
 	args := m.Called(conn)
 	return args.Error(0)
 }
@@ -62,6 +73,17 @@ func (m *MockConnectionRepository) ListActiveByUser(userID uint) ([]*entities.Ac
 	return args.Get(0).([]*entities.ActiveConnection), args.Error(1)
 }
 
+func (m *MockConnectionRepository) TouchActive(id uint, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+// SwapActivePassword mocks the compare-and-swap password update used by key rotation
+func (m *MockConnectionRepository) SwapActivePassword(id uint, oldRef, newRef string) (bool, error) {
+	args := m.Called(id, oldRef, newRef)
+	return args.Bool(0), args.Error(1)
+}
+
 // LogConnection mocks recording a connection log
 func (m *MockConnectionRepository) LogConnection(log *entities.ConnectionLog) error {
 	args := m.Called(log)
@@ -88,3 +110,16 @@ func (m *MockConnectionRepository) CountLogsByUserID(userID uint) (int64, error)
 	args := m.Called(userID)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+func (m *MockConnectionRepository) ListLogs(filter repositories.ConnectionLogFilter) ([]*entities.ConnectionLog, int64, error) {
+	args := m.Called(filter)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*entities.ConnectionLog), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockConnectionRepository) StreamLogs(filter repositories.ConnectionLogFilter, fn func(*entities.ConnectionLog) error) error {
+	args := m.Called(filter, fn)
+	return args.Error(0)
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSecretStore struct {
+	mock.Mock
+}
+
+func (m *MockSecretStore) Put(ctx context.Context, key, secret string) (string, error) {
+	args := m.Called(key, secret)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSecretStore) Get(ctx context.Context, ref string) (string, error) {
+	args := m.Called(ref)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSecretStore) Delete(ctx context.Context, ref string) error {
+	args := m.Called(ref)
+	return args.Error(0)
+}
@@ -42,3 +42,8 @@ func (m *MockSQLServerService) Close(db *sql.DB) error {
 	args := m.Called(db)
 	return args.Error(0)
 }
+
+func (m *MockSQLServerService) Release(userID uint, manager string) error {
+	args := m.Called(userID, manager)
+	return args.Error(0)
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"gorm.io/gorm"
+)
+
+// GormActivityRepository stores the API activity log
+type GormActivityRepository struct {
+	db *gorm.DB
+}
+
+func NewGormActivityRepository(db *gorm.DB) *GormActivityRepository {
+	return &GormActivityRepository{db: db}
+}
+
+func (r *GormActivityRepository) Create(ev *entities.ActivityEvent) error {
+	return r.db.Create(ev).Error
+}
+
+func (r *GormActivityRepository) CreateBatch(evs []entities.ActivityEvent) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	return r.db.Create(&evs).Error
+}
+
+func (r *GormActivityRepository) List(f repositories.ActivityFilter) ([]entities.ActivityEvent, int64, error) {
+	var total int64
+	if err := r.query(f).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	offset := f.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	list := []entities.ActivityEvent{}
+	if err := r.query(f).Order("occurred_at DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *GormActivityRepository) query(f repositories.ActivityFilter) *gorm.DB {
+	q := r.db.Model(&entities.ActivityEvent{})
+	for column, value := range map[string]*string{
+		"action":        f.Action,
+		"category":      f.Category,
+		"outcome":       f.Outcome,
+		"actor_name":    f.ActorName,
+		"ip_address":    f.IPAddress,
+		"request_id":    f.RequestID,
+		"resource_type": f.ResourceType,
+		"resource_id":   f.ResourceID,
+	} {
+		if value != nil {
+			q = q.Where(column+" = ?", *value)
+		}
+	}
+	if f.ActorID != nil {
+		q = q.Where("actor_id = ?", *f.ActorID)
+	}
+	if f.From != nil {
+		q = q.Where("occurred_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("occurred_at < ?", *f.To)
+	}
+	return q
+}
+
+func (r *GormActivityRepository) DeleteBefore(t time.Time) (int64, error) {
+	res := r.db.Where("occurred_at < ?", t).Delete(&entities.ActivityEvent{})
+	return res.RowsAffected, res.Error
+}
@@ -13,8 +13,12 @@ func NewGormAdminAuditRepository(db *gorm.DB) *GormAdminAuditRepository {
 	return &GormAdminAuditRepository{db: db}
 }
 
+// Create inserts the record and links it to the hash chain
 func (r *GormAdminAuditRepository) Create(log *entities.AdminActionLog) error {
-	return r.db.Create(log).Error
+	if err := r.db.Create(log).Error; err != nil {
+		return err
+	}
+	return sealRecord(r.db, entities.ChainAdminActions, log)
 }
 
 func (r *GormAdminAuditRepository) List(actorID *uint, targetType *string, action *string, limit int, offset int) ([]entities.AdminActionLog, error) {
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+type GormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAPIKeyRepository(db *gorm.DB) *GormAPIKeyRepository {
+	return &GormAPIKeyRepository{db: db}
+}
+
+func (r *GormAPIKeyRepository) Create(k *entities.APIKey) error {
+	return r.db.Create(k).Error
+}
+
+// GetByHash runs on every API key request; Find avoids logging unknown keys as errors
+func (r *GormAPIKeyRepository) GetByHash(hash string) (*entities.APIKey, error) {
+	var list []entities.APIKey
+	if err := r.db.Where("key_hash = ?", hash).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *GormAPIKeyRepository) GetByID(id uint) (*entities.APIKey, error) {
+	var list []entities.APIKey
+	if err := r.db.Where("id = ?", id).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *GormAPIKeyRepository) List(userID *uint) ([]entities.APIKey, error) {
+	var list []entities.APIKey
+	q := r.db.Model(&entities.APIKey{})
+	if userID != nil {
+		q = q.Where("user_id = ?", *userID)
+	}
+	if err := q.Order("created_at DESC, id DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *GormAPIKeyRepository) Revoke(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&entities.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *GormAPIKeyRepository) TouchLastUsed(id uint, at time.Time, ip string) error {
+	return r.db.Model(&entities.APIKey{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
@@ -18,8 +18,16 @@ func (r *GormAuditRepository) CreateAuditRun(run *entities.AuditRun) error {
 	return r.db.Create(run).Error
 }
 
+// UpdateAuditRun saves the run and links it to the hash chain once it is no
+// longer running; later changes to a finished run break the chain
 func (r *GormAuditRepository) UpdateAuditRun(run *entities.AuditRun) error {
-	return r.db.Save(run).Error
+	if err := r.db.Save(run).Error; err != nil {
+		return err
+	}
+	if run.Status == "running" || run.ChainSeq != 0 {
+		return nil
+	}
+	return sealRecord(r.db, entities.ChainAuditRuns, run)
 }
 
 func (r *GormAuditRepository) GetAuditRunByID(id uint) (*entities.AuditRun, error) {
@@ -31,7 +39,10 @@ func (r *GormAuditRepository) GetAuditRunByID(id uint) (*entities.AuditRun, erro
 }
 
 func (r *GormAuditRepository) CreateScriptResult(res *entities.AuditScriptResult) error {
-	return r.db.Create(res).Error
+	if err := r.db.Create(res).Error; err != nil {
+		return err
+	}
+	return sealRecord(r.db, entities.ChainAuditResults, res)
 }
 
 func (r *GormAuditRepository) ListScriptResultsByAuditRun(auditRunID uint) ([]entities.AuditScriptResult, error) {
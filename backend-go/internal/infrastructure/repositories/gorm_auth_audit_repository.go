@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormAuthAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewGormAuthAuditRepository(db *gorm.DB) *GormAuthAuditRepository {
+	return &GormAuthAuditRepository{db: db}
+}
+
+func (r *GormAuthAuditRepository) Create(log *entities.AuthAuditLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *GormAuthAuditRepository) List(event, username, ip *string, limit int, offset int) ([]entities.AuthAuditLog, error) {
+	var list []entities.AuthAuditLog
+	q := r.db.Model(&entities.AuthAuditLog{})
+	if event != nil {
+		q = q.Where("event = ?", *event)
+	}
+	if username != nil {
+		q = q.Where("username = ?", *username)
+	}
+	if ip != nil {
+		q = q.Where("ip_address = ?", *ip)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if err := q.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GormLoginThrottleRepository stores failed login counters
+type GormLoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+func NewGormLoginThrottleRepository(db *gorm.DB) *GormLoginThrottleRepository {
+	return &GormLoginThrottleRepository{db: db}
+}
+
+// Get is on the login hot path; Find avoids logging every miss as an error
+func (r *GormLoginThrottleRepository) Get(key string) (*entities.LoginThrottle, error) {
+	var list []entities.LoginThrottle
+	if err := r.db.Where("subject = ?", key).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *GormLoginThrottleRepository) RecordFailure(key string, at time.Time, window time.Duration) (*entities.LoginThrottle, error) {
+	var t entities.LoginThrottle
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// row lock on MySQL; sqlite serializes write transactions anyway
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("subject = ?", key).Limit(1).Find(&t)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			t = entities.LoginThrottle{Subject: key}
+		}
+		stale := t.LastFailureAt != nil && at.Sub(*t.LastFailureAt) > window
+		lockExpired := t.LockedUntil != nil && !at.Before(*t.LockedUntil)
+		if stale || lockExpired {
+			t.Failures = 0
+			t.LockedUntil = nil
+		}
+		t.Failures++
+		t.LastFailureAt = &at
+		return tx.Save(&t).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *GormLoginThrottleRepository) Lock(key string, until time.Time) error {
+	return r.db.Model(&entities.LoginThrottle{}).Where("subject = ?", key).Update("locked_until", until).Error
+}
+
+func (r *GormLoginThrottleRepository) Delay(key string, until time.Time) error {
+	return r.db.Model(&entities.LoginThrottle{}).Where("subject = ?", key).Update("retry_at", until).Error
+}
+
+func (r *GormLoginThrottleRepository) Reset(key string) error {
+	return r.db.Where("subject = ?", key).Delete(&entities.LoginThrottle{}).Error
+}
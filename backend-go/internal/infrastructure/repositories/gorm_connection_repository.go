@@ -1,9 +1,11 @@
 package repositories
 
 import (
+	"strings"
 	"time"
 
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
 	"gorm.io/gorm"
 )
 
@@ -42,8 +44,25 @@ func (r *GormConnectionRepository) GetActiveByUserIDAndManager(userID uint, mana
 	return &ac, nil
 }
 
-func (r *GormConnectionRepository) UpdateActive(conn *entities.ActiveConnection) error {
-	return r.db.Save(conn).Error
+func (r *GormConnectionRepository) FindActiveByUserAndManager(userID uint, manager string) (*entities.ActiveConnection, error) {
+	var ac entities.ActiveConnection
+	if err := r.db.Where("user_id = ? AND manager = ?", userID, manager).First(&ac).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ac, nil
+}
+
+func (r *GormConnectionRepository) UpdateActiveHealth(conn *entities.ActiveConnection) error {
+	return r.db.Model(&entities.ActiveConnection{}).Where("id = ?", conn.ID).Updates(map[string]interface{}{
+		"is_connected":      conn.IsConnected,
+		"health_status":     conn.HealthStatus,
+		"last_health_check": conn.LastHealthCheck,
+		"last_latency_ms":   conn.LastLatencyMs,
+		"last_disconnected": conn.LastDisconnected,
+	}).Error
 }
 
 func (r *GormConnectionRepository) DeleteActive(userID uint) error {
@@ -64,12 +83,26 @@ func (r *GormConnectionRepository) ListActive() ([]*entities.ActiveConnection, e
 
 func (r *GormConnectionRepository) ListActiveByUser(userID uint) ([]*entities.ActiveConnection, error) {
 	var list []*entities.ActiveConnection
-	if err := r.db.Where("user_id = ? AND is_connected = ?", userID, true).Find(&list).Error; err != nil {
+	if err := r.db.Where("user_id = ?", userID).Find(&list).Error; err != nil {
 		return nil, err
 	}
 	return list, nil
 }
 
+func (r *GormConnectionRepository) TouchActive(id uint, at time.Time) error {
+	return r.db.Model(&entities.ActiveConnection{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (r *GormConnectionRepository) SwapActivePassword(id uint, oldRef, newRef string) (bool, error) {
+	res := r.db.Model(&entities.ActiveConnection{}).
+		Where("id = ? AND password = ?", id, oldRef).
+		Update("password", newRef)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
 func (r *GormConnectionRepository) LogConnection(log *entities.ConnectionLog) error {
 	log.Timestamp = time.Now()
 	return r.db.Create(log).Error
@@ -77,7 +110,7 @@ func (r *GormConnectionRepository) LogConnection(log *entities.ConnectionLog) er
 
 func (r *GormConnectionRepository) GetLogsByUserID(userID uint, limit, offset int) ([]*entities.ConnectionLog, error) {
 	var logs []*entities.ConnectionLog
-	q := r.db.Where("user_id = ?", userID).Order("created_at DESC").Offset(offset)
+	q := r.db.Where("user_id = ?", userID).Order("timestamp DESC").Offset(offset)
 	if limit > 0 {
 		q = q.Limit(limit)
 	}
@@ -102,3 +135,100 @@ func (r *GormConnectionRepository) CountLogsByUserID(userID uint) (int64, error)
 	}
 	return cnt, nil
 }
+
+// logsQuery applies ConnectionLogFilter conditions (without pagination)
+func (r *GormConnectionRepository) logsQuery(f repositories.ConnectionLogFilter) *gorm.DB {
+	q := r.db.Model(&entities.ConnectionLog{})
+	if f.UserID != nil {
+		q = q.Where("user_id = ?", *f.UserID)
+	}
+	if f.StartDate != nil {
+		q = q.Where("timestamp >= ?", *f.StartDate)
+	}
+	if f.EndDate != nil {
+		q = q.Where("timestamp < ?", *f.EndDate)
+	}
+	if f.Status != nil {
+		q = q.Where("status = ?", *f.Status)
+	}
+	if f.Server != nil {
+		q = q.Where("server = ?", *f.Server)
+	}
+	if f.Manager != nil {
+		q = q.Where("manager = ?", *f.Manager)
+	}
+	if f.ServerScope != nil && !f.ServerScope.Global {
+		clause, args := serverScopeClause(f.ServerScope)
+		q = q.Where(clause, args...)
+	}
+	return q
+}
+
+// serverScopeClause translates the scope rules into SQL: any rule, every server
+// set of the rule, any pattern of the set (patterns use * as wildcard)
+func serverScopeClause(scope *entities.AccessScope) (string, []interface{}) {
+	if len(scope.Rules) == 0 {
+		return "1 = 0", nil
+	}
+	var rules []string
+	var args []interface{}
+	for _, rule := range scope.Rules {
+		sets := []string{"1 = 1"}
+		for _, set := range rule.ServerSets {
+			var likes []string
+			for _, p := range set {
+				likes = append(likes, "LOWER(server) LIKE ? ESCAPE '!'")
+				args = append(args, likePattern(p))
+			}
+			if len(likes) == 0 {
+				likes = append(likes, "1 = 0")
+			}
+			sets = append(sets, "("+strings.Join(likes, " OR ")+")")
+		}
+		rules = append(rules, "("+strings.Join(sets, " AND ")+")")
+	}
+	return strings.Join(rules, " OR "), args
+}
+
+func likePattern(p string) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "*", "%")
+	return r.Replace(strings.ToLower(p))
+}
+
+func (r *GormConnectionRepository) ListLogs(f repositories.ConnectionLogFilter) ([]*entities.ConnectionLog, int64, error) {
+	var total int64
+	if err := r.logsQuery(f).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	offset := f.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	var logs []*entities.ConnectionLog
+	if err := r.logsQuery(f).Order("timestamp DESC, id DESC").Limit(limit).Offset(offset).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
+
+func (r *GormConnectionRepository) StreamLogs(f repositories.ConnectionLogFilter, fn func(*entities.ConnectionLog) error) error {
+	rows, err := r.logsQuery(f).Order("timestamp DESC, id DESC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l entities.ConnectionLog
+		if err := r.db.ScanRows(rows, &l); err != nil {
+			return err
+		}
+		if err := fn(&l); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
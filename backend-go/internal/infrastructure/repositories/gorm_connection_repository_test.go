@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	ports "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGormConnectionRepository_ListAndStreamLogsWithFilters(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ConnectionLog{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	logs := []entities.ConnectionLog{
+		{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "a", DBUser: "sa", Timestamp: base, Status: "connected"},
+		{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "a", DBUser: "sa", Timestamp: base.Add(time.Hour), Status: "disconnected"},
+		{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "b", DBUser: "sa", Timestamp: base.AddDate(0, 0, 1), Status: "connected"},
+		{UserID: 2, Manager: "mssql", Driver: "mssql", Server: "a", DBUser: "sa", Timestamp: base, Status: "connected"},
+	}
+	// insert directly: LogConnection would overwrite the timestamp with time.Now()
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatalf("seed logs: %v", err)
+	}
+
+	repo := NewGormConnectionRepository(db)
+
+	user := uint(1)
+	list, total, err := repo.ListLogs(ports.ConnectionLogFilter{UserID: &user, Limit: 2})
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	if total != 3 || len(list) != 2 {
+		t.Fatalf("expected total 3 and page of 2, got total=%d len=%d", total, len(list))
+	}
+	if !list[0].Timestamp.After(list[1].Timestamp) {
+		t.Fatalf("expected newest first")
+	}
+
+	server, status := "a", "connected"
+	end := base.Add(2 * time.Hour)
+	list, total, err = repo.ListLogs(ports.ConnectionLogFilter{Server: &server, Status: &status, StartDate: &base, EndDate: &end})
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	if total != 2 || len(list) != 2 {
+		t.Fatalf("expected 2 matches across users, got total=%d len=%d", total, len(list))
+	}
+
+	var streamed int
+	if err := repo.StreamLogs(ports.ConnectionLogFilter{UserID: &user, Limit: 1}, func(l *entities.ConnectionLog) error {
+		streamed++
+		return nil
+	}); err != nil {
+		t.Fatalf("stream logs: %v", err)
+	}
+	if streamed != 3 {
+		t.Fatalf("expected stream to ignore pagination and return 3 rows, got %d", streamed)
+	}
+
+	// a scoped viewer only sees servers matched by its rules
+	scope := &entities.AccessScope{Rules: []entities.ScopeRule{{ServerSets: [][]string{{"B", "c*"}}}}}
+	if _, total, err = repo.ListLogs(ports.ConnectionLogFilter{ServerScope: scope}); err != nil || total != 1 {
+		t.Fatalf("expected only server b, got total=%d err=%v", total, err)
+	}
+	if _, total, _ = repo.ListLogs(ports.ConnectionLogFilter{ServerScope: &entities.AccessScope{}}); total != 0 {
+		t.Fatalf("expected no rows without any grant, got %d", total)
+	}
+}
+
+func TestGormConnectionRepository_SwapActivePasswordIsCompareAndSwap(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ActiveConnection{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	repo := NewGormConnectionRepository(db)
+	conn := &entities.ActiveConnection{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "s", DBUser: "sa", Password: "old", IsConnected: true}
+	if err := repo.CreateActive(conn); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if ok, err := repo.SwapActivePassword(conn.ID, "stale", "new"); err != nil || ok {
+		t.Fatalf("expected no swap when the stored value changed, ok=%v err=%v", ok, err)
+	}
+	if ok, err := repo.SwapActivePassword(conn.ID, "old", "new"); err != nil || !ok {
+		t.Fatalf("expected swap, ok=%v err=%v", ok, err)
+	}
+	got, _ := repo.GetActiveByUserIDAndManager(1, "mssql")
+	if got == nil || got.Password != "new" {
+		t.Fatalf("expected password to be replaced, got %+v", got)
+	}
+}
+
+func TestGormConnectionRepository_UpdateActiveHealthTouchesOnlyHealth(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.ActiveConnection{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	repo := NewGormConnectionRepository(db)
+	conn := &entities.ActiveConnection{UserID: 1, Manager: "mssql", Driver: "mssql", Server: "s", DBUser: "sa", Password: "old", IsConnected: true}
+	if err := repo.CreateActive(conn); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// the monitor works on a copy read before the password was rotated and the connection used
+	stale := *conn
+	used := time.Now()
+	if ok, _ := repo.SwapActivePassword(conn.ID, "old", "new"); !ok {
+		t.Fatalf("expected swap")
+	}
+	_ = repo.TouchActive(conn.ID, used)
+
+	checked := time.Now()
+	stale.IsConnected, stale.HealthStatus, stale.LastHealthCheck, stale.LastDisconnected = false, entities.HealthUnhealthy, &checked, &checked
+	if err := repo.UpdateActiveHealth(&stale); err != nil {
+		t.Fatalf("update health: %v", err)
+	}
+
+	if got, _ := repo.GetActiveByUserIDAndManager(1, "mssql"); got != nil {
+		t.Fatalf("an unhealthy connection is not active, got %+v", got)
+	}
+	got, err := repo.FindActiveByUserAndManager(1, "mssql")
+	if err != nil || got == nil {
+		t.Fatalf("expected to find the unhealthy connection to close it, err=%v", err)
+	}
+	if got.Password != "new" || got.LastUsedAt == nil || got.HealthStatus != entities.HealthUnhealthy || got.IsConnected {
+		t.Fatalf("expected only the health columns to change, got %+v", got)
+	}
+}
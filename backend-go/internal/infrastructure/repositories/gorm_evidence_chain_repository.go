@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	repoport "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"gorm.io/gorm"
+)
+
+// chainMu serializes sealing so two records never get the same sequence. The
+// chain assumes a single writer process per database.
+var chainMu sync.Mutex
+
+// sealRecord links an already stored record to the end of its chain
+func sealRecord(db *gorm.DB, chain string, rec entities.ChainedRecord) error {
+	chainMu.Lock()
+	defer chainMu.Unlock()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var last entities.ChainLink
+		if err := tx.Table(chain).Select("chain_seq, hash").Where("chain_seq > 0").
+			Order("chain_seq DESC").Limit(1).Scan(&last).Error; err != nil {
+			return err
+		}
+		link := rec.Link()
+		link.ChainSeq, link.PrevHash = last.ChainSeq+1, last.Hash
+		link.Hash = entities.ChainHash(chain, link.ChainSeq, link.PrevHash, rec.ChainContent())
+		return tx.Model(rec).UpdateColumns(map[string]interface{}{
+			"chain_seq": link.ChainSeq,
+			"prev_hash": link.PrevHash,
+			"hash":      link.Hash,
+		}).Error
+	})
+}
+
+// unsealed selects the records of a chain that can be sealed
+func unsealed(db *gorm.DB, chain string) *gorm.DB {
+	q := db.Where("COALESCE(chain_seq, 0) = 0")
+	if chain == entities.ChainAuditRuns {
+		q = q.Where("status <> ?", "running")
+	}
+	return q
+}
+
+type GormEvidenceChainRepository struct {
+	db *gorm.DB
+}
+
+func NewGormEvidenceChainRepository(db *gorm.DB) *GormEvidenceChainRepository {
+	return &GormEvidenceChainRepository{db: db}
+}
+
+func (r *GormEvidenceChainRepository) SealPending(chain string) (int, error) {
+	var recs []entities.ChainedRecord
+	switch chain {
+	case entities.ChainAdminActions:
+		var list []entities.AdminActionLog
+		if err := unsealed(r.db, chain).Order("id").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			recs = append(recs, &list[i])
+		}
+	case entities.ChainAuditRuns:
+		var list []entities.AuditRun
+		if err := unsealed(r.db, chain).Order("id").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			recs = append(recs, &list[i])
+		}
+	case entities.ChainAuditResults:
+		var list []entities.AuditScriptResult
+		if err := unsealed(r.db, chain).Order("id").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			recs = append(recs, &list[i])
+		}
+	default:
+		return 0, fmt.Errorf("unknown evidence chain %q", chain)
+	}
+	for i, rec := range recs {
+		if err := sealRecord(r.db, chain, rec); err != nil {
+			return i, err
+		}
+	}
+	return len(recs), nil
+}
+
+func (r *GormEvidenceChainRepository) ListLinks(chain string, afterSeq uint64, limit int) ([]repoport.ChainRecord, error) {
+	q := r.db.Where("chain_seq > ?", afterSeq).Order("chain_seq").Limit(limit)
+	var out []repoport.ChainRecord
+	switch chain {
+	case entities.ChainAdminActions:
+		var list []entities.AdminActionLog
+		if err := q.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for i := range list {
+			out = append(out, repoport.ChainRecord{RecordID: list[i].ID, ChainLink: list[i].ChainLink, Content: list[i].ChainContent()})
+		}
+	case entities.ChainAuditRuns:
+		var list []entities.AuditRun
+		if err := q.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for i := range list {
+			out = append(out, repoport.ChainRecord{RecordID: list[i].ID, ChainLink: list[i].ChainLink, Content: list[i].ChainContent()})
+		}
+	case entities.ChainAuditResults:
+		var list []entities.AuditScriptResult
+		if err := q.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for i := range list {
+			out = append(out, repoport.ChainRecord{RecordID: list[i].ID, ChainLink: list[i].ChainLink, Content: list[i].ChainContent()})
+		}
+	default:
+		return nil, fmt.Errorf("unknown evidence chain %q", chain)
+	}
+	return out, nil
+}
+
+func (r *GormEvidenceChainRepository) LastLink(chain string) (uint64, string, error) {
+	var last entities.ChainLink
+	if err := r.db.Table(chain).Select("chain_seq, hash").Where("chain_seq > 0").
+		Order("chain_seq DESC").Limit(1).Scan(&last).Error; err != nil {
+		return 0, "", err
+	}
+	return last.ChainSeq, last.Hash, nil
+}
+
+func (r *GormEvidenceChainRepository) CountUnsealed(chain string) (int64, error) {
+	var n int64
+	err := r.db.Table(chain).Where("COALESCE(chain_seq, 0) = 0").Count(&n).Error
+	return n, err
+}
+
+func (r *GormEvidenceChainRepository) CreateCheckpoint(cp *entities.ChainCheckpoint) error {
+	return r.db.Create(cp).Error
+}
+
+func (r *GormEvidenceChainRepository) ListCheckpoints(chain string) ([]entities.ChainCheckpoint, error) {
+	var list []entities.ChainCheckpoint
+	if err := r.db.Where("chain = ?", chain).Order("seq, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
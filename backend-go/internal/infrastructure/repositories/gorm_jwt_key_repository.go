@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormJWTKeyRepository stores the retire time of rotated JWT signing keys
+type GormJWTKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewGormJWTKeyRepository(db *gorm.DB) *GormJWTKeyRepository {
+	return &GormJWTKeyRepository{db: db}
+}
+
+// RetireAt inserts proposed unless a row for kid exists (several instances may
+// start at once) and returns the stored time
+func (r *GormJWTKeyRepository) RetireAt(kid string, proposed time.Time) (time.Time, error) {
+	row := entities.JWTKeyRetirement{KeyID: kid, RetireAt: proposed.UTC()}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		return time.Time{}, err
+	}
+	var stored entities.JWTKeyRetirement
+	if err := r.db.First(&stored, "key_id = ?", kid).Error; err != nil {
+		return time.Time{}, err
+	}
+	return stored.RetireAt, nil
+}
+
+func (r *GormJWTKeyRepository) Forget(kid string) error {
+	return r.db.Where("key_id = ?", kid).Delete(&entities.JWTKeyRetirement{}).Error
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGormJWTKeyRepository_KeepsFirstRetireTime(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	if err := db.AutoMigrate(&entities.JWTKeyRetirement{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	repo := NewGormJWTKeyRepository(db)
+
+	first := time.Date(2026, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	got, err := repo.RetireAt("k1", first)
+	if err != nil || !got.Equal(first) {
+		t.Fatalf("expected %v, got %v (%v)", first, got, err)
+	}
+	got, err = repo.RetireAt("k1", first.Add(time.Hour))
+	if err != nil || !got.Equal(first) {
+		t.Fatalf("expected the first retire time %v to be kept, got %v (%v)", first, got, err)
+	}
+
+	if err := repo.Forget("k1"); err != nil {
+		t.Fatalf("forget: %v", err)
+	}
+	later := first.Add(24 * time.Hour)
+	if got, err := repo.RetireAt("k1", later); err != nil || !got.Equal(later) {
+		t.Fatalf("expected a new retire time after forget, got %v (%v)", got, err)
+	}
+}
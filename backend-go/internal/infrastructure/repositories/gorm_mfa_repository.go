@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// GormMFARepository persists 2FA enrollments, recovery codes and login challenges
+type GormMFARepository struct {
+	db *gorm.DB
+}
+
+func NewGormMFARepository(db *gorm.DB) *GormMFARepository {
+	return &GormMFARepository{db: db}
+}
+
+func (r *GormMFARepository) GetTOTP(userID uint) (*entities.UserTOTP, error) {
+	var list []entities.UserTOTP
+	if err := r.db.Where("user_id = ?", userID).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *GormMFARepository) SaveTOTP(t *entities.UserTOTP) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", t.UserID).Delete(&entities.UserTOTP{}).Error; err != nil {
+			return err
+		}
+		t.ID = 0
+		return tx.Create(t).Error
+	})
+}
+
+func (r *GormMFARepository) MarkTOTPUsed(userID uint, step int64) (bool, error) {
+	res := r.db.Model(&entities.UserTOTP{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *GormMFARepository) ResetUser(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.UserTOTP{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.LoginChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error
+	})
+}
+
+func (r *GormMFARepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entities.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]entities.RecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, entities.RecoveryCode{UserID: userID, CodeHash: h})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *GormMFARepository) UseRecoveryCode(userID uint, hash string, at time.Time) (bool, error) {
+	res := r.db.Model(&entities.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *GormMFARepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&entities.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+func (r *GormMFARepository) CreateChallenge(c *entities.LoginChallenge) error {
+	return r.db.Create(c).Error
+}
+
+func (r *GormMFARepository) GetChallenge(tokenHash string) (*entities.LoginChallenge, error) {
+	var list []entities.LoginChallenge
+	if err := r.db.Where("token_hash = ?", tokenHash).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *GormMFARepository) IncrementChallengeAttempts(id uint) error {
+	return r.db.Model(&entities.LoginChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (r *GormMFARepository) ConsumeChallenge(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&entities.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
@@ -23,7 +23,12 @@ func (r *GormPermissionRepository) Update(permission *entities.Permission) error
 }
 
 func (r *GormPermissionRepository) Delete(id uint) error {
-	return r.db.Delete(&entities.Permission{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_permissions WHERE permission_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entities.Permission{}, id).Error
+	})
 }
 
 func (r *GormPermissionRepository) GetByID(id uint) (*entities.Permission, error) {
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	repoport "github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/ports/repositories"
+	"gorm.io/gorm"
+)
+
+type GormRBACTransactor struct {
+	db *gorm.DB
+}
+
+func NewGormRBACTransactor(db *gorm.DB) *GormRBACTransactor {
+	return &GormRBACTransactor{db: db}
+}
+
+// InTransaction hands fn repositories that share one transaction. Their own
+// nested Transaction calls become savepoints of it.
+func (t *GormRBACTransactor) InTransaction(fn func(roles repoport.RoleRepository, perms repoport.PermissionRepository, scopes repoport.ResourceScopeRepository) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormRoleRepository(tx), NewGormPermissionRepository(tx), NewGormResourceScopeRepository(tx))
+	})
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// GormResourceScopeRepository stores server groups and permission scopes
+type GormResourceScopeRepository struct {
+	db *gorm.DB
+}
+
+func NewGormResourceScopeRepository(db *gorm.DB) *GormResourceScopeRepository {
+	return &GormResourceScopeRepository{db: db}
+}
+
+func (r *GormResourceScopeRepository) ListServerGroups() ([]entities.ServerGroup, error) {
+	var groups []entities.ServerGroup
+	if err := r.db.Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *GormResourceScopeRepository) GetServerGroup(id uint) (*entities.ServerGroup, error) {
+	var groups []entities.ServerGroup
+	if err := r.db.Where("id = ?", id).Limit(1).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	return &groups[0], nil
+}
+
+func (r *GormResourceScopeRepository) CreateServerGroup(g *entities.ServerGroup) error {
+	return r.db.Create(g).Error
+}
+
+func (r *GormResourceScopeRepository) UpdateServerGroup(g *entities.ServerGroup) error {
+	return r.db.Save(g).Error
+}
+
+func (r *GormResourceScopeRepository) DeleteServerGroup(id uint) error {
+	return r.db.Delete(&entities.ServerGroup{}, id).Error
+}
+
+func (r *GormResourceScopeRepository) CountScopesByServerGroup(groupID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&entities.PermissionScope{}).Where("server_group_id = ?", groupID).Count(&n).Error
+	return n, err
+}
+
+// ListScopesByRoles runs on every scoped request; it is a single indexed query
+func (r *GormResourceScopeRepository) ListScopesByRoles(roleIDs []uint) ([]entities.PermissionScope, error) {
+	if len(roleIDs) == 0 {
+		return nil, nil
+	}
+	var scopes []entities.PermissionScope
+	if err := r.db.Preload("ServerGroup").Where("role_id IN ?", roleIDs).Order("id").Find(&scopes).Error; err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+func (r *GormResourceScopeRepository) ListScopes(roleID, permissionID uint) ([]entities.PermissionScope, error) {
+	var scopes []entities.PermissionScope
+	if err := r.db.Preload("ServerGroup").Where("role_id = ? AND permission_id = ?", roleID, permissionID).Order("id").Find(&scopes).Error; err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+func (r *GormResourceScopeRepository) ReplaceScopes(roleID, permissionID uint, scopes []entities.PermissionScope) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ? AND permission_id = ?", roleID, permissionID).Delete(&entities.PermissionScope{}).Error; err != nil {
+			return err
+		}
+		for i := range scopes {
+			scopes[i].ID = 0
+			scopes[i].RoleID = roleID
+			scopes[i].PermissionID = permissionID
+			if err := tx.Omit("ServerGroup").Create(&scopes[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *GormResourceScopeRepository) DeleteScopesByRole(roleID uint) error {
+	return r.db.Where("role_id = ?", roleID).Delete(&entities.PermissionScope{}).Error
+}
+
+func (r *GormResourceScopeRepository) DeleteScopesByPermission(permissionID uint) error {
+	return r.db.Where("permission_id = ?", permissionID).Delete(&entities.PermissionScope{}).Error
+}
@@ -1,8 +1,11 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GormRoleRepository persists roles and user-role relations
@@ -22,8 +25,17 @@ func (r *GormRoleRepository) Update(role *entities.Role) error {
 	return r.db.Save(role).Error
 }
 
+// Delete removes the role and its user assignments
 func (r *GormRoleRepository) Delete(id uint) error {
-	return r.db.Delete(&entities.Role{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&entities.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entities.Role{}, id).Error
+	})
 }
 
 func (r *GormRoleRepository) GetByID(id uint) (*entities.Role, error) {
@@ -37,15 +49,17 @@ func (r *GormRoleRepository) GetByID(id uint) (*entities.Role, error) {
 	return &role, nil
 }
 
+// GetByName is also used per request when authenticating API keys; Find avoids
+// logging missing roles as errors
 func (r *GormRoleRepository) GetByName(name string) (*entities.Role, error) {
-	var role entities.Role
-	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
-		}
+	var roles []entities.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).Limit(1).Find(&roles).Error; err != nil {
 		return nil, err
 	}
-	return &role, nil
+	if len(roles) == 0 {
+		return nil, nil
+	}
+	return &roles[0], nil
 }
 
 func (r *GormRoleRepository) List() ([]entities.Role, error) {
@@ -57,8 +71,11 @@ func (r *GormRoleRepository) List() ([]entities.Role, error) {
 }
 
 func (r *GormRoleRepository) AssignToUser(userID uint, roleID uint) error {
-	ur := entities.UserRole{UserID: userID, RoleID: roleID}
-	return r.db.Create(&ur).Error
+	return r.GrantToUser(&entities.UserRole{UserID: userID, RoleID: roleID})
+}
+
+func (r *GormRoleRepository) GrantToUser(grant *entities.UserRole) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(grant).Error
 }
 
 func (r *GormRoleRepository) RevokeFromUser(userID uint, roleID uint) error {
@@ -67,8 +84,52 @@ func (r *GormRoleRepository) RevokeFromUser(userID uint, roleID uint) error {
 
 func (r *GormRoleRepository) GetUserRoles(userID uint) ([]entities.Role, error) {
 	var roles []entities.Role
-	// Join via user_roles table
-	if err := r.db.Joins("JOIN user_roles ur ON ur.role_id = roles.id").Where("ur.user_id = ?", userID).Preload("Permissions").Find(&roles).Error; err != nil {
+	// Join via user_roles table; windows are stored in UTC
+	now := time.Now().UTC()
+	if err := r.db.Joins("JOIN user_roles ur ON ur.role_id = roles.id").Where("ur.user_id = ?", userID).
+		Where("(ur.valid_from IS NULL OR ur.valid_from <= ?) AND (ur.valid_until IS NULL OR ur.valid_until > ?)", now, now).
+		Preload("Permissions").Order("roles.id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *GormRoleRepository) ListRoleUsers(roleID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.Model(&entities.UserRole{}).Where("role_id = ?", roleID).Order("user_id").Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *GormRoleRepository) ListUserGrants(userID uint) ([]entities.UserRole, error) {
+	var grants []entities.UserRole
+	if err := r.db.Where("user_id = ?", userID).Order("role_id").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *GormRoleRepository) ListExpiredGrants(now time.Time) ([]entities.UserRole, error) {
+	var grants []entities.UserRole
+	if err := r.db.Where("valid_until IS NOT NULL AND valid_until <= ?", now.UTC()).Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+func (r *GormRoleRepository) DeleteExpiredGrant(userID, roleID uint, now time.Time) (bool, error) {
+	res := r.db.Where("user_id = ? AND role_id = ? AND valid_until IS NOT NULL AND valid_until <= ?", userID, roleID, now.UTC()).Delete(&entities.UserRole{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormRoleRepository) SetParent(roleID uint, parentID *uint) error {
+	return r.db.Model(&entities.Role{}).Where("id = ?", roleID).Update("parent_id", parentID).Error
+}
+
+func (r *GormRoleRepository) ListChildren(roleID uint) ([]entities.Role, error) {
+	var roles []entities.Role
+	if err := r.db.Where("parent_id = ?", roleID).Order("name ASC").Find(&roles).Error; err != nil {
 		return nil, err
 	}
 	return roles, nil
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// GormRoleRequestRepository stores just-in-time role requests
+type GormRoleRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRoleRequestRepository(db *gorm.DB) *GormRoleRequestRepository {
+	return &GormRoleRequestRepository{db: db}
+}
+
+func (r *GormRoleRequestRepository) Create(req *entities.RoleRequest) error {
+	return r.db.Omit("Role").Create(req).Error
+}
+
+func (r *GormRoleRequestRepository) GetByID(id uint) (*entities.RoleRequest, error) {
+	var reqs []entities.RoleRequest
+	if err := r.db.Preload("Role").Where("id = ?", id).Limit(1).Find(&reqs).Error; err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	return &reqs[0], nil
+}
+
+func (r *GormRoleRequestRepository) List(userID *uint, status string, limit, offset int) ([]entities.RoleRequest, error) {
+	q := r.db.Preload("Role").Model(&entities.RoleRequest{})
+	if userID != nil {
+		q = q.Where("user_id = ?", *userID)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if offset > 0 {
+		q = q.Offset(offset)
+	}
+	var reqs []entities.RoleRequest
+	if err := q.Order("created_at DESC, id DESC").Find(&reqs).Error; err != nil {
+		return nil, err
+	}
+	return reqs, nil
+}
+
+// Decide is a compare-and-swap on the pending status so two approvers cannot
+// both act on the same request
+func (r *GormRoleRequestRepository) Decide(req *entities.RoleRequest) (bool, error) {
+	res := r.db.Model(&entities.RoleRequest{}).
+		Where("id = ? AND status = ?", req.ID, entities.RoleRequestPending).
+		Updates(map[string]interface{}{
+			"status":        req.Status,
+			"decided_by":    req.DecidedBy,
+			"decided_at":    req.DecidedAt,
+			"decision_note": req.DecisionNote,
+			"valid_until":   req.ValidUntil,
+		})
+	return res.RowsAffected > 0, res.Error
+}
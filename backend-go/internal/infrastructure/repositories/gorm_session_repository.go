@@ -40,6 +40,9 @@ func (r *GormSessionRepository) GetActiveByUserID(userID uint) (*entities.Sessio
 func (r *GormSessionRepository) GetByToken(token string) (*entities.Session, error) {
 	var s entities.Session
 	if err := r.db.Where("token = ?", token).First(&s).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
 		return nil, err
 	}
 	return &s, nil
@@ -61,3 +64,130 @@ func (r *GormSessionRepository) ListActiveSessions() ([]entities.Session, error)
 	}
 	return list, nil
 }
+
+func (r *GormSessionRepository) GetByRefreshHash(hash string) (*entities.Session, error) {
+	var s entities.Session
+	if err := r.db.Where("refresh_token_hash = ?", hash).First(&s).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// MarkRotated is a compare-and-swap on rotated_at so two concurrent refreshes
+// with the same token cannot both succeed
+func (r *GormSessionRepository) MarkRotated(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&entities.Session{}).
+		Where("id = ? AND rotated_at IS NULL", id).
+		Updates(map[string]interface{}{"rotated_at": at, "is_active": false})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *GormSessionRepository) RevokeFamily(familyID string, at time.Time) error {
+	return r.db.Model(&entities.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{"is_active": false, "revoked_at": at}).Error
+}
+
+func (r *GormSessionRepository) GetByID(id uint) (*entities.Session, error) {
+	var s entities.Session
+	if err := r.db.First(&s, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *GormSessionRepository) RevokeByID(id uint, at time.Time) error {
+	return r.db.Model(&entities.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"is_active": false, "revoked_at": at}).Error
+}
+
+func (r *GormSessionRepository) RevokeByUser(userID uint, at time.Time) (int64, error) {
+	return r.revoke(at, "user_id = ?", userID)
+}
+
+func (r *GormSessionRepository) ExpireAccessByUser(userID uint, at time.Time) (int64, error) {
+	res := r.db.Model(&entities.Session{}).
+		Where("user_id = ? AND is_active = ? AND revoked_at IS NULL AND rotated_at IS NULL", userID, true).
+		Where("expires_at IS NULL OR expires_at > ?", at).
+		Update("expires_at", at)
+	return res.RowsAffected, res.Error
+}
+
+func (r *GormSessionRepository) RevokeAll(at time.Time) (int64, error) {
+	return r.revoke(at, "1 = 1")
+}
+
+// ListLiveByUser skips rotated rows (superseded by a refresh) and logins whose
+// refresh token (or, for legacy rows, access token) has expired
+func (r *GormSessionRepository) ListLiveByUser(userID uint, now time.Time) ([]entities.Session, error) {
+	var list []entities.Session
+	err := r.db.Where("user_id = ? AND is_active = ? AND revoked_at IS NULL AND rotated_at IS NULL", userID, true).
+		Where("(refresh_expires_at IS NOT NULL AND refresh_expires_at > ?) OR (refresh_expires_at IS NULL AND (expires_at IS NULL OR expires_at > ?))", now, now).
+		Order("COALESCE(started_at, created_at) ASC, id ASC").
+		Find(&list).Error
+	return list, err
+}
+
+// CreateWithinLimit serializes the logins of a user by locking its users row
+// first (a row lock on MySQL, the write lock on SQLite), so two concurrent
+// logins cannot both see room under the session limit
+func (r *GormSessionRepository) CreateWithinLimit(s *entities.Session, now, activeSince time.Time, decide func(active []entities.Session) ([]entities.Session, error)) ([]entities.Session, error) {
+	var replaced []entities.Session
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE users SET id = id WHERE id = ?", s.UserID).Error; err != nil {
+			return err
+		}
+		var active []entities.Session
+		err := tx.Where("user_id = ? AND is_active = ? AND revoked_at IS NULL AND rotated_at IS NULL", s.UserID, true).
+			Where("refresh_expires_at IS NULL OR refresh_expires_at > ?", now).
+			Where("expires_at IS NULL OR expires_at > ?", activeSince).
+			Order("COALESCE(started_at, created_at) ASC, id ASC").
+			Find(&active).Error
+		if err != nil {
+			return err
+		}
+		if replaced, err = decide(active); err != nil {
+			return err
+		}
+		for _, old := range replaced {
+			q := tx.Model(&entities.Session{}).Where("revoked_at IS NULL")
+			if old.FamilyID != "" {
+				q = q.Where("family_id = ?", old.FamilyID)
+			} else {
+				q = q.Where("id = ?", old.ID)
+			}
+			if err := q.Updates(map[string]interface{}{"is_active": false, "revoked_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(s).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// revoke marks every not-yet-revoked session matching the condition as revoked
+// and reports how many of them were still active
+func (r *GormSessionRepository) revoke(at time.Time, cond string, args ...interface{}) (int64, error) {
+	var active int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entities.Session{}).Where(cond, args...).Where("is_active = ?", true).Count(&active).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entities.Session{}).Where(cond, args...).Where("revoked_at IS NULL").
+			Updates(map[string]interface{}{"is_active": false, "revoked_at": at}).Error
+	})
+	return active, err
+}
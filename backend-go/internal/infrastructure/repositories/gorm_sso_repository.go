@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yken-neky/MicroSQL-AGo/backend-go/internal/domain/entities"
+	"gorm.io/gorm"
+)
+
+// GormSSORepository stores OIDC identity links and pending SSO logins
+type GormSSORepository struct {
+	db *gorm.DB
+}
+
+func NewGormSSORepository(db *gorm.DB) *GormSSORepository {
+	return &GormSSORepository{db: db}
+}
+
+func (r *GormSSORepository) CreateState(s *entities.OIDCLoginState) error {
+	return r.db.Create(s).Error
+}
+
+// ConsumeState flags the state as used in the same statement that checks it, so
+// a callback replayed in parallel cannot complete the login twice
+func (r *GormSSORepository) ConsumeState(hash string, now time.Time) (*entities.OIDCLoginState, error) {
+	var list []entities.OIDCLoginState
+	if err := r.db.Where("state_hash = ?", hash).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	s := &list[0]
+	res := r.db.Model(&entities.OIDCLoginState{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", s.ID, now).
+		Update("used_at", now)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected != 1 {
+		return nil, nil
+	}
+	s.UsedAt = &now
+	return s, nil
+}
+
+func (r *GormSSORepository) GetIdentity(issuer, subject string) (*entities.UserIdentity, error) {
+	var list []entities.UserIdentity
+	if err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).Limit(1).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *GormSSORepository) CreateIdentity(i *entities.UserIdentity) error {
+	return r.db.Create(i).Error
+}
+
+func (r *GormSSORepository) TouchIdentity(id uint, email string, at time.Time) error {
+	return r.db.Model(&entities.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+}
@@ -0,0 +1,37 @@
+// Package randtoken generates random secrets (refresh tokens, API keys, OIDC
+// state and nonces, JWT ids) from crypto/rand.
+package randtoken
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// New returns n random bytes as unpadded base64url. A crypto/rand failure is
+// returned, never a weak or empty token.
+func New(n int) (string, error) {
+	b, err := read(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hex returns n random bytes hex encoded
+func Hex(n int) (string, error) {
+	b, err := read(n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func read(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("crypto/rand: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,28 @@
+package randtoken
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestNewAndHex(t *testing.T) {
+	a, err := New(32)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	b, _ := New(32)
+	if a == b {
+		t.Fatalf("expected two tokens to differ")
+	}
+	if raw, err := base64.RawURLEncoding.DecodeString(a); err != nil || len(raw) != 32 {
+		t.Fatalf("expected 32 bytes of base64url, got %q (%v)", a, err)
+	}
+	h, err := Hex(16)
+	if err != nil {
+		t.Fatalf("hex: %v", err)
+	}
+	if raw, err := hex.DecodeString(h); err != nil || len(raw) != 16 {
+		t.Fatalf("expected 16 bytes of hex, got %q (%v)", h, err)
+	}
+}
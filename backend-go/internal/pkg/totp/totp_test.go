@@ -0,0 +1,51 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors (SHA1, seed "12345678901234567890"), last 6 digits
+func TestCodeAt_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		got, err := CodeAt(secret, Step(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Errorf("t=%d: want %s, got %s (%v)", unix, want, got, err)
+		}
+	}
+}
+
+func TestVerify_allowsSkewAndReportsStep(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Unix(1700000000, 0)
+	prev, _ := CodeAt(secret, Step(now)-1)
+
+	step, ok := Verify(secret, prev, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Fatalf("expected previous step to verify, got %d %v", step, ok)
+	}
+	if _, ok := Verify(secret, prev, now, 0); ok {
+		t.Fatalf("expected previous step to fail without skew")
+	}
+	if _, ok := Verify(secret, "12345", now, 1); ok {
+		t.Fatalf("expected short code to fail")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("MicroSQL AGo", "alice", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/MicroSQL%20AGo:alice?") || !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=MicroSQL+AGo") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}